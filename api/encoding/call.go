@@ -140,3 +140,13 @@ func (c *Call) RoutingDelegate() string {
 	}
 	return c.ic.req.RoutingDelegate
 }
+
+// Principal returns the authenticated identity of the caller, as established
+// by an authentication middleware. Returns an empty string if the request was
+// not authenticated.
+func (c *Call) Principal() string {
+	if c == nil {
+		return ""
+	}
+	return c.ic.principal
+}
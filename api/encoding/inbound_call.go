@@ -24,6 +24,7 @@ import (
 	"context"
 
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/principal"
 )
 
 // InboundCall holds information about the inbound call and its response.
@@ -34,6 +35,7 @@ import (
 type InboundCall struct {
 	resHeaders []keyValuePair
 	req        *transport.Request
+	principal  string
 }
 
 type inboundCallKey struct{} // context key for *InboundCall
@@ -42,7 +44,7 @@ type inboundCallKey struct{} // context key for *InboundCall
 //
 // A request context is returned and must be used in place of the original.
 func NewInboundCall(ctx context.Context) (context.Context, *InboundCall) {
-	call := &InboundCall{}
+	call := &InboundCall{principal: principal.FromContext(ctx)}
 	return context.WithValue(ctx, inboundCallKey{}, call), call
 }
 
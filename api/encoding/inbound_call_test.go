@@ -29,6 +29,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/principal"
 )
 
 func TestInboundCallReadFromRequest(t *testing.T) {
@@ -57,6 +58,7 @@ func TestInboundCallReadFromRequest(t *testing.T) {
 	assert.Equal(t, "shardKey", call.ShardKey())
 	assert.Equal(t, "routingKey", call.RoutingKey())
 	assert.Equal(t, "routingDelegate", call.RoutingDelegate())
+	assert.Equal(t, "", call.Principal())
 
 	assert.Equal(t, "World", call.Header("Hello"))
 	assert.Equal(t, "bar", call.Header("FOO"))
@@ -68,6 +70,12 @@ func TestInboundCallReadFromRequest(t *testing.T) {
 	assert.Equal(t, []string{"foo", "hello", "success"}, headerNames)
 }
 
+func TestInboundCallPrincipal(t *testing.T) {
+	ctx := principal.NewContext(context.Background(), "billing-admin")
+	ctx, _ = NewInboundCall(ctx)
+	assert.Equal(t, "billing-admin", CallFromContext(ctx).Principal())
+}
+
 func TestInboundCallWriteToResponse(t *testing.T) {
 	tests := []struct {
 		desc        string
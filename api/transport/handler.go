@@ -22,11 +22,11 @@ package transport
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"runtime/debug"
 	"time"
 
+	"go.uber.org/yarpc/internal/repanic"
 	"go.uber.org/yarpc/yarpcerrors"
 	"go.uber.org/zap/zapcore"
 )
@@ -98,6 +98,10 @@ type OnewayHandler interface {
 
 // DispatchUnaryHandler calls the handler h, recovering panics and timeout errors,
 // converting them to yarpc errors. All other errors are passed trough.
+//
+// Handlers registered with a dispatcher have their panics recovered, logged
+// and counted by the dispatcher itself, unless it is configured to propagate
+// them, in which case they are not recovered here either.
 func DispatchUnaryHandler(
 	ctx context.Context,
 	h UnaryHandler,
@@ -107,8 +111,11 @@ func DispatchUnaryHandler(
 ) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
+			if p, ok := r.(repanic.Panic); ok {
+				panic(p.Value)
+			}
 			log.Printf("Unary handler panicked: %v\n%s", r, debug.Stack())
-			err = fmt.Errorf("panic: %v", r)
+			err = yarpcerrors.InternalErrorf("panic: %v", r)
 		}
 	}()
 
@@ -125,7 +132,7 @@ func DispatchUnaryHandler(
 }
 
 // DispatchOnewayHandler calls the oneway handler, recovering from panics as
-// errors
+// errors, with the same exceptions as DispatchUnaryHandler.
 func DispatchOnewayHandler(
 	ctx context.Context,
 	h OnewayHandler,
@@ -133,8 +140,11 @@ func DispatchOnewayHandler(
 ) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
+			if p, ok := r.(repanic.Panic); ok {
+				panic(p.Value)
+			}
 			log.Printf("Oneway handler panicked: %v\n%s", r, debug.Stack())
-			err = fmt.Errorf("panic: %v", r)
+			err = yarpcerrors.InternalErrorf("panic: %v", r)
 		}
 	}()
 
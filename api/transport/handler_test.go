@@ -22,11 +22,12 @@ package transport
 
 import (
 	"context"
-	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/internal/repanic"
+	"go.uber.org/yarpc/yarpcerrors"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -83,8 +84,7 @@ func TestDispatchUnaryHandlerWithPanic(t *testing.T) {
 		time.Now(),
 		&Request{},
 		nil)
-	expectMsg := fmt.Sprintf("panic: %s", msg)
-	assert.Equal(t, err.Error(), expectMsg)
+	assert.Equal(t, yarpcerrors.InternalErrorf("panic: %s", msg), err)
 }
 
 func TestDispatchOnewayHandlerWithPanic(t *testing.T) {
@@ -97,6 +97,31 @@ func TestDispatchOnewayHandlerWithPanic(t *testing.T) {
 		context.Background(),
 		onewayHandlerFunc(handler),
 		nil)
-	expectMsg := fmt.Sprintf("panic: %s", msg)
-	assert.Equal(t, err.Error(), expectMsg)
+	assert.Equal(t, yarpcerrors.InternalErrorf("panic: %s", msg), err)
+}
+
+func TestDispatchHandlersPropagateRepanics(t *testing.T) {
+	p := repanic.Panic{Value: "crash"}
+
+	recovered := func(f func()) (r interface{}) {
+		defer func() { r = recover() }()
+		f()
+		return nil
+	}
+
+	assert.Equal(t, "crash", recovered(func() {
+		DispatchUnaryHandler(
+			context.Background(),
+			unaryHandlerFunc(func(context.Context, *Request, ResponseWriter) error { panic(p) }),
+			time.Now(),
+			&Request{},
+			nil)
+	}))
+
+	assert.Equal(t, "crash", recovered(func() {
+		DispatchOnewayHandler(
+			context.Background(),
+			onewayHandlerFunc(func(context.Context, *Request) error { panic(p) }),
+			nil)
+	}))
 }
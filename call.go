@@ -155,3 +155,10 @@ func (c *Call) RoutingKey() string {
 func (c *Call) RoutingDelegate() string {
 	return (*encoding.Call)(c).RoutingDelegate()
 }
+
+// Principal returns the authenticated identity of the caller, as established
+// by an authentication middleware. Returns an empty string if the request was
+// not authenticated.
+func (c *Call) Principal() string {
+	return (*encoding.Call)(c).Principal()
+}
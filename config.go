@@ -29,16 +29,21 @@ import (
 	"github.com/uber-go/tally"
 	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/internal/observability"
-	"go.uber.org/yarpc/internal/pally"
+	"go.uber.org/yarpc/internal/propagation"
+	"go.uber.org/yarpc/internal/ttl"
+	"go.uber.org/yarpc/x/metrics"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 const (
-	// Sleep between pushes to Tally metrics. At some point, we may want this
-	// to be configurable.
-	_tallyPushInterval = 500 * time.Millisecond
-	_packageName       = "yarpc"
+	// Sleep between pushes to Tally and other metrics sinks. At some point,
+	// we may want this to be configurable.
+	_metricsPushInterval = 500 * time.Millisecond
+	_packageName         = "yarpc"
+
+	_defaultRecentFailures      = 50
+	_defaultSlowestPerProcedure = 5
 )
 
 // LoggingConfig describes how logging should be configured.
@@ -49,6 +54,49 @@ type LoggingConfig struct {
 	// If supplied, ExtractContext is used to log request-scoped
 	// information carried on the context (e.g., trace and span IDs).
 	ContextExtractor func(context.Context) zapcore.Field
+	// Writes one structured line per sampled inbound request to an access
+	// log. By default, there is no access log.
+	AccessLog AccessLogConfig
+}
+
+// AccessLogConfig describes which inbound requests are written to the
+// access log. Each line has the caller, service, procedure, encoding,
+// transport, remote peer address, error code, request and response sizes,
+// and latency of the request.
+type AccessLogConfig struct {
+	// Logger for the access log, which is written at Info level. By
+	// default, the access log is disabled.
+	Zap *zap.Logger
+	// Fraction of requests written to the access log, from 0 to 1. By
+	// default, no requests are sampled, so only failed and slow requests
+	// are logged if enabled below. Set to 1 to log all requests.
+	SampleRate float64
+	// Sampling rates of individual procedures, overriding SampleRate. A
+	// rate of 0 logs no requests to the procedure other than failed and
+	// slow ones, even if SampleRate is higher.
+	ProcedureSampleRates map[string]float64
+	// Log all failed requests, regardless of sampling.
+	AlwaysLogErrors bool
+	// Log all requests which took at least this long, regardless of
+	// sampling. By default, slow requests are sampled like the others.
+	SlowThreshold time.Duration
+}
+
+func (c AccessLogConfig) options(name string) []observability.MiddlewareOption {
+	if c.Zap == nil {
+		return nil
+	}
+	procedures := make(map[string]float64, len(c.ProcedureSampleRates))
+	for procedure, rate := range c.ProcedureSampleRates {
+		procedures[procedure] = rate
+	}
+	return []observability.MiddlewareOption{observability.AccessLog(observability.AccessLogConfig{
+		Logger:               c.Zap.With(zap.String("dispatcher", name)),
+		SampleRate:           c.SampleRate,
+		ProcedureSampleRates: procedures,
+		LogErrors:            c.AlwaysLogErrors,
+		SlowThreshold:        c.SlowThreshold,
+	})}
 }
 
 func (c LoggingConfig) logger(name string) *zap.Logger {
@@ -74,30 +122,208 @@ type MetricsConfig struct {
 	// Tally scope used for pushing to M3 or StatsD-based systems. By
 	// default, metrics are collected in memory but not pushed.
 	Tally tally.Scope
+
+	// Additional sinks to push metrics to, alongside Tally. Use
+	// metrics.NewStatsDSink to emit the StatsD line protocol directly.
+	Sinks []metrics.Sink
+
+	// Overrides the bucket boundaries of latency histograms, keyed by metric
+	// name (for example, "success_latency_ms").
+	LatencyBuckets map[string][]time.Duration
+
+	// Overrides the bucket boundaries of payload size histograms, keyed by
+	// metric name (for example, "request_payload_bytes").
+	SizeBuckets map[string][]int64
+
+	// Labels the failures counter with the names of user-defined errors
+	// (see yarpcerrors.NamedErrorf). Outbound requests are labeled with the
+	// names returned by remote services, so only enable this if the names
+	// of all errors are known to be few. By default, the label is always
+	// "default".
+	ErrorNameLabels bool
 }
 
-func (c MetricsConfig) registry(name string, logger *zap.Logger) (*pally.Registry, context.CancelFunc) {
-	r := pally.NewRegistry(
-		pally.Labeled(pally.Labels{
+func (c MetricsConfig) registry(name string, logger *zap.Logger) (*metrics.Registry, context.CancelFunc) {
+	opts := []metrics.RegistryOption{
+		metrics.Labeled(metrics.Labels{
 			"component":  _packageName,
-			"dispatcher": pally.ScrubLabelValue(name),
+			"dispatcher": metrics.ScrubLabelValue(name),
 		}),
 		// Also expose all YARPC metrics via the default Prometheus registry.
-		pally.Federated(prometheus.DefaultRegisterer),
-	)
+		metrics.Federated(prometheus.DefaultRegisterer),
+	}
+	for metric, buckets := range c.LatencyBuckets {
+		opts = append(opts, metrics.LatencyBuckets(metric, buckets))
+	}
+	for metric, buckets := range c.SizeBuckets {
+		opts = append(opts, metrics.HistogramBuckets(metric, buckets))
+	}
+	r := metrics.NewRegistry(opts...)
 
-	if c.Tally == nil {
+	sinks := make([]metrics.Sink, 0, len(c.Sinks)+1)
+	if c.Tally != nil {
+		sinks = append(sinks, metrics.NewTallySink(c.Tally))
+	}
+	sinks = append(sinks, c.Sinks...)
+	if len(sinks) == 0 {
 		return r, func() {}
 	}
 
-	stop, err := r.Push(c.Tally, _tallyPushInterval)
+	stop, err := r.PushTo(_metricsPushInterval, sinks...)
 	if err != nil {
-		logger.Error("Failed to start pushing metrics to Tally.", zap.Error(err))
+		logger.Error("Failed to start pushing metrics.", zap.Error(err))
 		return r, func() {}
 	}
 	return r, stop
 }
 
+// PanicConfig describes how panics in inbound handlers should be handled.
+type PanicConfig struct {
+	// By default, the dispatcher recovers panics in inbound handlers and
+	// middleware, logs them with their stack trace, counts them in the
+	// "panics" metric, and fails the request with an Internal error.
+	//
+	// If Repanic is set, panics are still logged and counted, but then
+	// propagated, so that crash-only services exit. Note that some
+	// transports, like HTTP, serve unary requests from a server which
+	// recovers panics itself.
+	Repanic bool
+}
+
+func (c PanicConfig) options() []observability.MiddlewareOption {
+	if c.Repanic {
+		return []observability.MiddlewareOption{observability.Repanic()}
+	}
+	return nil
+}
+
+// RequestTrackingConfig describes how many requests the dispatcher keeps in
+// memory, so that the most recent failures and the slowest requests of each
+// procedure show up in Dispatcher.Introspect and the x/debug handler.
+type RequestTrackingConfig struct {
+	// Number of most recent failed requests, inbound or outbound, to keep.
+	// Defaults to 50. Negative values keep none.
+	RecentFailures int
+	// Number of slowest requests of each procedure to keep. Defaults to 5.
+	// Negative values keep none.
+	SlowestPerProcedure int
+}
+
+func (c RequestTrackingConfig) tracker() *observability.RequestTracker {
+	failures := c.RecentFailures
+	if failures == 0 {
+		failures = _defaultRecentFailures
+	}
+	slowest := c.SlowestPerProcedure
+	if slowest == 0 {
+		slowest = _defaultSlowestPerProcedure
+	}
+	if failures < 0 && slowest < 0 {
+		return nil
+	}
+	return observability.NewRequestTracker(failures, slowest)
+}
+
+// HeaderPropagationConfig describes which headers of inbound requests are
+// automatically added to the outbound calls made with their context, across
+// all encodings and transports.
+//
+// Headers set explicitly on outbound calls, with yarpc.WithHeader for
+// example, take precedence over propagated headers. Propagated headers are
+// added before outbound middleware runs, so middleware sees them.
+type HeaderPropagationConfig struct {
+	// Names of the headers to propagate. Header names are case-insensitive.
+	Headers []string
+
+	// Prefixes of the names of headers to propagate, like "x-tenant-".
+	Prefixes []string
+
+	// Maximum number of headers propagated from a request, and maximum total
+	// size in bytes of their names and values. Headers beyond these limits
+	// are dropped, in the alphabetical order of their names. These default
+	// to 16 headers and 4096 bytes.
+	MaxHeaders int
+	MaxBytes   int
+}
+
+func (c HeaderPropagationConfig) middleware() *propagation.Middleware {
+	if len(c.Headers) == 0 && len(c.Prefixes) == 0 {
+		return nil
+	}
+	return propagation.NewMiddleware(propagation.Config{
+		Headers:    c.Headers,
+		Prefixes:   c.Prefixes,
+		MaxHeaders: c.MaxHeaders,
+		MaxBytes:   c.MaxBytes,
+	})
+}
+
+// TTL bounds the time to live of outbound unary requests. Zero values are
+// unset.
+type TTL struct {
+	// TTL of requests made without a deadline.
+	Default time.Duration
+
+	// Maximum TTL of requests. Requests with a later deadline are sent with
+	// this TTL instead.
+	Max time.Duration
+}
+
+func (t TTL) policy() ttl.Policy {
+	return ttl.Policy{Default: t.Default, Max: t.Max}
+}
+
+// ServiceTTLConfig holds the TTLs of the requests sent to a service or
+// through an outbound.
+type ServiceTTLConfig struct {
+	TTL
+
+	// TTLs of specific procedures, by procedure name.
+	Procedures map[string]TTL
+}
+
+func (c ServiceTTLConfig) scope() ttl.Scope {
+	s := ttl.Scope{Policy: c.TTL.policy()}
+	if len(c.Procedures) > 0 {
+		s.Procedures = make(map[string]ttl.Policy, len(c.Procedures))
+		for name, t := range c.Procedures {
+			s.Procedures[name] = t.policy()
+		}
+	}
+	return s
+}
+
+// TTLConfig describes the default and maximum TTLs of outbound unary
+// requests.
+//
+// Each of Default and Max is taken from the most specific TTL which sets it,
+// in order: the procedure of the service, the procedure of the outbound, the
+// service, the outbound, and finally the TTL of all requests.
+type TTLConfig struct {
+	// TTL of all requests.
+	TTL
+
+	// TTLs of the requests sent through outbounds, by outbound key.
+	Outbounds map[string]ServiceTTLConfig
+
+	// TTLs of the requests sent to services, by service name.
+	Services map[string]ServiceTTLConfig
+}
+
+func (c TTLConfig) outbound(outboundKey string) ttl.Config {
+	cfg := ttl.Config{
+		Global:   c.TTL.policy(),
+		Outbound: c.Outbounds[outboundKey].scope(),
+	}
+	if len(c.Services) > 0 {
+		cfg.Services = make(map[string]ttl.Scope, len(c.Services))
+		for name, s := range c.Services {
+			cfg.Services[name] = s.scope()
+		}
+	}
+	return cfg
+}
+
 // Config specifies the parameters of a new Dispatcher constructed via
 // NewDispatcher.
 type Config struct {
@@ -134,4 +360,19 @@ type Config struct {
 
 	// Configures telemetry.
 	Metrics MetricsConfig
+
+	// Configures handling of panics in inbound handlers.
+	Panics PanicConfig
+
+	// Configures headers propagated from inbound requests to outbound calls.
+	// By default, no headers are propagated.
+	HeaderPropagation HeaderPropagationConfig
+
+	// Configures default and maximum TTLs of outbound unary requests. By
+	// default, requests are sent with the deadline of their context.
+	TTLs TTLConfig
+
+	// Configures how many failed and slow requests are kept in memory for
+	// introspection.
+	RequestTracking RequestTrackingConfig
 }
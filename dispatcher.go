@@ -32,10 +32,12 @@ import (
 	"go.uber.org/yarpc/internal/clientconfig"
 	"go.uber.org/yarpc/internal/errorsync"
 	"go.uber.org/yarpc/internal/inboundmiddleware"
+	"go.uber.org/yarpc/internal/introspection"
 	"go.uber.org/yarpc/internal/observability"
 	"go.uber.org/yarpc/internal/outboundmiddleware"
-	"go.uber.org/yarpc/internal/pally"
 	"go.uber.org/yarpc/internal/request"
+	"go.uber.org/yarpc/internal/ttl"
+	"go.uber.org/yarpc/x/metrics"
 	"go.uber.org/zap"
 )
 
@@ -79,23 +81,50 @@ func NewDispatcher(cfg Config) *Dispatcher {
 	extractor := cfg.Logging.extractor()
 
 	registry, stopPush := cfg.Metrics.registry(cfg.Name, logger)
-	cfg = addObservingMiddleware(cfg, registry, logger, extractor)
+	tracker := cfg.RequestTracking.tracker()
+	cfg = addPropagatingMiddleware(cfg)
+	cfg = addObservingMiddleware(cfg, registry, logger, extractor, tracker)
 
+	outbounds := convertOutbounds(cfg.Outbounds, cfg.OutboundMiddleware, cfg.TTLs)
 	return &Dispatcher{
 		name:              cfg.Name,
 		table:             middleware.ApplyRouteTable(NewMapRouter(cfg.Name), cfg.RouterMiddleware),
 		inbounds:          cfg.Inbounds,
-		outbounds:         convertOutbounds(cfg.Outbounds, cfg.OutboundMiddleware),
+		outbounds:         outbounds,
 		transports:        collectTransports(cfg.Inbounds, cfg.Outbounds),
 		inboundMiddleware: cfg.InboundMiddleware,
 		log:               logger,
 		registry:          registry,
 		stopRegistryPush:  stopPush,
+		peerGauges:        newPeerGauges(outbounds, registry, logger),
+		tracker:           tracker,
 	}
 }
 
-func addObservingMiddleware(cfg Config, registry *pally.Registry, logger *zap.Logger, extractor observability.ContextExtractor) Config {
-	observer := observability.NewMiddleware(logger, registry, extractor)
+// newPeerGauges builds gauges of the peers of the introspectable outbounds.
+func newPeerGauges(outbounds Outbounds, registry *metrics.Registry, logger *zap.Logger) *observability.PeerGauges {
+	introspectable := make(map[string]introspection.IntrospectableOutbound, len(outbounds))
+	for outboundKey, outs := range outbounds {
+		// Unary and oneway outbounds usually share their peer list, so only
+		// the peers of one of them are counted.
+		if o, ok := outs.Unary.(introspection.IntrospectableOutbound); ok {
+			introspectable[outboundKey] = o
+		} else if o, ok := outs.Oneway.(introspection.IntrospectableOutbound); ok {
+			introspectable[outboundKey] = o
+		}
+	}
+	return observability.NewPeerGauges(registry, logger, _metricsPushInterval, introspectable)
+}
+
+func addObservingMiddleware(cfg Config, registry *metrics.Registry, logger *zap.Logger, extractor observability.ContextExtractor, tracker *observability.RequestTracker) Config {
+	opts := append(cfg.Panics.options(), cfg.Logging.AccessLog.options(cfg.Name)...)
+	if tracker != nil {
+		opts = append(opts, observability.TrackRequests(tracker))
+	}
+	if cfg.Metrics.ErrorNameLabels {
+		opts = append(opts, observability.LabelErrorNames())
+	}
+	observer := observability.NewMiddleware(logger, registry, extractor, opts...)
 
 	cfg.InboundMiddleware.Unary = inboundmiddleware.UnaryChain(observer, cfg.InboundMiddleware.Unary)
 	cfg.InboundMiddleware.Oneway = inboundmiddleware.OnewayChain(observer, cfg.InboundMiddleware.Oneway)
@@ -106,8 +135,24 @@ func addObservingMiddleware(cfg Config, registry *pally.Registry, logger *zap.Lo
 	return cfg
 }
 
+func addPropagatingMiddleware(cfg Config) Config {
+	propagator := cfg.HeaderPropagation.middleware()
+	if propagator == nil {
+		return cfg
+	}
+
+	cfg.InboundMiddleware.Unary = inboundmiddleware.UnaryChain(propagator, cfg.InboundMiddleware.Unary)
+	cfg.InboundMiddleware.Oneway = inboundmiddleware.OnewayChain(propagator, cfg.InboundMiddleware.Oneway)
+
+	cfg.OutboundMiddleware.Unary = outboundmiddleware.UnaryChain(propagator, cfg.OutboundMiddleware.Unary)
+	cfg.OutboundMiddleware.Oneway = outboundmiddleware.OnewayChain(propagator, cfg.OutboundMiddleware.Oneway)
+
+	return cfg
+}
+
 // convertOutbounds applys outbound middleware and creates validator outbounds
-func convertOutbounds(outbounds Outbounds, mw OutboundMiddleware) Outbounds {
+// and TTL outbounds
+func convertOutbounds(outbounds Outbounds, mw OutboundMiddleware, ttls TTLConfig) Outbounds {
 	outboundSpecs := make(Outbounds, len(outbounds))
 
 	for outboundKey, outs := range outbounds {
@@ -121,10 +166,19 @@ func convertOutbounds(outbounds Outbounds, mw OutboundMiddleware) Outbounds {
 		)
 		serviceName := outboundKey
 
+		if outs.ServiceName != "" {
+			serviceName = outs.ServiceName
+		}
+
 		// apply outbound middleware and create ValidatorOutbounds
 		if outs.Unary != nil {
 			unaryOutbound = middleware.ApplyUnaryOutbound(outs.Unary, mw.Unary)
 			unaryOutbound = request.UnaryValidatorOutbound{UnaryOutbound: unaryOutbound}
+
+			// TTLs are applied before validation, which requires a deadline.
+			if cfg := ttls.outbound(outboundKey); !cfg.IsZero() {
+				unaryOutbound = ttl.NewUnaryOutbound(unaryOutbound, serviceName, cfg)
+			}
 		}
 
 		if outs.Oneway != nil {
@@ -132,10 +186,6 @@ func convertOutbounds(outbounds Outbounds, mw OutboundMiddleware) Outbounds {
 			onewayOutbound = request.OnewayValidatorOutbound{OnewayOutbound: onewayOutbound}
 		}
 
-		if outs.ServiceName != "" {
-			serviceName = outs.ServiceName
-		}
-
 		outboundSpecs[outboundKey] = transport.Outbounds{
 			ServiceName: serviceName,
 			Unary:       unaryOutbound,
@@ -189,8 +239,10 @@ type Dispatcher struct {
 	inboundMiddleware InboundMiddleware
 
 	log              *zap.Logger
-	registry         *pally.Registry
+	registry         *metrics.Registry
 	stopRegistryPush context.CancelFunc
+	peerGauges       *observability.PeerGauges
+	tracker          *observability.RequestTracker
 }
 
 // Inbounds returns a copy of the list of inbounds for this RPC object.
@@ -352,6 +404,10 @@ func (d *Dispatcher) Start() error {
 	}
 	d.log.Debug("Started inbounds.")
 
+	if err := d.peerGauges.Start(); err != nil {
+		return abort([]error{err})
+	}
+
 	d.log.Info("Started up.")
 	return nil
 }
@@ -375,6 +431,10 @@ func (d *Dispatcher) Stop() error {
 	var allErrs []error
 	d.log.Info("Starting shutdown.")
 
+	if err := d.peerGauges.Stop(); err != nil {
+		allErrs = append(allErrs, err)
+	}
+
 	// Stop Inbounds
 	d.log.Debug("Stopping inbounds.")
 	wait := errorsync.ErrorWaiter{}
@@ -20,6 +20,8 @@
 
 package introspection
 
+import "time"
+
 // IntrospectableChooser extends the Chooser interfaces.
 type IntrospectableChooser interface {
 	Introspect() ChooserStatus
@@ -34,6 +36,25 @@ type ChooserStatus struct {
 
 // PeerStatus is a collection of basic peers info.
 type PeerStatus struct {
-	Identifier string `json:"identifier"`
-	State      string `json:"state"`
+	Identifier string     `json:"identifier"`
+	State      string     `json:"state"`
+	Stats      *PeerStats `json:"stats,omitempty"`
+
+	// Connection status and number of pending requests of the peer, for
+	// peer lists which track them.
+	ConnectionStatus string `json:"connectionStatus,omitempty"`
+	PendingRequests  int    `json:"pendingRequests"`
+}
+
+// PeerStats summarizes the requests a peer list has sent to a peer.
+//
+// Latency quantiles decay over time so that they reflect the recent
+// behavior of the peer.
+type PeerStats struct {
+	Requests     int64            `json:"requests"`
+	Errors       int64            `json:"errors"`
+	ErrorsByCode map[string]int64 `json:"errorsByCode,omitempty"`
+	LatencyP50   time.Duration    `json:"latencyP50"`
+	LatencyP90   time.Duration    `json:"latencyP90"`
+	LatencyP99   time.Duration    `json:"latencyP99"`
 }
@@ -29,4 +29,9 @@ type DispatcherStatus struct {
 	Inbounds        []InboundStatus  `json:"inbounds"`
 	Outbounds       []OutboundStatus `json:"outbounds"`
 	PackageVersions []PackageVersion `json:"packageVersions"`
+
+	// RecentFailures are the most recent failed requests, newest first,
+	// and SlowestRequests the slowest requests of each procedure.
+	RecentFailures  []RequestStatus      `json:"recentFailures"`
+	SlowestRequests []SlowRequestsStatus `json:"slowestRequests"`
 }
@@ -30,4 +30,7 @@ type InboundStatus struct {
 	Transport string `json:"transport"`
 	Endpoint  string `json:"endpoint"`
 	State     string `json:"state"`
+
+	// Counters holds transport-specific event counts, if any.
+	Counters map[string]int64 `json:"counters,omitempty"`
 }
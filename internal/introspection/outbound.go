@@ -20,6 +20,8 @@
 
 package introspection
 
+import "time"
+
 // IntrospectableOutbound extends the Outbound interface.
 type IntrospectableOutbound interface {
 	Introspect() OutboundStatus
@@ -34,6 +36,31 @@ type OutboundStatus struct {
 	Chooser     ChooserStatus `json:"chooser"`
 	Service     string        `json:"service"`
 	OutboundKey string        `json:"outboundkey"`
+
+	// Splits is set for outbounds which split traffic between several
+	// child outbounds.
+	Splits []SplitStatus `json:"splits,omitempty"`
+
+	// TTLs are the default and maximum TTLs applied to requests, for all
+	// procedures and for the procedures with specific TTLs.
+	TTLs []TTLStatus `json:"ttls,omitempty"`
+}
+
+// TTLStatus is the default and maximum TTL applied to outbound requests.
+// Zero values are unset.
+type TTLStatus struct {
+	Procedure string        `json:"procedure,omitempty"`
+	Default   time.Duration `json:"default"`
+	Max       time.Duration `json:"max"`
+}
+
+// SplitStatus is the status of a child outbound of an outbound which splits
+// traffic between several child outbounds.
+type SplitStatus struct {
+	Name     string         `json:"name"`
+	Weight   int            `json:"weight"`
+	Calls    int64          `json:"calls"`
+	Outbound OutboundStatus `json:"outbound"`
 }
 
 // OutboundStatusNotSupported is returned when not valid OutboundStatus can be
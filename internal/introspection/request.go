@@ -0,0 +1,50 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package introspection
+
+import "time"
+
+// RequestStatus describes a request handled or made by a dispatcher.
+type RequestStatus struct {
+	// Direction is "inbound" or "outbound".
+	Direction string        `json:"direction"`
+	RPCType   string        `json:"rpcType"`
+	Caller    string        `json:"caller"`
+	Service   string        `json:"service"`
+	Procedure string        `json:"procedure"`
+	Encoding  string        `json:"encoding"`
+	Started   time.Time     `json:"started"`
+	Duration  time.Duration `json:"duration"`
+
+	// Code is the error code of failed requests, or "application_error".
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+	TraceID string `json:"traceID,omitempty"`
+}
+
+// SlowRequestsStatus lists the slowest requests of a procedure, slowest
+// first.
+type SlowRequestsStatus struct {
+	Direction string          `json:"direction"`
+	Service   string          `json:"service"`
+	Procedure string          `json:"procedure"`
+	Requests  []RequestStatus `json:"requests"`
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"math/rand"
+	"time"
+
+	"go.uber.org/yarpc/internal/remote"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessLogConfig configures the access log of inbound requests.
+type AccessLogConfig struct {
+	// Logger the access log is written to, at Info level.
+	Logger *zap.Logger
+
+	// Fraction of requests written to the access log, from 0 to 1.
+	SampleRate float64
+
+	// Sampling rates of individual procedures, overriding SampleRate.
+	ProcedureSampleRates map[string]float64
+
+	// Log all failed requests, regardless of sampling.
+	LogErrors bool
+
+	// Log all requests which took at least this long, regardless of
+	// sampling. Zero disables this.
+	SlowThreshold time.Duration
+}
+
+// AccessLog makes the middleware write one line per sampled inbound request
+// to an access log.
+func AccessLog(cfg AccessLogConfig) MiddlewareOption {
+	return func(m *Middleware) {
+		m.graph.accessLog = &accessLog{
+			AccessLogConfig: cfg,
+			random:          rand.Float64,
+		}
+	}
+}
+
+type accessLog struct {
+	AccessLogConfig
+
+	random func() float64 // for tests
+}
+
+// shouldLog returns true if a request to the given procedure is written to
+// the access log.
+func (l *accessLog) shouldLog(procedure string, elapsed time.Duration, failed bool) bool {
+	if failed && l.LogErrors {
+		return true
+	}
+	if l.SlowThreshold > 0 && elapsed >= l.SlowThreshold {
+		return true
+	}
+	rate, ok := l.ProcedureSampleRates[procedure]
+	if !ok {
+		rate = l.SampleRate
+	}
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	default:
+		return l.random() < rate
+	}
+}
+
+func (c call) endAccessLog(elapsed time.Duration, err error, isApplicationError bool, responseSize int64) {
+	l := c.accessLog
+	failed := err != nil || isApplicationError
+	if l == nil || !l.shouldLog(c.req.Procedure, elapsed, failed) {
+		return
+	}
+	ce := l.Logger.Check(zap.InfoLevel, "Inbound request.")
+	if ce == nil {
+		return
+	}
+
+	code, name := "ok", ""
+	if failed {
+		code, name = errorLabels(err, isApplicationError)
+	}
+	peer := remote.FromContext(c.ctx)
+	fields := []zapcore.Field{
+		zap.String("caller", c.req.Caller),
+		zap.String("service", c.req.Service),
+		zap.String("procedure", c.req.Procedure),
+		zap.String("encoding", string(c.req.Encoding)),
+		zap.String("rpcType", c.rpcType.String()),
+		zap.String("transport", peer.Transport),
+		zap.String("peer", peer.Address),
+		zap.String("code", code),
+		zap.Int64("requestSize", c.requestSize()),
+		zap.Duration("latency", elapsed),
+		c.extract(c.ctx),
+	}
+	if responseSize >= 0 {
+		fields = append(fields, zap.Int64("responseSize", responseSize))
+	}
+	if name != "" {
+		fields = append(fields, zap.String("errorName", name))
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	ce.Write(fields...)
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/remote"
+	"go.uber.org/yarpc/x/metrics"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessLog(t *testing.T) {
+	defer stubTime()()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	mw := NewMiddleware(zap.NewNop(), metrics.NewRegistry(), NewNopContextExtractor(), AccessLog(AccessLogConfig{
+		Logger:     zap.New(core),
+		SampleRate: 1,
+	}))
+
+	newRequest := func() *transport.Request {
+		return &transport.Request{
+			Caller:    "caller",
+			Service:   "service",
+			Encoding:  "raw",
+			Procedure: "procedure",
+			Body:      strings.NewReader("body"),
+		}
+	}
+	ctx := remote.NewContext(context.Background(), remote.Peer{
+		Transport: "http",
+		Address:   "127.0.0.1:4040",
+	})
+	baseFields := []zapcore.Field{
+		zap.String("caller", "caller"),
+		zap.String("service", "service"),
+		zap.String("procedure", "procedure"),
+		zap.String("encoding", "raw"),
+	}
+	failed := yarpcerrors.FromHeaders(yarpcerrors.CodeUnknown, "no-such-user", "no such user")
+
+	tests := []struct {
+		desc       string
+		call       func() error
+		wantFields []zapcore.Field
+	}{
+		{
+			desc: "unary success",
+			call: func() error {
+				return mw.Handle(ctx, newRequest(), &transporttest.FakeResponseWriter{}, handlerFunc(
+					func(_ context.Context, _ *transport.Request, w transport.ResponseWriter) error {
+						_, err := w.Write([]byte("abc"))
+						return err
+					}))
+			},
+			wantFields: []zapcore.Field{
+				zap.String("rpcType", "Unary"),
+				zap.String("transport", "http"),
+				zap.String("peer", "127.0.0.1:4040"),
+				zap.String("code", "ok"),
+				zap.Int64("requestSize", 4),
+				zap.Duration("latency", 0),
+				zap.Skip(),
+				zap.Int64("responseSize", 3),
+			},
+		},
+		{
+			desc: "unary application error",
+			call: func() error {
+				return mw.Handle(ctx, newRequest(), &transporttest.FakeResponseWriter{}, fakeHandler{applicationErr: true})
+			},
+			wantFields: []zapcore.Field{
+				zap.String("rpcType", "Unary"),
+				zap.String("transport", "http"),
+				zap.String("peer", "127.0.0.1:4040"),
+				zap.String("code", "application_error"),
+				zap.Int64("requestSize", 4),
+				zap.Duration("latency", 0),
+				zap.Skip(),
+				zap.Int64("responseSize", 0),
+			},
+		},
+		{
+			desc: "oneway error without peer",
+			call: func() error {
+				return mw.HandleOneway(context.Background(), newRequest(), fakeHandler{err: failed})
+			},
+			wantFields: []zapcore.Field{
+				zap.String("rpcType", "Oneway"),
+				zap.String("transport", ""),
+				zap.String("peer", ""),
+				zap.String("code", "unknown"),
+				zap.Int64("requestSize", 4),
+				zap.Duration("latency", 0),
+				zap.Skip(),
+				zap.String("errorName", "no-such-user"),
+				zap.Error(failed),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			tt.call()
+			entries := logs.TakeAll()
+			require.Len(t, entries, 1, "Unexpected number of logs written.")
+			assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+			assert.Equal(t, "Inbound request.", entries[0].Message)
+			assert.Equal(t, append(baseFields, tt.wantFields...), entries[0].Context)
+		})
+	}
+
+	t.Run("outbound calls are not logged", func(t *testing.T) {
+		_, err := mw.Call(ctx, newRequest(), fakeOutbound{})
+		require.NoError(t, err)
+		_, err = mw.CallOneway(ctx, newRequest(), fakeOutbound{})
+		require.NoError(t, err)
+		assert.Empty(t, logs.TakeAll())
+	})
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	tests := []struct {
+		desc      string
+		cfg       AccessLogConfig
+		procedure string
+		elapsed   time.Duration
+		failed    bool
+		want      bool
+	}{
+		{
+			desc: "all requests",
+			cfg:  AccessLogConfig{SampleRate: 1},
+			want: true,
+		},
+		{
+			desc: "no requests",
+			cfg:  AccessLogConfig{},
+		},
+		{
+			desc: "sampled in",
+			cfg:  AccessLogConfig{SampleRate: 0.6},
+			want: true,
+		},
+		{
+			desc: "sampled out",
+			cfg:  AccessLogConfig{SampleRate: 0.4},
+		},
+		{
+			desc:      "procedure rate",
+			cfg:       AccessLogConfig{ProcedureSampleRates: map[string]float64{"hot": 0.1, "cold": 1}},
+			procedure: "cold",
+			want:      true,
+		},
+		{
+			desc:      "procedure rate overrides the default",
+			cfg:       AccessLogConfig{SampleRate: 1, ProcedureSampleRates: map[string]float64{"hot": 0}},
+			procedure: "hot",
+		},
+		{
+			desc:   "failed requests are sampled",
+			cfg:    AccessLogConfig{},
+			failed: true,
+		},
+		{
+			desc:   "failed requests are always logged",
+			cfg:    AccessLogConfig{LogErrors: true},
+			failed: true,
+			want:   true,
+		},
+		{
+			desc:    "fast requests are sampled",
+			cfg:     AccessLogConfig{SlowThreshold: time.Second},
+			elapsed: time.Second - 1,
+		},
+		{
+			desc:    "slow requests are always logged",
+			cfg:     AccessLogConfig{SlowThreshold: time.Second},
+			elapsed: time.Second,
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			l := &accessLog{
+				AccessLogConfig: tt.cfg,
+				random:          func() float64 { return 0.5 },
+			}
+			assert.Equal(t, tt.want, l.shouldLog(tt.procedure, tt.elapsed, tt.failed))
+		})
+	}
+}
@@ -22,9 +22,14 @@ package observability
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/x/metrics"
 	"go.uber.org/yarpc/yarpcerrors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -35,21 +40,85 @@ import (
 // To prevent allocating on the heap on the request path, it's a value instead
 // of a pointer.
 type call struct {
-	edge    *edge
-	extract ContextExtractor
-	fields  [5]zapcore.Field
+	edge      *edge
+	extract   ContextExtractor
+	accessLog *accessLog    // nil unless inbound and enabled
+	inFlight  metrics.Gauge // nil unless inbound
+	tracker   *RequestTracker
+	fields    [5]zapcore.Field
 
 	started time.Time
 	ctx     context.Context
 	req     *transport.Request
 	rpcType transport.Type
 	inbound bool
+
+	// Whether failures are labeled with error names.
+	errorNames bool
+
+	// Size of the request body, if known upfront. Otherwise, reqBody counts
+	// the bytes read from it.
+	reqSize int64
+	reqBody *countingReader
+}
+
+// Request returns the request to pass on to the handler or outbound. When
+// the size of its body isn't known upfront, the returned request is a copy
+// whose body counts the bytes read from it.
+func (c *call) Request(req *transport.Request) *transport.Request {
+	if req.Body == nil {
+		return req
+	}
+	if n, ok := bodySize(req.Body); ok {
+		c.reqSize = n
+		return req
+	}
+	c.reqBody = &countingReader{Reader: req.Body}
+	r := *req
+	r.Body = c.reqBody
+	return &r
+}
+
+// Response records the size of the body of an outbound response. When it
+// isn't known upfront, the body is replaced with one which records the
+// number of bytes read from it when closed.
+func (c call) Response(res *transport.Response) {
+	if res == nil || res.Body == nil {
+		c.edge.responseSizes.Observe(0)
+		return
+	}
+	if n, ok := bodySize(res.Body); ok {
+		c.edge.responseSizes.Observe(n)
+		return
+	}
+	res.Body = &observedBody{ReadCloser: res.Body, sizes: c.edge.responseSizes}
 }
 
-func (c call) End(err error, isApplicationError bool) {
+// End records the outcome of the call. The size of the response body is
+// recorded if it is not negative.
+func (c call) End(err error, isApplicationError bool, responseSize int64) {
 	elapsed := _timeNow().Sub(c.started)
+	if c.inFlight != nil {
+		c.inFlight.Dec()
+	}
 	c.endLogs(elapsed, err, isApplicationError)
 	c.endStats(elapsed, err, isApplicationError)
+	c.endSizes(responseSize)
+	c.endAccessLog(elapsed, err, isApplicationError, responseSize)
+	c.endTracking(elapsed, err, isApplicationError)
+}
+
+// Panicked records that the handler of an inbound call panicked, and returns
+// the error with which the call fails.
+func (c call) Panicked(p *recovered) error {
+	c.edge.panics.Inc()
+	c.edge.logger.Error("Handler panicked.",
+		zap.String("rpcType", c.rpcType.String()),
+		zap.String("panic", fmt.Sprint(p.value)),
+		zap.String("stack", string(p.stack)),
+		c.extract(c.ctx),
+	)
+	return yarpcerrors.InternalErrorf("panic: %v", p.value)
 }
 
 func (c call) endLogs(elapsed time.Duration, err error, isApplicationError bool) {
@@ -83,6 +152,16 @@ func (c call) endStats(elapsed time.Duration, err error, isApplicationError bool
 		c.edge.latencies.Observe(elapsed)
 		return
 	}
+	code, name := errorLabels(err, isApplicationError)
+	if !c.errorNames {
+		name = ""
+	}
+	if counter, err := c.edge.failures.Get(code, name); err == nil {
+		counter.Inc()
+	}
+	if latencies, err := c.edge.failureLatencies.Get(code); err == nil {
+		latencies.Observe(elapsed)
+	}
 	// For now, assume that all application errors are the caller's fault.
 	if isApplicationError {
 		c.edge.callerErrLatencies.Observe(elapsed)
@@ -112,3 +191,139 @@ func (c call) endStats(elapsed time.Duration, err error, isApplicationError bool
 		counter.Inc()
 	}
 }
+
+// endTracking records failed calls and the slowest calls of each procedure
+// with the request tracker, if any.
+func (c call) endTracking(elapsed time.Duration, err error, isApplicationError bool) {
+	if c.tracker == nil {
+		return
+	}
+	failed := err != nil || isApplicationError
+	slow := c.tracker.slowRequests(c.inbound, c.req)
+	isSlow := slow != nil && slow.admits(elapsed)
+	if !failed && !isSlow {
+		return
+	}
+
+	status := introspection.RequestStatus{
+		Direction: direction(c.inbound),
+		RPCType:   c.rpcType.String(),
+		Caller:    c.req.Caller,
+		Service:   c.req.Service,
+		Procedure: c.req.Procedure,
+		Encoding:  string(c.req.Encoding),
+		Started:   c.started,
+		Duration:  elapsed,
+	}
+	status.TraceID = traceID(c.ctx)
+	if failed {
+		status.Code, _ = errorLabels(err, isApplicationError)
+		if err != nil {
+			status.Error = err.Error()
+		}
+		c.tracker.addFailure(status)
+	}
+	if isSlow {
+		slow.add(status)
+	}
+}
+
+func (c call) endSizes(responseSize int64) {
+	c.edge.requestSizes.Observe(c.requestSize())
+	if responseSize >= 0 {
+		c.edge.responseSizes.Observe(responseSize)
+	}
+}
+
+// requestSize returns the size of the request body, as far as it was read.
+func (c call) requestSize() int64 {
+	if c.reqBody != nil {
+		return c.reqBody.n
+	}
+	return c.reqSize
+}
+
+// errorLabels returns the error code and name with which a failed call is
+// counted. Application errors have the code "application_error", and errors
+// other than YARPC errors have the code "unknown".
+func errorLabels(err error, isApplicationError bool) (code, name string) {
+	if isApplicationError && err == nil {
+		return "application_error", ""
+	}
+	c := yarpcerrors.ErrorCode(err)
+	if c == yarpcerrors.CodeOK {
+		c = yarpcerrors.CodeUnknown
+	}
+	return c.String(), yarpcerrors.ErrorName(err)
+}
+
+// traceID returns the ID of the trace of the OpenTracing span on the
+// context, if its span context has a TraceID method returning a string, like
+// that of x/tracecontext, or a fmt.Stringer. OpenTracing has no interface for
+// trace IDs.
+func traceID(ctx context.Context) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	switch sc := span.Context().(type) {
+	case interface {
+		TraceID() string
+	}:
+		return sc.TraceID()
+	case interface {
+		TraceID() fmt.Stringer
+	}:
+		if id := sc.TraceID(); id != nil {
+			return id.String()
+		}
+	}
+	return ""
+}
+
+// bodySize returns the number of unread bytes of a body, if known.
+func bodySize(body io.Reader) (int64, bool) {
+	if b, ok := body.(interface {
+		Len() int
+	}); ok {
+		return int64(b.Len()), true
+	}
+	return 0, false
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.Reader
+
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// observedBody records the number of bytes read from a response body once
+// it is closed.
+type observedBody struct {
+	io.ReadCloser
+
+	sizes  metrics.Histogram
+	n      int64
+	closed bool
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.sizes.Observe(b.n)
+	}
+	return b.ReadCloser.Close()
+}
@@ -73,3 +73,20 @@ func stubTime() func() {
 	_timeNow = func() time.Time { return time.Time{} }
 	return func() { _timeNow = prev }
 }
+
+type handlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f handlerFunc) Handle(ctx context.Context, req *transport.Request, rw transport.ResponseWriter) error {
+	return f(ctx, req, rw)
+}
+
+type outboundFunc func(context.Context, *transport.Request) (*transport.Response, error)
+
+func (f outboundFunc) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	return f(ctx, req)
+}
+
+func (outboundFunc) Transports() []transport.Transport { return nil }
+func (outboundFunc) Start() error                      { return nil }
+func (outboundFunc) Stop() error                       { return nil }
+func (outboundFunc) IsRunning() bool                   { return true }
@@ -26,7 +26,8 @@ import (
 	"time"
 
 	"go.uber.org/yarpc/api/transport"
-	"go.uber.org/yarpc/internal/pally"
+	"go.uber.org/yarpc/internal/remote"
+	"go.uber.org/yarpc/x/metrics"
 	"go.uber.org/zap"
 )
 
@@ -36,8 +37,8 @@ var (
 	_digesterPool     = sync.Pool{New: func() interface{} {
 		return &digester{make([]byte, 0, 128)}
 	}}
-	// Latency buckets for histograms. At some point, we may want to make these
-	// configurable.
+	// Default latency buckets for histograms. yarpc.MetricsConfig.LatencyBuckets
+	// overrides them per metric.
 	_ms      = time.Millisecond
 	_buckets = []time.Duration{
 		1 * _ms,
@@ -91,6 +92,22 @@ var (
 		7500 * _ms,
 		10000 * _ms,
 	}
+	// Default size buckets for histograms of request and response bodies, in
+	// bytes. yarpc.MetricsConfig.SizeBuckets overrides them per metric.
+	_sizeBuckets = []int64{
+		0,
+		64,
+		256,
+		1 << 10,
+		4 << 10,
+		16 << 10,
+		64 << 10,
+		256 << 10,
+		1 << 20,
+		4 << 20,
+		16 << 20,
+		64 << 20,
+	}
 )
 
 // A digester creates a null-delimited byte slice from a series of strings. It's
@@ -131,20 +148,38 @@ func (d *digester) free() {
 // A graph represents a collection of services: each service is a node, and we
 // collect stats for each caller-callee-encoding-procedure-rk-sk-rd edge.
 type graph struct {
-	reg     *pally.Registry
-	logger  *zap.Logger
-	extract ContextExtractor
+	reg       *metrics.Registry
+	logger    *zap.Logger
+	extract   ContextExtractor
+	accessLog *accessLog
+	tracker   *RequestTracker
+
+	// Whether failures are labeled with error names.
+	errorNames bool
+
+	// Inbound requests being handled, by transport and procedure.
+	inFlight metrics.GaugeVector
 
 	edgesMu sync.RWMutex
 	edges   map[string]*edge
 }
 
-func newGraph(reg *pally.Registry, logger *zap.Logger, extract ContextExtractor) graph {
+func newGraph(reg *metrics.Registry, logger *zap.Logger, extract ContextExtractor) graph {
+	inFlight, err := reg.NewGaugeVector(metrics.Opts{
+		Name:           "in_flight_requests",
+		Help:           "Number of inbound requests being handled.",
+		VariableLabels: []string{"transport", "procedure"},
+	})
+	if err != nil {
+		logger.Error("Failed to create in-flight requests gauge.", zap.Error(err))
+		inFlight = metrics.NewNopGaugeVector()
+	}
 	return graph{
-		edges:   make(map[string]*edge, _defaultGraphSize),
-		reg:     reg,
-		logger:  logger,
-		extract: extract,
+		edges:    make(map[string]*edge, _defaultGraphSize),
+		reg:      reg,
+		logger:   logger,
+		extract:  extract,
+		inFlight: inFlight,
 	}
 }
 
@@ -162,7 +197,7 @@ func (g *graph) begin(ctx context.Context, rpcType transport.Type, isInbound boo
 	e := g.getOrCreateEdge(d.digest(), req)
 	d.free()
 
-	return call{
+	c := call{
 		edge:    e,
 		extract: g.extract,
 		started: now,
@@ -170,7 +205,19 @@ func (g *graph) begin(ctx context.Context, rpcType transport.Type, isInbound boo
 		req:     req,
 		rpcType: rpcType,
 		inbound: isInbound,
+		tracker: g.tracker,
+
+		errorNames: g.errorNames,
 	}
+	if isInbound {
+		c.accessLog = g.accessLog
+		transport := remote.FromContext(ctx).Transport
+		if gauge, err := g.inFlight.Get(transport, req.Procedure); err == nil {
+			c.inFlight = gauge
+			gauge.Inc()
+		}
+	}
+	return c
 }
 
 func (g *graph) getOrCreateEdge(key []byte, req *transport.Request) *edge {
@@ -207,46 +254,61 @@ func (g *graph) createEdge(key []byte, req *transport.Request) *edge {
 type edge struct {
 	logger *zap.Logger
 
-	calls          pally.Counter
-	successes      pally.Counter
-	callerFailures pally.CounterVector
-	serverFailures pally.CounterVector
+	calls          metrics.Counter
+	successes      metrics.Counter
+	panics         metrics.Counter
+	callerFailures metrics.CounterVector
+	serverFailures metrics.CounterVector
+	failures       metrics.CounterVector
+
+	latencies          metrics.Latencies
+	callerErrLatencies metrics.Latencies
+	serverErrLatencies metrics.Latencies
+	failureLatencies   metrics.LatenciesVector
 
-	latencies          pally.Latencies
-	callerErrLatencies pally.Latencies
-	serverErrLatencies pally.Latencies
+	requestSizes  metrics.Histogram
+	responseSizes metrics.Histogram
 }
 
 // newEdge constructs a new edge. Since Registries enforce metric uniqueness,
 // edges should be cached and re-used for each RPC.
-func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *edge {
-	labels := pally.Labels{
-		"source":           pally.ScrubLabelValue(req.Caller),
-		"dest":             pally.ScrubLabelValue(req.Service),
-		"procedure":        pally.ScrubLabelValue(req.Procedure),
-		"encoding":         pally.ScrubLabelValue(string(req.Encoding)),
-		"routing_key":      pally.ScrubLabelValue(req.RoutingKey),
-		"routing_delegate": pally.ScrubLabelValue(req.RoutingDelegate),
+func newEdge(logger *zap.Logger, reg *metrics.Registry, req *transport.Request) *edge {
+	labels := metrics.Labels{
+		"source":           metrics.ScrubLabelValue(req.Caller),
+		"dest":             metrics.ScrubLabelValue(req.Service),
+		"procedure":        metrics.ScrubLabelValue(req.Procedure),
+		"encoding":         metrics.ScrubLabelValue(string(req.Encoding)),
+		"routing_key":      metrics.ScrubLabelValue(req.RoutingKey),
+		"routing_delegate": metrics.ScrubLabelValue(req.RoutingDelegate),
 	}
-	calls, err := reg.NewCounter(pally.Opts{
+	calls, err := reg.NewCounter(metrics.Opts{
 		Name:        "calls",
 		Help:        "Total number of RPCs.",
 		ConstLabels: labels,
 	})
 	if err != nil {
 		logger.Error("Failed to create calls counter.", zap.Error(err))
-		calls = pally.NewNopCounter()
+		calls = metrics.NewNopCounter()
 	}
-	successes, err := reg.NewCounter(pally.Opts{
+	successes, err := reg.NewCounter(metrics.Opts{
 		Name:        "successes",
 		Help:        "Number of successful RPCs.",
 		ConstLabels: labels,
 	})
 	if err != nil {
 		logger.Error("Failed to create successes counter.", zap.Error(err))
-		successes = pally.NewNopCounter()
+		successes = metrics.NewNopCounter()
 	}
-	callerFailures, err := reg.NewCounterVector(pally.Opts{
+	panics, err := reg.NewCounter(metrics.Opts{
+		Name:        "panics",
+		Help:        "Number of RPCs whose handler panicked.",
+		ConstLabels: labels,
+	})
+	if err != nil {
+		logger.Error("Failed to create panics counter.", zap.Error(err))
+		panics = metrics.NewNopCounter()
+	}
+	callerFailures, err := reg.NewCounterVector(metrics.Opts{
 		Name:           "caller_failures",
 		Help:           "Number of RPCs failed because of caller error.",
 		ConstLabels:    labels,
@@ -254,9 +316,9 @@ func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *e
 	})
 	if err != nil {
 		logger.Error("Failed to create caller failures vector.", zap.Error(err))
-		callerFailures = pally.NewNopCounterVector()
+		callerFailures = metrics.NewNopCounterVector()
 	}
-	serverFailures, err := reg.NewCounterVector(pally.Opts{
+	serverFailures, err := reg.NewCounterVector(metrics.Opts{
 		Name:           "server_failures",
 		Help:           "Number of RPCs failed because of server error.",
 		ConstLabels:    labels,
@@ -264,10 +326,20 @@ func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *e
 	})
 	if err != nil {
 		logger.Error("Failed to create server failures vector.", zap.Error(err))
-		serverFailures = pally.NewNopCounterVector()
+		serverFailures = metrics.NewNopCounterVector()
+	}
+	failures, err := reg.NewCounterVector(metrics.Opts{
+		Name:           "failures",
+		Help:           "Number of failed RPCs by error code and, if enabled, name.",
+		ConstLabels:    labels,
+		VariableLabels: []string{"code", "error_name"},
+	})
+	if err != nil {
+		logger.Error("Failed to create failures vector.", zap.Error(err))
+		failures = metrics.NewNopCounterVector()
 	}
-	latencies, err := reg.NewLatencies(pally.LatencyOpts{
-		Opts: pally.Opts{
+	latencies, err := reg.NewLatencies(metrics.LatencyOpts{
+		Opts: metrics.Opts{
 			Name:        "success_latency_ms",
 			Help:        "Latency distribution of successful RPCs.",
 			ConstLabels: labels,
@@ -277,10 +349,10 @@ func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *e
 	})
 	if err != nil {
 		logger.Error("Failed to create success latency distribution.", zap.Error(err))
-		latencies = pally.NewNopLatencies()
+		latencies = metrics.NewNopLatencies()
 	}
-	callerErrLatencies, err := reg.NewLatencies(pally.LatencyOpts{
-		Opts: pally.Opts{
+	callerErrLatencies, err := reg.NewLatencies(metrics.LatencyOpts{
+		Opts: metrics.Opts{
 			Name:        "caller_failure_latency_ms",
 			Help:        "Latency distribution of RPCs failed because of caller error.",
 			ConstLabels: labels,
@@ -290,10 +362,10 @@ func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *e
 	})
 	if err != nil {
 		logger.Error("Failed to create caller failure latency distribution.", zap.Error(err))
-		callerErrLatencies = pally.NewNopLatencies()
+		callerErrLatencies = metrics.NewNopLatencies()
 	}
-	serverErrLatencies, err := reg.NewLatencies(pally.LatencyOpts{
-		Opts: pally.Opts{
+	serverErrLatencies, err := reg.NewLatencies(metrics.LatencyOpts{
+		Opts: metrics.Opts{
 			Name:        "server_failure_latency_ms",
 			Help:        "Latency distribution of RPCs failed because of server error.",
 			ConstLabels: labels,
@@ -303,7 +375,45 @@ func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *e
 	})
 	if err != nil {
 		logger.Error("Failed to create server failure latency distribution.", zap.Error(err))
-		serverErrLatencies = pally.NewNopLatencies()
+		serverErrLatencies = metrics.NewNopLatencies()
+	}
+	failureLatencies, err := reg.NewLatenciesVector(metrics.LatencyOpts{
+		Opts: metrics.Opts{
+			Name:           "failure_latency_ms",
+			Help:           "Latency distribution of failed RPCs by error code.",
+			ConstLabels:    labels,
+			VariableLabels: []string{"code"},
+		},
+		Unit:    _ms,
+		Buckets: _buckets,
+	})
+	if err != nil {
+		logger.Error("Failed to create failure latency distributions.", zap.Error(err))
+		failureLatencies = metrics.NewNopLatenciesVector()
+	}
+	requestSizes, err := reg.NewHistogram(metrics.HistogramOpts{
+		Opts: metrics.Opts{
+			Name:        "request_payload_bytes",
+			Help:        "Size distribution of request bodies.",
+			ConstLabels: labels,
+		},
+		Buckets: _sizeBuckets,
+	})
+	if err != nil {
+		logger.Error("Failed to create request size distribution.", zap.Error(err))
+		requestSizes = metrics.NewNopHistogram()
+	}
+	responseSizes, err := reg.NewHistogram(metrics.HistogramOpts{
+		Opts: metrics.Opts{
+			Name:        "response_payload_bytes",
+			Help:        "Size distribution of response bodies.",
+			ConstLabels: labels,
+		},
+		Buckets: _sizeBuckets,
+	})
+	if err != nil {
+		logger.Error("Failed to create response size distribution.", zap.Error(err))
+		responseSizes = metrics.NewNopHistogram()
 	}
 	logger = logger.With(
 		zap.String("source", req.Caller),
@@ -317,10 +427,15 @@ func newEdge(logger *zap.Logger, reg *pally.Registry, req *transport.Request) *e
 		logger:             logger,
 		calls:              calls,
 		successes:          successes,
+		panics:             panics,
 		callerFailures:     callerFailures,
 		serverFailures:     serverFailures,
+		failures:           failures,
 		latencies:          latencies,
 		callerErrLatencies: callerErrLatencies,
 		serverErrLatencies: serverErrLatencies,
+		failureLatencies:   failureLatencies,
+		requestSizes:       requestSizes,
+		responseSizes:      responseSizes,
 	}
 }
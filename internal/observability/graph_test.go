@@ -26,7 +26,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/yarpc/api/transport"
-	"go.uber.org/yarpc/internal/pally"
+	"go.uber.org/yarpc/x/metrics"
 	"go.uber.org/zap"
 )
 
@@ -34,7 +34,7 @@ func TestEdgeNopFallbacks(t *testing.T) {
 	// If we fail to create any of the metrics required for the edge, we should
 	// fall back to no-op implementations. The easiest way to trigger failures
 	// is to re-use the same Registry.
-	reg := pally.NewRegistry()
+	reg := metrics.NewRegistry()
 	req := &transport.Request{
 		Caller:          "caller",
 		Service:         "service",
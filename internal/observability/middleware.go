@@ -25,7 +25,8 @@ import (
 	"sync"
 
 	"go.uber.org/yarpc/api/transport"
-	"go.uber.org/yarpc/internal/pally"
+	"go.uber.org/yarpc/internal/repanic"
+	"go.uber.org/yarpc/x/metrics"
 	"go.uber.org/zap"
 )
 
@@ -39,15 +40,23 @@ type writer struct {
 	transport.ResponseWriter
 
 	isApplicationError bool
+	size               int64
 }
 
 func newWriter(rw transport.ResponseWriter) *writer {
 	w := _writerPool.Get().(*writer)
 	w.isApplicationError = false
+	w.size = 0
 	w.ResponseWriter = rw
 	return w
 }
 
+func (w *writer) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
 func (w *writer) SetApplicationError() {
 	w.isApplicationError = true
 	w.ResponseWriter.SetApplicationError()
@@ -57,51 +66,108 @@ func (w *writer) free() {
 	_writerPool.Put(w)
 }
 
+// MiddlewareOption customizes a Middleware.
+type MiddlewareOption func(*Middleware)
+
+// Repanic makes the middleware propagate panics of inbound handlers after
+// logging and counting them, rather than failing the request with an
+// Internal error.
+func Repanic() MiddlewareOption {
+	return func(m *Middleware) {
+		m.repanic = true
+	}
+}
+
+// TrackRequests records the most recent failed requests and the slowest
+// requests of each procedure, inbound and outbound, with the given tracker.
+func TrackRequests(t *RequestTracker) MiddlewareOption {
+	return func(m *Middleware) {
+		m.graph.tracker = t
+	}
+}
+
+// LabelErrorNames labels the failures counter with the names of
+// user-defined errors (see yarpcerrors.NamedErrorf). These names are chosen
+// by handlers, and by remote services for outbound requests, so they may
+// not be bounded.
+func LabelErrorNames() MiddlewareOption {
+	return func(m *Middleware) {
+		m.graph.errorNames = true
+	}
+}
+
 // Middleware is logging and metrics middleware for all RPC types.
+//
+// It recovers panics of inbound handlers, logging them with their stack
+// trace and counting them.
 type Middleware struct {
-	graph graph
+	graph   graph
+	repanic bool
 }
 
 // NewMiddleware constructs a Middleware.
-func NewMiddleware(logger *zap.Logger, reg *pally.Registry, extract ContextExtractor) *Middleware {
-	return &Middleware{newGraph(reg, logger, extract)}
+func NewMiddleware(logger *zap.Logger, reg *metrics.Registry, extract ContextExtractor, opts ...MiddlewareOption) *Middleware {
+	m := &Middleware{graph: newGraph(reg, logger, extract)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // Handle implements middleware.UnaryInbound.
 func (m *Middleware) Handle(ctx context.Context, req *transport.Request, w transport.ResponseWriter, h transport.UnaryHandler) error {
 	call := m.graph.begin(ctx, transport.Unary, true /* isInbound */, req)
 	wrappedWriter := newWriter(w)
-	err := h.Handle(ctx, req, wrappedWriter)
-	call.End(err, wrappedWriter.isApplicationError)
+	p, err := handleUnary(ctx, call.Request(req), wrappedWriter, h)
+	if p != nil {
+		err = call.Panicked(p)
+	}
+	call.End(err, wrappedWriter.isApplicationError, wrappedWriter.size)
 	wrappedWriter.free()
+	m.propagate(p)
 	return err
 }
 
 // Call implements middleware.UnaryOutbound.
 func (m *Middleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
 	call := m.graph.begin(ctx, transport.Unary, false /* isInbound */, req)
-	res, err := out.Call(ctx, req)
+	res, err := out.Call(ctx, call.Request(req))
 
 	isApplicationError := false
 	if res != nil {
 		isApplicationError = res.ApplicationError
 	}
-	call.End(err, isApplicationError)
+	if err == nil {
+		call.Response(res)
+	}
+	call.End(err, isApplicationError, -1 /* responseSize */)
 	return res, err
 }
 
 // HandleOneway implements middleware.OnewayInbound.
 func (m *Middleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
 	call := m.graph.begin(ctx, transport.Oneway, true /* isInbound */, req)
-	err := h.HandleOneway(ctx, req)
-	call.End(err, false /* isApplicationError */)
+	p, err := handleOneway(ctx, call.Request(req), h)
+	if p != nil {
+		err = call.Panicked(p)
+	}
+	call.End(err, false /* isApplicationError */, -1 /* responseSize */)
+	m.propagate(p)
 	return err
 }
 
 // CallOneway implements middleware.OnewayOutbound.
 func (m *Middleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
 	call := m.graph.begin(ctx, transport.Oneway, false /* isInbound */, req)
-	ack, err := out.CallOneway(ctx, req)
-	call.End(err, false /* isApplicationError */)
+	ack, err := out.CallOneway(ctx, call.Request(req))
+	call.End(err, false /* isApplicationError */, -1 /* responseSize */)
 	return ack, err
 }
+
+// propagate panics again with the recovered panic, if any, when configured
+// to do so.
+func (m *Middleware) propagate(p *recovered) {
+	if p != nil && m.repanic {
+		panic(repanic.Panic{Value: p.value})
+	}
+}
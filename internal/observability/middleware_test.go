@@ -33,8 +33,10 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
-	"go.uber.org/yarpc/internal/pally"
-	"go.uber.org/yarpc/internal/pally/pallytest"
+	"go.uber.org/yarpc/internal/remote"
+	"go.uber.org/yarpc/x/metrics"
+	"go.uber.org/yarpc/x/metrics/metricstest"
+	"go.uber.org/yarpc/yarpcerrors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
@@ -130,7 +132,7 @@ func TestMiddlewareLogging(t *testing.T) {
 
 	for _, tt := range tests {
 		core, logs := observer.New(zapcore.DebugLevel)
-		mw := NewMiddleware(zap.New(core), pally.NewRegistry(), NewNopContextExtractor())
+		mw := NewMiddleware(zap.New(core), metrics.NewRegistry(), NewNopContextExtractor())
 
 		getLog := func() observer.LoggedEntry {
 			entries := logs.TakeAll()
@@ -246,7 +248,7 @@ func TestUnaryInboundApplicationErrors(t *testing.T) {
 	}
 
 	core, logs := observer.New(zap.DebugLevel)
-	mw := NewMiddleware(zap.New(core), pally.NewRegistry(), NewNopContextExtractor())
+	mw := NewMiddleware(zap.New(core), metrics.NewRegistry(), NewNopContextExtractor())
 
 	assert.NoError(t, mw.Handle(
 		context.Background(),
@@ -271,7 +273,7 @@ func TestUnaryInboundApplicationErrors(t *testing.T) {
 
 func TestMiddlewareStats(t *testing.T) {
 	defer stubTime()()
-	reg := pally.NewRegistry()
+	reg := metrics.NewRegistry()
 	mw := NewMiddleware(zap.NewNop(), reg, NewNopContextExtractor())
 
 	err := mw.Handle(
@@ -294,5 +296,146 @@ func TestMiddlewareStats(t *testing.T) {
 	expected, err := ioutil.ReadFile("testdata/prom.txt")
 	assert.NoError(t, err, "Unexpected error reading testdata.")
 
-	pallytest.AssertPrometheus(t, reg, strings.TrimSpace(string(expected)))
+	metricstest.AssertPrometheus(t, reg, strings.TrimSpace(string(expected)))
+}
+
+func TestMiddlewareFailureStats(t *testing.T) {
+	defer stubTime()()
+	reg := metrics.NewRegistry()
+	mw := NewMiddleware(zap.NewNop(), reg, NewNopContextExtractor(), LabelErrorNames())
+	req := &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "raw",
+		Procedure: "procedure",
+		Body:      strings.NewReader("body"),
+	}
+
+	errs := []error{
+		yarpcerrors.ResourceExhaustedErrorf("too many requests"),
+		yarpcerrors.ResourceExhaustedErrorf("too many requests"),
+		yarpcerrors.InvalidArgumentErrorf("bad request"),
+		yarpcerrors.NamedErrorf("out-of-stock", "no more items"),
+		errors.New("great sadness"),
+	}
+	for _, err := range errs {
+		assert.Error(t, mw.Handle(context.Background(), req, &transporttest.FakeResponseWriter{}, fakeHandler{err, false}))
+	}
+	assert.NoError(t, mw.Handle(context.Background(), req, &transporttest.FakeResponseWriter{}, fakeHandler{nil, true}))
+
+	_, scraped := metricstest.Scrape(t, reg)
+	// Prometheus sorts labels by name.
+	labels := func(code, name string) string {
+		l := `{code="` + code + `",dest="service",encoding="raw",`
+		if name != "" {
+			l += `error_name="` + name + `",`
+		}
+		return l + `procedure="procedure",routing_delegate="default",routing_key="default",source="caller"}`
+	}
+	for _, want := range []string{
+		"failures" + labels("resource-exhausted", "default") + " 2",
+		"failures" + labels("invalid-argument", "default") + " 1",
+		"failures" + labels("unknown", "out-of-stock") + " 1",
+		"failures" + labels("unknown", "default") + " 1",
+		"failures" + labels("application_error", "default") + " 1",
+		"failure_latency_ms_count" + labels("resource-exhausted", "") + " 2",
+		"failure_latency_ms_count" + labels("unknown", "") + " 2",
+	} {
+		assert.Contains(t, scraped, want)
+	}
+}
+
+func TestMiddlewareFailureStatsWithoutErrorNames(t *testing.T) {
+	reg := metrics.NewRegistry()
+	mw := NewMiddleware(zap.NewNop(), reg, NewNopContextExtractor())
+	req := &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "raw",
+		Procedure: "procedure",
+		Body:      strings.NewReader("body"),
+	}
+
+	for _, name := range []string{"out-of-stock", "no-such-user"} {
+		err := yarpcerrors.NamedErrorf(name, "great sadness")
+		assert.Error(t, mw.Handle(context.Background(), req, &transporttest.FakeResponseWriter{}, fakeHandler{err, false}))
+	}
+
+	_, scraped := metricstest.Scrape(t, reg)
+	assert.Contains(t, scraped, `failures{code="unknown",dest="service",encoding="raw",error_name="default",`+
+		`procedure="procedure",routing_delegate="default",routing_key="default",source="caller"} 2`)
+	assert.NotContains(t, scraped, "out-of-stock")
+}
+
+func TestMiddlewarePayloadSizes(t *testing.T) {
+	reg := metrics.NewRegistry()
+	mw := NewMiddleware(zap.NewNop(), reg, NewNopContextExtractor())
+	req := &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "raw",
+		Procedure: "procedure",
+		// Neither the size of this body nor of the response is known
+		// upfront, so they are counted as they are read.
+		Body: ioutil.NopCloser(strings.NewReader("hello")),
+	}
+
+	out := outboundFunc(func(_ context.Context, req *transport.Request) (*transport.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(body))
+		return &transport.Response{Body: ioutil.NopCloser(strings.NewReader("hello, world"))}, nil
+	})
+	res, err := mw.Call(context.Background(), req, out)
+	require.NoError(t, err)
+
+	labels := `dest="service",encoding="raw",procedure="procedure",routing_delegate="default",routing_key="default",source="caller"`
+	_, scraped := metricstest.Scrape(t, reg)
+	assert.Contains(t, scraped, `response_payload_bytes_count{`+labels+`} 0`, "response size recorded before the body is closed")
+
+	_, err = ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.NoError(t, res.Body.Close())
+
+	w := &transporttest.FakeResponseWriter{}
+	err = mw.Handle(context.Background(), req, w, handlerFunc(func(_ context.Context, _ *transport.Request, w transport.ResponseWriter) error {
+		_, err := w.Write([]byte("abc"))
+		return err
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "abc", w.Body.String())
+
+	_, scraped = metricstest.Scrape(t, reg)
+	for _, want := range []string{
+		`request_payload_bytes_sum{` + labels + `} 5`,
+		`request_payload_bytes_count{` + labels + `} 2`,
+		`response_payload_bytes_sum{` + labels + `} 15`,
+		`response_payload_bytes_count{` + labels + `} 2`,
+	} {
+		assert.Contains(t, scraped, want)
+	}
+}
+
+func TestInFlightRequests(t *testing.T) {
+	reg := metrics.NewRegistry()
+	mw := NewMiddleware(zap.NewNop(), reg, NewNopContextExtractor())
+	ctx := remote.NewContext(context.Background(), remote.Peer{Transport: "http"})
+	req := &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "raw",
+		Procedure: "procedure",
+	}
+
+	err := mw.Handle(ctx, req, &transporttest.FakeResponseWriter{}, handlerFunc(
+		func(context.Context, *transport.Request, transport.ResponseWriter) error {
+			_, scraped := metricstest.Scrape(t, reg)
+			assert.Contains(t, scraped, `in_flight_requests{procedure="procedure",transport="http"} 1`)
+			return nil
+		}))
+	require.NoError(t, err)
+
+	_, scraped := metricstest.Scrape(t, reg)
+	assert.Contains(t, scraped, `in_flight_requests{procedure="procedure",transport="http"} 0`)
 }
@@ -0,0 +1,52 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"context"
+	"runtime/debug"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// recovered is a panic recovered from an inbound handler.
+type recovered struct {
+	value interface{}
+	stack []byte
+}
+
+func handleUnary(ctx context.Context, req *transport.Request, w transport.ResponseWriter, h transport.UnaryHandler) (p *recovered, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p = &recovered{value: r, stack: debug.Stack()}
+		}
+	}()
+	return nil, h.Handle(ctx, req, w)
+}
+
+func handleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) (p *recovered, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p = &recovered{value: r, stack: debug.Stack()}
+		}
+	}()
+	return nil, h.HandleOneway(ctx, req)
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/repanic"
+	"go.uber.org/yarpc/x/metrics"
+	"go.uber.org/yarpc/x/metrics/metricstest"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type panickingHandler struct{}
+
+func (panickingHandler) Handle(context.Context, *transport.Request, transport.ResponseWriter) error {
+	panic("oops")
+}
+
+func (panickingHandler) HandleOneway(context.Context, *transport.Request) error {
+	panic("oops")
+}
+
+var panicReq = &transport.Request{
+	Caller:    "caller",
+	Service:   "service",
+	Encoding:  "raw",
+	Procedure: "procedure",
+}
+
+func TestMiddlewareRecoversPanics(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	reg := metrics.NewRegistry()
+	mw := NewMiddleware(zap.New(core), reg, NewNopContextExtractor())
+
+	err := mw.Handle(context.Background(), panicReq, &transporttest.FakeResponseWriter{}, panickingHandler{})
+	assert.Equal(t, yarpcerrors.InternalErrorf("panic: oops"), err)
+
+	err = mw.HandleOneway(context.Background(), panicReq, panickingHandler{})
+	assert.Equal(t, yarpcerrors.InternalErrorf("panic: oops"), err)
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	for i, rpcType := range []string{"Unary", "Oneway"} {
+		entry := entries[i]
+		assert.Equal(t, "Handler panicked.", entry.Message)
+		fields := entry.ContextMap()
+		assert.Equal(t, "caller", fields["source"])
+		assert.Equal(t, "procedure", fields["procedure"])
+		assert.Equal(t, rpcType, fields["rpcType"])
+		assert.Equal(t, "oops", fields["panic"])
+		assert.Contains(t, fields["stack"], "panickingHandler")
+	}
+
+	_, scraped := metricstest.Scrape(t, reg)
+	assert.Contains(t, scraped,
+		`panics{dest="service",encoding="raw",procedure="procedure",routing_delegate="default",routing_key="default",source="caller"} 2`)
+	assert.Contains(t, scraped,
+		`server_failures{dest="service",encoding="raw",error="unexpected",procedure="procedure",routing_delegate="default",routing_key="default",source="caller"} 2`)
+}
+
+func TestMiddlewareRepanics(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	reg := metrics.NewRegistry()
+	mw := NewMiddleware(zap.New(core), reg, NewNopContextExtractor(), Repanic())
+
+	recovered := func(f func()) (r interface{}) {
+		defer func() { r = recover() }()
+		f()
+		return nil
+	}
+
+	assert.Equal(t, repanic.Panic{Value: "oops"}, recovered(func() {
+		mw.Handle(context.Background(), panicReq, &transporttest.FakeResponseWriter{}, panickingHandler{})
+	}))
+	assert.Equal(t, repanic.Panic{Value: "oops"}, recovered(func() {
+		mw.HandleOneway(context.Background(), panicReq, panickingHandler{})
+	}))
+
+	assert.Equal(t, 2, logs.Len(), "expected panics to be logged")
+	_, scraped := metricstest.Scrape(t, reg)
+	assert.Contains(t, scraped,
+		`panics{dest="service",encoding="raw",procedure="procedure",routing_delegate="default",routing_key="default",source="caller"} 2`)
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/pkg/lifecycle"
+	"go.uber.org/yarpc/x/metrics"
+	"go.uber.org/zap"
+)
+
+// PeerGauges keeps gauges of the peers of outbounds up to date: the number
+// of peers of their peer lists, how many are available and connecting, and
+// the number of requests pending on them.
+//
+// Gauges are updated periodically from the introspection of the outbounds,
+// while the PeerGauges are running.
+type PeerGauges struct {
+	once      *lifecycle.Once
+	interval  time.Duration
+	outbounds []peerGauges
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+type peerGauges struct {
+	outbound introspection.IntrospectableOutbound
+
+	peers      metrics.Gauge
+	available  metrics.Gauge
+	connecting metrics.Gauge
+	pending    metrics.Gauge
+}
+
+// NewPeerGauges builds PeerGauges for the given outbounds, by outbound key,
+// updated at the given interval.
+func NewPeerGauges(reg *metrics.Registry, logger *zap.Logger, interval time.Duration, outbounds map[string]introspection.IntrospectableOutbound) *PeerGauges {
+	newVector := func(name, help string) metrics.GaugeVector {
+		v, err := reg.NewGaugeVector(metrics.Opts{
+			Name:           name,
+			Help:           help,
+			VariableLabels: []string{"outbound"},
+		})
+		if err != nil {
+			logger.Error("Failed to create peer gauge.", zap.String("name", name), zap.Error(err))
+			return metrics.NewNopGaugeVector()
+		}
+		return v
+	}
+	peers := newVector("outbound_peers", "Number of peers of outbounds.")
+	available := newVector("outbound_available_peers", "Number of available peers of outbounds.")
+	connecting := newVector("outbound_connecting_peers", "Number of connecting peers of outbounds.")
+	pending := newVector("outbound_pending_requests", "Number of requests pending on the peers of outbounds.")
+
+	g := &PeerGauges{
+		once:      lifecycle.NewOnce(),
+		interval:  interval,
+		outbounds: make([]peerGauges, 0, len(outbounds)),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for key, o := range outbounds {
+		g.outbounds = append(g.outbounds, peerGauges{
+			outbound:   o,
+			peers:      peers.MustGet(key),
+			available:  available.MustGet(key),
+			connecting: connecting.MustGet(key),
+			pending:    pending.MustGet(key),
+		})
+	}
+	return g
+}
+
+// Start updates the gauges, and keeps updating them periodically until
+// stopped.
+func (g *PeerGauges) Start() error {
+	return g.once.Start(func() error {
+		g.Update()
+		go g.loop()
+		return nil
+	})
+}
+
+// Stop stops updating the gauges.
+func (g *PeerGauges) Stop() error {
+	return g.once.Stop(func() error {
+		close(g.stop)
+		<-g.done
+		return nil
+	})
+}
+
+func (g *PeerGauges) loop() {
+	defer close(g.done)
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.Update()
+		case <-g.stop:
+			return
+		}
+	}
+}
+
+// Update updates the gauges from the introspection of the outbounds.
+func (g *PeerGauges) Update() {
+	for _, o := range g.outbounds {
+		var counts peerCounts
+		counts.add(o.outbound.Introspect())
+		o.peers.Store(counts.peers)
+		o.available.Store(counts.available)
+		o.connecting.Store(counts.connecting)
+		o.pending.Store(counts.pending)
+	}
+}
+
+type peerCounts struct {
+	peers, available, connecting, pending int64
+}
+
+// add counts the peers of an outbound, including the peers of the child
+// outbounds it splits traffic between.
+func (c *peerCounts) add(status introspection.OutboundStatus) {
+	for _, p := range status.Chooser.Peers {
+		c.peers++
+		switch p.ConnectionStatus {
+		case peer.Available.String():
+			c.available++
+		case peer.Connecting.String():
+			c.connecting++
+		}
+		c.pending += int64(p.PendingRequests)
+	}
+	for _, s := range status.Splits {
+		c.add(s.Outbound)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/x/metrics"
+	"go.uber.org/yarpc/x/metrics/metricstest"
+	"go.uber.org/zap"
+)
+
+type introspectableOutbound struct {
+	status  introspection.OutboundStatus
+	updates int32
+}
+
+func (o *introspectableOutbound) Introspect() introspection.OutboundStatus {
+	atomic.AddInt32(&o.updates, 1)
+	return o.status
+}
+
+func TestPeerGauges(t *testing.T) {
+	single := &introspectableOutbound{status: introspection.OutboundStatus{
+		Chooser: introspection.ChooserStatus{Peers: []introspection.PeerStatus{
+			{ConnectionStatus: "Available", PendingRequests: 3},
+			{ConnectionStatus: "Available", PendingRequests: 1},
+			{ConnectionStatus: "Connecting"},
+			{ConnectionStatus: "Unavailable"},
+		}},
+	}}
+	split := &introspectableOutbound{status: introspection.OutboundStatus{
+		Splits: []introspection.SplitStatus{
+			{Outbound: single.status},
+			{Outbound: introspection.OutboundStatus{
+				Chooser: introspection.ChooserStatus{Peers: []introspection.PeerStatus{
+					{ConnectionStatus: "Available", PendingRequests: 2},
+				}},
+			}},
+		},
+	}}
+
+	reg := metrics.NewRegistry()
+	gauges := NewPeerGauges(reg, zap.NewNop(), time.Millisecond, map[string]introspection.IntrospectableOutbound{
+		"single": single,
+		"split":  split,
+	})
+	gauges.Update()
+
+	_, scraped := metricstest.Scrape(t, reg)
+	for _, want := range []string{
+		`outbound_peers{outbound="single"} 4`,
+		`outbound_available_peers{outbound="single"} 2`,
+		`outbound_connecting_peers{outbound="single"} 1`,
+		`outbound_pending_requests{outbound="single"} 4`,
+		`outbound_peers{outbound="split"} 5`,
+		`outbound_available_peers{outbound="split"} 3`,
+		`outbound_connecting_peers{outbound="split"} 1`,
+		`outbound_pending_requests{outbound="split"} 6`,
+	} {
+		assert.Contains(t, strings.Split(scraped, "\n"), want)
+	}
+}
+
+func TestPeerGaugesLifecycle(t *testing.T) {
+	o := &introspectableOutbound{}
+	gauges := NewPeerGauges(metrics.NewRegistry(), zap.NewNop(), time.Millisecond, map[string]introspection.IntrospectableOutbound{
+		"outbound": o,
+	})
+	require.NoError(t, gauges.Start())
+	assert.True(t, atomic.LoadInt32(&o.updates) >= 1, "gauges should be updated when started")
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&o.updates) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.True(t, atomic.LoadInt32(&o.updates) >= 3, "gauges should be updated periodically")
+
+	require.NoError(t, gauges.Stop())
+	updates := atomic.LoadInt32(&o.updates)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, updates, atomic.LoadInt32(&o.updates), "gauges should not be updated once stopped")
+	assert.NoError(t, gauges.Stop(), "stopping twice should succeed")
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+)
+
+// Beyond this many procedures, the slowest requests of new procedures aren't
+// tracked, so that requests for unknown procedures can't grow the tracker
+// without bound.
+const _maxTrackedProcedures = 1024
+
+// RequestTracker keeps the most recent failed requests and the slowest
+// requests of each procedure in bounded, in-memory buffers, so that they can
+// be inspected while debugging.
+//
+// Recording a request which succeeded and isn't among the slowest of its
+// procedure takes no exclusive locks.
+type RequestTracker struct {
+	// Kept first so that it's 64-bit aligned for atomic operations.
+	next     uint64 // atomic; the number of failures recorded
+	failures []failureSlot
+
+	slowest int
+	procsMu sync.RWMutex
+	procs   map[procedureKey]*slowRequests
+}
+
+// NewRequestTracker builds a RequestTracker which keeps the given number of
+// recent failures and slowest requests of each procedure.
+func NewRequestTracker(failures, slowest int) *RequestTracker {
+	if failures < 0 {
+		failures = 0
+	}
+	if slowest < 0 {
+		slowest = 0
+	}
+	return &RequestTracker{
+		failures: make([]failureSlot, failures),
+		slowest:  slowest,
+		procs:    make(map[procedureKey]*slowRequests),
+	}
+}
+
+// RecentFailures returns the most recent failed requests, newest first.
+func (t *RequestTracker) RecentFailures() []introspection.RequestStatus {
+	if t == nil || len(t.failures) == 0 {
+		return nil
+	}
+	n := uint64(len(t.failures))
+	next := atomic.LoadUint64(&t.next)
+	var oldest uint64
+	if next > n {
+		oldest = next - n
+	}
+	statuses := make([]introspection.RequestStatus, 0, next-oldest)
+	for i := next; i > oldest; i-- {
+		slot := &t.failures[(i-1)%n]
+		slot.Lock()
+		// Skip slots which were already overwritten by newer failures, or
+		// are still being written.
+		if slot.seq == i {
+			statuses = append(statuses, slot.status)
+		}
+		slot.Unlock()
+	}
+	return statuses
+}
+
+// SlowestRequests returns the slowest requests of each procedure, ordered by
+// direction, service and procedure.
+func (t *RequestTracker) SlowestRequests() []introspection.SlowRequestsStatus {
+	if t == nil {
+		return nil
+	}
+	t.procsMu.RLock()
+	statuses := make([]introspection.SlowRequestsStatus, 0, len(t.procs))
+	for key, s := range t.procs {
+		s.mu.Lock()
+		requests := make([]introspection.RequestStatus, len(s.requests))
+		copy(requests, s.requests)
+		s.mu.Unlock()
+		statuses = append(statuses, introspection.SlowRequestsStatus{
+			Direction: direction(key.inbound),
+			Service:   key.service,
+			Procedure: key.procedure,
+			Requests:  requests,
+		})
+	}
+	t.procsMu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		if a.Direction != b.Direction {
+			return a.Direction < b.Direction
+		}
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Procedure < b.Procedure
+	})
+	return statuses
+}
+
+func (t *RequestTracker) addFailure(status introspection.RequestStatus) {
+	if len(t.failures) == 0 {
+		return
+	}
+	seq := atomic.AddUint64(&t.next, 1)
+	slot := &t.failures[(seq-1)%uint64(len(t.failures))]
+	slot.Lock()
+	// A slower writer mustn't replace a newer failure in the same slot.
+	if seq > slot.seq {
+		slot.seq = seq
+		slot.status = status
+	}
+	slot.Unlock()
+}
+
+// slowRequests returns the slowest requests of the procedure of a request,
+// or nil if they aren't tracked.
+func (t *RequestTracker) slowRequests(inbound bool, req *transport.Request) *slowRequests {
+	if t.slowest == 0 {
+		return nil
+	}
+	key := procedureKey{inbound: inbound, service: req.Service, procedure: req.Procedure}
+	t.procsMu.RLock()
+	s := t.procs[key]
+	t.procsMu.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	t.procsMu.Lock()
+	defer t.procsMu.Unlock()
+	if s := t.procs[key]; s != nil {
+		return s
+	}
+	if len(t.procs) >= _maxTrackedProcedures {
+		return nil
+	}
+	s = &slowRequests{threshold: -1, capacity: t.slowest}
+	t.procs[key] = s
+	return s
+}
+
+type failureSlot struct {
+	sync.Mutex
+
+	seq    uint64 // the number of the failure in the slot, starting at 1
+	status introspection.RequestStatus
+}
+
+type procedureKey struct {
+	inbound   bool
+	service   string
+	procedure string
+}
+
+// slowRequests keeps the slowest requests of a procedure, slowest first.
+type slowRequests struct {
+	// Requests must be slower than this many nanoseconds to be kept. It's
+	// negative until the buffer is full.
+	threshold int64 // atomic
+
+	mu       sync.Mutex
+	capacity int
+	requests []introspection.RequestStatus
+}
+
+// admits reports whether a request which took the given time would be kept.
+func (s *slowRequests) admits(elapsed time.Duration) bool {
+	return int64(elapsed) > atomic.LoadInt64(&s.threshold)
+}
+
+func (s *slowRequests) add(status introspection.RequestStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.requests), func(i int) bool {
+		return s.requests[i].Duration < status.Duration
+	})
+	if i >= s.capacity {
+		return
+	}
+	if len(s.requests) < s.capacity {
+		s.requests = append(s.requests, introspection.RequestStatus{})
+	}
+	copy(s.requests[i+1:], s.requests[i:])
+	s.requests[i] = status
+	if len(s.requests) == s.capacity {
+		atomic.StoreInt64(&s.threshold, int64(s.requests[len(s.requests)-1].Duration))
+	}
+}
+
+func direction(inbound bool) string {
+	if inbound {
+		return "inbound"
+	}
+	return "outbound"
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package observability
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/x/metrics"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+// stubLatencies makes successive calls take the given times.
+func stubLatencies(latencies ...time.Duration) func() {
+	prev := _timeNow
+	var (
+		now   time.Time
+		ended = true
+	)
+	_timeNow = func() time.Time {
+		ended = !ended
+		if ended && len(latencies) > 0 {
+			now = now.Add(latencies[0])
+			latencies = latencies[1:]
+		}
+		return now
+	}
+	return func() { _timeNow = prev }
+}
+
+func TestRequestTrackerRecentFailures(t *testing.T) {
+	tracker := NewRequestTracker(3, 0)
+	assert.Empty(t, tracker.RecentFailures())
+
+	for i := 0; i < 5; i++ {
+		tracker.addFailure(introspection.RequestStatus{Procedure: fmt.Sprint(i)})
+	}
+	var procedures []string
+	for _, status := range tracker.RecentFailures() {
+		procedures = append(procedures, status.Procedure)
+	}
+	assert.Equal(t, []string{"4", "3", "2"}, procedures, "Expected the newest failures, newest first.")
+}
+
+func TestRequestTrackerSlowestRequests(t *testing.T) {
+	tracker := NewRequestTracker(0, 2)
+	req := &transport.Request{Service: "service", Procedure: "procedure"}
+
+	for _, d := range []time.Duration{2, 1, 3, 2} {
+		slow := tracker.slowRequests(true /* inbound */, req)
+		require.NotNil(t, slow)
+		if slow.admits(d) {
+			slow.add(introspection.RequestStatus{Duration: d})
+		}
+	}
+	assert.False(t, tracker.slowRequests(true, req).admits(2), "Expected requests faster than the kept ones to be dropped.")
+
+	statuses := tracker.SlowestRequests()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "inbound", statuses[0].Direction)
+	assert.Equal(t, "service", statuses[0].Service)
+	assert.Equal(t, "procedure", statuses[0].Procedure)
+	require.Len(t, statuses[0].Requests, 2)
+	assert.Equal(t, time.Duration(3), statuses[0].Requests[0].Duration)
+	assert.Equal(t, time.Duration(2), statuses[0].Requests[1].Duration)
+}
+
+func TestRequestTrackerDisabled(t *testing.T) {
+	var tracker *RequestTracker
+	assert.Nil(t, tracker.RecentFailures())
+	assert.Nil(t, tracker.SlowestRequests())
+
+	tracker = NewRequestTracker(-1, -1)
+	tracker.addFailure(introspection.RequestStatus{})
+	assert.Nil(t, tracker.slowRequests(false, &transport.Request{}))
+	assert.Empty(t, tracker.RecentFailures())
+	assert.Empty(t, tracker.SlowestRequests())
+}
+
+// tracedSpan is a span with the given span context.
+type tracedSpan struct {
+	opentracing.Span
+
+	context opentracing.SpanContext
+}
+
+func (s tracedSpan) Context() opentracing.SpanContext { return s.context }
+
+// stringTraceID is a span context whose trace ID is a string, like those of
+// x/tracecontext.
+type stringTraceID string
+
+func (stringTraceID) ForeachBaggageItem(func(k, v string) bool) {}
+func (id stringTraceID) TraceID() string                        { return string(id) }
+
+// stringerTraceID is a span context whose trace ID is a fmt.Stringer, like
+// those of Jaeger.
+type stringerTraceID uint64
+
+func (stringerTraceID) ForeachBaggageItem(func(k, v string) bool) {}
+func (id stringerTraceID) TraceID() fmt.Stringer                  { return traceIDStringer(id) }
+
+type traceIDStringer uint64
+
+func (id traceIDStringer) String() string { return fmt.Sprintf("%x", uint64(id)) }
+
+func TestTraceID(t *testing.T) {
+	tests := []struct {
+		desc string
+		ctx  context.Context
+		want string
+	}{
+		{desc: "no span", ctx: context.Background()},
+		{
+			desc: "no trace ID",
+			ctx:  opentracing.ContextWithSpan(context.Background(), opentracing.NoopTracer{}.StartSpan("test")),
+		},
+		{
+			desc: "string trace ID",
+			ctx: opentracing.ContextWithSpan(context.Background(), tracedSpan{
+				Span:    opentracing.NoopTracer{}.StartSpan("test"),
+				context: stringTraceID("abc"),
+			}),
+			want: "abc",
+		},
+		{
+			desc: "fmt.Stringer trace ID",
+			ctx: opentracing.ContextWithSpan(context.Background(), tracedSpan{
+				Span:    opentracing.NoopTracer{}.StartSpan("test"),
+				context: stringerTraceID(0xabc),
+			}),
+			want: "abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.want, traceID(tt.ctx))
+		})
+	}
+}
+
+func TestMiddlewareTracksRequests(t *testing.T) {
+	defer stubLatencies(3*time.Millisecond, time.Millisecond, 2*time.Millisecond, 5*time.Millisecond)()
+
+	tracker := NewRequestTracker(10, 2)
+	mw := NewMiddleware(zap.NewNop(), metrics.NewRegistry(), NewNopContextExtractor(), TrackRequests(tracker))
+
+	ctx := opentracing.ContextWithSpan(context.Background(), tracedSpan{
+		Span:    opentracing.NoopTracer{}.StartSpan("test"),
+		context: stringTraceID("4bf92f3577b34da6a3ce929d0e0e4736"),
+	})
+
+	newRequest := func() *transport.Request {
+		return &transport.Request{
+			Caller:    "caller",
+			Service:   "service",
+			Encoding:  "raw",
+			Procedure: "procedure",
+		}
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, mw.Handle(ctx, newRequest(), &transporttest.FakeResponseWriter{}, fakeHandler{}))
+	}
+	failed := yarpcerrors.UnavailableErrorf("no peers")
+	_, err := mw.Call(ctx, newRequest(), fakeOutbound{err: failed})
+	require.Error(t, err)
+
+	assert.Equal(t, []introspection.RequestStatus{{
+		Direction: "outbound",
+		RPCType:   "Unary",
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "procedure",
+		Encoding:  "raw",
+		Started:   time.Time{}.Add(6 * time.Millisecond),
+		Duration:  5 * time.Millisecond,
+		Code:      "unavailable",
+		Error:     failed.Error(),
+		TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+	}}, tracker.RecentFailures())
+
+	statuses := tracker.SlowestRequests()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "inbound", statuses[0].Direction)
+	require.Len(t, statuses[0].Requests, 2, "Expected only the slowest inbound requests.")
+	assert.Equal(t, 3*time.Millisecond, statuses[0].Requests[0].Duration)
+	assert.Equal(t, 2*time.Millisecond, statuses[0].Requests[1].Duration)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", statuses[0].Requests[0].TraceID)
+	assert.Equal(t, "outbound", statuses[1].Direction)
+	require.Len(t, statuses[1].Requests, 1)
+	assert.Equal(t, "unavailable", statuses[1].Requests[0].Code)
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerstats
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// _halfLife is how long it takes for an observed latency to weigh half
+	// as much as a fresh one.
+	_halfLife = time.Minute
+
+	// Bucket upper bounds grow geometrically by _bucketGrowth, starting at
+	// _firstBucket, for _numBuckets buckets. This covers roughly 100µs to
+	// three minutes with about 20% relative error.
+	_firstBucket  = 100 * time.Microsecond
+	_bucketGrowth = 1.2
+	_numBuckets   = 80
+)
+
+// _bucketBounds holds the upper bound of each histogram bucket. Latencies
+// beyond the last bound are counted in the last bucket.
+var _bucketBounds = func() []time.Duration {
+	bounds := make([]time.Duration, _numBuckets)
+	b := float64(_firstBucket)
+	for i := range bounds {
+		bounds[i] = time.Duration(b)
+		b *= _bucketGrowth
+	}
+	return bounds
+}()
+
+// decayingHistogram is a latency histogram whose observations lose weight
+// exponentially over time, so quantiles reflect recent behavior of a peer
+// rather than its whole lifetime.
+//
+// decayingHistogram is not safe for concurrent use.
+type decayingHistogram struct {
+	counts    [_numBuckets]float64
+	lastDecay time.Time
+}
+
+func newDecayingHistogram(now time.Time) *decayingHistogram {
+	return &decayingHistogram{lastDecay: now}
+}
+
+// decay scales down all the counts according to the time elapsed since the
+// last decay.
+func (h *decayingHistogram) decay(now time.Time) {
+	elapsed := now.Sub(h.lastDecay)
+	if elapsed <= 0 {
+		return
+	}
+	h.lastDecay = now
+
+	factor := math.Exp2(-float64(elapsed) / float64(_halfLife))
+	for i := range h.counts {
+		h.counts[i] *= factor
+	}
+}
+
+func (h *decayingHistogram) observe(now time.Time, d time.Duration) {
+	h.decay(now)
+	h.counts[bucketFor(d)]++
+}
+
+// quantile returns an estimate of the q-th quantile of the observed
+// latencies, interpolating linearly within the bucket it falls in. It
+// returns zero if nothing was observed.
+func (h *decayingHistogram) quantile(q float64) time.Duration {
+	var total float64
+	for _, c := range h.counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * total
+	var cumulative float64
+	for i, c := range h.counts {
+		if c == 0 || cumulative+c < rank {
+			cumulative += c
+			continue
+		}
+
+		var lower time.Duration
+		if i > 0 {
+			lower = _bucketBounds[i-1]
+		}
+		upper := _bucketBounds[i]
+		fraction := (rank - cumulative) / c
+		return lower + time.Duration(fraction*float64(upper-lower))
+	}
+	return _bucketBounds[len(_bucketBounds)-1]
+}
+
+// bucketFor returns the index of the smallest bucket whose upper bound is at
+// least d.
+func bucketFor(d time.Duration) int {
+	if d <= _firstBucket {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(_firstBucket)) / math.Log(_bucketGrowth)))
+	if i >= _numBuckets {
+		return _numBuckets - 1
+	}
+	// Guard against floating point error at bucket boundaries.
+	for i > 0 && d <= _bucketBounds[i-1] {
+		i--
+	}
+	for i < _numBuckets-1 && d > _bucketBounds[i] {
+		i++
+	}
+	return i
+}
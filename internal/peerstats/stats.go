@@ -0,0 +1,203 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package peerstats records the outcome and latency of requests that peer
+// lists send to each of their peers, for introspection.
+package peerstats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Set tracks Stats for every peer of a peer list, keyed by peer identifier.
+//
+// The zero value is not usable; use NewSet.
+type Set struct {
+	clock clock.Clock
+
+	mu    sync.RWMutex
+	stats map[string]*Stats
+}
+
+// SetOption customizes a Set.
+type SetOption func(*Set)
+
+// Clock specifies the clock the Set uses to measure request latencies.
+//
+// Defaults to the system clock.
+func Clock(c clock.Clock) SetOption {
+	return func(s *Set) {
+		s.clock = c
+	}
+}
+
+// NewSet builds a new, empty Set.
+func NewSet(opts ...SetOption) *Set {
+	s := &Set{
+		clock: clock.NewReal(),
+		stats: make(map[string]*Stats),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Get returns the Stats for the given peer identifier, creating them if
+// this is the first time the peer has been seen.
+func (s *Set) Get(id string) *Stats {
+	s.mu.RLock()
+	st, ok := s.stats[id]
+	s.mu.RUnlock()
+	if ok {
+		return st
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.stats[id]; ok {
+		return st
+	}
+	st = newStats(s.clock)
+	s.stats[id] = st
+	return st
+}
+
+// Lookup returns the Stats for the given peer identifier, if it has been
+// seen. Unlike Get, it never creates them.
+func (s *Set) Lookup(id string) (*Stats, bool) {
+	s.mu.RLock()
+	st, ok := s.stats[id]
+	s.mu.RUnlock()
+	return st, ok
+}
+
+// Remove forgets the Stats for the given peer identifier. Peer lists call
+// this when they release a peer.
+func (s *Set) Remove(id string) {
+	s.mu.Lock()
+	delete(s.stats, id)
+	s.mu.Unlock()
+}
+
+// Introspect returns a summary of the Stats for the given peer identifier,
+// or nil if no requests have been sent to that peer.
+func (s *Set) Introspect(id string) *introspection.PeerStats {
+	s.mu.RLock()
+	st, ok := s.stats[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	ps := st.Introspect()
+	if ps.Requests == 0 {
+		return nil
+	}
+	return ps
+}
+
+// Stats holds the request counts, error counts and decaying latency
+// distribution of a single peer.
+type Stats struct {
+	clock clock.Clock
+
+	mu           sync.Mutex
+	requests     int64
+	errors       int64
+	errorsByCode map[yarpcerrors.Code]int64
+	latencies    *decayingHistogram
+}
+
+func newStats(c clock.Clock) *Stats {
+	return &Stats{
+		clock:        c,
+		errorsByCode: make(map[yarpcerrors.Code]int64),
+		latencies:    newDecayingHistogram(c.Now()),
+	}
+}
+
+// Begin marks the start of a request to the peer. The returned function
+// must be called exactly once with the outcome of the request, typically
+// from the onFinish callback returned by a peer.Chooser.
+func (st *Stats) Begin() func(error) {
+	start := st.clock.Now()
+	return func(err error) {
+		st.observe(start, err)
+	}
+}
+
+func (st *Stats) observe(start time.Time, err error) {
+	now := st.clock.Now()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.requests++
+	if err != nil {
+		st.errors++
+		st.errorsByCode[errorCode(err)]++
+	}
+	st.latencies.observe(now, now.Sub(start))
+}
+
+// Introspect returns a snapshot of the Stats.
+func (st *Stats) Introspect() *introspection.PeerStats {
+	now := st.clock.Now()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	ps := &introspection.PeerStats{
+		Requests: st.requests,
+		Errors:   st.errors,
+	}
+	if len(st.errorsByCode) > 0 {
+		ps.ErrorsByCode = make(map[string]int64, len(st.errorsByCode))
+		for code, n := range st.errorsByCode {
+			ps.ErrorsByCode[code.String()] = n
+		}
+	}
+	st.latencies.decay(now)
+	ps.LatencyP50 = st.latencies.quantile(0.5)
+	ps.LatencyP90 = st.latencies.quantile(0.9)
+	ps.LatencyP99 = st.latencies.quantile(0.99)
+	return ps
+}
+
+// errorCode classifies the error a request finished with.
+func errorCode(err error) yarpcerrors.Code {
+	if yarpcerrors.IsYARPCError(err) {
+		return yarpcerrors.ErrorCode(err)
+	}
+	switch err {
+	case context.DeadlineExceeded:
+		return yarpcerrors.CodeDeadlineExceeded
+	case context.Canceled:
+		return yarpcerrors.CodeCancelled
+	default:
+		return yarpcerrors.CodeUnknown
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package peerstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func TestSetIntrospectUnknownPeer(t *testing.T) {
+	s := NewSet()
+	assert.Nil(t, s.Introspect("foo"))
+}
+
+func TestStatsCounts(t *testing.T) {
+	c := clock.NewFake()
+	s := NewSet(Clock(c))
+
+	for _, err := range []error{
+		nil,
+		nil,
+		yarpcerrors.UnavailableErrorf("down"),
+		yarpcerrors.UnavailableErrorf("still down"),
+		yarpcerrors.InternalErrorf("oops"),
+		context.DeadlineExceeded,
+		context.Canceled,
+		errors.New("great sadness"),
+	} {
+		record := s.Get("foo").Begin()
+		c.Add(time.Millisecond)
+		record(err)
+	}
+
+	stats := s.Introspect("foo")
+	require.NotNil(t, stats)
+	assert.Equal(t, int64(8), stats.Requests)
+	assert.Equal(t, int64(6), stats.Errors)
+	assert.Equal(t, map[string]int64{
+		"unavailable":       2,
+		"internal":          1,
+		"deadline-exceeded": 1,
+		"cancelled":         1,
+		"unknown":           1,
+	}, stats.ErrorsByCode)
+
+	s.Remove("foo")
+	assert.Nil(t, s.Introspect("foo"))
+}
+
+func TestSetLookup(t *testing.T) {
+	s := NewSet()
+	_, ok := s.Lookup("foo")
+	assert.False(t, ok, "Lookup must not create stats.")
+
+	st := s.Get("foo")
+	got, ok := s.Lookup("foo")
+	assert.True(t, ok)
+	assert.Equal(t, st, got)
+	assert.Nil(t, s.Introspect("foo"), "no requests sent yet")
+
+	s.Remove("foo")
+	_, ok = s.Lookup("foo")
+	assert.False(t, ok)
+}
+
+func TestStatsLatencyQuantiles(t *testing.T) {
+	c := clock.NewFake()
+	s := NewSet(Clock(c))
+
+	observe := func(d time.Duration) {
+		record := s.Get("foo").Begin()
+		c.Add(d)
+		record(nil)
+	}
+
+	for i := 0; i < 90; i++ {
+		observe(10 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		observe(time.Second)
+	}
+
+	stats := s.Introspect("foo")
+	assertWithin(t, 10*time.Millisecond, stats.LatencyP50)
+	assertWithin(t, time.Second, stats.LatencyP99)
+}
+
+func TestStatsLatencyDecays(t *testing.T) {
+	c := clock.NewFake()
+	s := NewSet(Clock(c))
+
+	observe := func(d time.Duration) {
+		record := s.Get("foo").Begin()
+		c.Add(d)
+		record(nil)
+	}
+
+	for i := 0; i < 100; i++ {
+		observe(time.Second)
+	}
+	assertWithin(t, time.Second, s.Introspect("foo").LatencyP50)
+
+	// After many half-lives, old slow requests no longer matter.
+	c.Add(20 * _halfLife)
+	for i := 0; i < 10; i++ {
+		observe(5 * time.Millisecond)
+	}
+	stats := s.Introspect("foo")
+	assertWithin(t, 5*time.Millisecond, stats.LatencyP50)
+	assertWithin(t, 5*time.Millisecond, stats.LatencyP99)
+	assert.Equal(t, int64(110), stats.Requests, "request counts must not decay")
+}
+
+func TestBucketFor(t *testing.T) {
+	assert.Equal(t, 0, bucketFor(0))
+	assert.Equal(t, 0, bucketFor(_firstBucket))
+	assert.Equal(t, _numBuckets-1, bucketFor(time.Hour))
+	for i, bound := range _bucketBounds {
+		assert.Equal(t, i, bucketFor(bound), "bound %v", bound)
+		if i+1 < len(_bucketBounds) {
+			assert.Equal(t, i+1, bucketFor(bound+1), "after bound %v", bound)
+		}
+	}
+}
+
+func TestEmptyHistogram(t *testing.T) {
+	h := newDecayingHistogram(time.Now())
+	assert.Equal(t, time.Duration(0), h.quantile(0.5))
+}
+
+// assertWithin checks that the estimate is within the relative error of the
+// histogram buckets.
+func assertWithin(t *testing.T, want, got time.Duration) {
+	assert.InEpsilon(t, float64(want), float64(got), _bucketGrowth-1,
+		"expected %v, got %v", want, got)
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package propagation forwards allowlisted headers of inbound requests to
+// the outbound calls made while handling them.
+package propagation
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+)
+
+const (
+	_defaultMaxHeaders = 16
+	_defaultMaxBytes   = 4096
+)
+
+type headersKey struct{} // context key for propagated headers
+
+// Config describes which headers are propagated.
+type Config struct {
+	Headers    []string
+	Prefixes   []string
+	MaxHeaders int
+	MaxBytes   int
+}
+
+// Middleware stashes allowlisted headers of inbound requests on their
+// context, and adds the headers stashed on the context of outbound calls to
+// them.
+type Middleware struct {
+	headers    map[string]struct{}
+	prefixes   []string
+	maxHeaders int
+	maxBytes   int
+}
+
+var (
+	_ middleware.UnaryInbound   = (*Middleware)(nil)
+	_ middleware.OnewayInbound  = (*Middleware)(nil)
+	_ middleware.UnaryOutbound  = (*Middleware)(nil)
+	_ middleware.OnewayOutbound = (*Middleware)(nil)
+)
+
+// NewMiddleware builds a Middleware from the given configuration.
+func NewMiddleware(cfg Config) *Middleware {
+	m := &Middleware{
+		headers:    make(map[string]struct{}, len(cfg.Headers)),
+		prefixes:   make([]string, 0, len(cfg.Prefixes)),
+		maxHeaders: cfg.MaxHeaders,
+		maxBytes:   cfg.MaxBytes,
+	}
+	for _, h := range cfg.Headers {
+		m.headers[transport.CanonicalizeHeaderKey(h)] = struct{}{}
+	}
+	for _, p := range cfg.Prefixes {
+		m.prefixes = append(m.prefixes, transport.CanonicalizeHeaderKey(p))
+	}
+	if m.maxHeaders <= 0 {
+		m.maxHeaders = _defaultMaxHeaders
+	}
+	if m.maxBytes <= 0 {
+		m.maxBytes = _defaultMaxBytes
+	}
+	return m
+}
+
+// FromContext returns the headers to propagate carried by the context.
+func FromContext(ctx context.Context) transport.Headers {
+	headers, _ := ctx.Value(headersKey{}).(transport.Headers)
+	return headers
+}
+
+// Handle implements middleware.UnaryInbound.
+func (m *Middleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	return h.Handle(m.stash(ctx, req), req, resw)
+}
+
+// HandleOneway implements middleware.OnewayInbound.
+func (m *Middleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	return h.HandleOneway(m.stash(ctx, req), req)
+}
+
+// Call implements middleware.UnaryOutbound.
+func (m *Middleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	return out.Call(ctx, propagate(ctx, req))
+}
+
+// CallOneway implements middleware.OnewayOutbound.
+func (m *Middleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	return out.CallOneway(ctx, propagate(ctx, req))
+}
+
+// stash returns a context carrying the headers of the request to propagate.
+//
+// Once the maximum number or total size of headers is reached, further
+// headers are dropped, in the alphabetical order of their names.
+func (m *Middleware) stash(ctx context.Context, req *transport.Request) context.Context {
+	var names []string
+	for k := range req.Headers.Items() {
+		if m.allowed(k) {
+			names = append(names, k)
+		}
+	}
+	if len(names) == 0 {
+		return ctx
+	}
+	sort.Strings(names)
+
+	items := req.Headers.Items()
+	headers := transport.NewHeadersWithCapacity(len(names))
+	size := 0
+	for _, k := range names {
+		v := items[k]
+		if headers.Len() == m.maxHeaders || size+len(k)+len(v) > m.maxBytes {
+			break
+		}
+		headers = headers.With(k, v)
+		size += len(k) + len(v)
+	}
+	return context.WithValue(ctx, headersKey{}, headers)
+}
+
+func (m *Middleware) allowed(k string) bool {
+	if _, ok := m.headers[k]; ok {
+		return true
+	}
+	for _, p := range m.prefixes {
+		if strings.HasPrefix(k, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// propagate returns a copy of the request with the headers carried by the
+// context added to it. Headers set explicitly on the request are kept.
+func propagate(ctx context.Context, req *transport.Request) *transport.Request {
+	propagated := FromContext(ctx)
+	if propagated.Len() == 0 {
+		return req
+	}
+
+	items := req.Headers.Items()
+	headers := transport.NewHeadersWithCapacity(len(items) + propagated.Len())
+	for k, v := range propagated.Items() {
+		headers = headers.With(k, v)
+	}
+	for k, v := range items {
+		headers = headers.With(k, v)
+	}
+
+	r := *req
+	r.Headers = headers
+	return &r
+}
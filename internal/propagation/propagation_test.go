@@ -0,0 +1,135 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package propagation
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+)
+
+type handlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f handlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+func TestStash(t *testing.T) {
+	tests := []struct {
+		msg     string
+		cfg     Config
+		headers map[string]string
+		want    map[string]string
+	}{
+		{
+			msg:     "nothing allowed",
+			headers: map[string]string{"x-tenant": "foo"},
+		},
+		{
+			msg:     "names and prefixes",
+			cfg:     Config{Headers: []string{"X-Tenant"}, Prefixes: []string{"X-Exp-"}},
+			headers: map[string]string{"x-tenant": "foo", "x-exp-a": "1", "x-exp-b": "2", "x-other": "bar"},
+			want:    map[string]string{"x-tenant": "foo", "x-exp-a": "1", "x-exp-b": "2"},
+		},
+		{
+			msg:     "max headers",
+			cfg:     Config{Prefixes: []string{"x-"}, MaxHeaders: 2},
+			headers: map[string]string{"x-c": "3", "x-b": "2", "x-a": "1"},
+			want:    map[string]string{"x-a": "1", "x-b": "2"},
+		},
+		{
+			msg:     "max bytes",
+			cfg:     Config{Prefixes: []string{"x-"}, MaxBytes: 10},
+			headers: map[string]string{"x-a": "1234", "x-b": "1234"},
+			want:    map[string]string{"x-a": "1234"},
+		},
+		{
+			msg:     "default max bytes",
+			cfg:     Config{Prefixes: []string{"x-"}},
+			headers: map[string]string{"x-a": strings.Repeat("a", 5000)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			m := NewMiddleware(tt.cfg)
+			req := &transport.Request{Headers: transport.HeadersFromMap(tt.headers)}
+
+			var got transport.Headers
+			err := m.Handle(context.Background(), req, &transporttest.FakeResponseWriter{},
+				handlerFunc(func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) error {
+					got = FromContext(ctx)
+					return nil
+				}))
+			require.NoError(t, err)
+			assert.Equal(t, transport.HeadersFromMap(tt.want).Items(), got.Items())
+		})
+	}
+}
+
+func TestPropagate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	m := NewMiddleware(Config{Headers: []string{"x-tenant", "x-exp"}})
+	unary := transporttest.NewMockUnaryOutbound(mockCtrl)
+	oneway := transporttest.NewMockOnewayOutbound(mockCtrl)
+
+	inbound := &transport.Request{Headers: transport.NewHeaders().With("x-tenant", "foo").With("x-exp", "a")}
+	outbound := &transport.Request{Service: "bar", Headers: transport.NewHeaders().With("x-exp", "b").With("baz", "qux")}
+	want := &transport.Request{
+		Service: "bar",
+		Headers: transport.NewHeaders().With("x-tenant", "foo").With("x-exp", "b").With("baz", "qux"),
+	}
+
+	unary.EXPECT().Call(gomock.Any(), want).Return(&transport.Response{}, nil)
+	oneway.EXPECT().CallOneway(gomock.Any(), want).Return(nil, nil)
+
+	err := m.Handle(context.Background(), inbound, &transporttest.FakeResponseWriter{},
+		handlerFunc(func(ctx context.Context, _ *transport.Request, _ transport.ResponseWriter) error {
+			if _, err := m.Call(ctx, outbound, unary); err != nil {
+				return err
+			}
+			_, err := m.CallOneway(ctx, outbound, oneway)
+			return err
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, outbound.Headers.Len(), "original request must not be modified")
+}
+
+func TestPropagateWithoutHeaders(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	m := NewMiddleware(Config{Headers: []string{"x-tenant"}})
+	req := &transport.Request{Service: "bar"}
+
+	unary := transporttest.NewMockUnaryOutbound(mockCtrl)
+	unary.EXPECT().Call(gomock.Any(), req).Return(&transport.Response{}, nil)
+	_, err := m.Call(context.Background(), req, unary)
+	require.NoError(t, err)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package requestkey builds keys identifying requests, for middleware which
+// caches or deduplicates them.
+package requestkey
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"strconv"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// Builder builds a key out of fields. Fields are prefixed with their length,
+// so that fields containing separators cannot make the keys of different
+// requests collide.
+//
+// The zero value is an empty key, ready to use.
+type Builder struct {
+	buf bytes.Buffer
+}
+
+// Add appends the given fields to the key.
+func (b *Builder) Add(fields ...string) {
+	for _, s := range fields {
+		b.buf.WriteString(strconv.Itoa(len(s)))
+		b.buf.WriteByte(':')
+		b.buf.WriteString(s)
+	}
+}
+
+// AddHeaders appends the names and values of the given headers to the key.
+// Missing headers are added with an empty value.
+func (b *Builder) AddHeaders(headers transport.Headers, names []string) {
+	for _, name := range names {
+		v, _ := headers.Get(name)
+		b.Add(name, v)
+	}
+}
+
+// AddBody appends a digest of the given request body to the key.
+func (b *Builder) AddBody(body []byte) {
+	sum := sha256.Sum256(body)
+	b.Add(hex.EncodeToString(sum[:]))
+}
+
+// String returns the key.
+func (b *Builder) String() string {
+	return b.buf.String()
+}
+
+// BufferBody reads the body of the request. It returns the body, along with
+// a copy of the request whose body may be read again.
+func BufferBody(req *transport.Request) (*transport.Request, []byte, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, nil, err
+		}
+	}
+	r := *req
+	r.Body = bytes.NewReader(body)
+	return &r, body, nil
+}
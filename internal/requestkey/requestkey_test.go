@@ -0,0 +1,85 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package requestkey
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+)
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func key(f func(*Builder)) string {
+	var b Builder
+	f(&b)
+	return b.String()
+}
+
+func TestBuilder(t *testing.T) {
+	assert.Equal(t, "3:foo0:", key(func(b *Builder) { b.Add("foo", "") }))
+
+	// Fields containing separators don't collide with other fields.
+	assert.NotEqual(t,
+		key(func(b *Builder) { b.Add("a:b", "c") }),
+		key(func(b *Builder) { b.Add("a", "b:c") }))
+	assert.NotEqual(t,
+		key(func(b *Builder) { b.Add("x") }),
+		key(func(b *Builder) { b.Add("1:x") }))
+
+	headers := transport.NewHeaders().With("tenant", "a")
+	assert.Equal(t,
+		key(func(b *Builder) { b.Add("tenant", "a", "missing", "") }),
+		key(func(b *Builder) { b.AddHeaders(headers, []string{"tenant", "missing"}) }))
+
+	assert.Equal(t,
+		key(func(b *Builder) { b.AddBody([]byte("hello")) }),
+		key(func(b *Builder) { b.AddBody([]byte("hello")) }))
+	assert.NotEqual(t,
+		key(func(b *Builder) { b.AddBody([]byte("hello")) }),
+		key(func(b *Builder) { b.AddBody([]byte("world")) }))
+}
+
+func TestBufferBody(t *testing.T) {
+	req := &transport.Request{Procedure: "echo", Body: bytes.NewReader([]byte("hello"))}
+	r, body, err := BufferBody(req)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, "echo", r.Procedure)
+	rest, err := ioutil.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(rest))
+
+	r, body, err = BufferBody(&transport.Request{})
+	require.NoError(t, err)
+	assert.Empty(t, body)
+	assert.NotNil(t, r.Body)
+
+	_, _, err = BufferBody(&transport.Request{Body: errReader{errors.New("great sadness")}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package ttl applies default and maximum TTLs to outbound unary requests.
+package ttl
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+)
+
+// Policy bounds the TTL of requests. Zero values are unset.
+type Policy struct {
+	// TTL of requests made without a deadline.
+	Default time.Duration
+
+	// Maximum TTL of requests.
+	Max time.Duration
+}
+
+func (p Policy) isZero() bool {
+	return p.Default == 0 && p.Max == 0
+}
+
+// merge returns the policy with its unset values taken from the fallback.
+func (p Policy) merge(fallback Policy) Policy {
+	if p.Default == 0 {
+		p.Default = fallback.Default
+	}
+	if p.Max == 0 {
+		p.Max = fallback.Max
+	}
+	return p
+}
+
+// apply returns a context whose deadline respects the policy.
+func (p Policy) apply(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	switch {
+	case !ok && p.Default > 0:
+		ttl := p.Default
+		if p.Max > 0 && ttl > p.Max {
+			ttl = p.Max
+		}
+		return context.WithTimeout(ctx, ttl)
+	case ok && p.Max > 0 && time.Until(deadline) > p.Max:
+		return context.WithTimeout(ctx, p.Max)
+	default:
+		return ctx, func() {}
+	}
+}
+
+// Scope holds the policies of an outbound or service.
+type Scope struct {
+	Policy
+
+	// Policies of specific procedures.
+	Procedures map[string]Policy
+}
+
+func (s Scope) isZero() bool {
+	return s.Policy.isZero() && len(s.Procedures) == 0
+}
+
+// Config holds the policies which apply to an outbound.
+type Config struct {
+	// Policy of all outbounds.
+	Global Policy
+
+	// Policies of the outbound.
+	Outbound Scope
+
+	// Policies by service name.
+	Services map[string]Scope
+}
+
+// IsZero returns whether no policy is configured.
+func (c Config) IsZero() bool {
+	if !c.Global.isZero() || !c.Outbound.isZero() {
+		return false
+	}
+	for _, s := range c.Services {
+		if !s.isZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// Policy returns the policy for a procedure of a service. Each value is
+// taken from the most specific policy which sets it: the procedure of the
+// service, the procedure of the outbound, the service, the outbound, and
+// finally the global policy.
+func (c Config) Policy(service, procedure string) Policy {
+	s := c.Services[service]
+	return s.Procedures[procedure].
+		merge(c.Outbound.Procedures[procedure]).
+		merge(s.Policy).
+		merge(c.Outbound.Policy).
+		merge(c.Global)
+}
+
+// UnaryOutbound applies TTL policies to the requests of a unary outbound.
+type UnaryOutbound struct {
+	transport.UnaryOutbound
+
+	service string
+	config  Config
+}
+
+// NewUnaryOutbound wraps an outbound to apply TTL policies to its requests.
+// The service is the name of the service the outbound sends requests to by
+// default, for introspection.
+func NewUnaryOutbound(o transport.UnaryOutbound, service string, cfg Config) *UnaryOutbound {
+	return &UnaryOutbound{UnaryOutbound: o, service: service, config: cfg}
+}
+
+// Call sends the request with a deadline which respects its TTL policy.
+func (o *UnaryOutbound) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	ctx, cancel := o.config.Policy(req.Service, req.Procedure).apply(ctx)
+
+	res, err := o.UnaryOutbound.Call(ctx, req)
+	if err != nil || res == nil || res.Body == nil {
+		cancel()
+		return res, err
+	}
+
+	// The response body may still be read with the context of the request,
+	// so the context is cancelled only once the body is closed.
+	r := *res
+	r.Body = cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return &r, nil
+}
+
+// Introspect returns the status of the underlying outbound, along with the
+// TTL policies of the procedures of its service.
+func (o *UnaryOutbound) Introspect() introspection.OutboundStatus {
+	status := introspection.OutboundStatusNotSupported
+	if i, ok := o.UnaryOutbound.(introspection.IntrospectableOutbound); ok {
+		status = i.Introspect()
+	}
+
+	p := o.config.Policy(o.service, "")
+	status.TTLs = append(status.TTLs, introspection.TTLStatus{Default: p.Default, Max: p.Max})
+
+	procedures := make(map[string]struct{})
+	for name := range o.config.Outbound.Procedures {
+		procedures[name] = struct{}{}
+	}
+	for name := range o.config.Services[o.service].Procedures {
+		procedures[name] = struct{}{}
+	}
+	names := make([]string, 0, len(procedures))
+	for name := range procedures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := o.config.Policy(o.service, name)
+		status.TTLs = append(status.TTLs, introspection.TTLStatus{
+			Procedure: name,
+			Default:   p.Default,
+			Max:       p.Max,
+		})
+	}
+	return status
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
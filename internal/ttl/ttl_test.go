@@ -0,0 +1,232 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ttl
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/introspection"
+)
+
+func TestConfigPolicy(t *testing.T) {
+	cfg := Config{
+		Global: Policy{Default: time.Second, Max: 10 * time.Second},
+		Outbound: Scope{
+			Policy: Policy{Max: 5 * time.Second},
+			Procedures: map[string]Policy{
+				"scan": {Default: 3 * time.Second},
+				"get":  {Max: 4 * time.Second},
+			},
+		},
+		Services: map[string]Scope{
+			"users": {
+				Policy: Policy{Default: 2 * time.Second},
+				Procedures: map[string]Policy{
+					"get": {Max: 6 * time.Second},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		service   string
+		procedure string
+		want      Policy
+	}{
+		{
+			service: "other",
+			want:    Policy{Default: time.Second, Max: 5 * time.Second},
+		},
+		{
+			service:   "other",
+			procedure: "scan",
+			want:      Policy{Default: 3 * time.Second, Max: 5 * time.Second},
+		},
+		{
+			service: "users",
+			want:    Policy{Default: 2 * time.Second, Max: 5 * time.Second},
+		},
+		{
+			service:   "users",
+			procedure: "scan",
+			want:      Policy{Default: 3 * time.Second, Max: 5 * time.Second},
+		},
+		{
+			service:   "users",
+			procedure: "get",
+			want:      Policy{Default: 2 * time.Second, Max: 6 * time.Second},
+		},
+		{
+			service:   "other",
+			procedure: "get",
+			want:      Policy{Default: time.Second, Max: 4 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service+"::"+tt.procedure, func(t *testing.T) {
+			assert.Equal(t, tt.want, cfg.Policy(tt.service, tt.procedure))
+		})
+	}
+}
+
+func TestConfigIsZero(t *testing.T) {
+	assert.True(t, Config{}.IsZero())
+	assert.True(t, Config{Services: map[string]Scope{"users": {}}}.IsZero())
+	assert.False(t, Config{Global: Policy{Max: time.Second}}.IsZero())
+	assert.False(t, Config{Outbound: Scope{Procedures: map[string]Policy{"get": {}}}}.IsZero())
+	assert.False(t, Config{Services: map[string]Scope{"users": {Policy: Policy{Default: time.Second}}}}.IsZero())
+}
+
+func TestUnaryOutboundCall(t *testing.T) {
+	tests := []struct {
+		desc   string
+		policy Policy
+
+		// TTL of the context of the request, if any.
+		ttl time.Duration
+
+		// Expected TTL of the request, if any.
+		want time.Duration
+	}{
+		{
+			desc: "no policy",
+		},
+		{
+			desc:   "no policy with deadline",
+			policy: Policy{},
+			ttl:    time.Minute,
+			want:   time.Minute,
+		},
+		{
+			desc:   "default",
+			policy: Policy{Default: time.Second},
+			want:   time.Second,
+		},
+		{
+			desc:   "default above max",
+			policy: Policy{Default: time.Minute, Max: time.Second},
+			want:   time.Second,
+		},
+		{
+			desc:   "deadline kept",
+			policy: Policy{Default: time.Second, Max: time.Hour},
+			ttl:    time.Minute,
+			want:   time.Minute,
+		},
+		{
+			desc:   "deadline clamped",
+			policy: Policy{Max: time.Second},
+			ttl:    time.Minute,
+			want:   time.Second,
+		},
+		{
+			desc:   "max without deadline",
+			policy: Policy{Max: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			ctx := context.Background()
+			if tt.ttl > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ttl)
+				defer cancel()
+			}
+
+			req := &transport.Request{Service: "users", Procedure: "get"}
+			out := transporttest.NewMockUnaryOutbound(mockCtrl)
+			out.EXPECT().Call(gomock.Any(), req).
+				Do(func(ctx context.Context, _ *transport.Request) {
+					deadline, ok := ctx.Deadline()
+					if tt.want == 0 {
+						assert.False(t, ok, "unexpected deadline")
+						return
+					}
+					require.True(t, ok, "expected a deadline")
+					assert.InDelta(t, tt.want, time.Until(deadline), float64(100*time.Millisecond))
+				}).
+				Return(&transport.Response{}, nil)
+
+			o := NewUnaryOutbound(out, "users", Config{Global: tt.policy})
+			_, err := o.Call(ctx, req)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestUnaryOutboundCancelOnClose(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var callCtx context.Context
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request) { callCtx = ctx }).
+		Return(&transport.Response{Body: ioutil.NopCloser(strings.NewReader("hello"))}, nil)
+
+	o := NewUnaryOutbound(out, "users", Config{Global: Policy{Default: time.Minute}})
+	res, err := o.Call(context.Background(), &transport.Request{Service: "users", Procedure: "get"})
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+	assert.NoError(t, callCtx.Err(), "context must not end before the body is closed")
+
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, context.Canceled, callCtx.Err())
+}
+
+func TestUnaryOutboundIntrospect(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	o := NewUnaryOutbound(transporttest.NewMockUnaryOutbound(mockCtrl), "users", Config{
+		Global: Policy{Default: time.Second, Max: time.Minute},
+		Outbound: Scope{
+			Procedures: map[string]Policy{"scan": {Default: 5 * time.Second}},
+		},
+		Services: map[string]Scope{
+			"users": {Procedures: map[string]Policy{"get": {Max: 2 * time.Second}}},
+			"other": {Procedures: map[string]Policy{"list": {Max: 3 * time.Second}}},
+		},
+	})
+
+	assert.Equal(t, []introspection.TTLStatus{
+		{Default: time.Second, Max: time.Minute},
+		{Procedure: "get", Default: time.Second, Max: 2 * time.Second},
+		{Procedure: "scan", Default: 5 * time.Second, Max: time.Minute},
+	}, o.Introspect().TTLs)
+}
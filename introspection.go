@@ -81,6 +81,8 @@ func (d *Dispatcher) Introspect() introspection.DispatcherStatus {
 		Inbounds:        inbounds,
 		Outbounds:       outbounds,
 		PackageVersions: PackageVersions,
+		RecentFailures:  d.tracker.RecentFailures(),
+		SlowestRequests: d.tracker.SlowestRequests(),
 	}
 }
 
@@ -30,6 +30,7 @@ import (
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/internal/peerstats"
 	"go.uber.org/yarpc/pkg/lifecycle"
 )
 
@@ -68,6 +69,7 @@ func New(transport peer.Transport, opts ...ListOption) *List {
 		availablePeerRing:  newPeerRing(cfg.capacity),
 		transport:          transport,
 		peerAvailableEvent: make(chan struct{}, 1),
+		stats:              peerstats.NewSet(),
 	}
 }
 
@@ -82,6 +84,7 @@ type List struct {
 	availablePeerRing  *peerRing
 	peerAvailableEvent chan struct{}
 	transport          peer.Transport
+	stats              *peerstats.Set
 
 	once *lifecycle.Once
 }
@@ -148,6 +151,7 @@ func (pl *List) addPeerIdentifier(pid peer.Identifier) error {
 		return err
 	}
 
+	pl.stats.Get(pid.Identifier())
 	return pl.addPeer(p)
 }
 
@@ -260,6 +264,7 @@ func (pl *List) removePeerIdentifier(pid peer.Identifier) error {
 		return err
 	}
 
+	pl.stats.Remove(pid.Identifier())
 	return pl.transport.ReleasePeer(pid, pl)
 }
 
@@ -293,10 +298,10 @@ func (pl *List) Choose(ctx context.Context, req *transport.Request) (peer.Peer,
 	}
 
 	for {
-		if nextPeer := pl.nextPeer(); nextPeer != nil {
+		if nextPeer, stats := pl.nextPeer(); nextPeer != nil {
 			pl.notifyPeerAvailable()
 			nextPeer.StartRequest()
-			return nextPeer, pl.getOnFinishFunc(nextPeer), nil
+			return nextPeer, pl.getOnFinishFunc(nextPeer, stats), nil
 		}
 
 		if err := pl.waitForPeerAddedEvent(ctx); err != nil {
@@ -310,13 +315,20 @@ func (pl *List) IsRunning() bool {
 	return pl.once.IsRunning()
 }
 
-// nextPeer grabs the next available peer from the PeerRing and returns it,
-// if there are no available peers it returns nil
-func (pl *List) nextPeer() peer.Peer {
+// nextPeer grabs the next available peer from the PeerRing and returns it
+// with its stats, if there are no available peers it returns nil
+func (pl *List) nextPeer() (peer.Peer, *peerstats.Stats) {
 	pl.lock.Lock()
+	defer pl.lock.Unlock()
+
 	p := pl.availablePeerRing.Next()
-	pl.lock.Unlock()
-	return p
+	if p == nil {
+		return nil, nil
+	}
+	// Stats are only looked up under the lock so that a concurrent removal
+	// of the peer can't leave them behind.
+	stats, _ := pl.stats.Lookup(p.Identifier())
+	return p, stats
 }
 
 // notifyPeerAvailable writes to a channel indicating that a Peer is currently
@@ -329,9 +341,15 @@ func (pl *List) notifyPeerAvailable() {
 }
 
 // getOnFinishFunc creates a closure that will be run at the end of the request
-func (pl *List) getOnFinishFunc(p peer.Peer) func(error) {
-	return func(_ error) {
+// and record its outcome in the peer's stats, if any.
+func (pl *List) getOnFinishFunc(p peer.Peer, stats *peerstats.Stats) func(error) {
+	if stats == nil {
+		return func(error) { p.EndRequest() }
+	}
+	record := stats.Begin()
+	return func(err error) {
 		p.EndRequest()
+		record(err)
 	}
 }
 
@@ -426,6 +444,9 @@ func (pl *List) Introspect() introspection.ChooserStatus {
 			State: fmt.Sprintf("%s, %d pending request(s)",
 				ps.ConnectionStatus.String(),
 				ps.PendingRequestCount),
+			Stats:            pl.stats.Introspect(peer.Identifier()),
+			ConnectionStatus: ps.ConnectionStatus.String(),
+			PendingRequests:  ps.PendingRequestCount,
 		}
 	}
 
@@ -28,10 +28,13 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/multierr"
 	"go.uber.org/yarpc/api/peer"
 	. "go.uber.org/yarpc/api/peer/peertest"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/yarpcerrors"
 )
 
 func TestRoundRobinList(t *testing.T) {
@@ -911,6 +914,40 @@ func TestIntrospect(t *testing.T) {
 	checkPeerStatus(t, peerIdentifierToPeerStatus, "foo", "Unavailable, 0 pending request(s)")
 	checkPeerStatus(t, peerIdentifierToPeerStatus, "bar", "Available, 1 pending request(s)")
 	checkPeerStatus(t, peerIdentifierToPeerStatus, "baz", "Available, 2 pending request(s)")
+	assert.Nil(t, peerIdentifierToPeerStatus["bar"].Stats, "no requests sent yet")
+	assert.Equal(t, "Unavailable", peerIdentifierToPeerStatus["foo"].ConnectionStatus)
+	assert.Equal(t, "Available", peerIdentifierToPeerStatus["baz"].ConnectionStatus)
+	assert.Equal(t, 2, peerIdentifierToPeerStatus["baz"].PendingRequests)
+}
+
+func TestIntrospectPeerStats(t *testing.T) {
+	pl := New(testTransport{})
+	assert.NoError(t, pl.Start())
+	assert.NoError(t, pl.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{newTestPeer("foo", 0, peer.Available)},
+	}))
+
+	for _, err := range []error{nil, yarpcerrors.UnavailableErrorf("down")} {
+		_, onFinish, chooseErr := pl.Choose(context.Background(), &transport.Request{})
+		require.NoError(t, chooseErr)
+		onFinish(err)
+	}
+
+	chooserStatus := pl.Introspect()
+	require.Len(t, chooserStatus.Peers, 1)
+	stats := chooserStatus.Peers[0].Stats
+	require.NotNil(t, stats)
+	assert.Equal(t, int64(2), stats.Requests)
+	assert.Equal(t, int64(1), stats.Errors)
+	assert.Equal(t, map[string]int64{"unavailable": 1}, stats.ErrorsByCode)
+
+	assert.NoError(t, pl.Update(peer.ListUpdates{
+		Removals: []peer.Identifier{newTestPeer("foo", 0, peer.Available)},
+	}))
+	assert.NoError(t, pl.Update(peer.ListUpdates{
+		Additions: []peer.Identifier{newTestPeer("foo", 0, peer.Available)},
+	}))
+	assert.Nil(t, pl.Introspect().Peers[0].Stats, "stats must be reset when a peer is removed")
 }
 
 func checkPeerStatus(
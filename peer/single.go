@@ -27,28 +27,28 @@ import (
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/internal/peerstats"
 	"go.uber.org/yarpc/pkg/lifecycle"
 )
 
 // Single implements the Chooser interface for a single peer
 type Single struct {
-	once          *lifecycle.Once
-	t             peer.Transport
-	pid           peer.Identifier
-	p             peer.Peer
-	err           error
-	boundOnFinish func(error)
+	once  *lifecycle.Once
+	t     peer.Transport
+	pid   peer.Identifier
+	p     peer.Peer
+	err   error
+	stats *peerstats.Set
 }
 
 // NewSingle creates a static Chooser with a single Peer
 func NewSingle(pid peer.Identifier, transport peer.Transport) *Single {
-	s := &Single{
-		once: lifecycle.NewOnce(),
-		pid:  pid,
-		t:    transport,
+	return &Single{
+		once:  lifecycle.NewOnce(),
+		pid:   pid,
+		t:     transport,
+		stats: peerstats.NewSet(),
 	}
-	s.boundOnFinish = s.onFinish
-	return s
 }
 
 // Choose returns the single peer
@@ -57,11 +57,11 @@ func (s *Single) Choose(ctx context.Context, _ *transport.Request) (peer.Peer, f
 		return nil, nil, err
 	}
 	s.p.StartRequest()
-	return s.p, s.boundOnFinish, s.err
-}
-
-func (s *Single) onFinish(_ error) {
-	s.p.EndRequest()
+	record := s.stats.Get(s.pid.Identifier()).Begin()
+	return s.p, func(err error) {
+		s.p.EndRequest()
+		record(err)
+	}, s.err
 }
 
 // NotifyStatusChanged receives notifications from the transport when the peer
@@ -115,6 +115,9 @@ func (s *Single) Introspect() introspection.ChooserStatus {
 		State: fmt.Sprintf("%s, %d pending request(s)",
 			peerStatus.ConnectionStatus.String(),
 			peerStatus.PendingRequestCount),
+		Stats:            s.stats.Introspect(s.pid.Identifier()),
+		ConnectionStatus: peerStatus.ConnectionStatus.String(),
+		PendingRequests:  peerStatus.PendingRequestCount,
 	}
 
 	return introspection.ChooserStatus{
@@ -0,0 +1,287 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package failover
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/pkg/lifecycle"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+var (
+	_ peer.Chooser                        = (*Chooser)(nil)
+	_ introspection.IntrospectableChooser = (*Chooser)(nil)
+)
+
+// Chooser is a peer.Chooser that tries an ordered set of choosers, using the
+// first one that is healthy.
+type Chooser struct {
+	once  *lifecycle.Once
+	tiers []*tier
+
+	chooseTimeout time.Duration
+}
+
+// New builds a failover Chooser from the given choosers, listed from highest
+// to lowest priority. Each chooser is typically a peer list bound to its own
+// peer list updater with peer.Bind.
+//
+// The failover Chooser starts and stops the given choosers over its own
+// lifecycle.
+func New(choosers []peer.Chooser, opts ...ChooserOption) *Chooser {
+	cfg := defaultChooserConfig
+	cfg.clock = clock.NewReal()
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	tiers := make([]*tier, len(choosers))
+	for i, c := range choosers {
+		tiers[i] = &tier{
+			chooser:        c,
+			clock:          cfg.clock,
+			errorThreshold: cfg.errorThreshold,
+			minRequests:    cfg.minRequests,
+			window:         cfg.window,
+			failbackAfter:  cfg.failbackAfter,
+			windowStart:    cfg.clock.Now(),
+		}
+	}
+
+	return &Chooser{
+		once:          lifecycle.NewOnce(),
+		tiers:         tiers,
+		chooseTimeout: cfg.chooseTimeout,
+	}
+}
+
+// Choose selects a peer from the first healthy chooser. If a chooser does not
+// produce a peer within the ChooseTimeout, it is marked unhealthy and the next
+// chooser is tried. The last chooser is used if all others are unhealthy.
+func (c *Chooser) Choose(ctx context.Context, req *transport.Request) (peer.Peer, func(error), error) {
+	if err := c.once.WaitUntilRunning(ctx); err != nil {
+		return nil, nil, err
+	}
+	if len(c.tiers) == 0 {
+		return nil, nil, yarpcerrors.UnavailableErrorf("failover peer chooser has no choosers")
+	}
+
+	last := len(c.tiers) - 1
+	for _, t := range c.tiers[:last] {
+		if !t.healthy() {
+			continue
+		}
+
+		chooseCtx, cancel := context.WithTimeout(ctx, c.chooseTimeout)
+		p, onFinish, err := t.chooser.Choose(chooseCtx, req)
+		cancel()
+		if err == nil {
+			return p, t.wrap(onFinish), nil
+		}
+		if ctx.Err() != nil {
+			// The request itself timed out or was cancelled; there is no
+			// point in trying the remaining choosers.
+			return nil, nil, err
+		}
+		t.trip()
+	}
+
+	t := c.tiers[last]
+	p, onFinish, err := t.chooser.Choose(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, t.wrap(onFinish), nil
+}
+
+// Start starts all the choosers.
+func (c *Chooser) Start() error {
+	return c.once.Start(c.start)
+}
+
+func (c *Chooser) start() error {
+	var errs error
+	for i, t := range c.tiers {
+		if err := t.chooser.Start(); err != nil {
+			errs = multierr.Append(errs, err)
+
+			// Abort the choosers that were already started.
+			for _, started := range c.tiers[:i] {
+				errs = multierr.Append(errs, started.chooser.Stop())
+			}
+			return errs
+		}
+	}
+	return nil
+}
+
+// Stop stops all the choosers.
+func (c *Chooser) Stop() error {
+	return c.once.Stop(c.stop)
+}
+
+func (c *Chooser) stop() error {
+	var errs error
+	for _, t := range c.tiers {
+		errs = multierr.Append(errs, t.chooser.Stop())
+	}
+	return errs
+}
+
+// IsRunning returns whether the failover chooser is running.
+func (c *Chooser) IsRunning() bool {
+	return c.once.IsRunning()
+}
+
+// Introspect returns a ChooserStatus summarizing the health of each chooser
+// and listing the peers of all the choosers.
+func (c *Chooser) Introspect() introspection.ChooserStatus {
+	state := "Stopped"
+	if c.IsRunning() {
+		state = "Running"
+	}
+
+	active := -1
+	var peers []introspection.PeerStatus
+	for i, t := range c.tiers {
+		healthy := t.healthy()
+		if healthy && active < 0 {
+			active = i
+		}
+
+		var status introspection.ChooserStatus
+		if ic, ok := t.chooser.(introspection.IntrospectableChooser); ok {
+			status = ic.Introspect()
+		}
+		health := "healthy"
+		if !healthy {
+			health = "unhealthy"
+		}
+		for _, ps := range status.Peers {
+			ps.State = fmt.Sprintf("chooser %d (%s, %s): %s", i, status.Name, health, ps.State)
+			peers = append(peers, ps)
+		}
+	}
+	if active < 0 {
+		active = len(c.tiers) - 1
+	}
+
+	return introspection.ChooserStatus{
+		Name:  "Failover",
+		State: fmt.Sprintf("%s (using chooser %d of %d)", state, active, len(c.tiers)),
+		Peers: peers,
+	}
+}
+
+// tier tracks the health of one of the choosers of a failover Chooser.
+type tier struct {
+	chooser peer.Chooser
+	clock   clock.Clock
+
+	errorThreshold float64
+	minRequests    int
+	window         time.Duration
+	failbackAfter  time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	requests    int
+	failures    int
+	downUntil   time.Time
+}
+
+// healthy reports whether requests should be sent to this chooser.
+func (t *tier) healthy() bool {
+	now := t.clock.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !now.Before(t.downUntil)
+}
+
+// trip marks the chooser unhealthy until the failback interval elapses.
+func (t *tier) trip() {
+	now := t.clock.Now()
+	t.mu.Lock()
+	t.tripLocked(now)
+	t.mu.Unlock()
+}
+
+// Must be run with the mutex held.
+func (t *tier) tripLocked(now time.Time) {
+	t.downUntil = now.Add(t.failbackAfter)
+	t.windowStart = t.downUntil
+	t.requests = 0
+	t.failures = 0
+}
+
+// wrap decorates the onFinish callback of a chosen peer so that the outcome
+// of the request counts toward the health of this chooser.
+func (t *tier) wrap(onFinish func(error)) func(error) {
+	return func(err error) {
+		onFinish(err)
+		t.record(err)
+	}
+}
+
+func (t *tier) record(err error) {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Requests which were in flight when the chooser tripped must not count
+	// toward the window which starts when it fails back.
+	if now.Before(t.downUntil) {
+		return
+	}
+
+	if now.Sub(t.windowStart) >= t.window {
+		t.windowStart = now
+		t.requests = 0
+		t.failures = 0
+	}
+
+	t.requests++
+	if isFailure(err) {
+		t.failures++
+	}
+
+	if t.requests >= t.minRequests &&
+		float64(t.failures) >= t.errorThreshold*float64(t.requests) &&
+		t.failures > 0 {
+		t.tripLocked(now)
+	}
+}
+
+// isFailure reports whether the error a request finished with counts against
+// the health of the chooser. Bad request errors are the caller's fault.
+func isFailure(err error) bool {
+	return err != nil && !yarpcerrors.IsInvalidArgument(err)
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/pkg/lifecycletest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func TestChoosePrimary(t *testing.T) {
+	primary := newFakeChooser("local")
+	fallback := newFakeChooser("remote")
+	c := New([]peer.Chooser{primary, fallback})
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	assert.Equal(t, "local", choose(t, c, nil))
+	assert.Equal(t, "local", choose(t, c, nil))
+	assert.Equal(t, 0, fallback.chosen)
+}
+
+func TestFailoverWhenPrimaryHasNoPeers(t *testing.T) {
+	fc := clock.NewFake()
+	primary := newFakeChooser("local")
+	primary.blocked = true
+	fallback := newFakeChooser("remote")
+	c := New([]peer.Chooser{primary, fallback},
+		ChooseTimeout(time.Millisecond),
+		FailbackAfter(time.Minute),
+		withClock(fc))
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	assert.Equal(t, "remote", choose(t, c, nil))
+	assert.Equal(t, 1, primary.attempts)
+
+	// The primary is skipped while it is unhealthy.
+	assert.Equal(t, "remote", choose(t, c, nil))
+	assert.Equal(t, 1, primary.attempts)
+	assert.Equal(t, "Running (using chooser 1 of 2)", c.Introspect().State)
+
+	// Once the failback interval elapses, the primary is tried again.
+	primary.blocked = false
+	fc.Add(time.Minute)
+	assert.Equal(t, "local", choose(t, c, nil))
+	assert.Equal(t, "Running (using chooser 0 of 2)", c.Introspect().State)
+}
+
+func TestFailoverOnErrorThreshold(t *testing.T) {
+	fc := clock.NewFake()
+	primary := newFakeChooser("local")
+	fallback := newFakeChooser("remote")
+	c := New([]peer.Chooser{primary, fallback},
+		ErrorThreshold(0.5),
+		MinRequests(4),
+		Window(time.Second),
+		FailbackAfter(time.Minute),
+		withClock(fc))
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	unavailable := yarpcerrors.UnavailableErrorf("down")
+	badRequest := yarpcerrors.InvalidArgumentErrorf("bad")
+
+	// Caller errors do not count against the primary.
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "local", choose(t, c, badRequest))
+	}
+
+	// Failures in an expired window are forgotten.
+	fc.Add(time.Second)
+	assert.Equal(t, "local", choose(t, c, unavailable))
+	assert.Equal(t, "local", choose(t, c, unavailable))
+	fc.Add(time.Second)
+	assert.Equal(t, "local", choose(t, c, nil))
+	assert.Equal(t, "local", choose(t, c, nil))
+	assert.Equal(t, "local", choose(t, c, unavailable))
+	assert.Equal(t, "local", choose(t, c, unavailable))
+
+	// The threshold was reached: traffic moves to the fallback.
+	assert.Equal(t, "remote", choose(t, c, unavailable))
+	assert.Equal(t, "remote", choose(t, c, unavailable))
+
+	fc.Add(time.Minute)
+	assert.Equal(t, "local", choose(t, c, nil))
+}
+
+func TestFailuresWhileDownAreIgnored(t *testing.T) {
+	fc := clock.NewFake()
+	primary := newFakeChooser("local")
+	fallback := newFakeChooser("remote")
+	c := New([]peer.Chooser{primary, fallback},
+		ErrorThreshold(0.5),
+		MinRequests(2),
+		Window(time.Hour),
+		FailbackAfter(time.Minute),
+		withClock(fc))
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	unavailable := yarpcerrors.UnavailableErrorf("down")
+
+	// A request is in flight when the primary trips.
+	_, onFinish, err := c.Choose(context.Background(), &transport.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "local", choose(t, c, unavailable))
+	assert.Equal(t, "local", choose(t, c, unavailable))
+	assert.Equal(t, "remote", choose(t, c, nil))
+
+	// Its failure mustn't count once the primary fails back.
+	onFinish(unavailable)
+	fc.Add(time.Minute)
+	assert.Equal(t, "local", choose(t, c, nil))
+	assert.Equal(t, "local", choose(t, c, nil))
+}
+
+func TestLastChooserIsAlwaysUsed(t *testing.T) {
+	primary := newFakeChooser("local")
+	primary.blocked = true
+	fallback := newFakeChooser("remote")
+	fallback.blocked = true
+	c := New([]peer.Chooser{primary, fallback}, ChooseTimeout(time.Millisecond))
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err := c.Choose(ctx, &transport.Request{})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, fallback.attempts)
+}
+
+func TestNoChoosers(t *testing.T) {
+	c := New(nil)
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	_, _, err := c.Choose(context.Background(), &transport.Request{})
+	assert.True(t, yarpcerrors.IsUnavailable(err))
+}
+
+func TestLifecycle(t *testing.T) {
+	primary := newFakeChooser("local")
+	fallback := newFakeChooser("remote")
+	c := New([]peer.Chooser{primary, fallback})
+
+	require.NoError(t, c.Start())
+	assert.True(t, c.IsRunning())
+	assert.True(t, primary.IsRunning())
+	assert.True(t, fallback.IsRunning())
+
+	require.NoError(t, c.Stop())
+	assert.False(t, c.IsRunning())
+	assert.False(t, primary.IsRunning())
+	assert.False(t, fallback.IsRunning())
+}
+
+func TestStartFailureStopsStartedChoosers(t *testing.T) {
+	primary := newFakeChooser("local")
+	fallback := newFakeChooser("remote")
+	fallback.startErr = errors.New("great sadness")
+	c := New([]peer.Chooser{primary, fallback})
+
+	assert.Error(t, c.Start())
+	assert.False(t, primary.IsRunning())
+}
+
+func TestIntrospect(t *testing.T) {
+	primary := newFakeChooser("local")
+	fallback := &introspectableChooser{
+		Lifecycle: lifecycletest.NewNop(),
+		status: introspection.ChooserStatus{
+			Name:  "RoundRobin",
+			Peers: []introspection.PeerStatus{{Identifier: "remote", State: "Available"}},
+		},
+	}
+	c := New([]peer.Chooser{primary, fallback})
+
+	status := c.Introspect()
+	assert.Equal(t, "Failover", status.Name)
+	assert.Equal(t, "Stopped (using chooser 0 of 2)", status.State)
+	assert.Equal(t, []introspection.PeerStatus{
+		{Identifier: "remote", State: "chooser 1 (RoundRobin, healthy): Available"},
+	}, status.Peers)
+}
+
+func choose(t *testing.T, c *Chooser, finishWith error) string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	p, onFinish, err := c.Choose(ctx, &transport.Request{})
+	require.NoError(t, err)
+	onFinish(finishWith)
+	return p.Identifier()
+}
+
+type fakeChooser struct {
+	transport.Lifecycle
+
+	id       string
+	blocked  bool
+	startErr error
+	attempts int
+	chosen   int
+}
+
+func newFakeChooser(id string) *fakeChooser {
+	return &fakeChooser{Lifecycle: lifecycletest.NewNop(), id: id}
+}
+
+func (c *fakeChooser) Start() error {
+	if c.startErr != nil {
+		return c.startErr
+	}
+	return c.Lifecycle.Start()
+}
+
+func (c *fakeChooser) Choose(ctx context.Context, _ *transport.Request) (peer.Peer, func(error), error) {
+	c.attempts++
+	if c.blocked {
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	}
+	c.chosen++
+	return fakePeer(c.id), func(error) {}, nil
+}
+
+type introspectableChooser struct {
+	transport.Lifecycle
+
+	status introspection.ChooserStatus
+}
+
+func (c *introspectableChooser) Choose(context.Context, *transport.Request) (peer.Peer, func(error), error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (c *introspectableChooser) Introspect() introspection.ChooserStatus {
+	return c.status
+}
+
+type fakePeer string
+
+func (p fakePeer) Identifier() string { return string(p) }
+
+func (p fakePeer) Status() peer.Status {
+	return peer.Status{ConnectionStatus: peer.Available}
+}
+
+func (fakePeer) StartRequest() {}
+
+func (fakePeer) EndRequest() {}
@@ -0,0 +1,38 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package failover provides a composite peer chooser that sends requests to
+// the first healthy chooser in an ordered set, falling back to the next one
+// when a chooser has no available peers or fails too many requests.
+//
+// This is useful during regional failover: the primary chooser selects local
+// peers, and remote peers are only used while the local ones are unusable.
+//
+// 	chooser := failover.New([]peer.Chooser{
+// 		peer.Bind(roundrobin.New(t), peer.BindPeers(localPeers)),
+// 		peer.Bind(roundrobin.New(t), peer.BindPeers(remotePeers)),
+// 	})
+//
+// A chooser is considered unhealthy when it fails to produce a peer within
+// the ChooseTimeout, or when the ratio of failed requests it served within a
+// Window reaches the ErrorThreshold. Unhealthy choosers are skipped until the
+// FailbackAfter interval elapses, after which requests are sent to them again.
+// The last chooser is always used as a last resort, even if unhealthy.
+package failover
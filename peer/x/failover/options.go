@@ -0,0 +1,108 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package failover
+
+import (
+	"time"
+
+	"go.uber.org/yarpc/internal/clock"
+)
+
+type chooserConfig struct {
+	chooseTimeout  time.Duration
+	errorThreshold float64
+	minRequests    int
+	window         time.Duration
+	failbackAfter  time.Duration
+	clock          clock.Clock
+}
+
+var defaultChooserConfig = chooserConfig{
+	chooseTimeout:  250 * time.Millisecond,
+	errorThreshold: 0.5,
+	minRequests:    10,
+	window:         10 * time.Second,
+	failbackAfter:  30 * time.Second,
+}
+
+// ChooserOption customizes the behavior of a failover chooser.
+type ChooserOption func(*chooserConfig)
+
+// ChooseTimeout specifies how long to wait for a chooser to produce a peer
+// before falling back to the next chooser. This bound does not apply to the
+// last chooser, which waits until the request deadline.
+//
+// Defaults to 250 milliseconds.
+func ChooseTimeout(d time.Duration) ChooserOption {
+	return func(c *chooserConfig) {
+		c.chooseTimeout = d
+	}
+}
+
+// ErrorThreshold specifies the ratio of failed requests, between 0 and 1,
+// at which a chooser is considered unhealthy.
+//
+// Requests that fail with an InvalidArgument error are assumed to be the
+// caller's fault and do not count as failures.
+//
+// Defaults to 0.5.
+func ErrorThreshold(ratio float64) ChooserOption {
+	return func(c *chooserConfig) {
+		c.errorThreshold = ratio
+	}
+}
+
+// MinRequests specifies the minimum number of requests a chooser must serve
+// within a Window before its error ratio is compared to the ErrorThreshold.
+//
+// Defaults to 10.
+func MinRequests(n int) ChooserOption {
+	return func(c *chooserConfig) {
+		c.minRequests = n
+	}
+}
+
+// Window specifies the interval over which request failures are counted.
+//
+// Defaults to 10 seconds.
+func Window(d time.Duration) ChooserOption {
+	return func(c *chooserConfig) {
+		c.window = d
+	}
+}
+
+// FailbackAfter specifies how long an unhealthy chooser is skipped before
+// requests are sent to it again.
+//
+// Defaults to 30 seconds.
+func FailbackAfter(d time.Duration) ChooserOption {
+	return func(c *chooserConfig) {
+		c.failbackAfter = d
+	}
+}
+
+// withClock specifies the clock used to measure windows and failback
+// intervals. It is only used for testing.
+func withClock(c clock.Clock) ChooserOption {
+	return func(cfg *chooserConfig) {
+		cfg.clock = c
+	}
+}
@@ -22,6 +22,7 @@ package peerheap
 
 import (
 	"context"
+	"fmt"
 	"math"
 	"sync"
 	"time"
@@ -29,6 +30,8 @@ import (
 	"go.uber.org/multierr"
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/internal/peerstats"
 	"go.uber.org/yarpc/pkg/lifecycle"
 )
 
@@ -66,6 +69,7 @@ type List struct {
 
 	byScore      peerHeap
 	byIdentifier map[string]*peerScore
+	stats        *peerstats.Set
 
 	peerAvailableEvent chan struct{}
 
@@ -98,6 +102,7 @@ func New(transport peer.Transport, opts ...HeapOption) *List {
 		once:               lifecycle.NewOnce(),
 		transport:          transport,
 		byIdentifier:       make(map[string]*peerScore),
+		stats:              peerstats.NewSet(),
 		peerAvailableEvent: make(chan struct{}, 1),
 		startupWait:        cfg.startupWait,
 	}
@@ -134,15 +139,15 @@ func (pl *List) retainPeer(pid peer.Identifier) error {
 		return peer.ErrPeerAddAlreadyInList(pid.Identifier())
 	}
 
-	ps := &peerScore{id: pid, list: pl}
+	ps := &peerScore{id: pid, list: pl, stats: pl.stats.Get(pid.Identifier())}
 	p, err := pl.transport.RetainPeer(pid, ps)
 	if err != nil {
+		pl.stats.Remove(pid.Identifier())
 		return err
 	}
 
 	ps.peer = p
 	ps.score = scorePeer(p)
-	ps.boundFinish = ps.finish
 	pl.byIdentifier[pid.Identifier()] = ps
 	pl.byScore.pushPeer(ps)
 	pl.internalNotifyStatusChanged(ps)
@@ -162,6 +167,7 @@ func (pl *List) releasePeer(pid peer.Identifier) error {
 
 	err := pl.transport.ReleasePeer(pid, ps)
 	delete(pl.byIdentifier, pid.Identifier())
+	pl.stats.Remove(pid.Identifier())
 	pl.byScore.delete(ps.idx)
 	ps.list = nil
 	return err
@@ -201,7 +207,7 @@ func (pl *List) Choose(ctx context.Context, _ *transport.Request) (peer.Peer, fu
 		if ps, ok := pl.get(); ok {
 			pl.notifyPeerAvailable()
 			ps.peer.StartRequest()
-			return ps.peer, ps.boundFinish, nil
+			return ps.peer, ps.begin(), nil
 		}
 
 		if err := pl.waitForPeerAvailableEvent(ctx); err != nil {
@@ -295,3 +301,42 @@ func scorePeer(p peer.Peer) int64 {
 	}
 	return score
 }
+
+// Introspect returns a ChooserStatus with a summary of the Peers.
+func (pl *List) Introspect() introspection.ChooserStatus {
+	state := "Stopped"
+	if pl.IsRunning() {
+		state = "Running"
+	}
+
+	pl.mu.Lock()
+	scores := make([]*peerScore, 0, len(pl.byIdentifier))
+	for _, ps := range pl.byIdentifier {
+		scores = append(scores, ps)
+	}
+	pl.mu.Unlock()
+
+	available := 0
+	peersStatus := make([]introspection.PeerStatus, 0, len(scores))
+	for _, ps := range scores {
+		status := ps.peer.Status()
+		if status.ConnectionStatus == peer.Available {
+			available++
+		}
+		peersStatus = append(peersStatus, introspection.PeerStatus{
+			Identifier: ps.peer.Identifier(),
+			State: fmt.Sprintf("%s, %d pending request(s)",
+				status.ConnectionStatus.String(),
+				status.PendingRequestCount),
+			Stats:            ps.stats.Introspect(),
+			ConnectionStatus: status.ConnectionStatus.String(),
+			PendingRequests:  status.PendingRequestCount,
+		})
+	}
+
+	return introspection.ChooserStatus{
+		Name:  "PeerHeap",
+		State: fmt.Sprintf("%s (%d/%d available)", state, available, len(scores)),
+		Peers: peersStatus,
+	}
+}
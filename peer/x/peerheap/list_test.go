@@ -22,6 +22,7 @@ package peerheap
 
 import (
 	"context"
+	"errors"
 	"sort"
 	"testing"
 	"time"
@@ -750,3 +751,19 @@ func TestPeerHeapList(t *testing.T) {
 		})
 	}
 }
+
+func TestRetainPeerErrorForgetsStats(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	transport := NewMockTransport(mockCtrl)
+	ExpectPeerRetainsWithError(transport, []string{"1"}, errors.New("retain failed"))
+
+	pl := New(transport)
+	pl.mu.Lock()
+	err := pl.retainPeer(MockPeerIdentifier("1"))
+	pl.mu.Unlock()
+
+	assert.Error(t, err)
+	assert.Nil(t, pl.stats.Introspect("1"), "stats of peers which failed to be retained should be forgotten")
+}
@@ -20,16 +20,19 @@
 
 package peerheap
 
-import "go.uber.org/yarpc/api/peer"
+import (
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/internal/peerstats"
+)
 
 // peerScore is a book-keeping object for each retained peer and
 // gets
 type peerScore struct {
 	// immutable after creation
-	peer        peer.Peer
-	id          peer.Identifier
-	list        *List
-	boundFinish func(error)
+	peer  peer.Peer
+	id    peer.Identifier
+	list  *List
+	stats *peerstats.Stats
 
 	status peer.Status
 	score  int64
@@ -51,6 +54,11 @@ func (ps *peerScore) NotifyStatusChanged(_ peer.Identifier) {
 	ps.list.peerScoreChanged(ps)
 }
 
-func (ps *peerScore) finish(error) {
-	ps.peer.EndRequest()
+// begin returns the onFinish callback for a request sent to this peer.
+func (ps *peerScore) begin() func(error) {
+	record := ps.stats.Begin()
+	return func(err error) {
+		ps.peer.EndRequest()
+		record(err)
+	}
 }
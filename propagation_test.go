@@ -0,0 +1,181 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package yarpc_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	. "go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/transport/http"
+)
+
+func TestHeaderPropagation(t *testing.T) {
+	// The backend replies with the headers it received.
+	backendInbound := http.NewTransport().NewInbound("127.0.0.1:0")
+	backend := NewDispatcher(Config{
+		Name:     "backend",
+		Inbounds: Inbounds{backendInbound},
+	})
+	backend.Register(raw.Procedure("headers", func(ctx context.Context, _ []byte) ([]byte, error) {
+		call := CallFromContext(ctx)
+		var headers []string
+		for _, k := range call.HeaderNames() {
+			headers = append(headers, k+"="+call.Header(k))
+		}
+		return []byte(strings.Join(headers, ",")), nil
+	}))
+	require.NoError(t, backend.Start())
+	defer backend.Stop()
+
+	// The frontend propagates headers to the backend, and records the headers
+	// its outbound middleware sees.
+	var (
+		mu   sync.Mutex
+		seen transport.Headers
+	)
+	recorder := middleware.UnaryOutboundFunc(func(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+		mu.Lock()
+		seen = req.Headers
+		mu.Unlock()
+		return out.Call(ctx, req)
+	})
+	frontendInbound := http.NewTransport().NewInbound("127.0.0.1:0")
+	frontend := NewDispatcher(Config{
+		Name:     "frontend",
+		Inbounds: Inbounds{frontendInbound},
+		Outbounds: Outbounds{
+			"backend": {
+				Unary: http.NewTransport().NewSingleOutbound(fmt.Sprintf("http://%v/", backendInbound.Addr())),
+			},
+		},
+		OutboundMiddleware: OutboundMiddleware{Unary: recorder},
+		HeaderPropagation: HeaderPropagationConfig{
+			Headers:    []string{"X-Request-Source"},
+			Prefixes:   []string{"x-tenant-"},
+			MaxHeaders: 2,
+			MaxBytes:   64,
+		},
+	})
+	backendClient := raw.New(frontend.ClientConfig("backend"))
+	frontend.Register(raw.Procedure("forward", func(ctx context.Context, body []byte) ([]byte, error) {
+		var opts []CallOption
+		if string(body) == "explicit" {
+			opts = append(opts, WithHeader("x-tenant-id", "explicit"))
+		}
+		return backendClient.Call(ctx, "headers", nil, opts...)
+	}))
+	require.NoError(t, frontend.Start())
+	defer frontend.Stop()
+
+	client := NewDispatcher(Config{
+		Name: "client",
+		Outbounds: Outbounds{
+			"frontend": {
+				Unary: http.NewTransport().NewSingleOutbound(fmt.Sprintf("http://%v/", frontendInbound.Addr())),
+			},
+		},
+	})
+	require.NoError(t, client.Start())
+	defer client.Stop()
+	frontendClient := raw.New(client.ClientConfig("frontend"))
+
+	tests := []struct {
+		desc    string
+		body    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			desc:    "no headers",
+			headers: map[string]string{"x-other": "a"},
+			want:    "",
+		},
+		{
+			desc: "allowlisted and prefixed headers",
+			headers: map[string]string{
+				"X-Request-Source": "web",
+				"x-tenant-id":      "a",
+				"x-other":          "b",
+			},
+			want: "x-request-source=web,x-tenant-id=a",
+		},
+		{
+			desc: "explicit headers take precedence",
+			body: "explicit",
+			headers: map[string]string{
+				"x-request-source": "web",
+				"x-tenant-id":      "a",
+			},
+			want: "x-request-source=web,x-tenant-id=explicit",
+		},
+		{
+			desc: "max headers",
+			headers: map[string]string{
+				"x-tenant-a": "a",
+				"x-tenant-b": "b",
+				"x-tenant-c": "c",
+			},
+			want: "x-tenant-a=a,x-tenant-b=b",
+		},
+		{
+			desc: "max bytes",
+			headers: map[string]string{
+				"x-request-source": "web",
+				"x-tenant-id":      strings.Repeat("a", 64),
+			},
+			want: "x-request-source=web",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var opts []CallOption
+			for k, v := range tt.headers {
+				opts = append(opts, WithHeader(k, v))
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+			defer cancel()
+			res, err := frontendClient.Call(ctx, "forward", []byte(tt.body), opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(res), "Unexpected headers received by the backend.")
+
+			mu.Lock()
+			defer mu.Unlock()
+			var got []string
+			for k, v := range seen.Items() {
+				got = append(got, k+"="+v)
+			}
+			sort.Strings(got)
+			assert.Equal(t, tt.want, strings.Join(got, ","), "Unexpected headers seen by outbound middleware.")
+		})
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/bufferpool"
 	"go.uber.org/yarpc/internal/iopool"
+	"go.uber.org/yarpc/internal/remote"
 	"go.uber.org/yarpc/pkg/errors"
 	"go.uber.org/yarpc/yarpcerrors"
 )
@@ -97,7 +98,10 @@ func (h handler) callHandler(responseWriter *responseWriter, req *http.Request,
 		return err
 	}
 
-	ctx := req.Context()
+	ctx := remote.NewContext(req.Context(), remote.Peer{
+		Transport: transportName,
+		Address:   req.RemoteAddr,
+	})
 	ctx, cancel, parseTTLErr := parseTTL(ctx, treq, popHeader(req.Header, TTLMSHeader))
 	// parseTTLErr != nil is a problem only if the request is unary.
 	defer cancel()
@@ -31,6 +31,7 @@ import (
 	"go.uber.org/multierr"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/bufferpool"
+	"go.uber.org/yarpc/internal/remote"
 	"go.uber.org/yarpc/pkg/errors"
 	"go.uber.org/yarpc/yarpcerrors"
 	ncontext "golang.org/x/net/context"
@@ -144,10 +145,13 @@ func (h handler) callHandler(ctx context.Context, call inboundCall, responseWrit
 	}
 	treq.Headers = headers
 
+	peer := remote.Peer{Transport: transportName}
 	if tcall, ok := call.(tchannelCall); ok {
 		tracer := h.tracer
 		ctx = tchannel.ExtractInboundSpan(ctx, tcall.InboundCall, headers.Items(), tracer)
+		peer.Address = tcall.RemotePeer().HostPort
 	}
+	ctx = remote.NewContext(ctx, peer)
 
 	body, err := call.Arg3Reader()
 	if err != nil {
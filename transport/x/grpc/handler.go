@@ -27,11 +27,13 @@ import (
 
 	"github.com/opentracing/opentracing-go"
 	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/remote"
 	"go.uber.org/yarpc/yarpcerrors"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	gpeer "google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	gtransport "google.golang.org/grpc/transport"
 )
@@ -42,6 +44,16 @@ var (
 	errInvalidGRPCMethod = yarpcerrors.InvalidArgumentErrorf("invalid stream method name for request")
 )
 
+const (
+	// _healthCheckProcedure is the procedure of the Check method of the
+	// standard grpc.health.v1.Health service.
+	_healthCheckProcedure = "grpc.health.v1.Health::Check"
+	// _healthCheckCaller is the caller of health checks which don't name
+	// one.
+	_healthCheckCaller = "grpc-health-check"
+	_protoEncoding     = transport.Encoding("proto")
+)
+
 type handler struct {
 	i *Inbound
 }
@@ -103,6 +115,12 @@ func (h *handler) handleBeforeErrorConversion(
 	ctx, span := extractOpenTracingSpan.Do(ctx, transportRequest)
 	defer span.Finish()
 
+	peer := remote.Peer{Transport: transportName}
+	if p, ok := gpeer.FromContext(ctx); ok && p.Addr != nil {
+		peer.Address = p.Addr.String()
+	}
+	ctx = remote.NewContext(ctx, peer)
+
 	if h.i.options.unaryInterceptor != nil {
 		return h.i.options.unaryInterceptor(
 			ctx,
@@ -146,6 +164,9 @@ func (h *handler) getTransportRequest(ctx context.Context, decodeFunc func(inter
 	}
 
 	transportRequest.Procedure = procedure
+	if procedure == _healthCheckProcedure {
+		h.setHealthCheckDefaults(transportRequest)
+	}
 	if err := transport.ValidateRequest(transportRequest); err != nil {
 		return nil, err
 	}
@@ -169,6 +190,27 @@ func procedureFromStreamMethod(streamMethod string) (string, error) {
 	return procedureToName(service, method)
 }
 
+// setHealthCheckDefaults fills in the attributes of health checks which
+// stock gRPC tooling, like grpc_health_probe, doesn't send. They are sent
+// with the protobuf encoding, to the service which registered the
+// grpc.health.v1.Health service.
+func (h *handler) setHealthCheckDefaults(transportRequest *transport.Request) {
+	if transportRequest.Encoding == "" {
+		transportRequest.Encoding = _protoEncoding
+	}
+	if transportRequest.Caller == "" {
+		transportRequest.Caller = _healthCheckCaller
+	}
+	if transportRequest.Service == "" {
+		for _, p := range h.i.router.Procedures() {
+			if p.Name == _healthCheckProcedure {
+				transportRequest.Service = p.Service
+				break
+			}
+		}
+	}
+}
+
 func (h *handler) call(ctx context.Context, transportRequest *transport.Request, responseMD metadata.MD) (interface{}, error) {
 	handlerSpec, err := h.i.router.Choose(ctx, transportRequest)
 	if err != nil {
@@ -0,0 +1,60 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/transport"
+)
+
+func TestSetHealthCheckDefaults(t *testing.T) {
+	inbound := NewTransport().NewInbound(nil)
+	inbound.SetRouter(newTestRouter([]transport.Procedure{
+		{Name: "foo::bar", Service: "other"},
+		{Name: _healthCheckProcedure, Service: "myservice"},
+	}))
+	h := newHandler(inbound)
+
+	req := &transport.Request{Procedure: _healthCheckProcedure}
+	h.setHealthCheckDefaults(req)
+	assert.Equal(t, &transport.Request{
+		Caller:    "grpc-health-check",
+		Service:   "myservice",
+		Encoding:  "proto",
+		Procedure: _healthCheckProcedure,
+	}, req)
+
+	req = &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "json",
+		Procedure: _healthCheckProcedure,
+	}
+	h.setHealthCheckDefaults(req)
+	assert.Equal(t, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "json",
+		Procedure: _healthCheckProcedure,
+	}, req, "Expected attributes of the request to be kept.")
+}
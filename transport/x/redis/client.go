@@ -36,8 +36,12 @@ type Client interface {
 	// BRPopLPush moves an item from the primary queue into a processing list.
 	// within the timeout.
 	BRPopLPush(from, to string, timeout time.Duration) ([]byte, error)
-	// LRem removes one item from the queue key
-	LRem(queue string, item []byte) error
+	// LRem removes one item from the queue key, returning the number of
+	// items removed: 0 if the item was not found in the list.
+	LRem(queue string, item []byte) (int64, error)
+	// LRange returns the items of the list between the start and stop
+	// indices, inclusive. Negative indices count from the end of the list.
+	LRange(list string, start, stop int64) ([][]byte, error)
 
 	// Endpoint returns the enpoint configured for this client.
 	Endpoint() string
@@ -0,0 +1,221 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+const defaultInboundTimeout = time.Second
+
+// TransportSpec returns a TransportSpec for the Redis transport.
+//
+// 	configurator.MustRegisterTransport(redis.TransportSpec())
+//
+// See TransportConfig, InboundConfig, and OutboundConfig for details on the
+// different configuration parameters supported by this Transport.
+//
+// Any Transport or Inbound option may be passed to this function. These
+// options will be applied BEFORE configuration parameters are interpreted.
+// This allows configuration parameters to override Option provided to
+// TransportSpec.
+func TransportSpec(opts ...Option) yarpcconfig.TransportSpec {
+	var ts transportSpec
+	for _, o := range opts {
+		switch opt := o.(type) {
+		case TransportOption:
+			ts.TransportOptions = append(ts.TransportOptions, opt)
+		case InboundOption:
+			ts.InboundOptions = append(ts.InboundOptions, opt)
+		default:
+			panic(fmt.Sprintf("unknown option of type %T: %v", o, o))
+		}
+	}
+	return ts.Spec()
+}
+
+// transportSpec holds the configurable parts of the Redis TransportSpec.
+//
+// These are usually runtime dependencies that cannot be parsed from
+// configuration.
+type transportSpec struct {
+	TransportOptions []TransportOption
+	InboundOptions   []InboundOption
+}
+
+func (ts *transportSpec) Spec() yarpcconfig.TransportSpec {
+	return yarpcconfig.TransportSpec{
+		Name:                transportName,
+		BuildTransport:      ts.buildTransport,
+		BuildInbound:        ts.buildInbound,
+		BuildOnewayOutbound: ts.buildOnewayOutbound,
+	}
+}
+
+// TransportConfig configures the shared Redis Transport. This is shared
+// between all Redis outbounds and inbounds of a Dispatcher.
+//
+//  transports:
+//    redis:
+//      connBackoff:
+//        exponential:
+//          first: 10ms
+//          max: 30s
+//
+// All parameters of TransportConfig are optional. This section may be omitted
+// in the transports section.
+type TransportConfig struct {
+	ConnBackoff yarpcconfig.Backoff `config:"connBackoff"`
+}
+
+func (ts *transportSpec) buildTransport(tc *TransportConfig, k *yarpcconfig.Kit) (transport.Transport, error) {
+	opts := append([]TransportOption(nil), ts.TransportOptions...)
+
+	strategy, err := tc.ConnBackoff.Strategy()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, ConnBackoff(strategy))
+
+	return NewTransport(opts...), nil
+}
+
+// InboundConfig configures a Redis inbound.
+//
+//  inbounds:
+//    redis:
+//      endpoints:
+//        - 10.0.0.1:6379
+//        - 10.0.0.2:6379
+//      queueKey: myservice
+//      processingKey: myservice:processing
+//      workers: 8
+type InboundConfig struct {
+	// Addresses of the Redis servers to consume from. At least one endpoint
+	// is required.
+	Endpoints []string `config:"endpoints"`
+
+	// Key of the list requests are read from. This field is required.
+	QueueKey string `config:"queueKey,interpolate"`
+
+	// Key of the list requests are kept in while they are handled. This
+	// field is required.
+	ProcessingKey string `config:"processingKey,interpolate"`
+
+	// How long to block on reading from a Redis server before trying again.
+	//
+	// Defaults to 1s.
+	Timeout time.Duration `config:"timeout"`
+
+	// Maximum number of requests handled concurrently. See Workers.
+	Workers int `config:"workers"`
+
+	// How long a request may stay in the processing list before it is
+	// re-queued. See VisibilityTimeout.
+	VisibilityTimeout time.Duration `config:"visibilityTimeout"`
+
+	// Number of delivery attempts after which a request is moved to the
+	// dead-letter list. See MaxAttempts.
+	MaxAttempts uint64 `config:"maxAttempts"`
+
+	// Key of the dead-letter list. See DeadLetterKey.
+	DeadLetterKey string `config:"deadLetterKey,interpolate"`
+}
+
+func (ts *transportSpec) buildInbound(ic *InboundConfig, t transport.Transport, k *yarpcconfig.Kit) (transport.Inbound, error) {
+	if len(ic.Endpoints) == 0 {
+		return nil, fmt.Errorf("inbound endpoints are required")
+	}
+	if ic.QueueKey == "" {
+		return nil, fmt.Errorf("inbound queueKey is required")
+	}
+	if ic.ProcessingKey == "" {
+		return nil, fmt.Errorf("inbound processingKey is required")
+	}
+
+	timeout := defaultInboundTimeout
+	if ic.Timeout > 0 {
+		timeout = ic.Timeout
+	}
+
+	opts := append([]InboundOption(nil), ts.InboundOptions...)
+	if ic.Workers > 0 {
+		opts = append(opts, Workers(ic.Workers))
+	}
+	if ic.VisibilityTimeout > 0 {
+		opts = append(opts, VisibilityTimeout(ic.VisibilityTimeout))
+	}
+	if ic.MaxAttempts > 0 {
+		opts = append(opts, MaxAttempts(ic.MaxAttempts))
+	}
+	if ic.DeadLetterKey != "" {
+		opts = append(opts, DeadLetterKey(ic.DeadLetterKey))
+	}
+
+	return t.(*Transport).NewInbound(ic.Endpoints, ic.QueueKey, ic.ProcessingKey, timeout, opts...), nil
+}
+
+// OutboundConfig configures a Redis outbound. The Redis outbound only
+// supports Oneway requests.
+//
+//  outbounds:
+//    myservice:
+//      redis:
+//        queueKey: myservice
+//        peer: 127.0.0.1:6379
+//
+// Requests may be spread over several Redis servers with a peer list.
+//
+//  outbounds:
+//    myservice:
+//      redis:
+//        queueKey: myservice
+//        round-robin:
+//          peers:
+//            - 10.0.0.1:6379
+//            - 10.0.0.2:6379
+type OutboundConfig struct {
+	yarpcconfig.PeerChooser
+
+	// Key of the list requests are pushed to. This field is required.
+	QueueKey string `config:"queueKey,interpolate"`
+}
+
+func (ts *transportSpec) buildOnewayOutbound(oc *OutboundConfig, t transport.Transport, k *yarpcconfig.Kit) (transport.OnewayOutbound, error) {
+	if oc.QueueKey == "" {
+		return nil, fmt.Errorf("outbound queueKey is required")
+	}
+	if oc.Empty() {
+		return nil, fmt.Errorf("outbound requires a peer or peer list")
+	}
+
+	x := t.(*Transport)
+	chooser, err := oc.BuildPeerChooser(x, hostport.Identify, k)
+	if err != nil {
+		return nil, fmt.Errorf("cannot configure peer chooser for Redis outbound: %v", err)
+	}
+	return x.NewOnewayOutbound(chooser, oc.QueueKey), nil
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/yarpcconfig"
+)
+
+func TestTransportSpec(t *testing.T) {
+	type attrs map[string]interface{}
+
+	type wantInbound struct {
+		Endpoints         []string
+		QueueKey          string
+		ProcessingKey     string
+		DeadLetterKey     string
+		Timeout           time.Duration
+		Workers           int
+		VisibilityTimeout time.Duration
+		MaxAttempts       uint64
+	}
+
+	type wantOutbound struct {
+		QueueKey string
+		Chooser  interface{}
+	}
+
+	tests := []struct {
+		desc string
+		cfg  attrs
+		opts []Option
+
+		wantErrors   []string
+		wantInbound  *wantInbound
+		wantOutbound *wantOutbound
+	}{
+		{
+			desc: "inbound without endpoints",
+			cfg: attrs{
+				"inbounds": attrs{
+					"redis": attrs{"queueKey": "q", "processingKey": "p"},
+				},
+			},
+			wantErrors: []string{"inbound endpoints are required"},
+		},
+		{
+			desc: "inbound without queue key",
+			cfg: attrs{
+				"inbounds": attrs{
+					"redis": attrs{"endpoints": []string{"127.0.0.1:6379"}, "processingKey": "p"},
+				},
+			},
+			wantErrors: []string{"inbound queueKey is required"},
+		},
+		{
+			desc: "inbound without processing key",
+			cfg: attrs{
+				"inbounds": attrs{
+					"redis": attrs{"endpoints": []string{"127.0.0.1:6379"}, "queueKey": "q"},
+				},
+			},
+			wantErrors: []string{"inbound processingKey is required"},
+		},
+		{
+			desc: "simple inbound",
+			cfg: attrs{
+				"inbounds": attrs{
+					"redis": attrs{
+						"endpoints":     []string{"127.0.0.1:6379"},
+						"queueKey":      "q",
+						"processingKey": "p",
+					},
+				},
+			},
+			wantInbound: &wantInbound{
+				Endpoints:         []string{"127.0.0.1:6379"},
+				QueueKey:          "q",
+				ProcessingKey:     "p",
+				DeadLetterKey:     "q:dead",
+				Timeout:           time.Second,
+				Workers:           1,
+				VisibilityTimeout: 5 * time.Minute,
+				MaxAttempts:       3,
+			},
+		},
+		{
+			desc: "inbound options",
+			opts: []Option{Workers(4), MaxAttempts(10)},
+			cfg: attrs{
+				"inbounds": attrs{
+					"redis": attrs{
+						"endpoints":     []string{"127.0.0.1:6379"},
+						"queueKey":      "q",
+						"processingKey": "p",
+					},
+				},
+			},
+			wantInbound: &wantInbound{
+				Endpoints:         []string{"127.0.0.1:6379"},
+				QueueKey:          "q",
+				ProcessingKey:     "p",
+				DeadLetterKey:     "q:dead",
+				Timeout:           time.Second,
+				Workers:           4,
+				VisibilityTimeout: 5 * time.Minute,
+				MaxAttempts:       10,
+			},
+		},
+		{
+			desc: "full inbound config",
+			opts: []Option{Workers(4)},
+			cfg: attrs{
+				"inbounds": attrs{
+					"redis": attrs{
+						"endpoints":         []string{"10.0.0.1:6379", "10.0.0.2:6379"},
+						"queueKey":          "q",
+						"processingKey":     "p",
+						"timeout":           "5s",
+						"workers":           16,
+						"visibilityTimeout": "1m",
+						"maxAttempts":       5,
+						"deadLetterKey":     "graveyard",
+					},
+				},
+			},
+			wantInbound: &wantInbound{
+				Endpoints:         []string{"10.0.0.1:6379", "10.0.0.2:6379"},
+				QueueKey:          "q",
+				ProcessingKey:     "p",
+				DeadLetterKey:     "graveyard",
+				Timeout:           5 * time.Second,
+				Workers:           16,
+				VisibilityTimeout: time.Minute,
+				MaxAttempts:       5,
+			},
+		},
+		{
+			desc: "outbound without queue key",
+			cfg: attrs{
+				"outbounds": attrs{
+					"myservice": attrs{
+						"redis": attrs{"peer": "127.0.0.1:6379"},
+					},
+				},
+			},
+			wantErrors: []string{"outbound queueKey is required"},
+		},
+		{
+			desc: "outbound without peers",
+			cfg: attrs{
+				"outbounds": attrs{
+					"myservice": attrs{
+						"redis": attrs{"queueKey": "q"},
+					},
+				},
+			},
+			wantErrors: []string{"outbound requires a peer or peer list"},
+		},
+		{
+			desc: "single peer outbound",
+			cfg: attrs{
+				"outbounds": attrs{
+					"myservice": attrs{
+						"redis": attrs{"queueKey": "q", "peer": "127.0.0.1:6379"},
+					},
+				},
+			},
+			wantOutbound: &wantOutbound{
+				QueueKey: "q",
+				Chooser:  &peer.Single{},
+			},
+		},
+		{
+			desc: "peer list outbound",
+			cfg: attrs{
+				"outbounds": attrs{
+					"myservice": attrs{
+						"redis": attrs{
+							"queueKey": "q",
+							"round-robin": attrs{
+								"peers": []string{"10.0.0.1:6379", "10.0.0.2:6379"},
+							},
+						},
+					},
+				},
+			},
+			wantOutbound: &wantOutbound{
+				QueueKey: "q",
+				Chooser:  &peer.BoundChooser{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			configurator := yarpcconfig.New()
+			require.NoError(t, configurator.RegisterTransport(TransportSpec(tt.opts...)))
+			require.NoError(t, configurator.RegisterPeerList(roundrobin.Spec()))
+
+			cfg, err := configurator.LoadConfig("foo", tt.cfg)
+			if len(tt.wantErrors) > 0 {
+				require.Error(t, err, "expected failure while loading config %+v", tt.cfg)
+				for _, msg := range tt.wantErrors {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+			require.NoError(t, err, "expected success while loading config %+v", tt.cfg)
+
+			if want := tt.wantInbound; want != nil {
+				require.Len(t, cfg.Inbounds, 1)
+				ib, ok := cfg.Inbounds[0].(*Inbound)
+				require.True(t, ok, "expected *Inbound, got %T", cfg.Inbounds[0])
+
+				endpoints := make([]string, len(ib.clients))
+				for i, client := range ib.clients {
+					endpoints[i] = client.Endpoint()
+				}
+				assert.Equal(t, want.Endpoints, endpoints, "endpoints")
+				assert.Equal(t, want.QueueKey, ib.queueKey, "queue key")
+				assert.Equal(t, want.ProcessingKey, ib.processingKey, "processing key")
+				assert.Equal(t, want.DeadLetterKey, ib.deadLetterKey, "dead-letter key")
+				assert.Equal(t, want.Timeout, ib.timeout, "timeout")
+				assert.Equal(t, want.Workers, cap(ib.workers), "workers")
+				assert.Equal(t, want.VisibilityTimeout, ib.visibilityTimeout, "visibility timeout")
+				assert.Equal(t, want.MaxAttempts, ib.maxAttempts, "max attempts")
+			}
+
+			if want := tt.wantOutbound; want != nil {
+				ob, ok := cfg.Outbounds["myservice"].Oneway.(*Outbound)
+				require.True(t, ok, "expected *Outbound, got %T", cfg.Outbounds["myservice"].Oneway)
+				assert.Nil(t, cfg.Outbounds["myservice"].Unary, "redis does not support unary outbounds")
+				assert.Equal(t, want.QueueKey, ob.queueKey, "queue key")
+				assert.IsType(t, want.Chooser, ob.Chooser(), "chooser")
+			}
+		})
+	}
+}
@@ -26,7 +26,15 @@
 //    that's acting as a queue
 //  - the inbound uses the atomic `BRPOPLPUSH` operation to dequeue items and
 //    place them in a processing list
-//  - processing failure/success cause a permanent removal of an item
+//  - items are handled by up to a fixed number of workers at a time (see
+//    Workers); stopping the inbound waits for in-flight requests
+//  - processing success causes a permanent removal of an item
+//  - processing failure re-queues the item with an incremented delivery
+//    attempt count, until it exhausts its attempts and is moved to a
+//    dead-letter list (see MaxAttempts and DeadLetterKey)
+//  - items stranded in the processing list, for example by a crashed
+//    consumer, are re-queued after a visibility timeout (see
+//    VisibilityTimeout)
 //
 // Sample usage:
 //
@@ -61,6 +69,35 @@
 // From here, standard Oneway RPCs made from the client to 'some-service' will
 // be transported to the server through a Redis queue.
 //
+// To spread a queue over several Redis servers, build the inbounds and
+// outbounds from a Transport. Outbounds choose a Redis server for each request
+// with a peer chooser, and inbounds consume from all given servers:
+//
+//   client-side:
+//      redisTransport := redis.NewTransport()
+//      redisOutbound := redisTransport.NewOnewayOutbound(
+//          peer.Bind(
+//              roundrobin.New(redisTransport),
+//              peer.BindPeers([]peer.Identifier{
+//                  hostport.PeerIdentifier("10.0.0.1:6379"),
+//                  hostport.PeerIdentifier("10.0.0.2:6379"),
+//              }),
+//          ),
+//          "my-queue-key",
+//      )
+//
+//   server-side:
+//      redisInbound := redisTransport.NewInbound(
+//          []string{"10.0.0.1:6379", "10.0.0.2:6379"},
+//          "my-queue-key",
+//          "my-processing-key",
+//          time.Second,
+//      )
+//
+// Inbounds and outbounds may also be declared in YARPC configuration by
+// registering TransportSpec with a yarpcconfig.Configurator. See
+// InboundConfig and OutboundConfig.
+//
 // USE OF THIS PACKAGE SHOULD BE FOR EXPERIMENTAL PURPOSES ONLY.
 // BEHAVIOR IS EXPECTED TO CHANGE.
 package redis
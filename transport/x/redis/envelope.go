@@ -0,0 +1,57 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import "encoding/binary"
+
+// envelopeMarker starts every item that carries a delivery attempt count.
+//
+// Items pushed by outbounds are serialized RPCs, which start with a
+// serialization version byte of 0. Those are treated as never having been
+// delivered. Once an inbound fails to process an item, it pushes the item
+// back wrapped in an envelope:
+//
+//	envelopeMarker | uvarint(attempts) | serialized RPC
+const envelopeMarker = byte(0xfe)
+
+// encodeItem wraps a serialized RPC in an envelope recording the number of
+// delivery attempts made so far.
+func encodeItem(attempts uint64, rpc []byte) []byte {
+	item := make([]byte, 1+binary.MaxVarintLen64+len(rpc))
+	item[0] = envelopeMarker
+	n := 1 + binary.PutUvarint(item[1:], attempts)
+	n += copy(item[n:], rpc)
+	return item[:n]
+}
+
+// decodeItem returns the number of delivery attempts made so far for an
+// item, and the serialized RPC it holds. Items without an envelope (or with
+// a malformed one) are returned as-is, with no prior attempts.
+func decodeItem(item []byte) (attempts uint64, rpc []byte) {
+	if len(item) == 0 || item[0] != envelopeMarker {
+		return 0, item
+	}
+	attempts, n := binary.Uvarint(item[1:])
+	if n <= 0 {
+		return 0, item
+	}
+	return attempts, item[1+n:]
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope(t *testing.T) {
+	tests := []struct {
+		desc         string
+		item         []byte
+		wantAttempts uint64
+		wantRPC      []byte
+	}{
+		{
+			desc:    "empty item",
+			item:    []byte{},
+			wantRPC: []byte{},
+		},
+		{
+			desc:    "bare serialized rpc",
+			item:    []byte{0, 1, 2, 3},
+			wantRPC: []byte{0, 1, 2, 3},
+		},
+		{
+			desc:         "envelope",
+			item:         encodeItem(2, []byte{0, 1, 2, 3}),
+			wantAttempts: 2,
+			wantRPC:      []byte{0, 1, 2, 3},
+		},
+		{
+			desc:         "envelope with large attempt count",
+			item:         encodeItem(1<<40, []byte{0}),
+			wantAttempts: 1 << 40,
+			wantRPC:      []byte{0},
+		},
+		{
+			desc:    "malformed envelope",
+			item:    []byte{envelopeMarker, 0xff},
+			wantRPC: []byte{envelopeMarker, 0xff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			attempts, rpc := decodeItem(tt.item)
+			assert.Equal(t, tt.wantAttempts, attempts)
+			assert.Equal(t, tt.wantRPC, rpc)
+		})
+	}
+}
@@ -23,12 +23,16 @@ package redis
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
+	"go.uber.org/atomic"
 	"go.uber.org/multierr"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/internal/remote"
 	"go.uber.org/yarpc/pkg/lifecycle"
 	"go.uber.org/yarpc/serialize"
 	"go.uber.org/yarpc/yarpcerrors"
@@ -40,19 +44,106 @@ const maxConnectRetries = 100
 
 var connectRetryDelay = 10 * time.Millisecond
 
+type inboundOptions struct {
+	workers           int
+	visibilityTimeout time.Duration
+	maxAttempts       uint64
+	deadLetterKey     string
+}
+
+var defaultInboundOptions = inboundOptions{
+	workers:           1,
+	visibilityTimeout: 5 * time.Minute,
+	maxAttempts:       3,
+}
+
+// InboundOption customizes the behavior of a Redis Inbound.
+type InboundOption func(*inboundOptions)
+
+func (InboundOption) redisOption() {}
+
+// Workers specifies the maximum number of requests the inbound handles
+// concurrently, across all of its Redis endpoints. Items are only taken from
+// a queue once a worker is free to handle them.
+//
+// Defaults to 1.
+func Workers(n int) InboundOption {
+	return func(options *inboundOptions) {
+		options.workers = n
+	}
+}
+
+// VisibilityTimeout specifies how long an item may stay in the processing
+// list before it is considered stranded, for example by a crashed consumer,
+// and re-queued. Re-queuing a stranded item counts as a failed delivery
+// attempt.
+//
+// Items are reaped between one and one and a half visibility timeouts after
+// the inbound first sees them in the processing list. A zero timeout
+// disables reaping.
+//
+// Defaults to 5 minutes.
+func VisibilityTimeout(timeout time.Duration) InboundOption {
+	return func(options *inboundOptions) {
+		options.visibilityTimeout = timeout
+	}
+}
+
+// MaxAttempts specifies how many times delivery of an item is attempted. An
+// item whose last attempt failed is moved to the dead-letter list instead of
+// being re-queued. Zero means items are re-queued indefinitely.
+//
+// Defaults to 3.
+func MaxAttempts(attempts uint64) InboundOption {
+	return func(options *inboundOptions) {
+		options.maxAttempts = attempts
+	}
+}
+
+// DeadLetterKey specifies the key of the list that items are moved to once
+// they have exhausted their delivery attempts. Nothing removes items from
+// that list; it is up to operators to inspect and drain it.
+//
+// Defaults to the queue key with a ":dead" suffix.
+func DeadLetterKey(key string) InboundOption {
+	return func(options *inboundOptions) {
+		options.deadLetterKey = key
+	}
+}
+
 // Inbound is a redis inbound that reads from the given queueKey. This will
 // wait for an item in the queue or until the timout is reached before trying
 // to read again.
+//
+// An inbound may consume from several Redis endpoints, reading from the same
+// queueKey on each of them concurrently.
+//
+// Items whose processing fails are re-queued with an incremented delivery
+// attempt count, until they exhaust their attempts and are moved to a
+// dead-letter list. Items left in the processing list for longer than the
+// visibility timeout are treated the same way.
 type Inbound struct {
-	router transport.Router
-	tracer opentracing.Tracer
+	router    transport.Router
+	tracer    opentracing.Tracer
+	transport *Transport
 
-	client        Client
+	clients       []Client
 	timeout       time.Duration
 	queueKey      string
 	processingKey string
+	deadLetterKey string
+
+	visibilityTimeout time.Duration
+	maxAttempts       uint64
+
+	requeued     atomic.Int64
+	deadLettered atomic.Int64
+
+	// workers holds a token for each request being handled.
+	workers chan struct{}
 
-	stop chan struct{}
+	stop    chan struct{}
+	loopsWg sync.WaitGroup
 
 	once *lifecycle.Once
 }
@@ -62,24 +153,61 @@ type Inbound struct {
 // queueKey - key for the queue in redis
 // processingKey - key for the list we'll store items we've popped from the queue
 // timeout - how long the inbound will block on reading from redis
-func NewInbound(client Client, queueKey, processingKey string, timeout time.Duration) *Inbound {
+func NewInbound(client Client, queueKey, processingKey string, timeout time.Duration, opts ...InboundOption) *Inbound {
+	return newInbound([]Client{client}, queueKey, processingKey, timeout, opts)
+}
+
+// NewInbound builds a redis Inbound that consumes from the Redis servers at
+// each of the given host:port endpoints.
+//
+// queueKey - key for the queue in redis
+// processingKey - key for the list we'll store items we've popped from the queue
+// timeout - how long the inbound will block on reading from redis
+func (t *Transport) NewInbound(endpoints []string, queueKey, processingKey string, timeout time.Duration, opts ...InboundOption) *Inbound {
+	clients := make([]Client, len(endpoints))
+	for idx, endpoint := range endpoints {
+		clients[idx] = t.newClient(endpoint)
+	}
+	i := newInbound(clients, queueKey, processingKey, timeout, opts)
+	i.transport = t
+	i.tracer = t.tracer
+	return i
+}
+
+func newInbound(clients []Client, queueKey, processingKey string, timeout time.Duration, opts []InboundOption) *Inbound {
+	options := defaultInboundOptions
+	options.deadLetterKey = queueKey + ":dead"
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.workers < 1 {
+		options.workers = 1
+	}
+
 	return &Inbound{
 		tracer: opentracing.GlobalTracer(),
 		once:   lifecycle.NewOnce(),
 
-		client:        client,
+		clients:       clients,
 		timeout:       timeout,
 		queueKey:      queueKey,
 		processingKey: processingKey,
+		deadLetterKey: options.deadLetterKey,
 
-		stop: make(chan struct{}),
+		visibilityTimeout: options.visibilityTimeout,
+		maxAttempts:       options.maxAttempts,
+
+		workers: make(chan struct{}, options.workers),
+		stop:    make(chan struct{}),
 	}
 }
 
-// Transports returns nil for now
+// Transports returns the inbound's Redis transport, if it was built from one.
 func (i *Inbound) Transports() []transport.Transport {
-	// TODO
-	return nil
+	if i.transport == nil {
+		return nil
+	}
+	return []transport.Transport{i.transport}
 }
 
 // WithTracer configures a tracer on this inbound.
@@ -112,31 +240,66 @@ func (i *Inbound) start() error {
 		return yarpcerrors.InternalErrorf("no router configured for transport inbound")
 	}
 
+	for idx, client := range i.clients {
+		if err := startClient(client); err != nil {
+			// Close the connections we already opened.
+			for _, started := range i.clients[:idx] {
+				err = multierr.Append(err, started.Stop())
+			}
+			return err
+		}
+	}
+
+	for _, client := range i.clients {
+		i.loopsWg.Add(1)
+		go i.startLoop(client)
+
+		if i.visibilityTimeout > 0 {
+			i.loopsWg.Add(1)
+			go i.reapLoop(client)
+		}
+	}
+	return nil
+}
+
+func startClient(client Client) error {
 	var err error
 	for attempt := 0; attempt < maxConnectRetries; attempt++ {
-		err = i.client.Start()
+		err = client.Start()
 		if err == nil {
-			break
+			return nil
 		}
 		time.Sleep(connectRetryDelay)
 	}
-	if err != nil {
-		return err
-	}
-
-	go i.startLoop()
-	return nil
+	return err
 }
 
-func (i *Inbound) startLoop() {
+// startLoop takes items from the queue of the given endpoint whenever a
+// worker is free, and handles each of them in its own goroutine.
+func (i *Inbound) startLoop(client Client) {
+	defer i.loopsWg.Done()
 	for {
 		select {
 		case <-i.stop:
 			return
-		default:
-			// TODO: log error
-			_ = i.handle()
+		case i.workers <- struct{}{}:
 		}
+
+		// TODO: log error
+		item, err := client.BRPopLPush(i.queueKey, i.processingKey, i.timeout)
+		if err != nil {
+			<-i.workers
+			continue
+		}
+
+		i.loopsWg.Add(1)
+		go func() {
+			defer i.loopsWg.Done()
+			defer func() { <-i.workers }()
+
+			// TODO: log error
+			_ = i.handleItem(client, item)
+		}()
 	}
 }
 
@@ -146,8 +309,15 @@ func (i *Inbound) Stop() error {
 }
 
 func (i *Inbound) stopClient() error {
+	// Let in-flight requests finish before closing connections.
 	close(i.stop)
-	return i.client.Stop()
+	i.loopsWg.Wait()
+
+	var err error
+	for _, client := range i.clients {
+		err = multierr.Append(err, client.Stop())
+	}
+	return err
 }
 
 // IsRunning returns whether the inbound is still processing requests.
@@ -155,19 +325,21 @@ func (i *Inbound) IsRunning() bool {
 	return i.once.IsRunning()
 }
 
-func (i *Inbound) handle() (err error) {
-	// TODO: logging
-	item, err := i.client.BRPopLPush(i.queueKey, i.processingKey, i.timeout)
-	if err != nil {
-		return err
+// handleItem processes an item taken from the queue into the processing
+// list, and removes it from there.
+func (i *Inbound) handleItem(client Client, item []byte) error {
+	if err := i.process(item); err != nil {
+		return multierr.Append(err, i.retry(client, item))
 	}
-	defer func() {
-		err = multierr.Append(err, i.client.LRem(i.queueKey, item))
-	}()
+	_, err := client.LRem(i.processingKey, item)
+	return err
+}
 
+func (i *Inbound) process(item []byte) error {
 	start := time.Now()
 
-	spanContext, req, err := serialize.FromBytes(i.tracer, item)
+	_, rpc := decodeItem(item)
+	spanContext, req, err := serialize.FromBytes(i.tracer, rpc)
 	if err != nil {
 		return err
 	}
@@ -180,6 +352,7 @@ func (i *Inbound) handle() (err error) {
 	}
 	ctx, span := extractOpenTracingSpan.Do(context.Background(), req)
 	defer span.Finish()
+	ctx = remote.NewContext(ctx, remote.Peer{Transport: transportName})
 
 	if err := transport.ValidateRequest(req); err != nil {
 		return transport.UpdateSpanWithErr(span, err)
@@ -198,12 +371,58 @@ func (i *Inbound) handle() (err error) {
 	return transport.DispatchOnewayHandler(ctx, spec.Oneway(), req)
 }
 
+// retry records a failed delivery attempt for an item of the processing
+// list, moving it back to the queue, or to the dead-letter list if it has
+// exhausted its attempts.
+//
+// The new copy is pushed before the item is removed from the processing
+// list, so that a failure in between delivers it twice rather than losing
+// it. Whoever removes an item from the processing list owns it, so if the
+// item is no longer there (because it was reaped, or handled by another
+// consumer) the new copy is taken back.
+func (i *Inbound) retry(client Client, item []byte) error {
+	attempts, rpc := decodeItem(item)
+	attempts++
+
+	key, counter := i.queueKey, &i.requeued
+	if i.maxAttempts > 0 && attempts >= i.maxAttempts {
+		key, counter = i.deadLetterKey, &i.deadLettered
+	}
+
+	next := encodeItem(attempts, rpc)
+	if err := client.LPush(key, next); err != nil {
+		return err
+	}
+
+	removed, err := client.LRem(i.processingKey, item)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		_, err := client.LRem(key, next)
+		return err
+	}
+
+	counter.Inc()
+	return nil
+}
+
 // Introspect returns the state of the inbound for introspection purposes.
 func (i *Inbound) Introspect() introspection.InboundStatus {
+	endpoints := make([]string, len(i.clients))
+	states := make([]string, len(i.clients))
+	for idx, client := range i.clients {
+		endpoints[idx] = client.Endpoint()
+		states[idx] = client.ConnectionState()
+	}
 	return introspection.InboundStatus{
 		Transport: "redis",
 		Endpoint: fmt.Sprintf("%s (queue: %s)",
-			i.client.Endpoint(), i.queueKey),
-		State: i.client.ConnectionState(),
+			strings.Join(endpoints, ", "), i.queueKey),
+		State: strings.Join(states, ", "),
+		Counters: map[string]int64{
+			"requeued":      i.requeued.Load(),
+			"dead-lettered": i.deadLettered.Load(),
+		},
 	}
 }
@@ -21,12 +21,21 @@
 package redis
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/opentracing/opentracing-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/encoding/raw"
 	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/serialize"
 	"go.uber.org/yarpc/transport/x/redis/redistest"
 )
 
@@ -35,12 +44,23 @@ func TestOperationOrder(t *testing.T) {
 	timeout := testtime.Second
 
 	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
 	client := redistest.NewMockClient(mockCtrl)
 
+	// The popped item is empty and fails processing, so it is re-queued.
+	done := make(chan struct{})
+	client.EXPECT().Start().Return(nil)
 	gomock.InOrder(
-		client.EXPECT().BRPopLPush(queueKey, processingKey, timeout),
-		client.EXPECT().LRem(queueKey, gomock.Any()),
+		client.EXPECT().BRPopLPush(queueKey, processingKey, timeout).Return([]byte{}, nil),
+		client.EXPECT().LPush(queueKey, encodeItem(1, nil)).Return(nil),
+		client.EXPECT().LRem(processingKey, []byte{}).
+			Do(func(string, []byte) { close(done) }).
+			Return(int64(1), nil),
 	)
+	client.EXPECT().BRPopLPush(queueKey, processingKey, timeout).
+		Return(nil, errors.New("no item found in queue")).
+		AnyTimes()
+	client.EXPECT().Stop().Return(nil)
 
 	inbound := NewInbound(client, queueKey, processingKey, timeout)
 	inbound.SetRouter(&transporttest.MockRouter{})
@@ -48,8 +68,184 @@ func TestOperationOrder(t *testing.T) {
 	assert.Equal(t, queueKey, inbound.queueKey)
 	assert.Equal(t, processingKey, inbound.processingKey)
 
-	// We're specifically testing the ingestion loop; with the current client
-	// code, it's extremely difficult to make substantive assertions about the
-	// number of messages handled.
-	inbound.handle()
+	require.NoError(t, inbound.Start())
+	<-done
+	require.NoError(t, inbound.Stop())
+}
+
+func TestHandleFailedItem(t *testing.T) {
+	// Items that are not valid RPCs always fail processing.
+	rpc := []byte("not an rpc")
+
+	tests := []struct {
+		desc        string
+		opts        []InboundOption
+		item        []byte
+		pushErr     error
+		removed     int64
+		wantPush    string
+		wantItem    []byte
+		wantCounter string
+	}{
+		{
+			desc:        "first failure",
+			item:        rpc,
+			wantPush:    "queueKey",
+			wantItem:    encodeItem(1, rpc),
+			removed:     1,
+			wantCounter: "requeued",
+		},
+		{
+			desc:        "second failure",
+			item:        encodeItem(1, rpc),
+			wantPush:    "queueKey",
+			wantItem:    encodeItem(2, rpc),
+			removed:     1,
+			wantCounter: "requeued",
+		},
+		{
+			desc:        "last failure",
+			item:        encodeItem(2, rpc),
+			wantPush:    "queueKey:dead",
+			wantItem:    encodeItem(3, rpc),
+			removed:     1,
+			wantCounter: "dead-lettered",
+		},
+		{
+			desc:        "custom max attempts and dead-letter key",
+			opts:        []InboundOption{MaxAttempts(1), DeadLetterKey("graveyard")},
+			item:        rpc,
+			wantPush:    "graveyard",
+			wantItem:    encodeItem(1, rpc),
+			removed:     1,
+			wantCounter: "dead-lettered",
+		},
+		{
+			desc:        "unlimited attempts",
+			opts:        []InboundOption{MaxAttempts(0)},
+			item:        encodeItem(1000, rpc),
+			wantPush:    "queueKey",
+			wantItem:    encodeItem(1001, rpc),
+			removed:     1,
+			wantCounter: "requeued",
+		},
+		{
+			desc:     "item taken by someone else",
+			item:     rpc,
+			wantPush: "queueKey",
+			wantItem: encodeItem(1, rpc),
+		},
+		{
+			desc:     "push failure",
+			item:     rpc,
+			pushErr:  errors.New("could not push item onto queue"),
+			wantPush: "queueKey",
+			wantItem: encodeItem(1, rpc),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			client := redistest.NewMockClient(mockCtrl)
+			client.EXPECT().Endpoint().Return("127.0.0.1:6379")
+			client.EXPECT().ConnectionState().Return("1/1 connection(s)")
+
+			// The item is only removed from the processing list once its
+			// new copy has been pushed, and that copy is taken back if the
+			// item was already gone.
+			calls := []*gomock.Call{
+				client.EXPECT().LPush(tt.wantPush, tt.wantItem).Return(tt.pushErr),
+			}
+			if tt.pushErr == nil {
+				calls = append(calls, client.EXPECT().LRem("processingKey", tt.item).Return(tt.removed, nil))
+			}
+			if tt.pushErr == nil && tt.removed == 0 {
+				calls = append(calls, client.EXPECT().LRem(tt.wantPush, tt.wantItem).Return(int64(1), nil))
+			}
+			gomock.InOrder(calls...)
+
+			inbound := NewInbound(client, "queueKey", "processingKey", testtime.Second, tt.opts...)
+			inbound.SetRouter(transporttest.NewMockRouter(mockCtrl))
+			assert.Error(t, inbound.handleItem(client, tt.item))
+
+			counters := inbound.Introspect().Counters
+			for _, name := range []string{"requeued", "dead-lettered"} {
+				want := int64(0)
+				if name == tt.wantCounter {
+					want = 1
+				}
+				assert.Equal(t, want, counters[name], "counter %q", name)
+			}
+		})
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tracer := opentracing.NoopTracer{}
+	item, err := serialize.ToBytes(tracer, tracer.StartSpan("test").Context(), &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "hello",
+		Body:      bytes.NewReader([]byte("hello!")),
+	})
+	require.NoError(t, err)
+
+	client := redistest.NewMockClient(mockCtrl)
+	client.EXPECT().Start().Return(nil)
+	client.EXPECT().BRPopLPush("queueKey", "processingKey", testtime.Millisecond).
+		Return(item, nil).
+		AnyTimes()
+	client.EXPECT().LRem("processingKey", item).Return(int64(1), nil).AnyTimes()
+	client.EXPECT().Stop().Return(nil)
+
+	var (
+		inFlight = make(chan struct{}, 10)
+		release  = make(chan struct{})
+	)
+	handler := transporttest.NewMockOnewayHandler(mockCtrl)
+	handler.EXPECT().HandleOneway(gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *transport.Request) {
+			inFlight <- struct{}{}
+			<-release
+		}).
+		Return(nil).
+		AnyTimes()
+
+	router := transporttest.NewMockRouter(mockCtrl)
+	router.EXPECT().Choose(gomock.Any(), gomock.Any()).
+		Return(transport.NewOnewayHandlerSpec(handler), nil).
+		AnyTimes()
+
+	inbound := NewInbound(client, "queueKey", "processingKey", testtime.Millisecond, Workers(2))
+	inbound.SetRouter(router)
+	require.NoError(t, inbound.Start())
+
+	// Two workers pick up requests, but no more.
+	<-inFlight
+	<-inFlight
+	select {
+	case <-inFlight:
+		t.Fatal("more requests in flight than workers")
+	case <-time.After(10 * testtime.Millisecond):
+	}
+
+	stopped := make(chan error)
+	go func() { stopped <- inbound.Stop() }()
+
+	// Stop waits for the in-flight requests.
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before in-flight requests finished")
+	case <-time.After(10 * testtime.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-stopped)
 }
@@ -26,8 +26,11 @@ import (
 	"time"
 
 	"github.com/opentracing/opentracing-go"
+	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/introspection"
+	peerbind "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/hostport"
 	"go.uber.org/yarpc/pkg/lifecycle"
 	"go.uber.org/yarpc/serialize"
 )
@@ -39,7 +42,11 @@ var (
 
 // Outbound is a redis OnewayOutbound that puts an RPC into the given queue key
 type Outbound struct {
-	client   Client
+	// Exactly one of client or chooser is set.
+	client    Client
+	chooser   peer.Chooser
+	transport *Transport
+
 	tracer   opentracing.Tracer
 	queueKey string
 
@@ -48,6 +55,9 @@ type Outbound struct {
 
 // NewOnewayOutbound creates a redis Outbound that satisfies transport.OnewayOutbound
 // queueKey - key for the queue in redis
+//
+// The outbound always pushes to the Redis endpoint of the given client. Use
+// Transport.NewOnewayOutbound to spread requests over several endpoints.
 func NewOnewayOutbound(client Client, queueKey string) *Outbound {
 	return &Outbound{
 		once:     lifecycle.NewOnce(),
@@ -57,10 +67,43 @@ func NewOnewayOutbound(client Client, queueKey string) *Outbound {
 	}
 }
 
-// Transports returns nil for now
+// NewOnewayOutbound builds a redis Outbound that uses the given peer chooser
+// to select the Redis endpoint each request is pushed to. The peers of the
+// chooser must be retained from this transport, identified by the host:port
+// addresses of Redis servers.
+//
+// queueKey - key for the queue in redis
+func (t *Transport) NewOnewayOutbound(chooser peer.Chooser, queueKey string) *Outbound {
+	return &Outbound{
+		once:      lifecycle.NewOnce(),
+		chooser:   chooser,
+		transport: t,
+		tracer:    t.tracer,
+		queueKey:  queueKey,
+	}
+}
+
+// NewSingleOnewayOutbound builds a redis Outbound that pushes all requests to
+// the Redis endpoint at the given host:port address.
+//
+// queueKey - key for the queue in redis
+func (t *Transport) NewSingleOnewayOutbound(addr, queueKey string) *Outbound {
+	return t.NewOnewayOutbound(peerbind.NewSingle(hostport.PeerIdentifier(addr), t), queueKey)
+}
+
+// Transports returns the outbound's Redis transport, if it was built from
+// one.
 func (o *Outbound) Transports() []transport.Transport {
-	// TODO
-	return nil
+	if o.transport == nil {
+		return nil
+	}
+	return []transport.Transport{o.transport}
+}
+
+// Chooser returns the outbound's peer chooser, or nil if the outbound was
+// built with a single Client.
+func (o *Outbound) Chooser() peer.Chooser {
+	return o.chooser
 }
 
 // WithTracer configures a tracer for the outbound
@@ -71,11 +114,17 @@ func (o *Outbound) WithTracer(tracer opentracing.Tracer) *Outbound {
 
 // Start creates connection to the redis instance
 func (o *Outbound) Start() error {
+	if o.chooser != nil {
+		return o.once.Start(o.chooser.Start)
+	}
 	return o.once.Start(o.client.Start)
 }
 
 // Stop stops the redis connection
 func (o *Outbound) Stop() error {
+	if o.chooser != nil {
+		return o.once.Stop(o.chooser.Stop)
+	}
 	return o.once.Stop(o.client.Stop)
 }
 
@@ -103,7 +152,13 @@ func (o *Outbound) CallOneway(ctx context.Context, req *transport.Request) (tran
 		return nil, transport.UpdateSpanWithErr(span, err)
 	}
 
-	err = o.client.LPush(o.queueKey, marshalledRPC)
+	client, onFinish, err := o.getClientForRequest(ctx, req)
+	if err != nil {
+		return nil, transport.UpdateSpanWithErr(span, err)
+	}
+
+	err = client.LPush(o.queueKey, marshalledRPC)
+	onFinish(err)
 	ack := time.Now()
 
 	if err != nil {
@@ -113,8 +168,53 @@ func (o *Outbound) CallOneway(ctx context.Context, req *transport.Request) (tran
 	return ack, nil
 }
 
+// getClientForRequest returns the client to push the request with, and a
+// callback to run once it was pushed.
+func (o *Outbound) getClientForRequest(ctx context.Context, req *transport.Request) (Client, func(error), error) {
+	if o.chooser == nil {
+		return o.client, func(error) {}, nil
+	}
+
+	p, onFinish, err := o.chooser.Choose(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rp, ok := p.(*redisPeer)
+	if !ok {
+		onFinish(nil)
+		return nil, nil, peer.ErrInvalidPeerConversion{
+			Peer:         p,
+			ExpectedType: "*redisPeer",
+		}
+	}
+
+	return rp.client, onFinish, nil
+}
+
 // Introspect returns basic status about this outbound.
 func (o *Outbound) Introspect() introspection.OutboundStatus {
+	if o.chooser != nil {
+		state := "Stopped"
+		if o.IsRunning() {
+			state = "Running"
+		}
+		var chooser introspection.ChooserStatus
+		if i, ok := o.chooser.(introspection.IntrospectableChooser); ok {
+			chooser = i.Introspect()
+		} else {
+			chooser = introspection.ChooserStatus{
+				Name: "Introspection not available",
+			}
+		}
+		return introspection.OutboundStatus{
+			Transport: transportName,
+			Endpoint:  fmt.Sprintf("queue: %s", o.queueKey),
+			State:     state,
+			Chooser:   chooser,
+		}
+	}
+
 	return introspection.OutboundStatus{
 		Transport: transportName,
 		Endpoint:  o.client.Endpoint(),
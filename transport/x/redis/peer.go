@@ -0,0 +1,106 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"time"
+
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/peer/hostport"
+)
+
+// redisPeer is a Redis endpoint retained by the peer lists of outbounds. Each
+// peer owns a Client connected to its endpoint.
+type redisPeer struct {
+	*hostport.Peer
+
+	transport *Transport
+	client    Client
+	released  chan struct{}
+}
+
+func newPeer(pid hostport.PeerIdentifier, t *Transport) *redisPeer {
+	return &redisPeer{
+		Peer:      hostport.NewPeer(pid, t),
+		transport: t,
+		client:    t.newClient(pid.Identifier()),
+		released:  make(chan struct{}),
+	}
+}
+
+func (p *redisPeer) release() {
+	close(p.released)
+}
+
+// maintainConn connects the peer's client once the transport has started,
+// backing off between failed attempts, and closes it when the peer is
+// released or the transport stops.
+func (p *redisPeer) maintainConn() {
+	defer p.transport.connectorsGroup.Done()
+
+	// Wait for start (or an early release).
+	select {
+	case <-p.transport.once.Started():
+	case <-p.released:
+		return
+	}
+
+	backoff := p.transport.connBackoffStrategy.Backoff()
+	for attempts := uint(0); ; attempts++ {
+		p.Peer.SetStatus(peer.Connecting)
+		if err := p.client.Start(); err == nil {
+			break
+		}
+		p.Peer.SetStatus(peer.Unavailable)
+		if !p.sleep(backoff.Duration(attempts)) {
+			// TODO: log error
+			_ = p.client.Stop()
+			return
+		}
+	}
+
+	p.Peer.SetStatus(peer.Available)
+	select {
+	case <-p.released:
+	case <-p.transport.once.Stopping():
+	}
+	p.Peer.SetStatus(peer.Unavailable)
+
+	// TODO: log error
+	_ = p.client.Stop()
+}
+
+// sleep waits for a duration, but exits early if the transport releases the
+// peer or stops. sleep returns whether it successfully waited the entire
+// duration.
+func (p *redisPeer) sleep(delay time.Duration) (completed bool) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-p.released:
+		return false
+	case <-p.transport.once.Stopping():
+		return false
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import "time"
+
+// reapLoop periodically re-queues the items of the processing list that have
+// been there for longer than the visibility timeout.
+func (i *Inbound) reapLoop(client Client) {
+	defer i.loopsWg.Done()
+
+	ticker := time.NewTicker(i.visibilityTimeout / 2)
+	defer ticker.Stop()
+
+	r := newReaper(i, client)
+	for {
+		select {
+		case <-i.stop:
+			return
+		case now := <-ticker.C:
+			// TODO: log error
+			_ = r.reap(now)
+		}
+	}
+}
+
+// reaper finds the items stranded in the processing list of a Redis
+// endpoint.
+//
+// Items do not record when they were moved to the processing list, so the
+// reaper remembers when it first saw each of them instead.
+type reaper struct {
+	inbound *Inbound
+	client  Client
+
+	firstSeen map[string]time.Time
+}
+
+func newReaper(i *Inbound, client Client) *reaper {
+	return &reaper{
+		inbound:   i,
+		client:    client,
+		firstSeen: make(map[string]time.Time),
+	}
+}
+
+// reap re-queues the items that have been in the processing list for longer
+// than the visibility timeout, as of the given time.
+func (r *reaper) reap(now time.Time) error {
+	items, err := r.client.LRange(r.inbound.processingKey, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]time.Time, len(items))
+	var stale [][]byte
+	for _, item := range items {
+		key := string(item)
+		if _, ok := seen[key]; ok {
+			// Identical items share the time the first of them was seen.
+			continue
+		}
+
+		first, ok := r.firstSeen[key]
+		if !ok {
+			first = now
+		}
+		if now.Sub(first) >= r.inbound.visibilityTimeout {
+			stale = append(stale, item)
+			continue
+		}
+		seen[key] = first
+	}
+	// Forget items that have left the processing list.
+	r.firstSeen = seen
+
+	for _, item := range stale {
+		// Losing the race for an item to its consumer is expected, so
+		// errors are ignored.
+		_ = r.inbound.retry(r.client, item)
+	}
+	return nil
+}
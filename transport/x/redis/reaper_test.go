@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/transport/x/redis/redistest"
+)
+
+func TestReaper(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	client := redistest.NewMockClient(mockCtrl)
+	inbound := NewInbound(client, "queueKey", "processingKey", time.Second,
+		VisibilityTimeout(time.Minute), MaxAttempts(2))
+	r := newReaper(inbound, client)
+
+	var (
+		a     = []byte("a")
+		b     = []byte("b")
+		c     = encodeItem(1, []byte("c"))
+		start = time.Unix(1500000000, 0)
+	)
+
+	// a and b show up.
+	client.EXPECT().LRange("processingKey", int64(0), int64(-1)).Return([][]byte{a, b}, nil)
+	assert.NoError(t, r.reap(start))
+
+	// a was processed, c shows up.
+	client.EXPECT().LRange("processingKey", int64(0), int64(-1)).Return([][]byte{b, c}, nil)
+	assert.NoError(t, r.reap(start.Add(30*time.Second)))
+
+	// b has been stranded for a whole visibility timeout, c not yet.
+	gomock.InOrder(
+		client.EXPECT().LRange("processingKey", int64(0), int64(-1)).Return([][]byte{b, c}, nil),
+		client.EXPECT().LPush("queueKey", encodeItem(1, b)).Return(nil),
+		client.EXPECT().LRem("processingKey", b).Return(int64(1), nil),
+	)
+	assert.NoError(t, r.reap(start.Add(time.Minute)))
+
+	// c has now been stranded too, and exhausts its attempts.
+	gomock.InOrder(
+		client.EXPECT().LRange("processingKey", int64(0), int64(-1)).Return([][]byte{c}, nil),
+		client.EXPECT().LPush("queueKey:dead", encodeItem(2, []byte("c"))).Return(nil),
+		client.EXPECT().LRem("processingKey", c).Return(int64(1), nil),
+	)
+	assert.NoError(t, r.reap(start.Add(90*time.Second)))
+
+	// a reappears, for a new delivery, and starts over.
+	client.EXPECT().LRange("processingKey", int64(0), int64(-1)).Return([][]byte{a}, nil)
+	assert.NoError(t, r.reap(start.Add(2*time.Minute)))
+
+	assert.Equal(t, int64(1), inbound.requeued.Load())
+	assert.Equal(t, int64(1), inbound.deadLettered.Load())
+}
+
+func TestReaperDuplicateItems(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	client := redistest.NewMockClient(mockCtrl)
+	inbound := NewInbound(client, "queueKey", "processingKey", time.Second,
+		VisibilityTimeout(time.Minute))
+	r := newReaper(inbound, client)
+
+	a := []byte("a")
+	start := time.Unix(1500000000, 0)
+
+	client.EXPECT().LRange("processingKey", int64(0), int64(-1)).Return([][]byte{a, a}, nil)
+	assert.NoError(t, r.reap(start))
+
+	// Both copies are re-queued; losing the race for the second one to a
+	// consumer is not an error, and its new copy is taken back.
+	gomock.InOrder(
+		client.EXPECT().LRange("processingKey", int64(0), int64(-1)).Return([][]byte{a, a}, nil),
+		client.EXPECT().LPush("queueKey", encodeItem(1, a)).Return(nil),
+		client.EXPECT().LRem("processingKey", a).Return(int64(1), nil),
+		client.EXPECT().LPush("queueKey", encodeItem(1, a)).Return(nil),
+		client.EXPECT().LRem("processingKey", a).Return(int64(0), nil),
+		client.EXPECT().LRem("queueKey", encodeItem(1, a)).Return(int64(1), nil),
+	)
+	assert.NoError(t, r.reap(start.Add(time.Minute)))
+	assert.Equal(t, int64(1), inbound.requeued.Load())
+}
+
+func TestReaperLRangeError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	client := redistest.NewMockClient(mockCtrl)
+	inbound := NewInbound(client, "queueKey", "processingKey", time.Second)
+	r := newReaper(inbound, client)
+
+	client.EXPECT().LRange("processingKey", int64(0), int64(-1)).Return(nil, errors.New("great sadness"))
+	assert.Error(t, r.reap(time.Now()))
+}
@@ -97,16 +97,33 @@ func (c *redis5Client) BRPopLPush(queueKey, processingKey string, timeout time.D
 	return item, nil
 }
 
-func (c *redis5Client) LRem(key string, item []byte) error {
+func (c *redis5Client) LRem(key string, item []byte) (int64, error) {
 	if !c.started.Load() {
-		return errNotStarted
+		return 0, errNotStarted
 	}
 
-	removed := c.client.LRem(key, 1, item).Val()
-	if removed <= 0 {
-		return errors.New("could not remove item from queue")
+	removed, err := c.client.LRem(key, 1, item).Result()
+	if err != nil {
+		return 0, errors.New("could not remove item from queue")
 	}
-	return nil
+	return removed, nil
+}
+
+func (c *redis5Client) LRange(list string, start, stop int64) ([][]byte, error) {
+	if !c.started.Load() {
+		return nil, errNotStarted
+	}
+
+	values, err := c.client.LRange(list, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([][]byte, len(values))
+	for i, v := range values {
+		items[i] = []byte(v)
+	}
+	return items, nil
 }
 
 // Endpoint returns the endpoint configured for this client.
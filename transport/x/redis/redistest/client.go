@@ -113,11 +113,25 @@ func (_mr *MockClientMockRecorder) LPush(arg0, arg1 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "LPush", reflect.TypeOf((*MockClient)(nil).LPush), arg0, arg1)
 }
 
+// LRange mocks base method
+func (_m *MockClient) LRange(_param0 string, _param1 int64, _param2 int64) ([][]byte, error) {
+	ret := _m.ctrl.Call(_m, "LRange", _param0, _param1, _param2)
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LRange indicates an expected call of LRange
+func (_mr *MockClientMockRecorder) LRange(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCallWithMethodType(_mr.mock, "LRange", reflect.TypeOf((*MockClient)(nil).LRange), arg0, arg1, arg2)
+}
+
 // LRem mocks base method
-func (_m *MockClient) LRem(_param0 string, _param1 []byte) error {
+func (_m *MockClient) LRem(_param0 string, _param1 []byte) (int64, error) {
 	ret := _m.ctrl.Call(_m, "LRem", _param0, _param1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // LRem indicates an expected call of LRem
@@ -0,0 +1,202 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	backoffapi "go.uber.org/yarpc/api/backoff"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/backoff"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/pkg/lifecycle"
+)
+
+var (
+	_ transport.Transport = (*Transport)(nil)
+	_ peer.Transport      = (*Transport)(nil)
+)
+
+type transportOptions struct {
+	newClient           func(addr string) Client
+	connBackoffStrategy backoffapi.Strategy
+	tracer              opentracing.Tracer
+}
+
+var defaultTransportOptions = transportOptions{
+	newClient:           NewRedis5Client,
+	connBackoffStrategy: backoff.DefaultExponential,
+}
+
+func newTransportOptions() transportOptions {
+	options := defaultTransportOptions
+	options.tracer = opentracing.GlobalTracer()
+	return options
+}
+
+// TransportOption customizes the behavior of a Redis Transport.
+type TransportOption func(*transportOptions)
+
+func (TransportOption) redisOption() {}
+
+// ClientFactory specifies how the transport creates a Client for a Redis
+// endpoint, given its address.
+//
+// Defaults to NewRedis5Client.
+func ClientFactory(f func(addr string) Client) TransportOption {
+	return func(options *transportOptions) {
+		options.newClient = f
+	}
+}
+
+// ConnBackoff specifies the connection backoff strategy for delays between
+// connection attempts to a Redis endpoint.
+//
+// This defaults to exponential backoff starting with 10ms fully jittered,
+// doubling each attempt, with a maximum interval of 30s.
+func ConnBackoff(s backoffapi.Strategy) TransportOption {
+	return func(options *transportOptions) {
+		options.connBackoffStrategy = s
+	}
+}
+
+// Tracer configures a tracer for the transport and all its inbounds and
+// outbounds.
+func Tracer(tracer opentracing.Tracer) TransportOption {
+	return func(options *transportOptions) {
+		options.tracer = tracer
+	}
+}
+
+// Transport keeps track of Redis endpoints, connecting to them on behalf of
+// the peer lists of its outbounds, and builds inbounds that consume from one
+// or more endpoints.
+//
+// Peers of a Redis Transport are identified by the host:port address of a
+// Redis server, using hostport.PeerIdentifier.
+type Transport struct {
+	lock sync.Mutex
+	once *lifecycle.Once
+
+	peers map[string]*redisPeer
+
+	newClient           func(addr string) Client
+	connBackoffStrategy backoffapi.Strategy
+	connectorsGroup     sync.WaitGroup
+
+	tracer opentracing.Tracer
+}
+
+// NewTransport creates a new Redis transport for managing connections to
+// Redis endpoints.
+func NewTransport(opts ...TransportOption) *Transport {
+	options := newTransportOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Transport{
+		once:                lifecycle.NewOnce(),
+		peers:               make(map[string]*redisPeer),
+		newClient:           options.newClient,
+		connBackoffStrategy: options.connBackoffStrategy,
+		tracer:              options.tracer,
+	}
+}
+
+// Start starts the Redis transport. Connections to retained peers are only
+// attempted once the transport has started.
+func (t *Transport) Start() error {
+	return t.once.Start(nil)
+}
+
+// Stop stops the Redis transport, closing the connections to all peers.
+func (t *Transport) Stop() error {
+	return t.once.Stop(func() error {
+		t.connectorsGroup.Wait()
+		return nil
+	})
+}
+
+// IsRunning returns whether the Redis transport is running.
+func (t *Transport) IsRunning() bool {
+	return t.once.IsRunning()
+}
+
+// RetainPeer gets or creates a Peer for the specified peer.Subscriber (usually
+// a peer.Chooser).
+func (t *Transport) RetainPeer(pid peer.Identifier, sub peer.Subscriber) (peer.Peer, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hppid, ok := pid.(hostport.PeerIdentifier)
+	if !ok {
+		return nil, peer.ErrInvalidPeerType{
+			ExpectedType:   "hostport.PeerIdentifier",
+			PeerIdentifier: pid,
+		}
+	}
+
+	p := t.getOrCreatePeer(hppid)
+	p.Subscribe(sub)
+	return p, nil
+}
+
+// getOrCreatePeer must be called with the lock held.
+func (t *Transport) getOrCreatePeer(pid hostport.PeerIdentifier) *redisPeer {
+	if p, ok := t.peers[pid.Identifier()]; ok {
+		return p
+	}
+
+	p := newPeer(pid, t)
+	t.peers[p.Identifier()] = p
+	t.connectorsGroup.Add(1)
+	go p.maintainConn()
+
+	return p
+}
+
+// ReleasePeer releases a peer from the peer.Subscriber and removes that peer
+// from the Transport if nothing is listening to it.
+func (t *Transport) ReleasePeer(pid peer.Identifier, sub peer.Subscriber) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	p, ok := t.peers[pid.Identifier()]
+	if !ok {
+		return peer.ErrTransportHasNoReferenceToPeer{
+			TransportName:  "redis.Transport",
+			PeerIdentifier: pid.Identifier(),
+		}
+	}
+
+	if err := p.Unsubscribe(sub); err != nil {
+		return err
+	}
+
+	if p.NumSubscribers() == 0 {
+		delete(t.peers, pid.Identifier())
+		p.release()
+	}
+
+	return nil
+}
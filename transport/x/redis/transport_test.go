@@ -0,0 +1,251 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/peer"
+	"go.uber.org/yarpc/api/peer/peertest"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/internal/backoff"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/peer/hostport"
+	"go.uber.org/yarpc/transport/x/redis/redistest"
+)
+
+// clientFactory returns a ClientFactory option that hands out the given
+// clients by address.
+func clientFactory(t *testing.T, clients map[string]Client) TransportOption {
+	return ClientFactory(func(addr string) Client {
+		client, ok := clients[addr]
+		require.True(t, ok, "unexpected client address %q", addr)
+		return client
+	})
+}
+
+func waitForStatus(t *testing.T, p peer.Peer, status peer.ConnectionStatus) {
+	deadline := time.Now().Add(testtime.Second)
+	for time.Now().Before(deadline) {
+		if p.Status().ConnectionStatus == status {
+			return
+		}
+		time.Sleep(testtime.Millisecond)
+	}
+	t.Fatalf("peer %q never became %v", p.Identifier(), status)
+}
+
+func TestTransportRetainWithInvalidPeerIdentifierType(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	trans := NewTransport()
+	_, err := trans.RetainPeer(peertest.NewMockIdentifier(mockCtrl), peertest.NewMockSubscriber(mockCtrl))
+	assert.IsType(t, peer.ErrInvalidPeerType{}, err)
+}
+
+func TestTransportReleaseUnretainedPeer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	trans := NewTransport()
+	err := trans.ReleasePeer(hostport.PeerIdentifier("127.0.0.1:6379"), peertest.NewMockSubscriber(mockCtrl))
+	assert.Equal(t, peer.ErrTransportHasNoReferenceToPeer{
+		TransportName:  "redis.Transport",
+		PeerIdentifier: "127.0.0.1:6379",
+	}, err)
+}
+
+func TestTransportPeerLifecycle(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	client := redistest.NewMockClient(mockCtrl)
+	gomock.InOrder(
+		client.EXPECT().Start().Return(errors.New("connection refused")),
+		client.EXPECT().Start().Return(nil),
+		client.EXPECT().Stop().Return(nil),
+	)
+
+	sub := peertest.NewMockSubscriber(mockCtrl)
+	sub.EXPECT().NotifyStatusChanged(gomock.Any()).AnyTimes()
+
+	trans := NewTransport(
+		clientFactory(t, map[string]Client{"127.0.0.1:6379": client}),
+		ConnBackoff(backoff.None),
+	)
+
+	p, err := trans.RetainPeer(hostport.PeerIdentifier("127.0.0.1:6379"), sub)
+	require.NoError(t, err)
+	assert.Equal(t, peer.Unavailable, p.Status().ConnectionStatus,
+		"peer must not connect before the transport starts")
+
+	// Retaining the same address again must reuse the peer.
+	other := peertest.NewMockSubscriber(mockCtrl)
+	same, err := trans.RetainPeer(hostport.PeerIdentifier("127.0.0.1:6379"), other)
+	require.NoError(t, err)
+	assert.True(t, p == same, "expected the same peer")
+	require.NoError(t, trans.ReleasePeer(hostport.PeerIdentifier("127.0.0.1:6379"), other))
+
+	require.NoError(t, trans.Start())
+	waitForStatus(t, p, peer.Available)
+
+	require.NoError(t, trans.ReleasePeer(hostport.PeerIdentifier("127.0.0.1:6379"), sub))
+	require.NoError(t, trans.Stop())
+	assert.Equal(t, peer.Unavailable, p.Status().ConnectionStatus)
+}
+
+func TestTransportStopClosesPeers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	client := redistest.NewMockClient(mockCtrl)
+	client.EXPECT().Start().Return(nil)
+	client.EXPECT().Stop().Return(nil)
+
+	sub := peertest.NewMockSubscriber(mockCtrl)
+	sub.EXPECT().NotifyStatusChanged(gomock.Any()).AnyTimes()
+
+	trans := NewTransport(clientFactory(t, map[string]Client{"127.0.0.1:6379": client}))
+	p, err := trans.RetainPeer(hostport.PeerIdentifier("127.0.0.1:6379"), sub)
+	require.NoError(t, err)
+
+	require.NoError(t, trans.Start())
+	waitForStatus(t, p, peer.Available)
+	require.NoError(t, trans.Stop())
+	assert.Equal(t, peer.Unavailable, p.Status().ConnectionStatus)
+}
+
+func TestTransportOutbound(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	queueKey := "queueKey"
+	client := redistest.NewMockClient(mockCtrl)
+	client.EXPECT().Start().Return(nil)
+	client.EXPECT().LPush(queueKey, gomock.Any()).Return(nil)
+	client.EXPECT().Stop().Return(nil)
+
+	trans := NewTransport(clientFactory(t, map[string]Client{"127.0.0.1:6379": client}))
+	out := trans.NewSingleOnewayOutbound("127.0.0.1:6379", queueKey)
+	assert.Equal(t, []transport.Transport{trans}, out.Transports())
+
+	require.NoError(t, trans.Start())
+	require.NoError(t, out.Start())
+	waitForStatus(t, trans.peers["127.0.0.1:6379"], peer.Available)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	ack, err := out.CallOneway(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "hello",
+		Body:      bytes.NewReader([]byte("hello!")),
+	})
+	assert.NotNil(t, ack)
+	assert.NoError(t, err)
+
+	status := out.Introspect()
+	assert.Equal(t, "queue: queueKey", status.Endpoint)
+	assert.Equal(t, "Running", status.State)
+	assert.Equal(t, "Single", status.Chooser.Name)
+
+	require.NoError(t, out.Stop())
+	require.NoError(t, trans.Stop())
+}
+
+func TestTransportOutboundInvalidPeer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	p := peertest.NewMockPeer(mockCtrl)
+	var finished bool
+	chooser := peertest.NewMockChooser(mockCtrl)
+	chooser.EXPECT().Start().Return(nil)
+	chooser.EXPECT().Choose(gomock.Any(), gomock.Any()).Return(p, func(error) { finished = true }, nil)
+
+	out := NewTransport().NewOnewayOutbound(chooser, "queueKey")
+	require.NoError(t, out.Start())
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	_, err := out.CallOneway(ctx, &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  raw.Encoding,
+		Procedure: "hello",
+		Body:      bytes.NewReader([]byte("hello!")),
+	})
+	assert.Equal(t, peer.ErrInvalidPeerConversion{Peer: p, ExpectedType: "*redisPeer"}, err)
+	assert.True(t, finished, "onFinish must be called")
+}
+
+func TestTransportInboundMultipleEndpoints(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var polled sync.WaitGroup
+	clients := make(map[string]Client)
+	for _, addr := range []string{"10.0.0.1:6379", "10.0.0.2:6379"} {
+		var once sync.Once
+		polled.Add(1)
+
+		client := redistest.NewMockClient(mockCtrl)
+		client.EXPECT().Start().Return(nil)
+		client.EXPECT().BRPopLPush("queueKey", "processingKey", testtime.Millisecond).
+			Do(func(string, string, time.Duration) { once.Do(polled.Done) }).
+			Return(nil, errors.New("no item found in queue")).
+			MinTimes(1)
+		client.EXPECT().Endpoint().Return(addr)
+		client.EXPECT().ConnectionState().Return("1/1 connection(s)")
+		client.EXPECT().Stop().Return(nil)
+		clients[addr] = client
+	}
+
+	trans := NewTransport(clientFactory(t, clients))
+	in := trans.NewInbound([]string{"10.0.0.1:6379", "10.0.0.2:6379"}, "queueKey", "processingKey", testtime.Millisecond)
+	in.SetRouter(transporttest.NewMockRouter(mockCtrl))
+	assert.Equal(t, []transport.Transport{trans}, in.Transports())
+
+	require.NoError(t, in.Start())
+
+	// Both endpoints must be consumed from.
+	polled.Wait()
+
+	status := in.Introspect()
+	assert.Equal(t, "10.0.0.1:6379, 10.0.0.2:6379 (queue: queueKey)", status.Endpoint)
+	assert.Equal(t, "1/1 connection(s), 1/1 connection(s)", status.State)
+
+	require.NoError(t, in.Stop())
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package shadow provides EXPERIMENTAL outbounds which copy traffic to a
+// shadow outbound, typically to validate a new transport or cluster during a
+// migration.
+//
+// Requests are sent to a primary outbound, whose responses are returned to
+// callers, and a sample of them is asynchronously copied to a shadow
+// outbound. The latency and errors of the shadow outbound never affect
+// callers.
+//
+// 	outbound := shadow.NewUnaryOutbound(
+// 		tchannelTransport.NewSingleOutbound("127.0.0.1:4040"),
+// 		grpcTransport.NewSingleOutbound("127.0.0.1:5050"),
+// 		shadow.SampleRate(0.1),
+// 		shadow.CompareResponses(shadow.Equal),
+// 		shadow.WithTally(scope),
+// 	)
+//
+// The number of shadow requests, their failures, and the results of
+// comparing their responses with those of the primary outbound are reported
+// as metrics.
+package shadow
@@ -0,0 +1,72 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import "github.com/uber-go/tally"
+
+var (
+	_callsName      = "shadow_calls"
+	_failuresName   = "shadow_failures"
+	_skippedName    = "shadow_skipped"
+	_comparedName   = "shadow_compared"
+	_resultTag      = "result"
+	_matchTag       = "match"
+	_mismatchTag    = "mismatch"
+	_reasonTag      = "reason"
+	_concurrencyTag = "max_concurrency"
+)
+
+type observer struct {
+	calls      tally.Counter
+	failures   tally.Counter
+	saturated  tally.Counter
+	matches    tally.Counter
+	mismatches tally.Counter
+}
+
+func newObserver(scope tally.Scope) *observer {
+	return &observer{
+		calls:      scope.Counter(_callsName),
+		failures:   scope.Counter(_failuresName),
+		saturated:  scope.Tagged(map[string]string{_reasonTag: _concurrencyTag}).Counter(_skippedName),
+		matches:    scope.Tagged(map[string]string{_resultTag: _matchTag}).Counter(_comparedName),
+		mismatches: scope.Tagged(map[string]string{_resultTag: _mismatchTag}).Counter(_comparedName),
+	}
+}
+
+func (o *observer) call(err error) {
+	o.calls.Inc(1)
+	if err != nil {
+		o.failures.Inc(1)
+	}
+}
+
+func (o *observer) skipSaturated() {
+	o.saturated.Inc(1)
+}
+
+func (o *observer) compared(match bool) {
+	if match {
+		o.matches.Inc(1)
+	} else {
+		o.mismatches.Inc(1)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"bytes"
+	"math/rand"
+	"time"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type config struct {
+	sampleRate     float64
+	timeout        time.Duration
+	maxConcurrency int
+	compare        Comparator
+	scope          tally.Scope
+	randFloat64    func() float64
+}
+
+var defaultConfig = config{
+	sampleRate:     1,
+	maxConcurrency: 100,
+	scope:          tally.NoopScope,
+	randFloat64:    rand.Float64,
+}
+
+// Option customizes the behavior of a shadowing outbound.
+type Option func(*config)
+
+// SampleRate specifies the fraction of requests, between 0 and 1, that are
+// copied to the shadow outbound.
+//
+// Defaults to 1.
+func SampleRate(rate float64) Option {
+	return func(c *config) {
+		c.sampleRate = rate
+	}
+}
+
+// Timeout specifies the timeout of requests to the shadow outbound. Shadow
+// requests are not cancelled with the requests they copy, so that they do
+// not depend on how fast the primary outbound responds.
+//
+// Defaults to the time remaining before the deadline of the copied request.
+func Timeout(d time.Duration) Option {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// MaxConcurrency specifies the maximum number of requests to the shadow
+// outbound in flight. Requests beyond this limit are not copied, so that a
+// slow shadow cannot accumulate requests.
+//
+// Defaults to 100.
+func MaxConcurrency(n int) Option {
+	return func(c *config) {
+		c.maxConcurrency = n
+	}
+}
+
+// CompareResponses compares the responses of unary requests from the
+// primary and shadow outbounds with the given Comparator, like Equal, and
+// counts mismatches.
+//
+// This buffers the responses of the primary outbound for copied requests.
+func CompareResponses(compare Comparator) Option {
+	return func(c *config) {
+		c.compare = compare
+	}
+}
+
+// WithTally sets a Tally scope that will be used to record shadowing
+// metrics.
+func WithTally(scope tally.Scope) Option {
+	return func(c *config) {
+		c.scope = scope
+	}
+}
+
+// withRand replaces the source of randomness used to sample requests.
+func withRand(f func() float64) Option {
+	return func(c *config) {
+		c.randFloat64 = f
+	}
+}
+
+// Result is the outcome of a unary request.
+type Result struct {
+	Body             []byte
+	ApplicationError bool
+	Err              error
+}
+
+// Comparator reports whether the results of a request from the primary and
+// shadow outbounds match.
+type Comparator func(primary, shadow Result) bool
+
+// Equal is a Comparator for results with the same body and kind of error:
+// either no error, an application error, or an error with the same code.
+func Equal(primary, shadow Result) bool {
+	if (primary.Err == nil) != (shadow.Err == nil) {
+		return false
+	}
+	if primary.Err != nil {
+		return yarpcerrors.ErrorCode(primary.Err) == yarpcerrors.ErrorCode(shadow.Err)
+	}
+	return primary.ApplicationError == shadow.ApplicationError &&
+		bytes.Equal(primary.Body, shadow.Body)
+}
@@ -0,0 +1,390 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/pkg/lifecycle"
+)
+
+// _defaultTimeout is the timeout of shadow requests copied from requests
+// without a deadline.
+const _defaultTimeout = time.Second
+
+var (
+	_ transport.UnaryOutbound  = (*UnaryOutbound)(nil)
+	_ transport.OnewayOutbound = (*OnewayOutbound)(nil)
+)
+
+// shadower holds the state shared by unary and oneway shadowing outbounds.
+type shadower struct {
+	once     *lifecycle.Once
+	cfg      config
+	observer *observer
+
+	// Limits the number of shadow requests in flight.
+	slots chan struct{}
+
+	// Tracks shadow requests in flight, so that Stop waits for them.
+	wg sync.WaitGroup
+}
+
+func newShadower(opts []Option) shadower {
+	cfg := defaultConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return shadower{
+		once:     lifecycle.NewOnce(),
+		cfg:      cfg,
+		observer: newObserver(cfg.scope),
+		slots:    make(chan struct{}, cfg.maxConcurrency),
+	}
+}
+
+// acquire reports whether the request should be copied to the shadow
+// outbound. If so, the caller must call release once the shadow request
+// completes.
+func (s *shadower) acquire() bool {
+	if s.cfg.sampleRate <= 0 {
+		return false
+	}
+	if s.cfg.sampleRate < 1 && s.cfg.randFloat64() >= s.cfg.sampleRate {
+		return false
+	}
+	select {
+	case s.slots <- struct{}{}:
+		s.wg.Add(1)
+		return true
+	default:
+		s.observer.skipSaturated()
+		return false
+	}
+}
+
+func (s *shadower) release() {
+	<-s.slots
+	s.wg.Done()
+}
+
+// copyRequest returns two copies of the request which may be sent
+// independently.
+func copyRequest(req *transport.Request) (primary, shadow *transport.Request, err error) {
+	var body []byte
+	if req.Body != nil {
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	p := *req
+	p.Body = bytes.NewReader(body)
+
+	s := *req
+	s.Body = bytes.NewReader(body)
+	s.Headers = transport.NewHeadersWithCapacity(req.Headers.Len())
+	for k, v := range req.Headers.Items() {
+		s.Headers = s.Headers.With(k, v)
+	}
+	return &p, &s, nil
+}
+
+// shadowContext returns a context for a shadow request, which carries the
+// values of the context of the copied request but not its deadline or
+// cancellation.
+func (s *shadower) shadowContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := s.cfg.timeout
+	if timeout == 0 {
+		timeout = _defaultTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = time.Until(deadline)
+		}
+	}
+	return context.WithTimeout(detachedContext{ctx}, timeout)
+}
+
+// detachedContext carries the values of its parent, without its deadline or
+// cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (s *shadower) start(primary, shadow transport.Outbound) error {
+	if err := primary.Start(); err != nil {
+		return err
+	}
+	if err := shadow.Start(); err != nil {
+		return multierr.Append(err, primary.Stop())
+	}
+	return nil
+}
+
+func (s *shadower) stop(primary, shadow transport.Outbound) error {
+	err := primary.Stop()
+	s.wg.Wait()
+	return multierr.Append(err, shadow.Stop())
+}
+
+// UnaryOutbound is a transport.UnaryOutbound that sends requests to a
+// primary outbound, and copies them to a shadow outbound.
+type UnaryOutbound struct {
+	shadower
+
+	primary transport.UnaryOutbound
+	shadow  transport.UnaryOutbound
+}
+
+// NewUnaryOutbound builds an outbound that sends requests to the primary
+// outbound and responds with its responses, while asynchronously copying a
+// sample of requests to the shadow outbound. Responses and errors of the
+// shadow outbound never reach callers.
+//
+// The shadowing outbound starts and stops both outbounds over its own
+// lifecycle.
+func NewUnaryOutbound(primary, shadow transport.UnaryOutbound, opts ...Option) *UnaryOutbound {
+	return &UnaryOutbound{
+		shadower: newShadower(opts),
+		primary:  primary,
+		shadow:   shadow,
+	}
+}
+
+// Transports returns the transports of both outbounds.
+func (o *UnaryOutbound) Transports() []transport.Transport {
+	return append(o.primary.Transports(), o.shadow.Transports()...)
+}
+
+// Start starts both outbounds.
+func (o *UnaryOutbound) Start() error {
+	return o.once.Start(func() error { return o.start(o.primary, o.shadow) })
+}
+
+// Stop stops both outbounds, once shadow requests in flight complete.
+func (o *UnaryOutbound) Stop() error {
+	return o.once.Stop(func() error { return o.stop(o.primary, o.shadow) })
+}
+
+// IsRunning returns whether the outbound is running.
+func (o *UnaryOutbound) IsRunning() bool {
+	return o.once.IsRunning()
+}
+
+// Call sends the request to the primary outbound, and a copy of it to the
+// shadow outbound.
+func (o *UnaryOutbound) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	if !o.acquire() {
+		return o.primary.Call(ctx, req)
+	}
+
+	req, shadowReq, err := copyRequest(req)
+	if err != nil {
+		o.release()
+		return nil, err
+	}
+
+	// primaryResult receives the result of the primary request, or nil if
+	// it cannot be compared.
+	var primaryResult chan *Result
+	if o.cfg.compare != nil {
+		primaryResult = make(chan *Result, 1)
+	}
+
+	shadowCtx, cancel := o.shadowContext(ctx)
+	go func() {
+		defer o.release()
+		defer cancel()
+
+		res, err := o.shadow.Call(shadowCtx, shadowReq)
+		o.observer.call(err)
+		shadowResult, err := readResult(res, err)
+		if primaryResult == nil {
+			return
+		}
+		if primary := <-primaryResult; primary != nil {
+			o.observer.compared(o.cfg.compare(*primary, shadowResult))
+		}
+	}()
+
+	if primaryResult == nil {
+		return o.primary.Call(ctx, req)
+	}
+
+	// The shadow request waits for the primary result, which must be sent
+	// even if the primary outbound panics.
+	var result *Result
+	defer func() { primaryResult <- result }()
+
+	res, err := o.primary.Call(ctx, req)
+	if res == nil || res.Body == nil {
+		result = &Result{Err: err}
+		if res != nil {
+			result.ApplicationError = res.ApplicationError
+		}
+		return res, err
+	}
+
+	// Failing to read the primary response only skips the comparison: the
+	// caller gets the response as the primary outbound returned it, errors
+	// included.
+	body, readErr := ioutil.ReadAll(res.Body)
+	closeErr := res.Body.Close()
+	if readErr == nil && closeErr == nil {
+		result = &Result{Body: body, ApplicationError: res.ApplicationError, Err: err}
+	}
+
+	r := *res
+	r.Body = &replayedBody{
+		body:     body,
+		readErr:  readErr,
+		closeErr: closeErr,
+	}
+	return &r, err
+}
+
+// replayedBody replays a response body which was already read, including
+// the errors encountered while reading and closing it.
+type replayedBody struct {
+	body     []byte
+	readErr  error
+	closeErr error
+}
+
+func (b *replayedBody) Read(p []byte) (int, error) {
+	if len(b.body) == 0 {
+		if b.readErr != nil {
+			return 0, b.readErr
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, b.body)
+	b.body = b.body[n:]
+	return n, nil
+}
+
+func (b *replayedBody) Close() error {
+	return b.closeErr
+}
+
+// readResult reads the response body of a request. It returns an error if
+// the body could not be read.
+func readResult(res *transport.Response, err error) (Result, error) {
+	result := Result{Err: err}
+	if res == nil {
+		return result, nil
+	}
+
+	result.ApplicationError = res.ApplicationError
+	if res.Body == nil {
+		return result, nil
+	}
+	body, readErr := ioutil.ReadAll(res.Body)
+	if closeErr := res.Body.Close(); readErr == nil {
+		readErr = closeErr
+	}
+	if readErr != nil {
+		result.Err = readErr
+		return result, readErr
+	}
+	result.Body = body
+	return result, nil
+}
+
+// OnewayOutbound is a transport.OnewayOutbound that sends requests to a
+// primary outbound, and copies them to a shadow outbound.
+type OnewayOutbound struct {
+	shadower
+
+	primary transport.OnewayOutbound
+	shadow  transport.OnewayOutbound
+}
+
+// NewOnewayOutbound builds an outbound that sends requests to the primary
+// outbound, while asynchronously copying a sample of requests to the shadow
+// outbound. Errors of the shadow outbound never reach callers.
+//
+// Responses of oneway requests are never compared.
+//
+// The shadowing outbound starts and stops both outbounds over its own
+// lifecycle.
+func NewOnewayOutbound(primary, shadow transport.OnewayOutbound, opts ...Option) *OnewayOutbound {
+	return &OnewayOutbound{
+		shadower: newShadower(opts),
+		primary:  primary,
+		shadow:   shadow,
+	}
+}
+
+// Transports returns the transports of both outbounds.
+func (o *OnewayOutbound) Transports() []transport.Transport {
+	return append(o.primary.Transports(), o.shadow.Transports()...)
+}
+
+// Start starts both outbounds.
+func (o *OnewayOutbound) Start() error {
+	return o.once.Start(func() error { return o.start(o.primary, o.shadow) })
+}
+
+// Stop stops both outbounds, once shadow requests in flight complete.
+func (o *OnewayOutbound) Stop() error {
+	return o.once.Stop(func() error { return o.stop(o.primary, o.shadow) })
+}
+
+// IsRunning returns whether the outbound is running.
+func (o *OnewayOutbound) IsRunning() bool {
+	return o.once.IsRunning()
+}
+
+// CallOneway sends the request to the primary outbound, and a copy of it to
+// the shadow outbound.
+func (o *OnewayOutbound) CallOneway(ctx context.Context, req *transport.Request) (transport.Ack, error) {
+	if !o.acquire() {
+		return o.primary.CallOneway(ctx, req)
+	}
+
+	req, shadowReq, err := copyRequest(req)
+	if err != nil {
+		o.release()
+		return nil, err
+	}
+
+	shadowCtx, cancel := o.shadowContext(ctx)
+	go func() {
+		defer o.release()
+		defer cancel()
+
+		_, err := o.shadow.CallOneway(shadowCtx, shadowReq)
+		o.observer.call(err)
+	}()
+
+	return o.primary.CallOneway(ctx, req)
+}
@@ -0,0 +1,438 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func newRequest(body string) *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "procedure",
+		Headers:   transport.NewHeaders().With("foo", "bar"),
+		Body:      bytes.NewBufferString(body),
+	}
+}
+
+func newResponse(body string) *transport.Response {
+	return &transport.Response{Body: ioutil.NopCloser(bytes.NewBufferString(body))}
+}
+
+func readBody(t *testing.T, req *transport.Request) string {
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func counters(scope tally.TestScope) map[string]int64 {
+	values := make(map[string]int64)
+	for name, c := range scope.Snapshot().Counters() {
+		values[name] = c.Value()
+	}
+	return values
+}
+
+func TestUnaryOutboundShadows(t *testing.T) {
+	tests := []struct {
+		desc          string
+		primaryBody   string
+		primaryErr    error
+		shadowBody    string
+		shadowErr     error
+		wantCounters  map[string]int64
+		wantErrorCode yarpcerrors.Code
+	}{
+		{
+			desc:        "match",
+			primaryBody: "hello",
+			shadowBody:  "hello",
+			wantCounters: map[string]int64{
+				"shadow_calls+":                   1,
+				"shadow_compared+result=match":    1,
+				"shadow_compared+result=mismatch": 0,
+			},
+		},
+		{
+			desc:        "mismatch",
+			primaryBody: "hello",
+			shadowBody:  "goodbye",
+			wantCounters: map[string]int64{
+				"shadow_calls+":                   1,
+				"shadow_compared+result=match":    0,
+				"shadow_compared+result=mismatch": 1,
+			},
+		},
+		{
+			desc:        "shadow fails",
+			primaryBody: "hello",
+			shadowErr:   yarpcerrors.UnavailableErrorf("great sadness"),
+			wantCounters: map[string]int64{
+				"shadow_calls+":                   1,
+				"shadow_failures+":                1,
+				"shadow_compared+result=mismatch": 1,
+			},
+		},
+		{
+			desc:          "both fail with the same code",
+			primaryErr:    yarpcerrors.UnavailableErrorf("great sadness"),
+			shadowErr:     yarpcerrors.UnavailableErrorf("other sadness"),
+			wantErrorCode: yarpcerrors.CodeUnavailable,
+			wantCounters: map[string]int64{
+				"shadow_calls+":                1,
+				"shadow_failures+":             1,
+				"shadow_compared+result=match": 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			primary := transporttest.NewMockUnaryOutbound(mockCtrl)
+			shadow := transporttest.NewMockUnaryOutbound(mockCtrl)
+			scope := tally.NewTestScope("", nil)
+			o := NewUnaryOutbound(primary, shadow, CompareResponses(Equal), WithTally(scope))
+
+			primary.EXPECT().Start().Return(nil)
+			shadow.EXPECT().Start().Return(nil)
+			require.NoError(t, o.Start())
+
+			var primaryRes, shadowRes *transport.Response
+			if tt.primaryErr == nil {
+				primaryRes = newResponse(tt.primaryBody)
+			}
+			if tt.shadowErr == nil {
+				shadowRes = newResponse(tt.shadowBody)
+			}
+
+			primary.EXPECT().Call(gomock.Any(), gomock.Any()).
+				Do(func(_ context.Context, req *transport.Request) {
+					assert.Equal(t, "body", readBody(t, req))
+				}).
+				Return(primaryRes, tt.primaryErr)
+			shadow.EXPECT().Call(gomock.Any(), gomock.Any()).
+				Do(func(_ context.Context, req *transport.Request) {
+					assert.Equal(t, "body", readBody(t, req))
+					assert.Equal(t, "procedure", req.Procedure)
+					v, ok := req.Headers.Get("foo")
+					assert.True(t, ok)
+					assert.Equal(t, "bar", v)
+				}).
+				Return(shadowRes, tt.shadowErr)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			res, err := o.Call(ctx, newRequest("body"))
+			if tt.primaryErr != nil {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantErrorCode, yarpcerrors.ErrorCode(err))
+			} else {
+				require.NoError(t, err)
+				body, err := ioutil.ReadAll(res.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.primaryBody, string(body))
+			}
+
+			primary.EXPECT().Stop().Return(nil)
+			shadow.EXPECT().Stop().Return(nil)
+			require.NoError(t, o.Stop())
+
+			got := counters(scope)
+			for name, want := range tt.wantCounters {
+				assert.Equal(t, want, got[name], "counter %v", name)
+			}
+		})
+	}
+}
+
+func TestUnaryOutboundShadowDoesNotBlockCaller(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	primary := transporttest.NewMockUnaryOutbound(mockCtrl)
+	shadow := transporttest.NewMockUnaryOutbound(mockCtrl)
+	o := NewUnaryOutbound(primary, shadow)
+
+	unblock := make(chan struct{})
+	primary.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("hello"), nil)
+	shadow.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request) {
+			<-unblock
+			assert.NoError(t, ctx.Err(), "shadow request must outlive the caller's context")
+		}).
+		Return(nil, errors.New("great sadness"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	res, err := o.Call(ctx, newRequest("body"))
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	cancel()
+
+	close(unblock)
+	o.wg.Wait()
+}
+
+// failingBody is a response body whose reads fail after some data.
+type failingBody struct {
+	data []byte
+	err  error
+}
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	if len(b.data) == 0 {
+		return 0, b.err
+	}
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+func (b *failingBody) Close() error { return nil }
+
+func TestUnaryOutboundPrimaryBodyReadFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	primary := transporttest.NewMockUnaryOutbound(mockCtrl)
+	shadow := transporttest.NewMockUnaryOutbound(mockCtrl)
+	scope := tally.NewTestScope("", nil)
+	o := NewUnaryOutbound(primary, shadow, CompareResponses(Equal), WithTally(scope))
+
+	readErr := errors.New("connection reset")
+	primary.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{
+		ApplicationError: true,
+		Body:             &failingBody{data: []byte("hel"), err: readErr},
+	}, nil)
+	shadow.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("hello"), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := o.Call(ctx, newRequest("body"))
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.True(t, res.ApplicationError)
+
+	// The caller sees the response as the primary outbound returned it.
+	body, err := ioutil.ReadAll(res.Body)
+	assert.Equal(t, readErr, err)
+	assert.Equal(t, "hel", string(body))
+
+	o.wg.Wait()
+	got := counters(scope)
+	assert.Equal(t, int64(0), got["shadow_compared+result=match"])
+	assert.Equal(t, int64(0), got["shadow_compared+result=mismatch"])
+}
+
+func TestUnaryOutboundPrimaryPanics(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	primary := transporttest.NewMockUnaryOutbound(mockCtrl)
+	shadow := transporttest.NewMockUnaryOutbound(mockCtrl)
+	o := NewUnaryOutbound(primary, shadow, CompareResponses(Equal))
+
+	primary.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *transport.Request) { panic("great sadness") })
+	shadow.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("hello"), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Panics(t, func() { o.Call(ctx, newRequest("body")) })
+
+	// The shadow request must not wait forever for the primary result.
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("shadow request did not complete")
+	}
+}
+
+func TestUnaryOutboundMaxConcurrency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	primary := transporttest.NewMockUnaryOutbound(mockCtrl)
+	shadow := transporttest.NewMockUnaryOutbound(mockCtrl)
+	scope := tally.NewTestScope("", nil)
+	o := NewUnaryOutbound(primary, shadow, MaxConcurrency(1), WithTally(scope))
+
+	unblock := make(chan struct{})
+	primary.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("hello"), nil).Times(2)
+	shadow.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *transport.Request) { <-unblock }).
+		Return(newResponse("hello"), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		_, err := o.Call(ctx, newRequest("body"))
+		require.NoError(t, err)
+	}
+
+	close(unblock)
+	o.wg.Wait()
+
+	got := counters(scope)
+	assert.Equal(t, int64(1), got["shadow_calls+"])
+	assert.Equal(t, int64(1), got["shadow_skipped+reason=max_concurrency"])
+}
+
+func TestUnaryOutboundSampleRate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	primary := transporttest.NewMockUnaryOutbound(mockCtrl)
+	shadow := transporttest.NewMockUnaryOutbound(mockCtrl)
+
+	samples := []float64{0.3, 0.7}
+	o := NewUnaryOutbound(primary, shadow, SampleRate(0.5), withRand(func() float64 {
+		s := samples[0]
+		samples = samples[1:]
+		return s
+	}))
+
+	primary.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("hello"), nil).Times(2)
+	shadow.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("hello"), nil).Times(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		_, err := o.Call(ctx, newRequest("body"))
+		require.NoError(t, err)
+	}
+	o.wg.Wait()
+}
+
+func TestUnaryOutboundStartFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	primary := transporttest.NewMockUnaryOutbound(mockCtrl)
+	shadow := transporttest.NewMockUnaryOutbound(mockCtrl)
+	o := NewUnaryOutbound(primary, shadow)
+
+	primary.EXPECT().Start().Return(nil)
+	shadow.EXPECT().Start().Return(errors.New("great sadness"))
+	primary.EXPECT().Stop().Return(nil)
+
+	assert.Error(t, o.Start())
+}
+
+func TestOnewayOutboundShadows(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	primary := transporttest.NewMockOnewayOutbound(mockCtrl)
+	shadow := transporttest.NewMockOnewayOutbound(mockCtrl)
+	scope := tally.NewTestScope("", nil)
+	o := NewOnewayOutbound(primary, shadow, WithTally(scope))
+
+	primary.EXPECT().Start().Return(nil)
+	shadow.EXPECT().Start().Return(nil)
+	require.NoError(t, o.Start())
+
+	primary.EXPECT().CallOneway(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *transport.Request) {
+			assert.Equal(t, "body", readBody(t, req))
+		}).
+		Return(nil, nil)
+	shadow.EXPECT().CallOneway(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *transport.Request) {
+			assert.Equal(t, "body", readBody(t, req))
+		}).
+		Return(nil, errors.New("great sadness"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := o.CallOneway(ctx, newRequest("body"))
+	require.NoError(t, err)
+
+	primary.EXPECT().Stop().Return(nil)
+	shadow.EXPECT().Stop().Return(nil)
+	require.NoError(t, o.Stop())
+
+	got := counters(scope)
+	assert.Equal(t, int64(1), got["shadow_calls+"])
+	assert.Equal(t, int64(1), got["shadow_failures+"])
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		desc            string
+		primary, shadow Result
+		want            bool
+	}{
+		{
+			desc:    "same body",
+			primary: Result{Body: []byte("a")},
+			shadow:  Result{Body: []byte("a")},
+			want:    true,
+		},
+		{
+			desc:    "different body",
+			primary: Result{Body: []byte("a")},
+			shadow:  Result{Body: []byte("b")},
+		},
+		{
+			desc:    "application error",
+			primary: Result{Body: []byte("a"), ApplicationError: true},
+			shadow:  Result{Body: []byte("a")},
+		},
+		{
+			desc:    "one error",
+			primary: Result{Err: yarpcerrors.InternalErrorf("great sadness")},
+			shadow:  Result{Body: []byte("a")},
+		},
+		{
+			desc:    "different codes",
+			primary: Result{Err: yarpcerrors.InternalErrorf("great sadness")},
+			shadow:  Result{Err: yarpcerrors.UnavailableErrorf("great sadness")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.want, Equal(tt.primary, tt.shadow))
+		})
+	}
+}
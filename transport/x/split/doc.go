@@ -0,0 +1,45 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package split provides EXPERIMENTAL outbounds which split traffic between
+// weighted child outbounds, typically to gradually move traffic from one
+// transport or cluster to another.
+//
+// 	outbound, err := split.NewUnaryOutbound(
+// 		split.UnaryChild{
+// 			Name:     "tchannel",
+// 			Weight:   90,
+// 			Outbound: tchannelTransport.NewSingleOutbound("127.0.0.1:4040"),
+// 		},
+// 		split.UnaryChild{
+// 			Name:     "grpc",
+// 			Weight:   10,
+// 			Outbound: grpcTransport.NewSingleOutbound("127.0.0.1:5050"),
+// 		},
+// 	)
+//
+// Requests with a shard key always go to the same child for as long as the
+// weights do not change. Weights may be changed at runtime with SetWeights.
+//
+// 	err := outbound.SetWeights(map[string]int{"tchannel": 50, "grpc": 50})
+//
+// Splitting outbounds may also be configured with yarpcconfig. See the
+// documentation of the yarpcconfig package.
+package split
@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package split
+
+import (
+	"context"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+)
+
+var (
+	_ transport.UnaryOutbound              = (*UnaryOutbound)(nil)
+	_ transport.OnewayOutbound             = (*OnewayOutbound)(nil)
+	_ introspection.IntrospectableOutbound = (*UnaryOutbound)(nil)
+	_ introspection.IntrospectableOutbound = (*OnewayOutbound)(nil)
+)
+
+// UnaryChild is a weighted child of a UnaryOutbound.
+type UnaryChild struct {
+	// Name identifies the child when changing weights. Names must be unique.
+	Name string
+
+	// Weight of the child. The child receives Weight out of the sum of the
+	// weights of all children of requests.
+	Weight int
+
+	Outbound transport.UnaryOutbound
+}
+
+// UnaryOutbound is a transport.UnaryOutbound which splits requests between
+// weighted child outbounds.
+type UnaryOutbound struct {
+	*splitter
+
+	outbounds []transport.UnaryOutbound
+}
+
+// NewUnaryOutbound builds an outbound which sends each request to one of the
+// given children, in proportion to their weights.
+//
+// Requests with a shard key are always sent to the same child for as long as
+// the weights do not change.
+//
+// The splitting outbound starts and stops its children over its own
+// lifecycle.
+func NewUnaryOutbound(children ...UnaryChild) (*UnaryOutbound, error) {
+	names := make([]string, len(children))
+	weights := make([]int, len(children))
+	outbounds := make([]transport.UnaryOutbound, len(children))
+	generic := make([]transport.Outbound, len(children))
+	for i, c := range children {
+		names[i] = c.Name
+		weights[i] = c.Weight
+		outbounds[i] = c.Outbound
+		generic[i] = c.Outbound
+	}
+
+	s, err := newSplitter(names, weights, generic)
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryOutbound{splitter: s, outbounds: outbounds}, nil
+}
+
+// Call sends the request to one of the child outbounds.
+func (o *UnaryOutbound) Call(ctx context.Context, req *transport.Request) (*transport.Response, error) {
+	return o.outbounds[o.pick(req)].Call(ctx, req)
+}
+
+// OnewayChild is a weighted child of a OnewayOutbound.
+type OnewayChild struct {
+	// Name identifies the child when changing weights. Names must be unique.
+	Name string
+
+	// Weight of the child. The child receives Weight out of the sum of the
+	// weights of all children of requests.
+	Weight int
+
+	Outbound transport.OnewayOutbound
+}
+
+// OnewayOutbound is a transport.OnewayOutbound which splits requests between
+// weighted child outbounds.
+type OnewayOutbound struct {
+	*splitter
+
+	outbounds []transport.OnewayOutbound
+}
+
+// NewOnewayOutbound builds an outbound which sends each request to one of
+// the given children, in proportion to their weights.
+//
+// Requests with a shard key are always sent to the same child for as long as
+// the weights do not change.
+//
+// The splitting outbound starts and stops its children over its own
+// lifecycle.
+func NewOnewayOutbound(children ...OnewayChild) (*OnewayOutbound, error) {
+	names := make([]string, len(children))
+	weights := make([]int, len(children))
+	outbounds := make([]transport.OnewayOutbound, len(children))
+	generic := make([]transport.Outbound, len(children))
+	for i, c := range children {
+		names[i] = c.Name
+		weights[i] = c.Weight
+		outbounds[i] = c.Outbound
+		generic[i] = c.Outbound
+	}
+
+	s, err := newSplitter(names, weights, generic)
+	if err != nil {
+		return nil, err
+	}
+	return &OnewayOutbound{splitter: s, outbounds: outbounds}, nil
+}
+
+// CallOneway sends the request to one of the child outbounds.
+func (o *OnewayOutbound) CallOneway(ctx context.Context, req *transport.Request) (transport.Ack, error) {
+	return o.outbounds[o.pick(req)].CallOneway(ctx, req)
+}
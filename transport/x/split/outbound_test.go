@@ -0,0 +1,260 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package split
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+)
+
+func TestNewUnaryOutboundErrors(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+
+	tests := []struct {
+		desc     string
+		children []UnaryChild
+		wantErr  string
+	}{
+		{
+			desc:    "no children",
+			wantErr: "at least one child outbound is required",
+		},
+		{
+			desc:     "no name",
+			children: []UnaryChild{{Weight: 1, Outbound: out}},
+			wantErr:  "child outbounds must have a name",
+		},
+		{
+			desc: "duplicate name",
+			children: []UnaryChild{
+				{Name: "a", Weight: 1, Outbound: out},
+				{Name: "a", Weight: 1, Outbound: out},
+			},
+			wantErr: `duplicate child outbound "a"`,
+		},
+		{
+			desc:     "negative weight",
+			children: []UnaryChild{{Name: "a", Weight: -1, Outbound: out}},
+			wantErr:  `weight of child outbound "a" must not be negative, got -1`,
+		},
+		{
+			desc:     "zero weights",
+			children: []UnaryChild{{Name: "a", Outbound: out}},
+			wantErr:  "at least one child outbound must have a positive weight",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := NewUnaryOutbound(tt.children...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestUnaryOutboundSplits(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	a := transporttest.NewMockUnaryOutbound(mockCtrl)
+	b := transporttest.NewMockUnaryOutbound(mockCtrl)
+	o, err := NewUnaryOutbound(
+		UnaryChild{Name: "a", Weight: 3, Outbound: a},
+		UnaryChild{Name: "b", Weight: 1, Outbound: b},
+	)
+	require.NoError(t, err)
+
+	// Cycle through every value in [0, 4) so that requests are split
+	// exactly by weight.
+	next := 0
+	o.intn = func(n int) int {
+		assert.Equal(t, 4, n)
+		v := next % n
+		next++
+		return v
+	}
+
+	a.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil).Times(3)
+	b.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil).Times(1)
+	for i := 0; i < 4; i++ {
+		_, err := o.Call(context.Background(), &transport.Request{})
+		require.NoError(t, err)
+	}
+
+	status := o.Introspect()
+	assert.Equal(t, "split", status.Transport)
+	require.Len(t, status.Splits, 2)
+	assert.Equal(t, "a", status.Splits[0].Name)
+	assert.Equal(t, 3, status.Splits[0].Weight)
+	assert.Equal(t, int64(3), status.Splits[0].Calls)
+	assert.Equal(t, "b", status.Splits[1].Name)
+	assert.Equal(t, 1, status.Splits[1].Weight)
+	assert.Equal(t, int64(1), status.Splits[1].Calls)
+}
+
+func TestUnaryOutboundShardKeyIsSticky(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	a := transporttest.NewMockUnaryOutbound(mockCtrl)
+	b := transporttest.NewMockUnaryOutbound(mockCtrl)
+	o, err := NewUnaryOutbound(
+		UnaryChild{Name: "a", Weight: 1, Outbound: a},
+		UnaryChild{Name: "b", Weight: 1, Outbound: b},
+	)
+	require.NoError(t, err)
+	o.intn = func(int) int {
+		t.Fatal("requests with a shard key must not be routed randomly")
+		return 0
+	}
+
+	a.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil).AnyTimes()
+	b.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil).AnyTimes()
+
+	for _, key := range []string{"foo", "bar", "baz", "qux"} {
+		first := o.pick(&transport.Request{ShardKey: key})
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, first, o.pick(&transport.Request{ShardKey: key}), "shard key %q moved", key)
+		}
+	}
+}
+
+func TestSetWeights(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	a := transporttest.NewMockUnaryOutbound(mockCtrl)
+	b := transporttest.NewMockUnaryOutbound(mockCtrl)
+	o, err := NewUnaryOutbound(
+		UnaryChild{Name: "a", Weight: 1, Outbound: a},
+		UnaryChild{Name: "b", Weight: 0, Outbound: b},
+	)
+	require.NoError(t, err)
+
+	assert.Error(t, o.SetWeights(map[string]int{"c": 1}), "unknown child")
+	assert.Error(t, o.SetWeights(map[string]int{"a": -1}), "negative weight")
+	assert.Error(t, o.SetWeights(map[string]int{"a": 0}), "zero weights")
+	assert.Equal(t, map[string]int{"a": 1, "b": 0}, o.Weights(), "weights must not change on error")
+
+	require.NoError(t, o.SetWeights(map[string]int{"a": 0, "b": 5}))
+	assert.Equal(t, map[string]int{"a": 0, "b": 5}, o.Weights())
+
+	b.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{}, nil).Times(2)
+	_, err = o.Call(context.Background(), &transport.Request{})
+	require.NoError(t, err)
+	_, err = o.Call(context.Background(), &transport.Request{ShardKey: "foo"})
+	require.NoError(t, err)
+}
+
+func TestSetWeightsConcurrently(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const n = 50
+	children := make([]UnaryChild, n)
+	want := make(map[string]int, n)
+	for i := range children {
+		name := strconv.Itoa(i)
+		children[i] = UnaryChild{Name: name, Weight: 1, Outbound: transporttest.NewMockUnaryOutbound(mockCtrl)}
+		want[name] = i + 2
+	}
+	o, err := NewUnaryOutbound(children...)
+	require.NoError(t, err)
+
+	// Updates of different children must all be applied.
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for name, w := range want {
+		wg.Add(1)
+		go func(name string, w int) {
+			defer wg.Done()
+			<-start
+			assert.NoError(t, o.SetWeights(map[string]int{name: w}))
+		}(name, w)
+	}
+	close(start)
+	wg.Wait()
+	assert.Equal(t, want, o.Weights())
+}
+
+func TestOutboundLifecycle(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	a := transporttest.NewMockOnewayOutbound(mockCtrl)
+	b := transporttest.NewMockOnewayOutbound(mockCtrl)
+	o, err := NewOnewayOutbound(
+		OnewayChild{Name: "a", Weight: 1, Outbound: a},
+		OnewayChild{Name: "b", Weight: 1, Outbound: b},
+	)
+	require.NoError(t, err)
+
+	ta := transporttest.NewMockTransport(mockCtrl)
+	tb := transporttest.NewMockTransport(mockCtrl)
+	a.EXPECT().Transports().Return([]transport.Transport{ta})
+	b.EXPECT().Transports().Return([]transport.Transport{tb})
+	assert.Equal(t, []transport.Transport{ta, tb}, o.Transports())
+
+	a.EXPECT().Start().Return(nil)
+	b.EXPECT().Start().Return(nil)
+	require.NoError(t, o.Start())
+	assert.True(t, o.IsRunning())
+
+	a.EXPECT().CallOneway(gomock.Any(), gomock.Any()).Return(nil, nil)
+	o.intn = func(int) int { return 0 }
+	_, err = o.CallOneway(context.Background(), &transport.Request{})
+	require.NoError(t, err)
+
+	a.EXPECT().Stop().Return(nil)
+	b.EXPECT().Stop().Return(errors.New("great sadness"))
+	assert.Error(t, o.Stop())
+}
+
+func TestStartFailureStopsStartedChildren(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	a := transporttest.NewMockOnewayOutbound(mockCtrl)
+	b := transporttest.NewMockOnewayOutbound(mockCtrl)
+	o, err := NewOnewayOutbound(
+		OnewayChild{Name: "a", Weight: 1, Outbound: a},
+		OnewayChild{Name: "b", Weight: 1, Outbound: b},
+	)
+	require.NoError(t, err)
+
+	a.EXPECT().Start().Return(nil)
+	b.EXPECT().Start().Return(errors.New("great sadness"))
+	a.EXPECT().Stop().Return(nil)
+	assert.Error(t, o.Start())
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package split
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/pkg/lifecycle"
+)
+
+// splitter routes requests between weighted children. It is shared by unary
+// and oneway splitting outbounds.
+type splitter struct {
+	once     *lifecycle.Once
+	names    []string
+	children []transport.Outbound
+	calls    []*atomic.Int64
+
+	mu      sync.RWMutex
+	weights []int
+	total   int
+
+	// Returns a random number in [0, n). Replaced in tests.
+	intn func(n int) int
+}
+
+func newSplitter(names []string, weights []int, children []transport.Outbound) (*splitter, error) {
+	if len(children) == 0 {
+		return nil, errors.New("at least one child outbound is required")
+	}
+
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name == "" {
+			return nil, errors.New("child outbounds must have a name")
+		}
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("duplicate child outbound %q", name)
+		}
+		seen[name] = struct{}{}
+	}
+
+	s := &splitter{
+		once:     lifecycle.NewOnce(),
+		names:    names,
+		children: children,
+		calls:    make([]*atomic.Int64, len(children)),
+		intn:     rand.Intn,
+	}
+	for i := range s.calls {
+		s.calls[i] = atomic.NewInt64(0)
+	}
+	if err := s.setWeightsLocked(weights); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// setWeightsLocked validates and applies the weights of all children. The
+// caller must hold the write lock, unless the splitter isn't shared yet.
+func (s *splitter) setWeightsLocked(weights []int) error {
+	total := 0
+	for i, w := range weights {
+		if w < 0 {
+			return fmt.Errorf("weight of child outbound %q must not be negative, got %d", s.names[i], w)
+		}
+		total += w
+	}
+	if total == 0 {
+		return errors.New("at least one child outbound must have a positive weight")
+	}
+
+	s.weights = weights
+	s.total = total
+	return nil
+}
+
+// SetWeights changes the weights of the named child outbounds. Children
+// which are not named keep their weights.
+//
+// An error is returned, and no weight is changed, if a name does not match
+// a child, if a weight is negative, or if all weights would be zero.
+func (s *splitter) SetWeights(weights map[string]int) error {
+	// Concurrent updates of different children must not undo one another.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newWeights := append([]int(nil), s.weights...)
+	for name, w := range weights {
+		i := s.index(name)
+		if i < 0 {
+			return fmt.Errorf("unknown child outbound %q", name)
+		}
+		newWeights[i] = w
+	}
+	return s.setWeightsLocked(newWeights)
+}
+
+// Weights returns the current weights of the child outbounds by name.
+func (s *splitter) Weights() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	weights := make(map[string]int, len(s.names))
+	for i, name := range s.names {
+		weights[name] = s.weights[i]
+	}
+	return weights
+}
+
+func (s *splitter) index(name string) int {
+	for i, n := range s.names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// pick returns the index of the child which should receive the request.
+//
+// Requests with a shard key are routed to the same child for as long as the
+// weights do not change. Other requests are routed randomly.
+func (s *splitter) pick(req *transport.Request) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	if req.ShardKey != "" {
+		h := fnv.New32a()
+		h.Write([]byte(req.ShardKey))
+		n = int(h.Sum32() % uint32(s.total))
+	} else {
+		n = s.intn(s.total)
+	}
+
+	for i, w := range s.weights {
+		if n < w {
+			s.calls[i].Inc()
+			return i
+		}
+		n -= w
+	}
+	panic("unreachable")
+}
+
+// Transports returns the transports of all child outbounds.
+func (s *splitter) Transports() []transport.Transport {
+	var transports []transport.Transport
+	for _, o := range s.children {
+		transports = append(transports, o.Transports()...)
+	}
+	return transports
+}
+
+// Start starts all child outbounds.
+func (s *splitter) Start() error {
+	return s.once.Start(func() error {
+		for i, o := range s.children {
+			if err := o.Start(); err != nil {
+				for _, started := range s.children[:i] {
+					err = multierr.Append(err, started.Stop())
+				}
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Stop stops all child outbounds.
+func (s *splitter) Stop() error {
+	return s.once.Stop(func() error {
+		var err error
+		for _, o := range s.children {
+			err = multierr.Append(err, o.Stop())
+		}
+		return err
+	})
+}
+
+// IsRunning returns whether the outbound is running.
+func (s *splitter) IsRunning() bool {
+	return s.once.IsRunning()
+}
+
+// Introspect returns the weights and number of calls of the child outbounds.
+func (s *splitter) Introspect() introspection.OutboundStatus {
+	state := "Stopped"
+	if s.IsRunning() {
+		state = "Running"
+	}
+
+	weights := s.Weights()
+	splits := make([]introspection.SplitStatus, len(s.children))
+	for i, o := range s.children {
+		var status introspection.OutboundStatus
+		if o, ok := o.(introspection.IntrospectableOutbound); ok {
+			status = o.Introspect()
+		} else {
+			status.Transport = "Introspection not supported"
+		}
+		splits[i] = introspection.SplitStatus{
+			Name:     s.names[i],
+			Weight:   weights[s.names[i]],
+			Calls:    s.calls[i].Load(),
+			Outbound: status,
+		}
+	}
+
+	return introspection.OutboundStatus{
+		Transport: "split",
+		State:     state,
+		Splits:    splits,
+	}
+}
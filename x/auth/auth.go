@@ -0,0 +1,57 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"context"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// authorizationHeader is the name of the request header credentials are sent
+// in.
+const authorizationHeader = "authorization"
+
+// CredentialProvider provides credentials for outbound requests.
+type CredentialProvider interface {
+	// Credentials returns the credentials for the given request, in the form
+	// "<scheme> <credentials>".
+	//
+	// Providers that need the request body to build credentials must replace
+	// the body of the request with an equivalent reader after reading it.
+	Credentials(ctx context.Context, req *transport.Request) (string, error)
+}
+
+// Verifier verifies the credentials of inbound requests for an
+// authorization scheme.
+type Verifier interface {
+	// Scheme returns the name of the authorization scheme handled by this
+	// verifier, for example "Bearer". Schemes are case insensitive.
+	Scheme() string
+
+	// Verify checks the given credentials of a request, without the scheme
+	// prefix, and returns the principal they identify.
+	//
+	// Verifiers that need the request body to check credentials must
+	// replace the body of the request with an equivalent reader after
+	// reading it.
+	Verify(ctx context.Context, req *transport.Request, credentials string) (principal string, err error)
+}
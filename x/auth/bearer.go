@@ -0,0 +1,207 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+)
+
+const bearerScheme = "Bearer"
+
+var errUnknownBearerToken = errors.New("unknown bearer token")
+
+// StaticBearer is a CredentialProvider that authenticates all requests with
+// the same bearer token.
+type StaticBearer struct {
+	token string
+}
+
+var _ CredentialProvider = (*StaticBearer)(nil)
+
+// NewStaticBearer builds a CredentialProvider that authenticates all requests
+// with the given bearer token.
+func NewStaticBearer(token string) *StaticBearer {
+	return &StaticBearer{token: token}
+}
+
+// Credentials returns the bearer token.
+func (b *StaticBearer) Credentials(context.Context, *transport.Request) (string, error) {
+	return bearerScheme + " " + b.token, nil
+}
+
+type tokenFileOptions struct {
+	refreshInterval time.Duration
+	clock           clock.Clock
+}
+
+var defaultTokenFileOptions = tokenFileOptions{
+	refreshInterval: 10 * time.Second,
+	clock:           clock.NewReal(),
+}
+
+// TokenFileOption customizes the behavior of a TokenFile.
+type TokenFileOption func(*tokenFileOptions)
+
+// RefreshInterval specifies how often the token file is checked for changes.
+//
+// Defaults to 10 seconds.
+func RefreshInterval(d time.Duration) TokenFileOption {
+	return func(options *tokenFileOptions) {
+		options.refreshInterval = d
+	}
+}
+
+func withTokenFileClock(c clock.Clock) TokenFileOption {
+	return func(options *tokenFileOptions) {
+		options.clock = c
+	}
+}
+
+// TokenFile is a CredentialProvider that authenticates requests with a bearer
+// token read from a file. The file is re-read when it changes, so the token
+// may be rotated by an external process.
+//
+// Leading and trailing whitespace in the file is ignored.
+type TokenFile struct {
+	path            string
+	refreshInterval time.Duration
+	clock           clock.Clock
+
+	lock      sync.Mutex
+	token     string
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+var _ CredentialProvider = (*TokenFile)(nil)
+
+// NewTokenFile builds a CredentialProvider that authenticates requests with
+// the bearer token in the file at the given path. It fails if the file cannot
+// be read or holds no token.
+func NewTokenFile(path string, opts ...TokenFileOption) (*TokenFile, error) {
+	options := defaultTokenFileOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	f := &TokenFile{
+		path:            path,
+		refreshInterval: options.refreshInterval,
+		clock:           options.clock,
+	}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	f.checkedAt = f.clock.Now()
+	return f, nil
+}
+
+// Credentials returns the current bearer token from the file.
+//
+// If the file changed but can no longer be read, the last token read from it
+// is used.
+func (f *TokenFile) Credentials(context.Context, *transport.Request) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if now := f.clock.Now(); now.Sub(f.checkedAt) >= f.refreshInterval {
+		f.checkedAt = now
+		// TODO: log error
+		_ = f.load()
+	}
+	return bearerScheme + " " + f.token, nil
+}
+
+// load reads the token from the file if it changed since the last time it was
+// read. load must be called with the lock held.
+func (f *TokenFile) load() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	if f.token != "" && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	token := strings.TrimSpace(string(contents))
+	if token == "" {
+		return fmt.Errorf("token file %q is empty", f.path)
+	}
+
+	f.token = token
+	f.modTime = info.ModTime()
+	return nil
+}
+
+// BearerVerifier is a Verifier for bearer tokens.
+type BearerVerifier struct {
+	tokens map[string]string
+}
+
+var _ Verifier = (*BearerVerifier)(nil)
+
+// NewBearerVerifier builds a Verifier that accepts the given bearer tokens,
+// mapped to the principal each of them identifies.
+func NewBearerVerifier(tokens map[string]string) *BearerVerifier {
+	v := &BearerVerifier{tokens: make(map[string]string, len(tokens))}
+	for token, principal := range tokens {
+		v.tokens[token] = principal
+	}
+	return v
+}
+
+// Scheme returns "Bearer".
+func (*BearerVerifier) Scheme() string {
+	return bearerScheme
+}
+
+// Verify returns the principal of the given bearer token.
+func (v *BearerVerifier) Verify(_ context.Context, _ *transport.Request, credentials string) (string, error) {
+	// Compare against every token in constant time so that timing does not
+	// reveal how much of a token was guessed right.
+	var principal string
+	found := false
+	for token, p := range v.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(credentials)) == 1 {
+			principal = p
+			found = true
+		}
+	}
+	if !found {
+		return "", errUnknownBearerToken
+	}
+	return principal, nil
+}
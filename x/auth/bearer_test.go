@@ -0,0 +1,95 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+)
+
+func TestStaticBearer(t *testing.T) {
+	creds, err := NewStaticBearer("s3cr3t").Credentials(context.Background(), &transport.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer s3cr3t", creds)
+}
+
+func TestTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yarpc-auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "token")
+
+	_, err = NewTokenFile(path)
+	assert.Error(t, err, "missing token file must fail")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("  \n"), 0600))
+	_, err = NewTokenFile(path)
+	assert.Error(t, err, "empty token file must fail")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+	clk := clock.NewFake()
+	f, err := NewTokenFile(path, RefreshInterval(time.Minute), withTokenFileClock(clk))
+	require.NoError(t, err)
+
+	credentials := func() string {
+		creds, err := f.Credentials(context.Background(), &transport.Request{})
+		require.NoError(t, err)
+		return creds
+	}
+	assert.Equal(t, "Bearer first", credentials())
+
+	// The token is rotated, but not picked up before the refresh interval.
+	require.NoError(t, ioutil.WriteFile(path, []byte("second\n"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Hour)))
+	assert.Equal(t, "Bearer first", credentials())
+
+	clk.Add(time.Minute)
+	assert.Equal(t, "Bearer second", credentials())
+
+	// A broken rotation keeps the last good token.
+	require.NoError(t, os.Remove(path))
+	clk.Add(time.Minute)
+	assert.Equal(t, "Bearer second", credentials())
+}
+
+func TestBearerVerifier(t *testing.T) {
+	v := NewBearerVerifier(map[string]string{
+		"token-a": "alice",
+		"token-b": "bob",
+	})
+	assert.Equal(t, "Bearer", v.Scheme())
+
+	p, err := v.Verify(context.Background(), &transport.Request{}, "token-b")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", p)
+
+	_, err = v.Verify(context.Background(), &transport.Request{}, "token-c")
+	assert.Equal(t, errUnknownBearerToken, err)
+}
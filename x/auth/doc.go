@@ -0,0 +1,58 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package auth provides EXPERIMENTAL middleware that authenticates requests.
+//
+// Outbound middleware attaches credentials obtained from a CredentialProvider
+// to every request, in an "authorization" header of the form
+// "<scheme> <credentials>".
+//
+// 	signer := auth.NewHMACSigner("my-key", secret)
+// 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+// 		OutboundMiddleware: yarpc.OutboundMiddleware{
+// 			Unary: auth.NewOutboundMiddleware(signer),
+// 		},
+// 		...
+// 	})
+//
+// Inbound middleware verifies these credentials with the Verifier for their
+// scheme, rejecting requests without valid credentials with an
+// Unauthenticated error. The principal established by the Verifier is
+// available to handlers through yarpc.Call.
+//
+// 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+// 		InboundMiddleware: yarpc.InboundMiddleware{
+// 			Unary: auth.NewInboundMiddleware(
+// 				auth.NewHMACVerifier(map[string][]byte{"my-key": secret}),
+// 			),
+// 		},
+// 		...
+// 	})
+//
+// 	func Refund(ctx context.Context, req *RefundRequest) (*RefundResponse, error) {
+// 		principal := yarpc.CallFromContext(ctx).Principal()
+// 		...
+// 	}
+//
+// Bearer tokens may be static (see StaticBearer), or read from a file that is
+// rotated by an external process (see TokenFile). HMAC signatures (see
+// HMACSigner) cover the procedure, body and time of a request, so they cannot
+// be replayed for other requests.
+package auth
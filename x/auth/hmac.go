@@ -0,0 +1,245 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/zap"
+)
+
+const hmacScheme = "HMAC"
+
+var (
+	errMalformedHMAC   = errors.New("malformed HMAC credentials")
+	errUnknownHMACKey  = errors.New("invalid HMAC credentials")
+	errInvalidHMACSig  = errors.New("invalid HMAC signature")
+	errHMACClockSkewed = errors.New("HMAC signature timestamp is outside the allowed clock skew")
+)
+
+type hmacOptions struct {
+	maxClockSkew time.Duration
+	clock        clock.Clock
+	logger       *zap.Logger
+}
+
+var defaultHMACOptions = hmacOptions{
+	maxClockSkew: 5 * time.Minute,
+	clock:        clock.NewReal(),
+	logger:       zap.NewNop(),
+}
+
+// HMACOption customizes the behavior of an HMACSigner or HMACVerifier.
+type HMACOption func(*hmacOptions)
+
+// MaxClockSkew specifies how far the timestamp of a signed request may be
+// from the current time for an HMACVerifier to accept it. This bounds the
+// time during which a captured request may be replayed.
+//
+// Defaults to 5 minutes.
+func MaxClockSkew(d time.Duration) HMACOption {
+	return func(options *hmacOptions) {
+		options.maxClockSkew = d
+	}
+}
+
+// HMACLogger sets the logger to which an HMACVerifier reports requests
+// signed with unknown keys. Callers are only told that their credentials are
+// invalid, so that they cannot probe which key IDs exist.
+func HMACLogger(logger *zap.Logger) HMACOption {
+	return func(options *hmacOptions) {
+		options.logger = logger
+	}
+}
+
+func withHMACClock(c clock.Clock) HMACOption {
+	return func(options *hmacOptions) {
+		options.clock = c
+	}
+}
+
+// HMACSigner is a CredentialProvider that signs requests with a shared
+// secret. The HMAC-SHA256 signature covers the procedure, the body and the
+// time of the request:
+//
+// 	HMAC keyId=<key ID>,ts=<unix seconds>,sig=<base64url signature>
+//
+// The signer reads the whole request body to sign it.
+type HMACSigner struct {
+	keyID  string
+	secret []byte
+	clock  clock.Clock
+}
+
+var _ CredentialProvider = (*HMACSigner)(nil)
+
+// NewHMACSigner builds a CredentialProvider that signs requests with the
+// given secret. The key ID identifies the secret to the verifier, and must
+// not contain commas.
+func NewHMACSigner(keyID string, secret []byte, opts ...HMACOption) *HMACSigner {
+	options := defaultHMACOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &HMACSigner{
+		keyID:  keyID,
+		secret: secret,
+		clock:  options.clock,
+	}
+}
+
+// Credentials signs the request.
+func (s *HMACSigner) Credentials(_ context.Context, req *transport.Request) (string, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	ts := strconv.FormatInt(s.clock.Now().Unix(), 10)
+	sig := base64.RawURLEncoding.EncodeToString(sign(s.secret, req.Procedure, ts, body))
+	return fmt.Sprintf("%s keyId=%s,ts=%s,sig=%s", hmacScheme, s.keyID, ts, sig), nil
+}
+
+// HMACVerifier is a Verifier for requests signed by an HMACSigner.
+type HMACVerifier struct {
+	keys         map[string][]byte
+	maxClockSkew time.Duration
+	clock        clock.Clock
+	logger       *zap.Logger
+}
+
+var _ Verifier = (*HMACVerifier)(nil)
+
+// NewHMACVerifier builds a Verifier that accepts requests signed with any of
+// the given secrets, indexed by key ID. The key ID of a valid signature is
+// the principal of the request.
+func NewHMACVerifier(keys map[string][]byte, opts ...HMACOption) *HMACVerifier {
+	options := defaultHMACOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	v := &HMACVerifier{
+		keys:         make(map[string][]byte, len(keys)),
+		maxClockSkew: options.maxClockSkew,
+		clock:        options.clock,
+		logger:       options.logger,
+	}
+	for keyID, secret := range keys {
+		v.keys[keyID] = secret
+	}
+	return v
+}
+
+// Scheme returns "HMAC".
+func (*HMACVerifier) Scheme() string {
+	return hmacScheme
+}
+
+// Verify checks the signature of the request and returns its key ID.
+func (v *HMACVerifier) Verify(_ context.Context, req *transport.Request, credentials string) (string, error) {
+	var keyID, ts, sig string
+	for _, field := range strings.Split(credentials, ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return "", errMalformedHMAC
+		}
+		switch kv[0] {
+		case "keyId":
+			keyID = kv[1]
+		case "ts":
+			ts = kv[1]
+		case "sig":
+			sig = kv[1]
+		}
+	}
+	if keyID == "" || ts == "" || sig == "" {
+		return "", errMalformedHMAC
+	}
+
+	secret, ok := v.keys[keyID]
+	if !ok {
+		v.logger.Info("Request signed with unknown HMAC key.",
+			zap.String("keyID", keyID),
+			zap.String("caller", req.Caller),
+			zap.String("procedure", req.Procedure))
+		return "", errUnknownHMACKey
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", errMalformedHMAC
+	}
+	skew := v.clock.Now().Sub(time.Unix(unix, 0))
+	if skew > v.maxClockSkew || skew < -v.maxClockSkew {
+		return "", errHMACClockSkewed
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", errMalformedHMAC
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal(got, sign(secret, req.Procedure, ts, body)) {
+		return "", errInvalidHMACSig
+	}
+	return keyID, nil
+}
+
+func sign(secret []byte, procedure, ts string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(procedure))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'\n'})
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// readBody reads the whole body of the request, replacing it with an
+// equivalent reader.
+func readBody(req *transport.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = bytes.NewReader(body)
+	return body, nil
+}
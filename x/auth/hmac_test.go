@@ -0,0 +1,179 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	zapobserver "go.uber.org/zap/zaptest/observer"
+)
+
+func TestHMAC(t *testing.T) {
+	clk := clock.NewFake()
+	secret := []byte("shared secret")
+	signer := NewHMACSigner("billing", secret, withHMACClock(clk))
+	verifier := NewHMACVerifier(map[string][]byte{"billing": secret},
+		MaxClockSkew(time.Minute), withHMACClock(clk))
+	assert.Equal(t, "HMAC", verifier.Scheme())
+
+	newRequest := func(procedure, body string) *transport.Request {
+		return &transport.Request{
+			Procedure: procedure,
+			Body:      bytes.NewReader([]byte(body)),
+		}
+	}
+
+	// sign returns the credentials of a request, without their scheme.
+	sign := func(req *transport.Request) string {
+		creds, err := signer.Credentials(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(creds, "HMAC "), "unexpected credentials %q", creds)
+
+		// The body must still be readable.
+		if req.Body != nil {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			req.Body = bytes.NewReader(body)
+		}
+
+		return strings.TrimPrefix(creds, "HMAC ")
+	}
+
+	tests := []struct {
+		desc     string
+		signed   *transport.Request
+		verified *transport.Request
+		advance  time.Duration
+		tamper   func(string) string
+		wantErr  error
+	}{
+		{
+			desc:     "valid signature",
+			signed:   newRequest("Billing::refund", "{}"),
+			verified: newRequest("Billing::refund", "{}"),
+		},
+		{
+			desc:     "valid signature without body",
+			signed:   &transport.Request{Procedure: "Billing::refund"},
+			verified: &transport.Request{Procedure: "Billing::refund"},
+		},
+		{
+			desc:     "within clock skew",
+			signed:   newRequest("Billing::refund", "{}"),
+			verified: newRequest("Billing::refund", "{}"),
+			advance:  time.Minute,
+		},
+		{
+			desc:     "outside clock skew",
+			signed:   newRequest("Billing::refund", "{}"),
+			verified: newRequest("Billing::refund", "{}"),
+			advance:  time.Minute + time.Second,
+			wantErr:  errHMACClockSkewed,
+		},
+		{
+			desc:     "different procedure",
+			signed:   newRequest("Billing::refund", "{}"),
+			verified: newRequest("Billing::charge", "{}"),
+			wantErr:  errInvalidHMACSig,
+		},
+		{
+			desc:     "different body",
+			signed:   newRequest("Billing::refund", `{"amount":1}`),
+			verified: newRequest("Billing::refund", `{"amount":1000}`),
+			wantErr:  errInvalidHMACSig,
+		},
+		{
+			desc:     "unknown key",
+			signed:   newRequest("Billing::refund", "{}"),
+			verified: newRequest("Billing::refund", "{}"),
+			tamper:   func(s string) string { return strings.Replace(s, "keyId=billing", "keyId=other", 1) },
+			wantErr:  errUnknownHMACKey,
+		},
+		{
+			desc:     "missing signature",
+			signed:   newRequest("Billing::refund", "{}"),
+			verified: newRequest("Billing::refund", "{}"),
+			tamper:   func(s string) string { return s[:strings.Index(s, ",sig=")] },
+			wantErr:  errMalformedHMAC,
+		},
+		{
+			desc:     "garbage",
+			signed:   newRequest("Billing::refund", "{}"),
+			verified: newRequest("Billing::refund", "{}"),
+			tamper:   func(string) string { return "garbage" },
+			wantErr:  errMalformedHMAC,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			creds := sign(tt.signed)
+			if tt.tamper != nil {
+				creds = tt.tamper(creds)
+			}
+			clk.Add(tt.advance)
+
+			p, err := verifier.Verify(context.Background(), tt.verified, creds)
+			switch {
+			case tt.wantErr != nil:
+				assert.Equal(t, tt.wantErr, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, "billing", p)
+			}
+		})
+	}
+}
+
+func TestHMACUnknownKeyIsLogged(t *testing.T) {
+	core, logs := zapobserver.New(zapcore.DebugLevel)
+	signer := NewHMACSigner("other", []byte("other secret"))
+	verifier := NewHMACVerifier(map[string][]byte{"billing": []byte("shared secret")},
+		HMACLogger(zap.New(core)))
+
+	req := &transport.Request{Caller: "frontend", Procedure: "Billing::refund"}
+	creds, err := signer.Credentials(context.Background(), req)
+	require.NoError(t, err)
+
+	_, err = verifier.Verify(context.Background(), req, strings.TrimPrefix(creds, "HMAC "))
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "other", "key ID must not be reported to the caller")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Request signed with unknown HMAC key.", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{
+		"keyID":     "other",
+		"caller":    "frontend",
+		"procedure": "Billing::refund",
+	}, entries[0].ContextMap())
+}
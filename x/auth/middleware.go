@@ -0,0 +1,164 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/principal"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// OutboundMiddleware attaches credentials to outbound requests.
+type OutboundMiddleware struct {
+	provider CredentialProvider
+}
+
+var (
+	_ middleware.UnaryOutbound  = (*OutboundMiddleware)(nil)
+	_ middleware.OnewayOutbound = (*OutboundMiddleware)(nil)
+)
+
+// NewOutboundMiddleware builds an outbound middleware that attaches the
+// credentials of the given provider to every request.
+func NewOutboundMiddleware(provider CredentialProvider) *OutboundMiddleware {
+	return &OutboundMiddleware{provider: provider}
+}
+
+// Call attaches credentials to a unary request.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	req, err := m.withCredentials(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return out.Call(ctx, req)
+}
+
+// CallOneway attaches credentials to a oneway request.
+func (m *OutboundMiddleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	req, err := m.withCredentials(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return out.CallOneway(ctx, req)
+}
+
+// withCredentials returns a copy of the request that carries credentials.
+func (m *OutboundMiddleware) withCredentials(ctx context.Context, req *transport.Request) (*transport.Request, error) {
+	r := *req
+	credentials, err := m.provider.Credentials(ctx, &r)
+	if err != nil {
+		return nil, yarpcerrors.UnauthenticatedErrorf("failed to obtain credentials: %v", err)
+	}
+
+	headers := transport.NewHeadersWithCapacity(req.Headers.Len() + 1)
+	for k, v := range req.Headers.Items() {
+		headers = headers.With(k, v)
+	}
+	r.Headers = headers.With(authorizationHeader, credentials)
+	return &r, nil
+}
+
+// InboundMiddleware rejects inbound requests without valid credentials.
+type InboundMiddleware struct {
+	verifiers map[string]Verifier
+}
+
+var (
+	_ middleware.UnaryInbound  = (*InboundMiddleware)(nil)
+	_ middleware.OnewayInbound = (*InboundMiddleware)(nil)
+)
+
+// NewInboundMiddleware builds an inbound middleware that verifies the
+// credentials of every request with the verifier for their scheme.
+//
+// Requests without credentials, or with credentials that no verifier accepts,
+// fail with an Unauthenticated error. Handlers of other requests may obtain
+// the verified principal with yarpc.CallFromContext(ctx).Principal().
+func NewInboundMiddleware(verifiers ...Verifier) *InboundMiddleware {
+	m := &InboundMiddleware{verifiers: make(map[string]Verifier, len(verifiers))}
+	for _, v := range verifiers {
+		m.verifiers[strings.ToLower(v.Scheme())] = v
+	}
+	return m
+}
+
+// Handle authenticates a unary request.
+func (m *InboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	ctx, req, err := m.authenticate(ctx, req)
+	if err != nil {
+		return err
+	}
+	return h.Handle(ctx, req, resw)
+}
+
+// HandleOneway authenticates a oneway request.
+func (m *InboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	ctx, req, err := m.authenticate(ctx, req)
+	if err != nil {
+		return err
+	}
+	return h.HandleOneway(ctx, req)
+}
+
+// authenticate verifies the credentials of the request. It returns a context
+// carrying the principal of the request, and a copy of the request without
+// its credentials.
+func (m *InboundMiddleware) authenticate(ctx context.Context, req *transport.Request) (context.Context, *transport.Request, error) {
+	authorization, ok := req.Headers.Get(authorizationHeader)
+	if !ok || authorization == "" {
+		return nil, nil, yarpcerrors.UnauthenticatedErrorf("missing credentials")
+	}
+
+	parts := strings.SplitN(authorization, " ", 2)
+	if len(parts) != 2 {
+		return nil, nil, yarpcerrors.UnauthenticatedErrorf("malformed credentials")
+	}
+	scheme, credentials := parts[0], parts[1]
+
+	verifier, ok := m.verifiers[strings.ToLower(scheme)]
+	if !ok {
+		return nil, nil, yarpcerrors.UnauthenticatedErrorf("unsupported authorization scheme %q", scheme)
+	}
+
+	r := *req
+	p, err := verifier.Verify(ctx, &r, credentials)
+	if err != nil {
+		if yarpcerrors.IsUnauthenticated(err) {
+			return nil, nil, err
+		}
+		return nil, nil, yarpcerrors.UnauthenticatedErrorf("invalid credentials: %v", err)
+	}
+
+	// Keep credentials away from handlers.
+	headers := transport.NewHeadersWithCapacity(req.Headers.Len())
+	for k, v := range req.Headers.Items() {
+		if k != authorizationHeader {
+			headers = headers.With(k, v)
+		}
+	}
+	r.Headers = headers
+
+	return principal.NewContext(ctx, p), &r, nil
+}
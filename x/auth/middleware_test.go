@@ -0,0 +1,209 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/principal"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type providerFunc func(context.Context, *transport.Request) (string, error)
+
+func (f providerFunc) Credentials(ctx context.Context, req *transport.Request) (string, error) {
+	return f(ctx, req)
+}
+
+func TestMiddlewareRoundTrip(t *testing.T) {
+	secret := []byte("shared secret")
+	tests := []struct {
+		desc          string
+		provider      CredentialProvider
+		wantPrincipal string
+	}{
+		{
+			desc:          "bearer",
+			provider:      NewStaticBearer("token-a"),
+			wantPrincipal: "alice",
+		},
+		{
+			desc:          "hmac",
+			provider:      NewHMACSigner("billing-admin", secret),
+			wantPrincipal: "billing-admin",
+		},
+	}
+
+	in := NewInboundMiddleware(
+		NewBearerVerifier(map[string]string{"token-a": "alice"}),
+		NewHMACVerifier(map[string][]byte{"billing-admin": secret}),
+	)
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			out := NewOutboundMiddleware(tt.provider)
+			req := &transport.Request{
+				Caller:    "caller",
+				Service:   "billing",
+				Procedure: "Billing::refund",
+				Headers:   transport.NewHeaders().With("foo", "bar"),
+				Body:      bytes.NewReader([]byte("{}")),
+			}
+
+			// Send the request through the outbound middleware, then the
+			// inbound middleware.
+			outbound := transporttest.NewMockUnaryOutbound(mockCtrl)
+			handler := transporttest.NewMockUnaryHandler(mockCtrl)
+			var handleErr error
+			outbound.EXPECT().Call(gomock.Any(), gomock.Any()).Do(
+				func(ctx context.Context, sent *transport.Request) {
+					_, ok := sent.Headers.Get("authorization")
+					assert.True(t, ok, "credentials must be sent")
+					handleErr = in.Handle(ctx, sent, &transporttest.FakeResponseWriter{}, handler)
+				}).Return(nil, nil)
+			handler.EXPECT().Handle(gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func(ctx context.Context, got *transport.Request, _ transport.ResponseWriter) {
+					assert.Equal(t, tt.wantPrincipal, principal.FromContext(ctx))
+
+					_, ok := got.Headers.Get("authorization")
+					assert.False(t, ok, "credentials must not reach the handler")
+					assert.Equal(t, map[string]string{"foo": "bar"}, got.Headers.Items())
+
+					body, err := ioutil.ReadAll(got.Body)
+					require.NoError(t, err)
+					assert.Equal(t, "{}", string(body))
+				}).Return(nil)
+
+			_, err := out.Call(context.Background(), req, outbound)
+			assert.NoError(t, err)
+			assert.NoError(t, handleErr)
+			assert.Equal(t, map[string]string{"foo": "bar"}, req.Headers.Items(),
+				"headers of the original request must not change")
+		})
+	}
+}
+
+func TestOutboundMiddlewareOneway(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	outbound := transporttest.NewMockOnewayOutbound(mockCtrl)
+	outbound.EXPECT().CallOneway(gomock.Any(), gomock.Any()).Do(
+		func(_ context.Context, req *transport.Request) {
+			creds, _ := req.Headers.Get("authorization")
+			assert.Equal(t, "Bearer token", creds)
+		}).Return(nil, nil)
+
+	_, err := NewOutboundMiddleware(NewStaticBearer("token")).
+		CallOneway(context.Background(), &transport.Request{}, outbound)
+	assert.NoError(t, err)
+}
+
+func TestOutboundMiddlewareProviderError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	m := NewOutboundMiddleware(providerFunc(func(context.Context, *transport.Request) (string, error) {
+		return "", errors.New("great sadness")
+	}))
+
+	_, err := m.Call(context.Background(), &transport.Request{}, transporttest.NewMockUnaryOutbound(mockCtrl))
+	assert.True(t, yarpcerrors.IsUnauthenticated(err), "unexpected error %v", err)
+
+	_, err = m.CallOneway(context.Background(), &transport.Request{}, transporttest.NewMockOnewayOutbound(mockCtrl))
+	assert.True(t, yarpcerrors.IsUnauthenticated(err), "unexpected error %v", err)
+}
+
+func TestInboundMiddlewareRejects(t *testing.T) {
+	tests := []struct {
+		desc          string
+		authorization string
+		wantMessage   string
+	}{
+		{
+			desc:        "no credentials",
+			wantMessage: "missing credentials",
+		},
+		{
+			desc:          "no scheme",
+			authorization: "token-a",
+			wantMessage:   "malformed credentials",
+		},
+		{
+			desc:          "unknown scheme",
+			authorization: "Basic dXNlcjpwYXNz",
+			wantMessage:   `unsupported authorization scheme "Basic"`,
+		},
+		{
+			desc:          "invalid credentials",
+			authorization: "Bearer token-b",
+			wantMessage:   "invalid credentials: unknown bearer token",
+		},
+	}
+
+	m := NewInboundMiddleware(NewBearerVerifier(map[string]string{"token-a": "alice"}))
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			headers := transport.NewHeaders()
+			if tt.authorization != "" {
+				headers = headers.With("authorization", tt.authorization)
+			}
+			req := &transport.Request{Headers: headers}
+
+			err := m.Handle(context.Background(), req, &transporttest.FakeResponseWriter{},
+				transporttest.NewMockUnaryHandler(mockCtrl))
+			assert.Equal(t, yarpcerrors.UnauthenticatedErrorf("%s", tt.wantMessage), err)
+
+			err = m.HandleOneway(context.Background(), req, transporttest.NewMockOnewayHandler(mockCtrl))
+			assert.Equal(t, yarpcerrors.UnauthenticatedErrorf("%s", tt.wantMessage), err)
+		})
+	}
+}
+
+func TestInboundMiddlewareOneway(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	handler := transporttest.NewMockOnewayHandler(mockCtrl)
+	handler.EXPECT().HandleOneway(gomock.Any(), gomock.Any()).Do(
+		func(ctx context.Context, _ *transport.Request) {
+			assert.Equal(t, "alice", principal.FromContext(ctx))
+		}).Return(nil)
+
+	m := NewInboundMiddleware(NewBearerVerifier(map[string]string{"token-a": "alice"}))
+	req := &transport.Request{Headers: transport.NewHeaders().With("Authorization", "bearer token-a")}
+	assert.NoError(t, m.HandleOneway(context.Background(), req, handler))
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package authz provides EXPERIMENTAL middleware that authorizes inbound
+// requests with a declarative policy.
+//
+// Policies are written in YAML as a list of rules that allow or deny the
+// requests matching their callers, principals, services, procedures and
+// encodings (see ParsePolicy). A request denied by any rule is denied, even
+// if other rules allow it.
+//
+// 	policy, err := authz.ReadPolicyFile("/etc/myservice/authz.yaml")
+// 	if err != nil {
+// 		log.Fatal(err)
+// 	}
+// 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+// 		InboundMiddleware: yarpc.InboundMiddleware{
+// 			Unary: authz.NewInboundMiddleware(policy, authz.WithTally(scope)),
+// 		},
+// 		...
+// 	})
+//
+// Denied requests fail with a PermissionDenied error. To audit a policy
+// before enforcing it, run it in dry run mode, which logs the requests it
+// would deny and lets them through.
+package authz
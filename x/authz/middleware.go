@@ -0,0 +1,153 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package authz
+
+import (
+	"context"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/principal"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+// MiddlewareOption customizes the behavior of an authorization middleware.
+type MiddlewareOption interface {
+	apply(*middlewareOptions)
+}
+
+type authzOptionFunc func(*middlewareOptions)
+
+func (f authzOptionFunc) apply(opts *middlewareOptions) { f(opts) }
+
+// middlewareOptions enumerates the options for authorization middleware.
+type middlewareOptions struct {
+	scope  tally.Scope
+	logger *zap.Logger
+	dryRun bool
+}
+
+var defaultMiddlewareOptions = middlewareOptions{
+	scope:  tally.NoopScope,
+	logger: zap.NewNop(),
+}
+
+// WithTally sets a Tally scope that will be used to record, for every rule of
+// the policy, the number of requests it allowed or denied.
+func WithTally(scope tally.Scope) MiddlewareOption {
+	return authzOptionFunc(func(opts *middlewareOptions) {
+		opts.scope = scope
+	})
+}
+
+// WithLogger sets the logger to which requests that would be denied in dry
+// run mode are reported.
+func WithLogger(logger *zap.Logger) MiddlewareOption {
+	return authzOptionFunc(func(opts *middlewareOptions) {
+		opts.logger = logger
+	})
+}
+
+// DryRun makes the middleware log the requests that the policy denies
+// instead of rejecting them, regardless of the dryRun setting of the policy.
+func DryRun() MiddlewareOption {
+	return authzOptionFunc(func(opts *middlewareOptions) {
+		opts.dryRun = true
+	})
+}
+
+// InboundMiddleware rejects inbound requests that its policy denies.
+type InboundMiddleware struct {
+	policy   *Policy
+	dryRun   bool
+	logger   *zap.Logger
+	observer *observer
+}
+
+var (
+	_ middleware.UnaryInbound  = (*InboundMiddleware)(nil)
+	_ middleware.OnewayInbound = (*InboundMiddleware)(nil)
+)
+
+// NewInboundMiddleware builds an inbound middleware that authorizes every
+// request with the given policy.
+//
+// Requests that the policy denies fail with a PermissionDenied error, unless
+// the policy or middleware is in dry run mode. Policies that match on
+// principals should be placed after middleware that authenticates requests,
+// like that of the x/auth package.
+func NewInboundMiddleware(policy *Policy, opts ...MiddlewareOption) *InboundMiddleware {
+	options := defaultMiddlewareOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	return &InboundMiddleware{
+		policy:   policy,
+		dryRun:   options.dryRun || policy.dryRun,
+		logger:   options.logger,
+		observer: newObserver(options.scope, policy),
+	}
+}
+
+// Handle authorizes a unary request.
+func (m *InboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	if err := m.authorize(ctx, req); err != nil {
+		return err
+	}
+	return h.Handle(ctx, req, resw)
+}
+
+// HandleOneway authorizes a oneway request.
+func (m *InboundMiddleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	if err := m.authorize(ctx, req); err != nil {
+		return err
+	}
+	return h.HandleOneway(ctx, req)
+}
+
+// authorize returns a PermissionDenied error if the policy denies the
+// request, unless running in dry run mode.
+func (m *InboundMiddleware) authorize(ctx context.Context, req *transport.Request) error {
+	p := principal.FromContext(ctx)
+	d := m.policy.decide(req, p)
+	m.observer.decided(d)
+	if d.allowed {
+		return nil
+	}
+
+	if m.dryRun {
+		m.logger.Warn("Authorization policy would deny request.",
+			zap.String("rule", d.rule),
+			zap.String("caller", req.Caller),
+			zap.String("principal", p),
+			zap.String("service", req.Service),
+			zap.String("procedure", req.Procedure),
+			zap.String("encoding", string(req.Encoding)),
+		)
+		return nil
+	}
+
+	return yarpcerrors.PermissionDeniedErrorf(
+		"caller %q is not allowed to call %q of service %q", req.Caller, req.Procedure, req.Service)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/principal"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	zapobserver "go.uber.org/zap/zaptest/observer"
+)
+
+var (
+	refundReq = &transport.Request{
+		Caller:    "billing-admin",
+		Service:   "billing",
+		Procedure: "Billing::refund",
+		Encoding:  "thrift",
+	}
+	deniedReq = &transport.Request{
+		Caller:    "frontend",
+		Service:   "billing",
+		Procedure: "Billing::refund",
+		Encoding:  "thrift",
+	}
+)
+
+func TestInboundMiddleware(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	scope := tally.NewTestScope("", nil)
+	m := NewInboundMiddleware(policy, WithTally(scope))
+
+	ctx := context.Background()
+	resw := &transporttest.FakeResponseWriter{}
+
+	unary := transporttest.NewMockUnaryHandler(mockCtrl)
+	unary.EXPECT().Handle(ctx, refundReq, resw).Return(nil)
+	require.NoError(t, m.Handle(ctx, refundReq, resw, unary))
+
+	oneway := transporttest.NewMockOnewayHandler(mockCtrl)
+	oneway.EXPECT().HandleOneway(ctx, refundReq).Return(nil)
+	require.NoError(t, m.HandleOneway(ctx, refundReq, oneway))
+
+	err = m.Handle(ctx, deniedReq, resw, unary)
+	require.Error(t, err)
+	assert.True(t, yarpcerrors.IsPermissionDenied(err), "expected PermissionDenied error, got %v", err)
+	assert.Contains(t, err.Error(), `caller "frontend" is not allowed to call "Billing::refund" of service "billing"`)
+
+	err = m.HandleOneway(principal.NewContext(ctx, "intern-bob"), refundReq, oneway)
+	assert.True(t, yarpcerrors.IsPermissionDenied(err), "expected PermissionDenied error, got %v", err)
+
+	counters := scope.Snapshot().Counters()
+	wantCounters := map[string]int64{
+		"authz_decisions+decision=allowed,rule=refunds-by-admins": 2,
+		"authz_decisions+decision=denied,rule=default":            1,
+		"authz_decisions+decision=denied,rule=no-interns":         1,
+		"authz_decisions+decision=denied,rule=no-json":            0,
+	}
+	for name, want := range wantCounters {
+		require.Contains(t, counters, name)
+		assert.Equal(t, want, counters[name].Value(), "counter %s", name)
+	}
+}
+
+func TestInboundMiddlewareDryRun(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	dryRunPolicy, err := ParsePolicy([]byte("dryRun: true\n" + testPolicy))
+	require.NoError(t, err)
+
+	tests := []struct {
+		msg    string
+		policy *Policy
+		opts   []MiddlewareOption
+	}{
+		{msg: "option", policy: policy, opts: []MiddlewareOption{DryRun()}},
+		{msg: "policy", policy: dryRunPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			core, logs := zapobserver.New(zapcore.DebugLevel)
+			m := NewInboundMiddleware(tt.policy, append(tt.opts, WithLogger(zap.New(core)))...)
+
+			ctx := context.Background()
+			resw := &transporttest.FakeResponseWriter{}
+			handler := transporttest.NewMockUnaryHandler(mockCtrl)
+			handler.EXPECT().Handle(ctx, deniedReq, resw).Return(nil)
+			require.NoError(t, m.Handle(ctx, deniedReq, resw, handler))
+
+			entries := logs.AllUntimed()
+			require.Len(t, entries, 1)
+			assert.Equal(t, "Authorization policy would deny request.", entries[0].Message)
+			assert.Equal(t, map[string]interface{}{
+				"rule":      "default",
+				"caller":    "frontend",
+				"principal": "",
+				"service":   "billing",
+				"procedure": "Billing::refund",
+				"encoding":  "thrift",
+			}, entries[0].ContextMap())
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package authz
+
+import "github.com/uber-go/tally"
+
+var (
+	_decisionsName = "authz_decisions"
+	_ruleTag       = "rule"
+	_decisionTag   = "decision"
+	_allowedTag    = "allowed"
+	_deniedTag     = "denied"
+)
+
+type ruleCounters struct {
+	allowed tally.Counter
+	denied  tally.Counter
+}
+
+// observer counts the decisions made by every rule of a policy.
+type observer struct {
+	rules map[string]ruleCounters
+}
+
+func newObserver(scope tally.Scope, policy *Policy) *observer {
+	o := &observer{rules: make(map[string]ruleCounters, len(policy.rules)+1)}
+	o.rules[defaultRule] = newRuleCounters(scope, defaultRule)
+	for _, r := range policy.rules {
+		o.rules[r.Name] = newRuleCounters(scope, r.Name)
+	}
+	return o
+}
+
+func newRuleCounters(scope tally.Scope, rule string) ruleCounters {
+	scope = scope.Tagged(map[string]string{_ruleTag: rule})
+	return ruleCounters{
+		allowed: scope.Tagged(map[string]string{_decisionTag: _allowedTag}).Counter(_decisionsName),
+		denied:  scope.Tagged(map[string]string{_decisionTag: _deniedTag}).Counter(_decisionsName),
+	}
+}
+
+func (o *observer) decided(d decision) {
+	c := o.rules[d.rule]
+	if d.allowed {
+		c.allowed.Inc(1)
+	} else {
+		c.denied.Inc(1)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package authz
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/glob"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultRule is the name under which decisions made without a matching rule
+// are reported.
+const defaultRule = "default"
+
+type effect string
+
+const (
+	allow effect = "allow"
+	deny  effect = "deny"
+)
+
+// Policy decides which callers may call which procedures.
+//
+// A policy is a list of rules, each of which allows or denies the requests it
+// matches. A request denied by any rule is denied, even if other rules allow
+// it. Requests that no rule matches get the default effect of the policy.
+type Policy struct {
+	rules         []rule
+	defaultEffect effect
+	dryRun        bool
+}
+
+// rule allows or denies the requests whose attributes each match one of the
+// corresponding patterns. Rules without patterns for an attribute match all
+// values of that attribute.
+type rule struct {
+	Name       string   `yaml:"name"`
+	Effect     effect   `yaml:"effect"`
+	Callers    []string `yaml:"callers"`
+	Principals []string `yaml:"principals"`
+	Services   []string `yaml:"services"`
+	Procedures []string `yaml:"procedures"`
+	Encodings  []string `yaml:"encodings"`
+}
+
+type policyConfig struct {
+	Default effect `yaml:"default"`
+	DryRun  bool   `yaml:"dryRun"`
+	Rules   []rule `yaml:"rules"`
+}
+
+// ParsePolicy parses a policy from YAML.
+//
+// 	default: deny
+// 	rules:
+// 	  - name: refunds-by-admins
+// 	    effect: allow
+// 	    principals: [billing-admin]
+// 	    procedures: ["Billing::refund"]
+// 	  - name: reads
+// 	    effect: allow
+// 	    services: [billing]
+// 	    procedures: ["Billing::get*"]
+//
+// The default effect, applied to requests that no rule matches, may be
+// "allow" or "deny", and defaults to "deny".
+//
+// Rules must have a unique name, which may not be "default", and an effect.
+// They may restrict the callers, principals (see yarpc.Call.Principal),
+// services, procedures and encodings of the requests they match. Patterns
+// may use '*' to match any sequence of characters.
+//
+// Callers name themselves in the request, so matching callers is not
+// authentication: any client may claim to be billing-admin. Rules that grant
+// access should match principals, which are only set by an authenticating
+// middleware such as x/auth's, running before this one.
+//
+// Unknown keys are rejected, so that a misspelled restriction does not
+// silently widen a rule.
+//
+// Setting dryRun to true makes the policy log requests it would deny instead
+// of rejecting them, which helps to audit a new policy.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var cfg policyConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse authorization policy: %v", err)
+	}
+
+	p := &Policy{
+		rules:         cfg.Rules,
+		defaultEffect: cfg.Default,
+		dryRun:        cfg.DryRun,
+	}
+	switch p.defaultEffect {
+	case "":
+		p.defaultEffect = deny
+	case allow, deny:
+	default:
+		return nil, fmt.Errorf(`invalid default effect %q: must be "allow" or "deny"`, cfg.Default)
+	}
+
+	names := make(map[string]struct{}, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+		if r.Name == defaultRule {
+			return nil, fmt.Errorf("rule %d may not be named %q", i, defaultRule)
+		}
+		if _, ok := names[r.Name]; ok {
+			return nil, fmt.Errorf("rule %q is defined multiple times", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if r.Effect != allow && r.Effect != deny {
+			return nil, fmt.Errorf(`rule %q has invalid effect %q: must be "allow" or "deny"`, r.Name, r.Effect)
+		}
+	}
+
+	return p, nil
+}
+
+// ReadPolicyFile parses a policy from the YAML file at the given path.
+//
+// See ParsePolicy for the format of the file.
+func ReadPolicyFile(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(data)
+}
+
+// decision is the outcome of evaluating a policy for a request.
+type decision struct {
+	allowed bool
+
+	// Name of the rule that decided the outcome, or defaultRule.
+	rule string
+}
+
+// decide evaluates the policy for a request made by the given principal.
+func (p *Policy) decide(req *transport.Request, principal string) decision {
+	var allowedBy string
+	for _, r := range p.rules {
+		if !r.matches(req, principal) {
+			continue
+		}
+		if r.Effect == deny {
+			return decision{allowed: false, rule: r.Name}
+		}
+		if allowedBy == "" {
+			allowedBy = r.Name
+		}
+	}
+
+	if allowedBy != "" {
+		return decision{allowed: true, rule: allowedBy}
+	}
+	return decision{allowed: p.defaultEffect == allow, rule: defaultRule}
+}
+
+func (r *rule) matches(req *transport.Request, principal string) bool {
+	return matchAny(r.Callers, req.Caller) &&
+		matchAny(r.Principals, principal) &&
+		matchAny(r.Services, req.Service) &&
+		matchAny(r.Procedures, req.Procedure) &&
+		matchAny(r.Encodings, string(req.Encoding))
+}
+
+// matchAny reports whether s matches any of the patterns. Every string
+// matches an empty list of patterns.
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if glob.Match(pattern, s) {
+			return true
+		}
+	}
+	return false
+}
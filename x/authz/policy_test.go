@@ -0,0 +1,198 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package authz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+)
+
+func TestParsePolicyErrors(t *testing.T) {
+	tests := []struct {
+		msg     string
+		give    string
+		wantErr string
+	}{
+		{
+			msg:     "invalid yaml",
+			give:    "rules: {",
+			wantErr: "failed to parse authorization policy",
+		},
+		{
+			msg:     "invalid default",
+			give:    "default: maybe",
+			wantErr: `invalid default effect "maybe"`,
+		},
+		{
+			msg:     "unnamed rule",
+			give:    "rules: [{effect: allow}]",
+			wantErr: "rule 0 has no name",
+		},
+		{
+			msg:     "rule named default",
+			give:    "rules: [{name: default, effect: allow}]",
+			wantErr: `rule 0 may not be named "default"`,
+		},
+		{
+			msg:     "duplicate rule",
+			give:    "rules: [{name: a, effect: allow}, {name: a, effect: deny}]",
+			wantErr: `rule "a" is defined multiple times`,
+		},
+		{
+			msg:     "missing effect",
+			give:    "rules: [{name: a}]",
+			wantErr: `rule "a" has invalid effect ""`,
+		},
+		{
+			msg:     "invalid effect",
+			give:    "rules: [{name: a, effect: permit}]",
+			wantErr: `rule "a" has invalid effect "permit"`,
+		},
+		{
+			msg:     "unknown rule key",
+			give:    "rules: [{name: a, effect: allow, principal: [admin]}]",
+			wantErr: "field principal not found",
+		},
+		{
+			msg:     "unknown top-level key",
+			give:    "dryrun: true",
+			wantErr: "field dryrun not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			_, err := ParsePolicy([]byte(tt.give))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+const testPolicy = `
+rules:
+  - name: refunds-by-admins
+    effect: allow
+    callers: [billing-admin]
+    services: [billing]
+    procedures: ["Billing::refund"]
+  - name: reads
+    effect: allow
+    services: [billing]
+    procedures: ["Billing::get*"]
+  - name: no-json
+    effect: deny
+    encodings: [json]
+  - name: no-interns
+    effect: deny
+    principals: ["intern-*"]
+`
+
+func TestDecide(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	tests := []struct {
+		msg       string
+		req       transport.Request
+		principal string
+		want      decision
+	}{
+		{
+			msg: "allowed refund",
+			req: transport.Request{
+				Caller:    "billing-admin",
+				Service:   "billing",
+				Procedure: "Billing::refund",
+				Encoding:  "thrift",
+			},
+			want: decision{allowed: true, rule: "refunds-by-admins"},
+		},
+		{
+			msg: "refund by other caller",
+			req: transport.Request{
+				Caller:    "frontend",
+				Service:   "billing",
+				Procedure: "Billing::refund",
+				Encoding:  "thrift",
+			},
+			want: decision{allowed: false, rule: defaultRule},
+		},
+		{
+			msg: "read",
+			req: transport.Request{
+				Caller:    "frontend",
+				Service:   "billing",
+				Procedure: "Billing::getInvoice",
+				Encoding:  "thrift",
+			},
+			want: decision{allowed: true, rule: "reads"},
+		},
+		{
+			msg: "deny overrides allow",
+			req: transport.Request{
+				Caller:    "frontend",
+				Service:   "billing",
+				Procedure: "Billing::getInvoice",
+				Encoding:  "json",
+			},
+			want: decision{allowed: false, rule: "no-json"},
+		},
+		{
+			msg: "denied principal",
+			req: transport.Request{
+				Caller:    "billing-admin",
+				Service:   "billing",
+				Procedure: "Billing::refund",
+				Encoding:  "thrift",
+			},
+			principal: "intern-bob",
+			want:      decision{allowed: false, rule: "no-interns"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.decide(&tt.req, tt.principal))
+		})
+	}
+}
+
+func TestDecideDefaultAllow(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+default: allow
+rules:
+  - name: no-deletes
+    effect: deny
+    procedures: ["*::delete*"]
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		decision{allowed: true, rule: defaultRule},
+		policy.decide(&transport.Request{Procedure: "Users::get"}, ""))
+	assert.Equal(t,
+		decision{allowed: false, rule: "no-deletes"},
+		policy.decide(&transport.Request{Procedure: "Users::deleteAll"}, ""))
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/multierr"
+	iconfig "go.uber.org/yarpc/internal/config"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// MiddlewareConfig is a definition of how to create a caching middleware.
+type MiddlewareConfig struct {
+	// MaxBytes bounds the size of the cache. Defaults to 32 MiB.
+	MaxBytes int `config:"maxBytes"`
+
+	// Procedures lists the procedures whose responses are cached.
+	Procedures []ProcedureConfig `config:"procedures"`
+}
+
+// ProcedureConfig defines how the responses of a procedure are cached.
+type ProcedureConfig struct {
+	// Service is the name of the service of the procedure. If empty, the
+	// procedure is cached for all services.
+	Service string `config:"service"`
+
+	// Procedure is the name of the procedure.
+	Procedure string `config:"procedure"`
+
+	// TTL is how long responses are cached.
+	TTL time.Duration `config:"ttl"`
+
+	// Headers lists the request headers which are part of the cache key.
+	Headers []string `config:"headers"`
+
+	// ErrorTTL is how long errors with one of ErrorCodes are cached.
+	ErrorTTL time.Duration `config:"errorTTL"`
+
+	// ErrorCodes lists the codes of errors which are cached, like
+	// "not-found".
+	ErrorCodes []string `config:"errorCodes"`
+}
+
+func (p ProcedureConfig) option() (MiddlewareOption, error) {
+	if p.Procedure == "" {
+		return nil, fmt.Errorf("did not specify a procedure to cache for service %q", p.Service)
+	}
+	if p.TTL < 0 || p.ErrorTTL < 0 {
+		return nil, fmt.Errorf("TTLs of procedure %q must not be negative", p.Procedure)
+	}
+
+	codes := make([]yarpcerrors.Code, len(p.ErrorCodes))
+	for i, name := range p.ErrorCodes {
+		if err := codes[i].UnmarshalText([]byte(name)); err != nil {
+			return nil, fmt.Errorf("invalid error code for procedure %q: %v", p.Procedure, err)
+		}
+	}
+
+	return CacheProcedure(p.Service, p.Procedure, p.TTL,
+		KeyHeaders(p.Headers...),
+		CacheErrors(p.ErrorTTL, codes...),
+	), nil
+}
+
+// NewUnaryMiddlewareFromConfig builds a caching middleware from a
+// MiddlewareConfig decoded from the given map[string]interface{}, like
+// parsed YAML.
+//
+// 	maxBytes: 16777216
+// 	procedures:
+// 	  - service: users
+// 	    procedure: getUser
+// 	    ttl: 5s
+// 	    headers: [x-tenant]
+// 	    errorTTL: 1s
+// 	    errorCodes: [not-found]
+//
+// Options given as arguments apply after the configuration.
+func NewUnaryMiddlewareFromConfig(src interface{}, opts ...MiddlewareOption) (*OutboundMiddleware, error) {
+	var cfg MiddlewareConfig
+	if err := iconfig.DecodeInto(&cfg, src); err != nil {
+		return nil, err
+	}
+
+	var (
+		cfgOpts []MiddlewareOption
+		errs    error
+	)
+	if cfg.MaxBytes > 0 {
+		cfgOpts = append(cfgOpts, MaxBytes(cfg.MaxBytes))
+	}
+	for _, p := range cfg.Procedures {
+		opt, err := p.option()
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		cfgOpts = append(cfgOpts, opt)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	return NewUnaryMiddleware(append(cfgOpts, opts...)...), nil
+}
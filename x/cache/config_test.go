@@ -0,0 +1,150 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/yarpcerrors"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNewUnaryMiddlewareFromConfig(t *testing.T) {
+	tests := []struct {
+		desc    string
+		give    string
+		wantErr string
+		check   func(*testing.T, *OutboundMiddleware)
+	}{
+		{
+			desc: "procedures",
+			give: whitespace.Expand(`
+				maxBytes: 1024
+				procedures:
+					- service: users
+					  procedure: getUser
+					  ttl: 5s
+					  headers: [X-Tenant]
+					  errorTTL: 1s
+					  errorCodes: [not-found, unavailable]
+					- procedure: getGroup
+					  ttl: 1m
+			`),
+			check: func(t *testing.T, mw *OutboundMiddleware) {
+				assert.Equal(t, 1024, mw.cache.maxBytes)
+				assert.Equal(t, map[serviceProcedure]*procedurePolicy{
+					{service: "users", procedure: "getUser"}: {
+						ttl:         5 * time.Second,
+						headers:     []string{"x-tenant"},
+						negativeTTL: time.Second,
+						negativeCodes: map[yarpcerrors.Code]struct{}{
+							yarpcerrors.CodeNotFound:    {},
+							yarpcerrors.CodeUnavailable: {},
+						},
+					},
+					{procedure: "getGroup"}: {
+						ttl:           time.Minute,
+						negativeCodes: map[yarpcerrors.Code]struct{}{},
+					},
+				}, mw.procedures)
+			},
+		},
+		{
+			desc: "missing procedure",
+			give: whitespace.Expand(`
+				procedures:
+					- service: users
+					  ttl: 5s
+			`),
+			wantErr: `did not specify a procedure to cache for service "users"`,
+		},
+		{
+			desc: "negative TTL",
+			give: whitespace.Expand(`
+				procedures:
+					- procedure: getUser
+					  ttl: -5s
+			`),
+			wantErr: `TTLs of procedure "getUser" must not be negative`,
+		},
+		{
+			desc: "unknown code",
+			give: whitespace.Expand(`
+				procedures:
+					- procedure: getUser
+					  ttl: 5s
+					  errorCodes: [sadness]
+			`),
+			wantErr: "unknown code string: sadness",
+		},
+		{
+			desc: "unknown key",
+			give: whitespace.Expand(`
+				procedure:
+					- procedure: getUser
+			`),
+			wantErr: "invalid keys: procedure",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var data map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(tt.give), &data))
+
+			mw, err := NewUnaryMiddlewareFromConfig(data)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, mw)
+		})
+	}
+}
+
+func TestNewUnaryMiddlewareFromConfigCaches(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mw, err := NewUnaryMiddlewareFromConfig(map[string]interface{}{
+		"procedures": []interface{}{
+			map[string]interface{}{"procedure": "get", "ttl": "1m"},
+		},
+	})
+	require.NoError(t, err)
+
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("response"), nil)
+	for i := 0; i < 2; i++ {
+		res, err := mw.Call(context.Background(), newRequest("get", "a"), out)
+		require.NoError(t, err)
+		assert.Equal(t, "response", readResponse(t, res))
+	}
+}
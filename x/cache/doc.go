@@ -0,0 +1,40 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package cache provides an EXPERIMENTAL outbound middleware which caches
+// the responses of unary requests to procedures whose data may be stale for
+// a short time.
+//
+// 	mw := cache.NewUnaryMiddleware(
+// 		cache.CacheProcedure("users", "getUser", 5*time.Second,
+// 			cache.KeyHeaders("x-tenant"),
+// 			cache.CacheErrors(time.Second, yarpcerrors.CodeNotFound),
+// 		),
+// 		cache.WithTally(scope),
+// 	)
+//
+// The middleware may also be built from configuration with
+// NewUnaryMiddlewareFromConfig.
+//
+// Responses are cached by service, procedure, encoding, selected headers, and
+// a hash of the request body, in an in-memory cache bounded in size which
+// evicts least recently used responses first. Cache hits and misses are
+// counted per service and procedure.
+package cache
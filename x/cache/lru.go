@@ -0,0 +1,124 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+)
+
+// entry is a cached response or error.
+type entry struct {
+	key     string
+	headers transport.Headers
+	body    []byte
+	err     error
+	expires time.Time
+	size    int
+}
+
+func (e *entry) computeSize() {
+	e.size = len(e.key) + len(e.body)
+	for k, v := range e.headers.Items() {
+		e.size += len(k) + len(v)
+	}
+}
+
+// lru is a cache of entries holding at most maxBytes of keys, headers and
+// bodies, which evicts the least recently used entries first.
+type lru struct {
+	maxBytes int
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // of *entry, most recently used first
+}
+
+func newLRU(maxBytes int, c clock.Clock) *lru {
+	return &lru{
+		maxBytes: maxBytes,
+		clock:    c,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the entry for the given key, if it has not expired.
+func (c *lru) get(key string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	ent := e.Value.(*entry)
+	if !c.clock.Now().Before(ent.expires) {
+		c.remove(e)
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+	return ent, true
+}
+
+// put adds the entry to the cache, replacing any entry with the same key.
+// Entries larger than the cache are not added.
+func (c *lru) put(ent *entry) {
+	ent.computeSize()
+	if ent.size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[ent.key]; ok {
+		c.remove(e)
+	}
+
+	c.entries[ent.key] = c.order.PushFront(ent)
+	c.size += ent.size
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *lru) remove(e *list.Element) {
+	ent := e.Value.(*entry)
+	c.order.Remove(e)
+	delete(c.entries, ent.key)
+	c.size -= ent.size
+}
+
+// len returns the number of entries in the cache.
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+)
+
+func TestLRU(t *testing.T) {
+	clk := clock.NewFake()
+	// Each entry below has a one byte key and a three byte body.
+	c := newLRU(8, clk)
+
+	c.put(&entry{key: "a", body: []byte("aaa"), expires: clk.Now().Add(time.Minute)})
+	c.put(&entry{key: "b", body: []byte("bbb"), expires: clk.Now().Add(time.Minute)})
+	assert.Equal(t, 2, c.len())
+
+	// Using a makes b the least recently used entry.
+	_, ok := c.get("a")
+	assert.True(t, ok)
+
+	c.put(&entry{key: "c", body: []byte("ccc"), expires: clk.Now().Add(time.Second)})
+	assert.Equal(t, 2, c.len())
+	_, ok = c.get("b")
+	assert.False(t, ok, "b must have been evicted")
+
+	clk.Add(time.Second)
+	_, ok = c.get("c")
+	assert.False(t, ok, "c must have expired")
+	_, ok = c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, c.len())
+	assert.Equal(t, 4, c.size)
+}
+
+func TestLRUReplace(t *testing.T) {
+	clk := clock.NewFake()
+	c := newLRU(100, clk)
+
+	c.put(&entry{key: "a", body: []byte("old"), expires: clk.Now().Add(time.Minute)})
+	c.put(&entry{key: "a", body: []byte("newer"), expires: clk.Now().Add(time.Minute)})
+
+	ent, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "newer", string(ent.body))
+	assert.Equal(t, 1, c.len())
+	assert.Equal(t, 6, c.size)
+}
+
+func TestLRUSkipsLargeEntries(t *testing.T) {
+	clk := clock.NewFake()
+	c := newLRU(8, clk)
+
+	c.put(&entry{
+		key:     "a",
+		headers: transport.NewHeaders().With("foo", "bar"),
+		body:    []byte("aaa"),
+		expires: clk.Now().Add(time.Minute),
+	})
+	assert.Equal(t, 0, c.len(), "headers count towards the size")
+}
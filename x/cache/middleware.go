@@ -0,0 +1,155 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/requestkey"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// OutboundMiddleware caches the responses of unary requests.
+type OutboundMiddleware struct {
+	procedures map[serviceProcedure]*procedurePolicy
+	cache      *lru
+	observer   *observer
+}
+
+var _ middleware.UnaryOutbound = (*OutboundMiddleware)(nil)
+
+// NewUnaryMiddleware builds an outbound middleware which caches the
+// responses of the procedures configured with CacheProcedure.
+//
+// Responses are cached by service, procedure, encoding, the headers
+// selected with KeyHeaders, and a hash of the request body. Application
+// errors are never cached, and errors are cached only if requested with
+// CacheErrors.
+func NewUnaryMiddleware(opts ...MiddlewareOption) *OutboundMiddleware {
+	options := newMiddlewareOptions()
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	return &OutboundMiddleware{
+		procedures: options.procedures,
+		cache:      newLRU(options.maxBytes, options.clock),
+		observer:   newObserver(options.scope),
+	}
+}
+
+// Call responds with a cached response, if any, or sends the request and
+// caches its response.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	policy := m.policy(req)
+	if policy == nil {
+		return out.Call(ctx, req)
+	}
+
+	req, key, err := cacheKey(req, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if ent, ok := m.cache.get(key); ok {
+		m.observer.hit(req.Service, req.Procedure)
+		if ent.err != nil {
+			return nil, ent.err
+		}
+		return ent.response(), nil
+	}
+	m.observer.miss(req.Service, req.Procedure)
+
+	res, err := out.Call(ctx, req)
+	if err != nil {
+		if _, ok := policy.negativeCodes[yarpcerrors.ErrorCode(err)]; ok && policy.negativeTTL > 0 {
+			m.cache.put(&entry{
+				key:     key,
+				err:     err,
+				expires: m.cache.clock.Now().Add(policy.negativeTTL),
+			})
+		}
+		return res, err
+	}
+	if res.ApplicationError || policy.ttl <= 0 {
+		return res, nil
+	}
+
+	var body []byte
+	if res.Body != nil {
+		body, err = ioutil.ReadAll(res.Body)
+		if closeErr := res.Body.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ent := &entry{
+		key:     key,
+		headers: res.Headers,
+		body:    body,
+		expires: m.cache.clock.Now().Add(policy.ttl),
+	}
+	m.cache.put(ent)
+	return ent.response(), nil
+}
+
+// policy returns the policy for the procedure of the request, or nil if its
+// responses are not cached.
+func (m *OutboundMiddleware) policy(req *transport.Request) *procedurePolicy {
+	if p, ok := m.procedures[serviceProcedure{service: req.Service, procedure: req.Procedure}]; ok {
+		return p
+	}
+	return m.procedures[serviceProcedure{procedure: req.Procedure}]
+}
+
+// response builds a response from a cached entry.
+func (e *entry) response() *transport.Response {
+	// Headers are mutable, so each response gets its own copy.
+	headers := transport.NewHeadersWithCapacity(e.headers.Len())
+	for k, v := range e.headers.Items() {
+		headers = headers.With(k, v)
+	}
+	return &transport.Response{
+		Headers: headers,
+		Body:    ioutil.NopCloser(bytes.NewReader(e.body)),
+	}
+}
+
+// cacheKey returns the cache key of the request, along with a copy of the
+// request whose body may be read again.
+func cacheKey(req *transport.Request, policy *procedurePolicy) (*transport.Request, string, error) {
+	r, body, err := requestkey.BufferBody(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var key requestkey.Builder
+	key.Add(req.Service, req.Procedure, string(req.Encoding))
+	key.AddHeaders(req.Headers, policy.headers)
+	key.AddBody(body)
+	return r, key.String(), nil
+}
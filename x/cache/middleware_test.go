@@ -0,0 +1,212 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func newRequest(procedure, body string) *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: procedure,
+		Encoding:  "json",
+		Headers:   transport.NewHeaders(),
+		Body:      bytes.NewBufferString(body),
+	}
+}
+
+func newResponse(body string) *transport.Response {
+	return &transport.Response{
+		Headers: transport.NewHeaders().With("foo", "bar"),
+		Body:    ioutil.NopCloser(bytes.NewBufferString(body)),
+	}
+}
+
+func readResponse(t *testing.T, res *transport.Response) string {
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	return string(body)
+}
+
+func TestMiddlewareCachesResponses(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	clk := clock.NewFake()
+	scope := tally.NewTestScope("", nil)
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	mw := NewUnaryMiddleware(
+		CacheProcedure("service", "get", time.Minute),
+		WithTally(scope),
+		withClock(clk),
+	)
+
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *transport.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "request", string(body), "request body must be forwarded")
+		}).
+		Return(newResponse("response"), nil)
+
+	for i := 0; i < 3; i++ {
+		res, err := mw.Call(context.Background(), newRequest("get", "request"), out)
+		require.NoError(t, err)
+		assert.Equal(t, "response", readResponse(t, res))
+		assert.Equal(t, transport.NewHeaders().With("foo", "bar"), res.Headers)
+	}
+
+	// Expired responses are fetched again.
+	clk.Add(time.Minute)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("fresh"), nil)
+	res, err := mw.Call(context.Background(), newRequest("get", "request"), out)
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", readResponse(t, res))
+
+	counters := scope.Snapshot().Counters()
+	assert.Equal(t, int64(2), counters["cache_hits+procedure=get,service=service"].Value())
+	assert.Equal(t, int64(2), counters["cache_misses+procedure=get,service=service"].Value())
+}
+
+func TestMiddlewareCacheKey(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	mw := NewUnaryMiddleware(CacheProcedure("", "get", time.Minute, KeyHeaders("X-Tenant")))
+
+	tests := []struct {
+		desc    string
+		req     *transport.Request
+		wantHit bool
+	}{
+		{desc: "first", req: newRequest("get", "a")},
+		{desc: "same", req: newRequest("get", "a"), wantHit: true},
+		{desc: "different body", req: newRequest("get", "b")},
+		{
+			desc: "different encoding",
+			req: func() *transport.Request {
+				r := newRequest("get", "a")
+				r.Encoding = "thrift"
+				return r
+			}(),
+		},
+		{
+			desc: "different service",
+			req: func() *transport.Request {
+				r := newRequest("get", "a")
+				r.Service = "other"
+				return r
+			}(),
+		},
+		{
+			desc: "key header",
+			req: func() *transport.Request {
+				r := newRequest("get", "a")
+				r.Headers = r.Headers.With("x-tenant", "foo")
+				return r
+			}(),
+		},
+		{
+			desc: "ignored header",
+			req: func() *transport.Request {
+				r := newRequest("get", "a")
+				r.Headers = r.Headers.With("x-other", "foo")
+				return r
+			}(),
+			wantHit: true,
+		},
+		{desc: "uncached procedure", req: newRequest("set", "a")},
+		{desc: "uncached procedure again", req: newRequest("set", "a")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			if !tt.wantHit {
+				out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("response"), nil)
+			}
+			res, err := mw.Call(context.Background(), tt.req, out)
+			require.NoError(t, err)
+			assert.Equal(t, "response", readResponse(t, res))
+		})
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	clk := clock.NewFake()
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	mw := NewUnaryMiddleware(
+		CacheProcedure("service", "get", time.Minute, CacheErrors(time.Second, yarpcerrors.CodeNotFound)),
+		withClock(clk),
+	)
+
+	t.Run("cached code", func(t *testing.T) {
+		out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(nil, yarpcerrors.NotFoundErrorf("no user"))
+		for i := 0; i < 2; i++ {
+			_, err := mw.Call(context.Background(), newRequest("get", "a"), out)
+			assert.True(t, yarpcerrors.IsNotFound(err))
+		}
+
+		clk.Add(time.Second)
+		out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(newResponse("found"), nil)
+		res, err := mw.Call(context.Background(), newRequest("get", "a"), out)
+		require.NoError(t, err)
+		assert.Equal(t, "found", readResponse(t, res))
+	})
+
+	t.Run("other code", func(t *testing.T) {
+		out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(nil, yarpcerrors.UnavailableErrorf("great sadness")).Times(2)
+		for i := 0; i < 2; i++ {
+			_, err := mw.Call(context.Background(), newRequest("get", "b"), out)
+			assert.True(t, yarpcerrors.IsUnavailable(err))
+		}
+	})
+
+	t.Run("application error", func(t *testing.T) {
+		out.EXPECT().Call(gomock.Any(), gomock.Any()).
+			Return(&transport.Response{ApplicationError: true, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil).
+			Times(2)
+		for i := 0; i < 2; i++ {
+			res, err := mw.Call(context.Background(), newRequest("get", "c"), out)
+			require.NoError(t, err)
+			assert.True(t, res.ApplicationError)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import "github.com/uber-go/tally"
+
+const (
+	_hitsName     = "cache_hits"
+	_missesName   = "cache_misses"
+	_serviceTag   = "service"
+	_procedureTag = "procedure"
+)
+
+type observer struct {
+	scope tally.Scope
+}
+
+func newObserver(scope tally.Scope) *observer {
+	return &observer{scope: scope}
+}
+
+func (o *observer) tagged(service, procedure string) tally.Scope {
+	return o.scope.Tagged(map[string]string{
+		_serviceTag:   service,
+		_procedureTag: procedure,
+	})
+}
+
+func (o *observer) hit(service, procedure string) {
+	o.tagged(service, procedure).Counter(_hitsName).Inc(1)
+}
+
+func (o *observer) miss(service, procedure string) {
+	o.tagged(service, procedure).Counter(_missesName).Inc(1)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cache
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// MiddlewareOption customizes the behavior of an OutboundMiddleware.
+type MiddlewareOption interface {
+	apply(*middlewareOptions)
+}
+
+type middlewareOptionFunc func(*middlewareOptions)
+
+func (f middlewareOptionFunc) apply(opts *middlewareOptions) { f(opts) }
+
+type middlewareOptions struct {
+	maxBytes   int
+	procedures map[serviceProcedure]*procedurePolicy
+	scope      tally.Scope
+	clock      clock.Clock
+}
+
+func newMiddlewareOptions() middlewareOptions {
+	return middlewareOptions{
+		maxBytes:   32 * 1024 * 1024,
+		procedures: make(map[serviceProcedure]*procedurePolicy),
+		scope:      tally.NoopScope,
+		clock:      clock.NewReal(),
+	}
+}
+
+type serviceProcedure struct {
+	service   string
+	procedure string
+}
+
+// procedurePolicy specifies how responses of a procedure are cached.
+type procedurePolicy struct {
+	ttl           time.Duration
+	headers       []string
+	negativeTTL   time.Duration
+	negativeCodes map[yarpcerrors.Code]struct{}
+}
+
+// MaxBytes bounds the size of the cache, counting the keys, headers and
+// bodies of cached responses. Least recently used responses are evicted
+// first. Defaults to 32 MiB.
+func MaxBytes(n int) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.maxBytes = n
+	})
+}
+
+// CacheProcedure caches the successful responses of the given procedure for
+// the given TTL. If service is empty, the procedure is cached for all
+// services, unless a policy for the specific service exists.
+//
+// Only the procedures configured with CacheProcedure are cached.
+func CacheProcedure(service, procedure string, ttl time.Duration, opts ...ProcedureOption) MiddlewareOption {
+	return middlewareOptionFunc(func(o *middlewareOptions) {
+		p := &procedurePolicy{
+			ttl:           ttl,
+			negativeCodes: make(map[yarpcerrors.Code]struct{}),
+		}
+		for _, opt := range opts {
+			opt.applyProcedure(p)
+		}
+		sort.Strings(p.headers)
+		o.procedures[serviceProcedure{service: service, procedure: procedure}] = p
+	})
+}
+
+// WithTally sets a Tally scope that will be used to record cache hits and
+// misses.
+func WithTally(scope tally.Scope) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.scope = scope
+	})
+}
+
+// withClock replaces the clock used to expire responses.
+func withClock(c clock.Clock) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.clock = c
+	})
+}
+
+// ProcedureOption customizes how the responses of a procedure are cached.
+type ProcedureOption interface {
+	applyProcedure(*procedurePolicy)
+}
+
+type procedureOptionFunc func(*procedurePolicy)
+
+func (f procedureOptionFunc) applyProcedure(p *procedurePolicy) { f(p) }
+
+// KeyHeaders adds the values of the given request headers to the cache key,
+// so that requests which differ in these headers are cached separately.
+// Other headers are ignored.
+func KeyHeaders(names ...string) ProcedureOption {
+	return procedureOptionFunc(func(p *procedurePolicy) {
+		for _, name := range names {
+			p.headers = append(p.headers, strings.ToLower(name))
+		}
+	})
+}
+
+// CacheErrors caches errors with the given codes for the given TTL, so that
+// repeated requests for missing data, for example, do not reach the
+// service.
+//
+// 	cache.CacheErrors(5*time.Second, yarpcerrors.CodeNotFound)
+func CacheErrors(ttl time.Duration, codes ...yarpcerrors.Code) ProcedureOption {
+	return procedureOptionFunc(func(p *procedurePolicy) {
+		p.negativeTTL = ttl
+		for _, c := range codes {
+			p.negativeCodes[c] = struct{}{}
+		}
+	})
+}
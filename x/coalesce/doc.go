@@ -0,0 +1,37 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package coalesce provides an EXPERIMENTAL outbound middleware which
+// coalesces concurrent identical requests into a single request, so that a
+// burst of identical reads, like after a cache miss, reaches the service
+// once.
+//
+// 	mw := coalesce.NewUnaryMiddleware(
+// 		coalesce.Procedure("users", "getUser"),
+// 		coalesce.Procedure("users", "getGroup"),
+// 	)
+//
+// Coalescing is enabled per procedure, and should only be enabled for
+// procedures without side effects.
+//
+// Requests are coalesced regardless of their headers, unless they are listed
+// with KeyHeaders. Headers which change the response, like credentials,
+// must be listed, or callers may receive responses meant for others.
+package coalesce
@@ -0,0 +1,250 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package coalesce
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/requestkey"
+)
+
+// MiddlewareOption customizes the behavior of an OutboundMiddleware.
+type MiddlewareOption interface {
+	apply(*OutboundMiddleware)
+}
+
+type middlewareOptionFunc func(*OutboundMiddleware)
+
+func (f middlewareOptionFunc) apply(m *OutboundMiddleware) { f(m) }
+
+// Procedure enables coalescing for the given procedure. If service is empty,
+// the procedure is coalesced for all services.
+//
+// Only procedures which are safe to call once on behalf of several callers,
+// like reads, should be coalesced.
+func Procedure(service, procedure string) MiddlewareOption {
+	return middlewareOptionFunc(func(m *OutboundMiddleware) {
+		m.procedures[serviceProcedure{service: service, procedure: procedure}] = struct{}{}
+	})
+}
+
+// KeyHeaders adds the given request headers to the key which identifies
+// identical requests.
+//
+// Requests are coalesced regardless of their other headers, so every header
+// which may change the response, like credentials or a tenant, must be
+// listed. Otherwise a caller may receive a response meant for another.
+func KeyHeaders(names ...string) MiddlewareOption {
+	return middlewareOptionFunc(func(m *OutboundMiddleware) {
+		m.headers = append(m.headers, names...)
+	})
+}
+
+type serviceProcedure struct {
+	service   string
+	procedure string
+}
+
+// OutboundMiddleware coalesces concurrent identical unary requests into a
+// single request.
+type OutboundMiddleware struct {
+	procedures map[serviceProcedure]struct{}
+	headers    []string
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+var _ middleware.UnaryOutbound = (*OutboundMiddleware)(nil)
+
+// NewUnaryMiddleware builds an outbound middleware which coalesces
+// concurrent requests to the procedures enabled with the Procedure option.
+//
+// Requests are identical if they have the same caller, service, procedure,
+// encoding, routing key, routing delegate, shard key, headers selected with
+// KeyHeaders, and body. Only one of concurrent identical requests is sent,
+// and its response is given to all their callers.
+//
+// Callers whose context is done stop waiting for the response. The request
+// is cancelled once all its callers stopped waiting. Note that the request
+// is sent with the deadline and context values of the first caller.
+func NewUnaryMiddleware(opts ...MiddlewareOption) *OutboundMiddleware {
+	m := &OutboundMiddleware{
+		procedures: make(map[serviceProcedure]struct{}),
+		calls:      make(map[string]*call),
+	}
+	for _, o := range opts {
+		o.apply(m)
+	}
+	return m
+}
+
+// call is a request in flight on behalf of one or more callers.
+type call struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	// Set once done is closed.
+	res  *transport.Response
+	body []byte
+	err  error
+}
+
+// Call sends the request, unless an identical request is in flight, in
+// which case it waits for the response of that request.
+func (m *OutboundMiddleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	if !m.enabled(req) {
+		return out.Call(ctx, req)
+	}
+
+	req, key, err := requestKey(req, m.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	c, ok := m.calls[key]
+	if !ok {
+		var (
+			callCtx context.Context
+			cancel  context.CancelFunc
+		)
+		if deadline, ok := ctx.Deadline(); ok {
+			callCtx, cancel = context.WithDeadline(detachedContext{ctx}, deadline)
+		} else {
+			callCtx, cancel = context.WithCancel(detachedContext{ctx})
+		}
+		c = &call{done: make(chan struct{}), cancel: cancel}
+		m.calls[key] = c
+		go m.do(callCtx, key, c, req, out)
+	}
+	c.waiters++
+	m.mu.Unlock()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		m.leave(key, c)
+		return nil, ctx.Err()
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.response(), nil
+}
+
+func (m *OutboundMiddleware) enabled(req *transport.Request) bool {
+	if _, ok := m.procedures[serviceProcedure{service: req.Service, procedure: req.Procedure}]; ok {
+		return true
+	}
+	_, ok := m.procedures[serviceProcedure{procedure: req.Procedure}]
+	return ok
+}
+
+// do sends the request and records its response.
+func (m *OutboundMiddleware) do(ctx context.Context, key string, c *call, req *transport.Request, out transport.UnaryOutbound) {
+	defer c.cancel()
+
+	res, err := out.Call(ctx, req)
+	if err == nil && res.Body != nil {
+		c.body, err = ioutil.ReadAll(res.Body)
+		if closeErr := res.Body.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	c.res, c.err = res, err
+
+	m.mu.Lock()
+	if m.calls[key] == c {
+		delete(m.calls, key)
+	}
+	m.mu.Unlock()
+	close(c.done)
+}
+
+// leave stops waiting for the response of a call, cancelling it if no other
+// caller is waiting for it.
+func (m *OutboundMiddleware) leave(key string, c *call) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+	if m.calls[key] == c {
+		delete(m.calls, key)
+	}
+	c.cancel()
+}
+
+// response builds a copy of the response of the call for one of its
+// callers.
+func (c *call) response() *transport.Response {
+	res := *c.res
+	res.Headers = transport.NewHeadersWithCapacity(c.res.Headers.Len())
+	for k, v := range c.res.Headers.Items() {
+		res.Headers = res.Headers.With(k, v)
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(c.body))
+	return &res
+}
+
+// requestKey returns the key identifying identical requests, along with a
+// copy of the request whose body may be read again.
+func requestKey(req *transport.Request, headers []string) (*transport.Request, string, error) {
+	r, body, err := requestkey.BufferBody(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var key requestkey.Builder
+	key.Add(
+		req.Caller,
+		req.Service,
+		req.Procedure,
+		string(req.Encoding),
+		req.RoutingKey,
+		req.RoutingDelegate,
+		req.ShardKey,
+	)
+	key.AddHeaders(req.Headers, headers)
+	key.AddBody(body)
+	return r, key.String(), nil
+}
+
+// detachedContext carries the values of its parent, without its deadline or
+// cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
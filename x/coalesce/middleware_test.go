@@ -0,0 +1,276 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package coalesce
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func newRequest(procedure, body string) *transport.Request {
+	return &transport.Request{
+		Service:   "service",
+		Procedure: procedure,
+		Encoding:  "json",
+		Body:      bytes.NewBufferString(body),
+	}
+}
+
+func readResponse(t *testing.T, res *transport.Response) string {
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// waitForWaiters waits until the given number of callers wait for a call.
+func waitForWaiters(t *testing.T, m *OutboundMiddleware, n int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		total := 0
+		for _, c := range m.calls {
+			total += c.waiters
+		}
+		m.mu.Unlock()
+		if total == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters", n)
+}
+
+func TestCoalescesConcurrentRequests(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	m := NewUnaryMiddleware(Procedure("service", "get"))
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+
+	unblock := make(chan struct{})
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *transport.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "request", string(body))
+			<-unblock
+		}).
+		Return(&transport.Response{
+			Headers: transport.NewHeaders().With("foo", "bar"),
+			Body:    ioutil.NopCloser(bytes.NewBufferString("response")),
+		}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			res, err := m.Call(ctx, newRequest("get", "request"), out)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "response", readResponse(t, res))
+				assert.Equal(t, transport.NewHeaders().With("foo", "bar"), res.Headers)
+			}
+		}()
+	}
+
+	waitForWaiters(t, m, 10)
+	close(unblock)
+	wg.Wait()
+
+	m.mu.Lock()
+	assert.Empty(t, m.calls, "completed calls must be forgotten")
+	m.mu.Unlock()
+}
+
+func TestDoesNotCoalesceDifferentRequests(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	m := NewUnaryMiddleware(Procedure("", "get"), KeyHeaders("x-tenant"))
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+
+	reqs := []*transport.Request{
+		newRequest("get", "a"),
+		newRequest("get", "b"),
+		func() *transport.Request {
+			r := newRequest("get", "a")
+			r.ShardKey = "shard"
+			return r
+		}(),
+		func() *transport.Request {
+			r := newRequest("get", "a")
+			r.RoutingKey = "canary"
+			return r
+		}(),
+		func() *transport.Request {
+			r := newRequest("get", "a")
+			r.Caller = "other"
+			return r
+		}(),
+		func() *transport.Request {
+			r := newRequest("get", "a")
+			r.Headers = transport.NewHeaders().With("x-tenant", "other")
+			return r
+		}(),
+		newRequest("set", "a"),
+		newRequest("set", "a"),
+	}
+
+	unblock := make(chan struct{})
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *transport.Request) { <-unblock }).
+		Return(&transport.Response{Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil).
+		Times(len(reqs))
+
+	var wg sync.WaitGroup
+	for _, req := range reqs {
+		wg.Add(1)
+		go func(req *transport.Request) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err := m.Call(ctx, req, out)
+			assert.NoError(t, err)
+		}(req)
+	}
+
+	waitForWaiters(t, m, 6)
+	close(unblock)
+	wg.Wait()
+}
+
+func TestCoalescedErrors(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	m := NewUnaryMiddleware(Procedure("service", "get"))
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+
+	unblock := make(chan struct{})
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *transport.Request) { <-unblock }).
+		Return(nil, yarpcerrors.UnavailableErrorf("great sadness"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err := m.Call(ctx, newRequest("get", "a"), out)
+			assert.True(t, yarpcerrors.IsUnavailable(err))
+		}()
+	}
+
+	waitForWaiters(t, m, 3)
+	close(unblock)
+	wg.Wait()
+}
+
+func TestWaitersLeaveIndependently(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	m := NewUnaryMiddleware(Procedure("service", "get"))
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+
+	unblock := make(chan struct{})
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request) {
+			<-unblock
+			assert.NoError(t, ctx.Err(), "call must not be cancelled while a caller waits")
+		}).
+		Return(&transport.Response{Body: ioutil.NopCloser(bytes.NewBufferString("response"))}, nil)
+
+	// The first caller gives up before the response arrives.
+	firstCtx, cancelFirst := context.WithTimeout(context.Background(), time.Second)
+	firstErr := make(chan error)
+	go func() {
+		_, err := m.Call(firstCtx, newRequest("get", "a"), out)
+		firstErr <- err
+	}()
+	waitForWaiters(t, m, 1)
+
+	secondRes := make(chan *transport.Response)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		res, err := m.Call(ctx, newRequest("get", "a"), out)
+		assert.NoError(t, err)
+		secondRes <- res
+	}()
+	waitForWaiters(t, m, 2)
+
+	cancelFirst()
+	assert.Equal(t, context.Canceled, <-firstErr)
+
+	close(unblock)
+	res := <-secondRes
+	require.NotNil(t, res)
+	assert.Equal(t, "response", readResponse(t, res))
+}
+
+func TestCallCancelledWhenAllWaitersLeave(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	m := NewUnaryMiddleware(Procedure("service", "get"))
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+
+	cancelled := make(chan struct{})
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, _ *transport.Request) {
+			<-ctx.Done()
+			close(cancelled)
+		}).
+		Return(nil, yarpcerrors.CancelledErrorf("cancelled"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := m.Call(ctx, newRequest("get", "a"), out)
+		assert.Equal(t, context.Canceled, err)
+	}()
+	waitForWaiters(t, m, 1)
+
+	cancel()
+	<-done
+	<-cancelled
+
+	m.mu.Lock()
+	assert.Empty(t, m.calls)
+	m.mu.Unlock()
+}
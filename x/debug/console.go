@@ -0,0 +1,492 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package debug
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	yarpcjson "go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/encoding/protobuf"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/encoding/thrift"
+	"go.uber.org/yarpc/internal/introspection"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// Requests without a timeout are given this long to complete.
+	_defaultConsoleTimeout = 5 * time.Second
+
+	// Invocations larger than this are rejected.
+	_maxConsoleRequestSize = 4 << 20
+
+	// ConsoleCaller is the caller name of the requests which the console
+	// sends to the dispatcher's own procedures.
+	ConsoleCaller = "yarpc-debug-console"
+)
+
+// _consoleTmpl is the template of the console page.
+var _consoleTmpl = template.Must(template.New("console").Parse(`
+<html>
+	<head>
+	<title>/debug/yarpc console</title>
+	<style type="text/css">
+		body {
+			font-family: "Courier New", Courier, monospace;
+		}
+		table {
+			color:#333333;
+			border-width: 1px;
+			border-color: #3A3A3A;
+			border-collapse: collapse;
+		}
+		table th {
+			border-width: 1px;
+			padding: 8px;
+			border-style: solid;
+			border-color: #3A3A3A;
+			background-color: #B3B3B3;
+		}
+		table td {
+			border-width: 1px;
+			padding: 8px;
+			border-style: solid;
+			border-color: #3A3A3A;
+			background-color: #ffffff;
+		}
+		label {
+			display: block;
+			margin-top: 8px;
+		}
+		textarea {
+			width: 60em;
+			height: 8em;
+		}
+	</style>
+	</head>
+	<body>
+
+<h1>/debug/yarpc console</h1>
+
+<h3>Procedures of "{{.Service}}"</h3>
+<table>
+	<tr>
+		<th>Procedure</th>
+		<th>Encoding</th>
+		<th>Signature</th>
+		<th>RPC Type</th>
+	</tr>
+	{{range .Procedures}}
+	<tr>
+		<td>{{.Name}}</td>
+		<td>{{.Encoding}}</td>
+		<td>{{.Signature}}</td>
+		<td>{{.RPCType}}</td>
+	</tr>
+	{{end}}
+</table>
+
+<h3>Invoke</h3>
+<form id="invoke">
+	<label>Target
+		<select name="outbound">
+			<option value="">local ({{.Service}})</option>
+			{{range .Outbounds}}
+			<option value="{{.OutboundKey}}">outbound {{.OutboundKey}} ({{.Service}})</option>
+			{{end}}
+		</select>
+	</label>
+	<label>Procedure <input name="procedure" list="procedures" size="40" /></label>
+	<datalist id="procedures">
+		{{range .Procedures}}
+		<option value="{{.Name}}">
+		{{end}}
+	</datalist>
+	<label>Encoding
+		<select name="encoding">
+			<option>json</option>
+			<option>raw</option>
+			<option>proto</option>
+		</select>
+	</label>
+	<label>RPC Type
+		<select name="rpcType">
+			<option>unary</option>
+			<option>oneway</option>
+		</select>
+	</label>
+	<label>Timeout <input name="timeout" value="{{.Timeout}}" /></label>
+	<label>Headers (JSON object)<br /><textarea name="headers">{}</textarea></label>
+	<label>Body (JSON, or text for raw)<br /><textarea name="body">{}</textarea></label>
+	<p><input type="submit" value="Invoke" /></p>
+</form>
+<pre id="response"></pre>
+
+<script>
+document.getElementById("invoke").addEventListener("submit", function(event) {
+	event.preventDefault();
+	var form = event.target;
+	var output = document.getElementById("response");
+	var request = {
+		outbound: form.outbound.value,
+		procedure: form.procedure.value,
+		encoding: form.encoding.value,
+		rpcType: form.rpcType.value,
+		timeout: form.timeout.value
+	};
+	try {
+		request.headers = JSON.parse(form.headers.value || "{}");
+		request.body = form.encoding.value === "raw" ? form.body.value : JSON.parse(form.body.value);
+	} catch (e) {
+		output.textContent = "Invalid JSON: " + e;
+		return;
+	}
+	var xhr = new XMLHttpRequest();
+	xhr.open("POST", window.location.href);
+	xhr.setRequestHeader("Content-Type", "application/json");
+	xhr.onload = function() {
+		try {
+			output.textContent = JSON.stringify(JSON.parse(xhr.responseText), null, 2);
+		} catch (e) {
+			output.textContent = xhr.responseText;
+		}
+	};
+	xhr.send(JSON.stringify(request));
+});
+</script>
+
+	</body>
+</html>
+`))
+
+// consoleData is rendered by the console page.
+type consoleData struct {
+	Service    string                    `json:"service"`
+	Procedures []introspection.Procedure `json:"procedures"`
+	Outbounds  []consoleOutbound         `json:"outbounds"`
+	Timeout    string                    `json:"timeout"`
+}
+
+// consoleOutbound is an outbound through which the console can make
+// requests.
+type consoleOutbound struct {
+	OutboundKey string   `json:"outboundKey"`
+	Service     string   `json:"service"`
+	RPCTypes    []string `json:"rpcTypes"`
+}
+
+func newConsoleData(status introspection.DispatcherStatus) *consoleData {
+	byKey := make(map[string]*consoleOutbound)
+	var keys []string
+	for _, o := range status.Outbounds {
+		out, ok := byKey[o.OutboundKey]
+		if !ok {
+			out = &consoleOutbound{OutboundKey: o.OutboundKey, Service: o.Service}
+			byKey[o.OutboundKey] = out
+			keys = append(keys, o.OutboundKey)
+		}
+		out.RPCTypes = append(out.RPCTypes, o.RPCType)
+	}
+	sort.Strings(keys)
+	outbounds := make([]consoleOutbound, 0, len(keys))
+	for _, key := range keys {
+		outbounds = append(outbounds, *byKey[key])
+	}
+	return &consoleData{
+		Service:    status.Name,
+		Procedures: status.Procedures,
+		Outbounds:  outbounds,
+		Timeout:    _defaultConsoleTimeout.String(),
+	}
+}
+
+// invokeRequest is a request to invoke a procedure from the console.
+type invokeRequest struct {
+	// Outbound through which to send the request. If empty, the request is
+	// handled by the dispatcher's own procedures.
+	Outbound  string            `json:"outbound"`
+	Procedure string            `json:"procedure"`
+	Encoding  string            `json:"encoding"`
+	RPCType   string            `json:"rpcType"`
+	Headers   map[string]string `json:"headers"`
+	Timeout   string            `json:"timeout"`
+
+	// Body is a JSON value for JSON and protobuf requests, and a string
+	// holding the body of raw requests.
+	Body json.RawMessage `json:"body"`
+}
+
+// invokeResponse is the outcome of a procedure invoked from the console.
+type invokeResponse struct {
+	Body             json.RawMessage   `json:"body,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	ApplicationError bool              `json:"applicationError,omitempty"`
+	Code             string            `json:"code,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	Duration         string            `json:"duration"`
+}
+
+// invoke handles a request of the console to invoke a procedure, locally or
+// through an outbound.
+func (h *handler) invoke(w http.ResponseWriter, r *http.Request) {
+	if status, err := checkInvokeRequest(r); err != nil {
+		h.writeJSON(w, status, errorResponse{err.Error()})
+		return
+	}
+
+	var req invokeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, _maxConsoleRequestSize)).Decode(&req); err != nil {
+		h.writeJSON(w, http.StatusBadRequest, errorResponse{fmt.Sprintf("failed to decode request: %v", err)})
+		return
+	}
+	treq, timeout, err := h.newTransportRequest(&req)
+	if err != nil {
+		h.writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	var (
+		started = time.Now()
+		res     invokeResponse
+	)
+	if req.Outbound == "" {
+		res = h.invokeLocally(ctx, &req, treq)
+	} else {
+		res = h.invokeOutbound(ctx, &req, treq)
+	}
+	res.Duration = time.Since(started).String()
+	h.writeJSON(w, http.StatusOK, res)
+}
+
+// checkInvokeRequest rejects invocations which may have been sent by a page
+// of another origin, returning the status with which to reject them.
+//
+// The console only sends JSON, which browsers don't send across origins
+// without a preflight request, which the console never allows. The origin
+// of the request, if any, must also be the host it was sent to.
+func checkInvokeRequest(r *http.Request) (int, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return http.StatusUnsupportedMediaType, errors.New("invocations must have the application/json content type")
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return 0, nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != r.Host {
+		return http.StatusForbidden, fmt.Errorf("cross-origin invocations are not allowed: %q is not %q", origin, r.Host)
+	}
+	return 0, nil
+}
+
+func (h *handler) newTransportRequest(req *invokeRequest) (*transport.Request, time.Duration, error) {
+	if req.Procedure == "" {
+		return nil, 0, fmt.Errorf("procedure is required")
+	}
+	switch req.RPCType {
+	case "", "unary", "oneway":
+	default:
+		return nil, 0, fmt.Errorf("unsupported RPC type %q", req.RPCType)
+	}
+
+	timeout := _defaultConsoleTimeout
+	if req.Timeout != "" {
+		d, err := time.ParseDuration(req.Timeout)
+		if err != nil || d <= 0 {
+			return nil, 0, fmt.Errorf("invalid timeout %q", req.Timeout)
+		}
+		timeout = d
+	}
+
+	encoding, err := consoleEncoding(transport.Encoding(req.Encoding))
+	if err != nil {
+		return nil, 0, err
+	}
+	body := []byte(req.Body)
+	if encoding == raw.Encoding {
+		var s string
+		if err := json.Unmarshal(req.Body, &s); err != nil {
+			return nil, 0, fmt.Errorf("the body of raw requests must be a string: %v", err)
+		}
+		body = []byte(s)
+	}
+
+	treq := &transport.Request{
+		Caller:    ConsoleCaller,
+		Service:   h.dispatcher.Name(),
+		Encoding:  encoding,
+		Procedure: req.Procedure,
+		Headers:   transport.HeadersFromMap(req.Headers),
+		Body:      bytes.NewReader(body),
+	}
+	return treq, timeout, nil
+}
+
+// consoleEncoding returns the encoding with which to send a request of the
+// given encoding, whose body the console receives as JSON. Protobuf
+// procedures also accept JSON, but Thrift requests can't be transcoded.
+func consoleEncoding(encoding transport.Encoding) (transport.Encoding, error) {
+	switch encoding {
+	case "", yarpcjson.Encoding, protobuf.Encoding:
+		return yarpcjson.Encoding, nil
+	case raw.Encoding:
+		return raw.Encoding, nil
+	case thrift.Encoding:
+		return "", fmt.Errorf("cannot transcode JSON to %q, invoke Thrift procedures with a client instead", encoding)
+	default:
+		return "", fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+func (h *handler) invokeLocally(ctx context.Context, req *invokeRequest, treq *transport.Request) invokeResponse {
+	spec, err := h.dispatcher.Router().Choose(ctx, treq)
+	if err != nil {
+		return newInvokeResponse(err)
+	}
+	switch spec.Type() {
+	case transport.Unary:
+		rw := &responseWriter{}
+		err := spec.Unary().Handle(ctx, treq, rw)
+		res := newInvokeResponse(err)
+		res.Headers = rw.headers.Items()
+		res.ApplicationError = rw.applicationError
+		res.Body = responseBody(treq.Encoding, rw.Bytes())
+		return res
+	case transport.Oneway:
+		return newInvokeResponse(spec.Oneway().HandleOneway(ctx, treq))
+	default:
+		return newInvokeResponse(yarpcerrors.UnimplementedErrorf("procedure %q has unsupported RPC type %v", treq.Procedure, spec.Type()))
+	}
+}
+
+func (h *handler) invokeOutbound(ctx context.Context, req *invokeRequest, treq *transport.Request) invokeResponse {
+	if !h.hasOutbound(req.Outbound, req.RPCType) {
+		return newInvokeResponse(yarpcerrors.InvalidArgumentErrorf("no %v outbound %q", rpcType(req.RPCType), req.Outbound))
+	}
+	cc := h.dispatcher.ClientConfig(req.Outbound)
+	treq.Caller = cc.Caller()
+	treq.Service = cc.Service()
+
+	if rpcType(req.RPCType) == "oneway" {
+		_, err := cc.GetOnewayOutbound().CallOneway(ctx, treq)
+		return newInvokeResponse(err)
+	}
+	tres, err := cc.GetUnaryOutbound().Call(ctx, treq)
+	res := newInvokeResponse(err)
+	if tres == nil {
+		return res
+	}
+	res.Headers = tres.Headers.Items()
+	res.ApplicationError = tres.ApplicationError
+	if tres.Body != nil {
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(tres.Body); err != nil && res.Error == "" {
+			res.Error = fmt.Sprintf("failed to read response body: %v", err)
+		}
+		if err := tres.Body.Close(); err != nil && res.Error == "" {
+			res.Error = fmt.Sprintf("failed to close response body: %v", err)
+		}
+		res.Body = responseBody(treq.Encoding, buf.Bytes())
+	}
+	return res
+}
+
+func (h *handler) hasOutbound(outboundKey, requested string) bool {
+	for _, o := range h.dispatcher.Introspect().Outbounds {
+		if o.OutboundKey == outboundKey && o.RPCType == rpcType(requested) {
+			return true
+		}
+	}
+	return false
+}
+
+// rpcType returns the RPC type of console requests, which default to unary.
+func rpcType(requested string) string {
+	if requested == "" {
+		return "unary"
+	}
+	return requested
+}
+
+func newInvokeResponse(err error) invokeResponse {
+	if err == nil {
+		return invokeResponse{}
+	}
+	res := invokeResponse{Error: err.Error()}
+	if yarpcerrors.IsYARPCError(err) {
+		res.Code = yarpcerrors.ErrorCode(err).String()
+	}
+	return res
+}
+
+// responseBody returns a response body as JSON. JSON bodies are returned as
+// they are, and others as strings.
+func responseBody(encoding transport.Encoding, body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if encoding == yarpcjson.Encoding {
+		var v json.RawMessage
+		if err := json.Unmarshal(body, &v); err == nil {
+			return v
+		}
+	}
+	b, _ := json.Marshal(string(body)) // marshaling strings can't fail
+	return b
+}
+
+// responseWriter buffers the response of a procedure invoked locally.
+type responseWriter struct {
+	bytes.Buffer
+
+	headers          transport.Headers
+	applicationError bool
+}
+
+func (w *responseWriter) AddHeaders(h transport.Headers) {
+	for k, v := range h.Items() {
+		w.headers = w.headers.With(k, v)
+	}
+}
+
+func (w *responseWriter) SetApplicationError() {
+	w.applicationError = true
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,338 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	yarpcjson "go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/encoding/protobuf"
+	"go.uber.org/yarpc/encoding/raw"
+	"go.uber.org/yarpc/encoding/thrift"
+	yarpchttp "go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func newConsoleDispatcher(t *testing.T, url string) *yarpc.Dispatcher {
+	httpTransport := yarpchttp.NewTransport()
+	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+		Name: "test",
+		Outbounds: yarpc.Outbounds{
+			"other": {
+				Unary: httpTransport.NewSingleOutbound(url),
+			},
+		},
+	})
+	dispatcher.Register(yarpcjson.Procedure("echo",
+		func(_ context.Context, body map[string]interface{}) (map[string]interface{}, error) {
+			return body, nil
+		}))
+	dispatcher.Register(yarpcjson.Procedure("fail",
+		func(context.Context, map[string]interface{}) (map[string]interface{}, error) {
+			return nil, yarpcerrors.NotFoundErrorf("no such thing")
+		}))
+	dispatcher.Register(raw.Procedure("whoami",
+		func(ctx context.Context, _ []byte) ([]byte, error) {
+			return []byte(yarpc.CallFromContext(ctx).Caller()), nil
+		}))
+	dispatcher.Register(raw.Procedure("shout",
+		func(_ context.Context, body []byte) ([]byte, error) {
+			return []byte(strings.ToUpper(string(body))), nil
+		}))
+	require.NoError(t, dispatcher.Start())
+	return dispatcher
+}
+
+func serve(h http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	responseRecorder := httptest.NewRecorder()
+	h(responseRecorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return responseRecorder
+}
+
+// invoke posts an invocation to the console, as the console page does.
+func invoke(h http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	responseRecorder := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/debug/yarpc?page=console", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", "http://"+req.Host)
+	h(responseRecorder, req)
+	return responseRecorder
+}
+
+func TestHandlerJSON(t *testing.T) {
+	dispatcher := newTestDispatcher()
+
+	responseRecorder := serve(NewHandler(dispatcher), "GET", "/debug/yarpc?format=json", "")
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+
+	var data tmplData
+	require.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &data))
+	require.Len(t, data.Dispatchers, 1)
+	assert.Equal(t, "test", data.Dispatchers[0].Name)
+	assert.Len(t, data.Dispatchers[0].Outbounds, 2)
+	assert.NotEmpty(t, data.PackageVersions)
+}
+
+func TestConsoleDisabled(t *testing.T) {
+	dispatcher := newTestDispatcher()
+	h := NewHandler(dispatcher)
+
+	assert.Equal(t, http.StatusNotFound, serve(h, "GET", "/debug/yarpc?page=console", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(h, "POST", "/debug/yarpc?page=console", `{"procedure": "echo"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(h, "GET", "/debug/yarpc?page=unknown", "").Code)
+}
+
+func TestConsolePage(t *testing.T) {
+	dispatcher := newConsoleDispatcher(t, "http://127.0.0.1:1234")
+	defer dispatcher.Stop()
+	h := NewHandler(dispatcher, EnableConsole())
+
+	responseRecorder := serve(h, "GET", "/debug/yarpc?page=console", "")
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	out := responseRecorder.Body.String()
+	assert.Contains(t, out, "<td>shout</td>")
+	assert.Contains(t, out, `<option value="other">outbound other (other)</option>`)
+
+	responseRecorder = serve(h, "GET", "/debug/yarpc?page=console&format=json", "")
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	var data consoleData
+	require.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &data))
+	assert.Equal(t, "test", data.Service)
+	assert.Len(t, data.Procedures, 4)
+	assert.Equal(t, []consoleOutbound{{OutboundKey: "other", Service: "other", RPCTypes: []string{"unary"}}}, data.Outbounds)
+}
+
+func TestConsoleInvoke(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Rpc-Header-Procedure", r.Header.Get("Rpc-Procedure"))
+		w.Write([]byte(r.Header.Get("Rpc-Caller") + " to " + r.Header.Get("Rpc-Service") + ": " + string(body)))
+	}))
+	defer server.Close()
+
+	dispatcher := newConsoleDispatcher(t, server.URL)
+	defer dispatcher.Stop()
+	h := NewHandler(dispatcher, EnableConsole())
+
+	tests := []struct {
+		desc       string
+		body       string
+		wantStatus int
+		want       invokeResponse
+	}{
+		{
+			desc:       "local JSON",
+			body:       `{"procedure": "echo", "body": {"hello": "world"}}`,
+			wantStatus: http.StatusOK,
+			want:       invokeResponse{Body: json.RawMessage(`{"hello":"world"}`)},
+		},
+		{
+			desc:       "local raw",
+			body:       `{"procedure": "shout", "encoding": "raw", "body": "hello"}`,
+			wantStatus: http.StatusOK,
+			want:       invokeResponse{Body: json.RawMessage(`"HELLO"`)},
+		},
+		{
+			desc:       "local caller",
+			body:       `{"procedure": "whoami", "encoding": "raw", "body": ""}`,
+			wantStatus: http.StatusOK,
+			want:       invokeResponse{Body: json.RawMessage(`"yarpc-debug-console"`)},
+		},
+		{
+			desc:       "local error",
+			body:       `{"procedure": "fail", "body": {}}`,
+			wantStatus: http.StatusOK,
+			want: invokeResponse{
+				ApplicationError: true,
+				Code:             "not-found",
+				Error:            yarpcerrors.NotFoundErrorf("no such thing").Error(),
+			},
+		},
+		{
+			desc:       "outbound",
+			body:       `{"outbound": "other", "procedure": "ping", "encoding": "raw", "body": "hi", "timeout": "1s"}`,
+			wantStatus: http.StatusOK,
+			want: invokeResponse{
+				Body:    json.RawMessage(`"test to other: hi"`),
+				Headers: map[string]string{"procedure": "ping"},
+			},
+		},
+		{
+			desc:       "unknown outbound",
+			body:       `{"outbound": "nope", "procedure": "ping", "body": {}}`,
+			wantStatus: http.StatusOK,
+			want: invokeResponse{
+				Code:  "invalid-argument",
+				Error: yarpcerrors.InvalidArgumentErrorf(`no unary outbound "nope"`).Error(),
+			},
+		},
+		{
+			desc:       "raw body is not a string",
+			body:       `{"procedure": "shout", "encoding": "raw", "body": {}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			desc:       "invalid timeout",
+			body:       `{"procedure": "echo", "timeout": "soon"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			desc:       "missing procedure",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			desc:       "invalid JSON",
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			responseRecorder := invoke(h, tt.body)
+			require.Equal(t, tt.wantStatus, responseRecorder.Code, responseRecorder.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				var res errorResponse
+				require.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &res))
+				assert.NotEmpty(t, res.Error)
+				return
+			}
+
+			var res invokeResponse
+			require.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &res))
+			assert.NotEmpty(t, res.Duration)
+			res.Duration = ""
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestConsoleRejectsCrossOriginInvocations(t *testing.T) {
+	dispatcher := newConsoleDispatcher(t, "http://127.0.0.1:1234")
+	defer dispatcher.Stop()
+	h := NewHandler(dispatcher, EnableConsole())
+
+	tests := []struct {
+		desc        string
+		contentType string
+		origin      string
+		wantStatus  int
+	}{
+		{
+			desc:        "same origin",
+			contentType: "application/json; charset=utf-8",
+			origin:      "http://example.com",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			desc:        "no origin",
+			contentType: "application/json",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			desc:       "no content type",
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			desc:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			origin:      "http://example.com",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			desc:        "text",
+			contentType: "text/plain",
+			origin:      "http://evil.example",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			desc:        "other origin",
+			contentType: "application/json",
+			origin:      "http://evil.example",
+			wantStatus:  http.StatusForbidden,
+		},
+		{
+			desc:        "other port",
+			contentType: "application/json",
+			origin:      "http://example.com:8080",
+			wantStatus:  http.StatusForbidden,
+		},
+		{
+			desc:        "null origin",
+			contentType: "application/json",
+			origin:      "null",
+			wantStatus:  http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://example.com/debug/yarpc?page=console",
+				strings.NewReader(`{"procedure": "echo", "body": {}}`))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			responseRecorder := httptest.NewRecorder()
+			h(responseRecorder, req)
+			assert.Equal(t, tt.wantStatus, responseRecorder.Code, responseRecorder.Body.String())
+		})
+	}
+}
+
+func TestConsoleEncoding(t *testing.T) {
+	tests := []struct {
+		give    transport.Encoding
+		want    transport.Encoding
+		wantErr bool
+	}{
+		{give: "", want: yarpcjson.Encoding},
+		{give: yarpcjson.Encoding, want: yarpcjson.Encoding},
+		{give: protobuf.Encoding, want: yarpcjson.Encoding},
+		{give: raw.Encoding, want: raw.Encoding},
+		{give: thrift.Encoding, wantErr: true},
+		{give: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := consoleEncoding(tt.give)
+		if tt.wantErr {
+			assert.Error(t, err, "expected an error for %q", tt.give)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
@@ -21,9 +21,11 @@
 package debug
 
 import (
+	"encoding/json"
 	"html/template"
 	"io"
 	"net/http"
+	"net/url"
 	"runtime/debug"
 
 	"go.uber.org/yarpc"
@@ -121,7 +123,7 @@ var (
 		<tr>
 			<td>{{.Transport}}</td>
 			<td>{{.Endpoint}}</td>
-			<td>{{.State}}</td>
+			<td>{{.State}}{{range $name, $count := .Counters}}<br>{{$name}}: {{$count}}{{end}}</td>
 		</tr>
 		{{end}}
 	</table>
@@ -155,14 +157,43 @@ var (
 			<td>{{.Service}}</td>
 			<td>{{.Transport}}</td>
 			<td>{{.RPCType}}</td>
-			<td>{{.Endpoint}}</td>
+			<td>
+				{{.Endpoint}}
+				{{with .Splits}}
+				<ul>
+				{{range .}}
+					<li>{{.Name}} ({{.Outbound.Transport}}): weight {{.Weight}}, {{.Calls}} call(s)</li>
+				{{end}}
+				</ul>
+				{{end}}
+				{{with .TTLs}}
+				<ul>
+				{{range .}}
+					<li>TTL{{with .Procedure}} of {{.}}{{end}}:{{with .Default}} default {{.}}{{end}}{{with .Max}} max {{.}}{{end}}</li>
+				{{end}}
+				</ul>
+				{{end}}
+			</td>
 			<td>{{.State}}</td>
 			<td>{{.Chooser.Name}}</td>
 			<td>{{.Chooser.State}}</td>
 			<td>
 				<ul>
 				{{range .Chooser.Peers}}
-					<li>{{.Identifier}} ({{.State}})</li>
+					<li>
+						{{.Identifier}} ({{.State}})
+						{{with .Stats}}
+						<br />
+						<small>
+							{{.Requests}} request(s), {{.Errors}} error(s)
+							{{range $code, $count := .ErrorsByCode}}
+							[{{$code}}: {{$count}}]
+							{{end}}
+							<br />
+							p50={{.LatencyP50}} p90={{.LatencyP90}} p99={{.LatencyP99}}
+						</small>
+						{{end}}
+					</li>
 				{{end}}
 				</ul>
 			</td>
@@ -170,6 +201,64 @@ var (
 		</tbody>
 		{{end}}
 	</table>
+	<h3>Recent Failures</h3>
+	<table>
+		<tr>
+			<th>Started</th>
+			<th>Direction</th>
+			<th>RPC Type</th>
+			<th>Caller</th>
+			<th>Service</th>
+			<th>Procedure</th>
+			<th>Encoding</th>
+			<th>Duration</th>
+			<th>Code</th>
+			<th>Error</th>
+			<th>Trace ID</th>
+		</tr>
+		{{range .RecentFailures}}
+		<tr>
+			<td>{{.Started.Format "2006-01-02T15:04:05.000Z07:00"}}</td>
+			<td>{{.Direction}}</td>
+			<td>{{.RPCType}}</td>
+			<td>{{.Caller}}</td>
+			<td>{{.Service}}</td>
+			<td>{{.Procedure}}</td>
+			<td>{{.Encoding}}</td>
+			<td>{{.Duration}}</td>
+			<td>{{.Code}}</td>
+			<td>{{.Error}}</td>
+			<td>{{.TraceID}}</td>
+		</tr>
+		{{end}}
+	</table>
+	<h3>Slowest Requests</h3>
+	<table>
+		<tr>
+			<th>Direction</th>
+			<th>Service</th>
+			<th>Procedure</th>
+			<th>Requests</th>
+		</tr>
+		{{range .SlowestRequests}}
+		<tr>
+			<td>{{.Direction}}</td>
+			<td>{{.Service}}</td>
+			<td>{{.Procedure}}</td>
+			<td>
+				<ul>
+				{{range .Requests}}
+					<li>
+						{{.Duration}} from {{.Caller}} at {{.Started.Format "2006-01-02T15:04:05.000Z07:00"}}
+						{{with .Code}}({{.}}){{end}}
+						{{with .TraceID}}<br /><small>trace {{.}}</small>{{end}}
+					</li>
+				{{end}}
+				</ul>
+			</td>
+		</tr>
+		{{end}}
+	</table>
 {{end}}
 	</body>
 </html>
@@ -177,6 +266,10 @@ var (
 )
 
 // NewHandler returns a http.HandlerFunc to expose dispatcher status and package versions.
+//
+// Pages are rendered as HTML, or as JSON when requested with the
+// "format=json" query parameter. With the EnableConsole option, the
+// "page=console" query parameter serves a console to invoke procedures.
 func NewHandler(dispatcher *yarpc.Dispatcher, opts ...Option) http.HandlerFunc {
 	return newHandler(dispatcher, opts...).handle
 }
@@ -185,6 +278,7 @@ type handler struct {
 	dispatcher *yarpc.Dispatcher
 	logger     *zap.Logger
 	tmpl       templateIface
+	console    bool
 }
 
 func newHandler(dispatcher *yarpc.Dispatcher, options ...Option) *handler {
@@ -193,27 +287,77 @@ func newHandler(dispatcher *yarpc.Dispatcher, options ...Option) *handler {
 		dispatcher: dispatcher,
 		logger:     opts.logger,
 		tmpl:       opts.tmpl,
+		console:    opts.console,
 	}
 }
 
-func (h *handler) handle(responseWriter http.ResponseWriter, _ *http.Request) {
+func (h *handler) handle(responseWriter http.ResponseWriter, req *http.Request) {
 	defer func() {
 		if r := recover(); r != nil {
 			responseWriter.WriteHeader(http.StatusInternalServerError)
 			h.logger.Error("Unary handler panicked:", zap.Any("recover", r), zap.ByteString("stacktrace", debug.Stack()))
 		}
 	}()
+
+	var query url.Values
+	if req != nil {
+		query = req.URL.Query()
+	}
+	asJSON := query.Get("format") == "json"
+	switch query.Get("page") {
+	case "":
+		data := newTmplData(h.dispatcher.Introspect())
+		if asJSON {
+			h.writeJSON(responseWriter, http.StatusOK, data)
+			return
+		}
+		h.writeHTML(responseWriter, h.tmpl, data)
+	case "console":
+		if !h.console {
+			http.NotFound(responseWriter, req)
+			return
+		}
+		if req.Method == http.MethodPost {
+			h.invoke(responseWriter, req)
+			return
+		}
+		data := newConsoleData(h.dispatcher.Introspect())
+		if asJSON {
+			h.writeJSON(responseWriter, http.StatusOK, data)
+			return
+		}
+		h.writeHTML(responseWriter, _consoleTmpl, data)
+	default:
+		http.NotFound(responseWriter, req)
+	}
+}
+
+func (h *handler) writeHTML(responseWriter http.ResponseWriter, tmpl templateIface, data interface{}) {
 	responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := h.tmpl.Execute(responseWriter, newTmplData(h.dispatcher.Introspect())); err != nil {
+	if err := tmpl.Execute(responseWriter, data); err != nil {
 		// TODO: does this work, since we already tried a write?
 		responseWriter.WriteHeader(http.StatusInternalServerError)
 		h.logger.Error("yarpc/debug: failed executing template", zap.Error(err))
 	}
 }
 
+func (h *handler) writeJSON(responseWriter http.ResponseWriter, status int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		h.logger.Error("yarpc/debug: failed marshaling JSON", zap.Error(err))
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(status)
+	if _, err := responseWriter.Write(body); err != nil {
+		h.logger.Error("yarpc/debug: failed writing JSON", zap.Error(err))
+	}
+}
+
 type tmplData struct {
-	Dispatchers     []introspection.DispatcherStatus
-	PackageVersions []introspection.PackageVersion
+	Dispatchers     []introspection.DispatcherStatus `json:"dispatchers"`
+	PackageVersions []introspection.PackageVersion   `json:"packageVersions"`
 }
 
 func newTmplData(dispatcherStatus introspection.DispatcherStatus) *tmplData {
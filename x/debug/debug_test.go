@@ -21,6 +21,7 @@
 package debug
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
@@ -28,10 +29,13 @@ import (
 	"net/http/httptest"
 	"testing"
 	"text/template"
+	"time"
 
 	"go.uber.org/yarpc"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/internal/introspection"
 	yarpchttp "go.uber.org/yarpc/transport/http"
 )
 
@@ -76,6 +80,90 @@ func TestHandlerError(t *testing.T) {
 	require.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
 }
 
+func TestDefaultTemplatePeerStats(t *testing.T) {
+	data := newTmplData(introspection.DispatcherStatus{
+		Name: "test",
+		Outbounds: []introspection.OutboundStatus{{
+			OutboundKey: "test-client",
+			Chooser: introspection.ChooserStatus{
+				Name: "RoundRobin",
+				Peers: []introspection.PeerStatus{{
+					Identifier: "127.0.0.1:1234",
+					State:      "Available, 0 pending request(s)",
+					Stats: &introspection.PeerStats{
+						Requests:     10,
+						Errors:       2,
+						ErrorsByCode: map[string]int64{"unavailable": 2},
+						LatencyP50:   5 * time.Millisecond,
+						LatencyP90:   20 * time.Millisecond,
+						LatencyP99:   time.Second,
+					},
+				}},
+			},
+		}},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, _defaultTmpl.Execute(&buf, data))
+	out := buf.String()
+	assert.Contains(t, out, "10 request(s), 2 error(s)")
+	assert.Contains(t, out, "[unavailable: 2]")
+	assert.Contains(t, out, "p50=5ms p90=20ms p99=1s")
+}
+
+func TestDefaultTemplateTTLs(t *testing.T) {
+	data := newTmplData(introspection.DispatcherStatus{
+		Name: "test",
+		Outbounds: []introspection.OutboundStatus{{
+			OutboundKey: "test-client",
+			TTLs: []introspection.TTLStatus{
+				{Default: time.Second, Max: 10 * time.Second},
+				{Procedure: "scan", Max: time.Minute},
+			},
+		}},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, _defaultTmpl.Execute(&buf, data))
+	out := buf.String()
+	assert.Contains(t, out, "TTL: default 1s max 10s")
+	assert.Contains(t, out, "TTL of scan: max 1m0s")
+}
+
+func TestDefaultTemplateRequests(t *testing.T) {
+	started := time.Date(2017, 10, 1, 12, 30, 0, 0, time.UTC)
+	data := newTmplData(introspection.DispatcherStatus{
+		Name: "test",
+		RecentFailures: []introspection.RequestStatus{{
+			Direction: "outbound",
+			Service:   "users",
+			Procedure: "getUser",
+			Started:   started,
+			Duration:  5 * time.Millisecond,
+			Code:      "unavailable",
+			Error:     "no peers available",
+			TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		}},
+		SlowestRequests: []introspection.SlowRequestsStatus{{
+			Direction: "inbound",
+			Service:   "test",
+			Procedure: "scan",
+			Requests: []introspection.RequestStatus{{
+				Caller:   "batch",
+				Started:  started,
+				Duration: 2 * time.Second,
+			}},
+		}},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, _defaultTmpl.Execute(&buf, data))
+	out := buf.String()
+	assert.Contains(t, out, "<td>no peers available</td>")
+	assert.Contains(t, out, "<td>4bf92f3577b34da6a3ce929d0e0e4736</td>")
+	assert.Contains(t, out, "2s from batch at 2017-10-01T12:30:00.000Z")
+}
+
 func newTestDispatcher() *yarpc.Dispatcher {
 	httpTransport := yarpchttp.NewTransport()
 	return yarpc.NewDispatcher(yarpc.Config{
@@ -31,8 +31,9 @@ type optionFunc func(*options)
 
 // opts represents the combined options supplied by the user.
 type options struct {
-	logger *zap.Logger
-	tmpl   templateIface
+	logger  *zap.Logger
+	tmpl    templateIface
+	console bool
 }
 
 // Logger specifies the logger that should be used to log.
@@ -43,6 +44,24 @@ func Logger(logger *zap.Logger) Option {
 	})
 }
 
+// EnableConsole serves an interactive console on the "page=console" query
+// parameter, which lists the registered procedures and invokes procedures
+// with raw or JSON bodies, either locally or through the dispatcher's
+// outbounds. Protobuf procedures are invoked with JSON bodies.
+//
+// Requests to the dispatcher's own procedures have the ConsoleCaller caller
+// name, so that handlers and middleware can tell them apart, and requests
+// through outbounds have the caller name of the outbound. The console only
+// accepts invocations sent as JSON from its own origin.
+//
+// The console is disabled by default: anyone who can reach the handler can
+// make requests on behalf of the service with it.
+func EnableConsole() Option {
+	return optionFunc(func(opts *options) {
+		opts.console = true
+	})
+}
+
 // tmpl specifies the template to use.
 // It is only used for testing.
 func tmpl(tmpl templateIface) Option {
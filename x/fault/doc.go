@@ -0,0 +1,52 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package fault provides EXPERIMENTAL middleware that injects faults into
+// requests, to test how services and their callers cope with failures.
+//
+// An Injector delays requests, fails them with an error of a chosen code, or
+// drops oneway requests, according to rules matching their direction,
+// caller, service, procedure and headers. Rules apply to a percentage of the
+// requests they match, and expire after a TTL.
+//
+// 	injector := fault.NewInjector()
+// 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+// 		InboundMiddleware: yarpc.InboundMiddleware{
+// 			Unary:  injector,
+// 			Oneway: injector,
+// 		},
+// 		...
+// 	})
+// 	fault.Register(dispatcher, injector, fault.AllowChanges(authorize))
+//
+// Register exposes a JSON procedure to list the rules and, with AllowChanges,
+// procedures to change them at runtime, without redeploying the service.
+// Since changing rules can take the service down, every change is checked by
+// the given authorization function. For example, calling yarpc::faults::set
+// with
+//
+// 	{"name": "slow-get", "procedure": "KeyValue::get*", "percentage": 10, "delay": "500ms", "ttl": "15m"}
+//
+// delays 10% of the calls to the getters of the KeyValue service by half a
+// second, for the next 15 minutes.
+//
+// Faults are never injected into inbound requests to procedures whose names
+// start with "yarpc::", so that rules can always be removed.
+package fault
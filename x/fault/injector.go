@@ -0,0 +1,272 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// _metaPrefix is the prefix of the procedures registered by YARPC itself,
+// including those which control the injector, into which faults are never
+// injected.
+const _metaPrefix = "yarpc::"
+
+// InjectorOption customizes the behavior of an Injector.
+type InjectorOption interface {
+	apply(*injectorOptions)
+}
+
+type injectorOptionFunc func(*injectorOptions)
+
+func (f injectorOptionFunc) apply(opts *injectorOptions) { f(opts) }
+
+type injectorOptions struct {
+	defaultTTL  time.Duration
+	clock       clock.Clock
+	randFloat64 func() float64
+}
+
+var defaultInjectorOptions = injectorOptions{
+	defaultTTL:  10 * time.Minute,
+	clock:       clock.NewReal(),
+	randFloat64: rand.Float64,
+}
+
+// DefaultTTL sets the duration after which rules set without a TTL expire.
+// Defaults to 10 minutes.
+func DefaultTTL(ttl time.Duration) InjectorOption {
+	return injectorOptionFunc(func(opts *injectorOptions) {
+		opts.defaultTTL = ttl
+	})
+}
+
+// withClock replaces the clock used to delay requests and expire rules.
+func withClock(c clock.Clock) InjectorOption {
+	return injectorOptionFunc(func(opts *injectorOptions) {
+		opts.clock = c
+	})
+}
+
+// withRand replaces the source of randomness used to pick requests.
+func withRand(f func() float64) InjectorOption {
+	return injectorOptionFunc(func(opts *injectorOptions) {
+		opts.randFloat64 = f
+	})
+}
+
+// Injector is inbound and outbound middleware which injects faults into
+// requests according to rules that may be changed at runtime.
+type Injector struct {
+	opts injectorOptions
+
+	mu    sync.Mutex
+	rules []ActiveRule
+}
+
+var (
+	_ middleware.UnaryInbound   = (*Injector)(nil)
+	_ middleware.OnewayInbound  = (*Injector)(nil)
+	_ middleware.UnaryOutbound  = (*Injector)(nil)
+	_ middleware.OnewayOutbound = (*Injector)(nil)
+)
+
+// NewInjector builds an Injector without rules.
+func NewInjector(opts ...InjectorOption) *Injector {
+	options := defaultInjectorOptions
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &Injector{opts: options}
+}
+
+// Set adds a rule to the injector, replacing the rule with the same name if
+// any. Rules are evaluated in the order in which they were first set, and
+// only the first rule matching a request applies to it.
+func (i *Injector) Set(r Rule) (ActiveRule, error) {
+	if err := r.validate(); err != nil {
+		return ActiveRule{}, err
+	}
+	if r.TTL == 0 {
+		r.TTL = i.opts.defaultTTL
+	}
+	active := ActiveRule{Rule: r, Expires: i.opts.clock.Now().Add(r.TTL)}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for j, existing := range i.rules {
+		if existing.Name == r.Name {
+			i.rules[j] = active
+			return active, nil
+		}
+	}
+	i.rules = append(i.rules, active)
+	return active, nil
+}
+
+// Remove removes the rule with the given name, and reports whether it
+// existed.
+func (i *Injector) Remove(name string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.expire()
+	for j, r := range i.rules {
+		if r.Name == name {
+			i.rules = append(i.rules[:j], i.rules[j+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the rules of the injector that have not expired.
+func (i *Injector) Rules() []ActiveRule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.expire()
+	rules := make([]ActiveRule, len(i.rules))
+	copy(rules, i.rules)
+	return rules
+}
+
+// expire removes expired rules. It must be called with the lock held.
+func (i *Injector) expire() {
+	now := i.opts.clock.Now()
+	rules := i.rules[:0]
+	for _, r := range i.rules {
+		if now.Before(r.Expires) {
+			rules = append(rules, r)
+		}
+	}
+	// Let removed rules be garbage collected.
+	for j := len(rules); j < len(i.rules); j++ {
+		i.rules[j] = ActiveRule{}
+	}
+	i.rules = rules
+}
+
+// pick returns the rule that applies to the request, if any.
+func (i *Injector) pick(dir Direction, req *transport.Request) *Rule {
+	if dir == Inbound && strings.HasPrefix(req.Procedure, _metaPrefix) {
+		return nil
+	}
+
+	i.mu.Lock()
+	if len(i.rules) == 0 {
+		i.mu.Unlock()
+		return nil
+	}
+	i.expire()
+	var rule *Rule
+	for _, r := range i.rules {
+		if r.matches(dir, req) {
+			rule = &r.Rule
+			break
+		}
+	}
+	i.mu.Unlock()
+
+	if rule == nil || i.opts.randFloat64()*100 >= rule.Percentage {
+		return nil
+	}
+	return rule
+}
+
+// inject injects the faults of the rule that applies to the request, if
+// any. It reports whether a oneway request should be dropped.
+func (i *Injector) inject(ctx context.Context, dir Direction, req *transport.Request, oneway bool) (drop bool, err error) {
+	r := i.pick(dir, req)
+	if r == nil {
+		return false, nil
+	}
+
+	if r.Delay > 0 {
+		t := i.opts.clock.Timer(r.Delay)
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			t.Stop()
+			if ctx.Err() == context.Canceled {
+				return false, yarpcerrors.CancelledErrorf("cancelled during delay injected by rule %q", r.Name)
+			}
+			return false, yarpcerrors.DeadlineExceededErrorf("timed out during delay injected by rule %q", r.Name)
+		}
+	}
+
+	if r.Abort != yarpcerrors.CodeOK {
+		return false, yarpcerrors.FromHeaders(r.Abort, "", fmt.Sprintf("fault injected by rule %q", r.Name))
+	}
+	return oneway && r.Drop, nil
+}
+
+// Handle injects faults into unary inbound requests.
+func (i *Injector) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	if _, err := i.inject(ctx, Inbound, req, false /* oneway */); err != nil {
+		return err
+	}
+	return h.Handle(ctx, req, resw)
+}
+
+// HandleOneway injects faults into oneway inbound requests.
+func (i *Injector) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	drop, err := i.inject(ctx, Inbound, req, true /* oneway */)
+	if err != nil || drop {
+		return err
+	}
+	return h.HandleOneway(ctx, req)
+}
+
+// Call injects faults into unary outbound calls.
+func (i *Injector) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	if _, err := i.inject(ctx, Outbound, req, false /* oneway */); err != nil {
+		return nil, err
+	}
+	return out.Call(ctx, req)
+}
+
+// CallOneway injects faults into oneway outbound calls.
+func (i *Injector) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	drop, err := i.inject(ctx, Outbound, req, true /* oneway */)
+	if err != nil {
+		return nil, err
+	}
+	if drop {
+		return droppedAck{}, nil
+	}
+	return out.CallOneway(ctx, req)
+}
+
+// droppedAck acknowledges oneway calls that were dropped.
+type droppedAck struct{}
+
+func (droppedAck) String() string { return "dropped by fault injection" }
@@ -0,0 +1,240 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func TestRuleValidation(t *testing.T) {
+	tests := []struct {
+		msg     string
+		rule    Rule
+		wantErr string
+	}{
+		{
+			msg:     "no name",
+			rule:    Rule{Abort: yarpcerrors.CodeInternal},
+			wantErr: "rule has no name",
+		},
+		{
+			msg:     "invalid direction",
+			rule:    Rule{Name: "a", Direction: "sideways", Abort: yarpcerrors.CodeInternal},
+			wantErr: `rule "a" has invalid direction "sideways"`,
+		},
+		{
+			msg:     "invalid percentage",
+			rule:    Rule{Name: "a", Percentage: 101, Abort: yarpcerrors.CodeInternal},
+			wantErr: `rule "a" has invalid percentage 101`,
+		},
+		{
+			msg:     "negative delay",
+			rule:    Rule{Name: "a", Delay: -time.Second},
+			wantErr: `rule "a" may not have negative durations`,
+		},
+		{
+			msg:     "no faults",
+			rule:    Rule{Name: "a", Percentage: 100},
+			wantErr: `rule "a" injects no faults`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			_, err := NewInjector().Set(tt.rule)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	req := &transport.Request{
+		Caller:    "frontend",
+		Service:   "keyvalue",
+		Procedure: "KeyValue::getValue",
+		Headers:   transport.NewHeaders().With("x-gameday", "true"),
+	}
+
+	tests := []struct {
+		msg  string
+		rule Rule
+		dir  Direction
+		want bool
+	}{
+		{msg: "empty", want: true},
+		{msg: "same direction", rule: Rule{Direction: Inbound}, dir: Inbound, want: true},
+		{msg: "other direction", rule: Rule{Direction: Outbound}, dir: Inbound},
+		{msg: "caller", rule: Rule{Caller: "frontend"}, want: true},
+		{msg: "other caller", rule: Rule{Caller: "backend"}},
+		{msg: "service", rule: Rule{Service: "key*"}, want: true},
+		{msg: "procedure", rule: Rule{Procedure: "KeyValue::get*"}, want: true},
+		{msg: "other procedure", rule: Rule{Procedure: "KeyValue::set*"}},
+		{msg: "header", rule: Rule{Headers: map[string]string{"X-Gameday": "true"}}, want: true},
+		{msg: "other header value", rule: Rule{Headers: map[string]string{"x-gameday": "false"}}},
+		{msg: "missing header", rule: Rule{Headers: map[string]string{"x-other": "true"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			dir := tt.dir
+			if dir == "" {
+				dir = Outbound
+			}
+			assert.Equal(t, tt.want, tt.rule.matches(dir, req))
+		})
+	}
+}
+
+func TestSetAndExpire(t *testing.T) {
+	fc := clock.NewFake()
+	injector := NewInjector(withClock(fc), DefaultTTL(time.Minute))
+
+	_, err := injector.Set(Rule{Name: "a", Abort: yarpcerrors.CodeInternal})
+	require.NoError(t, err)
+	_, err = injector.Set(Rule{Name: "b", Abort: yarpcerrors.CodeInternal, TTL: time.Hour})
+	require.NoError(t, err)
+	active, err := injector.Set(Rule{Name: "a", Abort: yarpcerrors.CodeUnavailable, TTL: 2 * time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, fc.Now().Add(2*time.Minute), active.Expires)
+
+	rules := injector.Rules()
+	require.Len(t, rules, 2)
+	assert.Equal(t, "a", rules[0].Name, "replaced rules keep their position")
+	assert.Equal(t, yarpcerrors.CodeUnavailable, rules[0].Abort)
+	assert.Equal(t, "b", rules[1].Name)
+
+	fc.Add(2 * time.Minute)
+	rules = injector.Rules()
+	require.Len(t, rules, 1)
+	assert.Equal(t, "b", rules[0].Name)
+
+	assert.True(t, injector.Remove("b"))
+	assert.False(t, injector.Remove("b"))
+	assert.Empty(t, injector.Rules())
+}
+
+func TestAbort(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	sample := 0.5
+	injector := NewInjector(withRand(func() float64 { return sample }))
+	_, err := injector.Set(Rule{
+		Name:       "unavailable",
+		Procedure:  "KeyValue::*",
+		Percentage: 25,
+		Abort:      yarpcerrors.CodeUnavailable,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	req := &transport.Request{Service: "keyvalue", Procedure: "KeyValue::getValue"}
+	resw := &transporttest.FakeResponseWriter{}
+
+	// Requests outside the percentage are not affected.
+	handler := transporttest.NewMockUnaryHandler(mockCtrl)
+	handler.EXPECT().Handle(ctx, req, resw).Return(nil)
+	require.NoError(t, injector.Handle(ctx, req, resw, handler))
+
+	sample = 0.2
+	wantErr := yarpcerrors.UnavailableErrorf(`fault injected by rule "unavailable"`)
+
+	assert.Equal(t, wantErr, injector.Handle(ctx, req, resw, handler))
+	assert.Equal(t, wantErr, injector.HandleOneway(ctx, req, transporttest.NewMockOnewayHandler(mockCtrl)))
+	_, err = injector.Call(ctx, req, transporttest.NewMockUnaryOutbound(mockCtrl))
+	assert.Equal(t, wantErr, err)
+	_, err = injector.CallOneway(ctx, req, transporttest.NewMockOnewayOutbound(mockCtrl))
+	assert.Equal(t, wantErr, err)
+
+	// Meta procedures are never affected.
+	metaReq := &transport.Request{Service: "keyvalue", Procedure: "yarpc::faults::remove"}
+	_, err = injector.Set(Rule{Name: "everything", Percentage: 100, Abort: yarpcerrors.CodeInternal})
+	require.NoError(t, err)
+	handler.EXPECT().Handle(ctx, metaReq, resw).Return(nil)
+	require.NoError(t, injector.Handle(ctx, metaReq, resw, handler))
+}
+
+func TestDrop(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	injector := NewInjector()
+	_, err := injector.Set(Rule{Name: "drop", Percentage: 100, Drop: true})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	req := &transport.Request{Service: "keyvalue", Procedure: "KeyValue::setValue"}
+
+	// Dropping has no effect on unary requests.
+	resw := &transporttest.FakeResponseWriter{}
+	handler := transporttest.NewMockUnaryHandler(mockCtrl)
+	handler.EXPECT().Handle(ctx, req, resw).Return(nil)
+	require.NoError(t, injector.Handle(ctx, req, resw, handler))
+
+	// Oneway handlers and outbounds are not called.
+	require.NoError(t, injector.HandleOneway(ctx, req, transporttest.NewMockOnewayHandler(mockCtrl)))
+	ack, err := injector.CallOneway(ctx, req, transporttest.NewMockOnewayOutbound(mockCtrl))
+	require.NoError(t, err)
+	assert.NotNil(t, ack)
+}
+
+func TestDelay(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	injector := NewInjector()
+	_, err := injector.Set(Rule{Name: "slow", Percentage: 100, Delay: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	req := &transport.Request{Service: "keyvalue", Procedure: "KeyValue::getValue"}
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(ctx, req).Return(&transport.Response{}, nil)
+
+	start := time.Now()
+	_, err = injector.Call(ctx, req, out)
+	require.NoError(t, err)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond, "expected call to be delayed")
+
+	_, err = injector.Set(Rule{Name: "slow", Percentage: 100, Delay: time.Hour})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = injector.Call(ctx, req, out)
+	assert.True(t, yarpcerrors.IsDeadlineExceeded(err), "unexpected error: %v", err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = injector.Call(ctx, req, out)
+	assert.True(t, yarpcerrors.IsCancelled(err), "unexpected error: %v", err)
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// RegisterOption customizes the procedures registered by Register.
+type RegisterOption interface {
+	apply(*service)
+}
+
+type registerOptionFunc func(*service)
+
+func (f registerOptionFunc) apply(s *service) { f(s) }
+
+// AllowChanges registers the procedures which set and remove rules, and
+// authorizes every call to them with the given function. Calls for which it
+// returns an error are rejected with that error, or with a PermissionDenied
+// error if it is not a YARPC error.
+//
+// The function will usually check the verified principal of the request
+// (see yarpc.CallFromContext). The caller name is chosen by the client, so
+// checking it alone does not keep anyone from injecting faults.
+func AllowChanges(authorize func(ctx context.Context) error) RegisterOption {
+	return registerOptionFunc(func(s *service) {
+		s.authorize = authorize
+	})
+}
+
+// Register registers a procedure on a dispatcher to list the rules of an
+// injector.
+//
+// 	yarpc::faults() {"rules": [{"name": "...", ...}]}
+//
+// With the AllowChanges option, it also registers procedures to set and
+// remove rules at runtime.
+//
+// 	yarpc::faults::set({"name": "...", "procedure": "...", "percentage": 10, "delay": "100ms", "abort": "unavailable", "ttl": "5m"})
+// 	yarpc::faults::remove({"name": "..."}) {"removed": true}
+//
+// Anyone who may call these can fail or stall every request of the service,
+// so they are only registered with an explicit authorization function.
+func Register(d *yarpc.Dispatcher, i *Injector, opts ...RegisterOption) {
+	s := &service{injector: i}
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+	d.Register(s.Procedures())
+}
+
+// service exposes the rules of an injector via Procedures().
+type service struct {
+	injector *Injector
+
+	// authorize authorizes changes to the rules. Rules cannot be changed if
+	// it is nil.
+	authorize func(context.Context) error
+}
+
+// jsonRule is the JSON representation of a rule, with durations such as
+// "1.5s".
+type jsonRule struct {
+	Name       string            `json:"name"`
+	Direction  Direction         `json:"direction,omitempty"`
+	Caller     string            `json:"caller,omitempty"`
+	Service    string            `json:"service,omitempty"`
+	Procedure  string            `json:"procedure,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Percentage float64           `json:"percentage"`
+	Delay      string            `json:"delay,omitempty"`
+	Abort      *yarpcerrors.Code `json:"abort,omitempty"`
+	Drop       bool              `json:"drop,omitempty"`
+	TTL        string            `json:"ttl,omitempty"`
+	Expires    *time.Time        `json:"expires,omitempty"`
+}
+
+func (r *jsonRule) toRule() (Rule, error) {
+	rule := Rule{
+		Name:       r.Name,
+		Direction:  r.Direction,
+		Caller:     r.Caller,
+		Service:    r.Service,
+		Procedure:  r.Procedure,
+		Headers:    r.Headers,
+		Percentage: r.Percentage,
+		Drop:       r.Drop,
+	}
+	if r.Abort != nil {
+		rule.Abort = *r.Abort
+	}
+
+	var err error
+	if r.Delay != "" {
+		if rule.Delay, err = time.ParseDuration(r.Delay); err != nil {
+			return Rule{}, err
+		}
+	}
+	if r.TTL != "" {
+		if rule.TTL, err = time.ParseDuration(r.TTL); err != nil {
+			return Rule{}, err
+		}
+	}
+	return rule, nil
+}
+
+func fromActiveRule(r ActiveRule) jsonRule {
+	expires := r.Expires
+	rule := jsonRule{
+		Name:       r.Name,
+		Direction:  r.Direction,
+		Caller:     r.Caller,
+		Service:    r.Service,
+		Procedure:  r.Procedure,
+		Headers:    r.Headers,
+		Percentage: r.Percentage,
+		Drop:       r.Drop,
+		TTL:        r.TTL.String(),
+		Expires:    &expires,
+	}
+	if r.Delay > 0 {
+		rule.Delay = r.Delay.String()
+	}
+	if r.Abort != yarpcerrors.CodeOK {
+		abort := r.Abort
+		rule.Abort = &abort
+	}
+	return rule
+}
+
+type rulesResponse struct {
+	Rules []jsonRule `json:"rules"`
+}
+
+func (s *service) list(ctx context.Context, body interface{}) (*rulesResponse, error) {
+	active := s.injector.Rules()
+	rules := make([]jsonRule, 0, len(active))
+	for _, r := range active {
+		rules = append(rules, fromActiveRule(r))
+	}
+	return &rulesResponse{Rules: rules}, nil
+}
+
+// authorizeChange checks that the caller may change the rules.
+func (s *service) authorizeChange(ctx context.Context) error {
+	if s.authorize == nil {
+		return yarpcerrors.PermissionDeniedErrorf("fault injection rules cannot be changed")
+	}
+	err := s.authorize(ctx)
+	if err != nil && !yarpcerrors.IsYARPCError(err) {
+		return yarpcerrors.PermissionDeniedErrorf("not allowed to change fault injection rules: %v", err)
+	}
+	return err
+}
+
+func (s *service) set(ctx context.Context, body *jsonRule) (*jsonRule, error) {
+	if err := s.authorizeChange(ctx); err != nil {
+		return nil, err
+	}
+	rule, err := body.toRule()
+	if err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf("invalid rule: %v", err)
+	}
+	active, err := s.injector.Set(rule)
+	if err != nil {
+		return nil, yarpcerrors.InvalidArgumentErrorf("invalid rule: %v", err)
+	}
+	res := fromActiveRule(active)
+	return &res, nil
+}
+
+type removeRequest struct {
+	Name string `json:"name"`
+}
+
+type removeResponse struct {
+	Removed bool `json:"removed"`
+}
+
+func (s *service) remove(ctx context.Context, body *removeRequest) (*removeResponse, error) {
+	if err := s.authorizeChange(ctx); err != nil {
+		return nil, err
+	}
+	return &removeResponse{Removed: s.injector.Remove(body.Name)}, nil
+}
+
+// Procedures returns the procedures to register on a dispatcher.
+func (s *service) Procedures() []transport.Procedure {
+	type method struct {
+		Name      string
+		Handler   interface{}
+		Signature string
+	}
+	methods := []method{
+		{"yarpc::faults", s.list,
+			`faults() {"rules": [{"name": "...", "percentage": 0, "expires": "..."}]}`},
+	}
+	if s.authorize != nil {
+		methods = append(methods,
+			method{"yarpc::faults::set", s.set,
+				`set({"name": "...", "procedure": "...", "percentage": 0, "delay": "...", "abort": "...", "ttl": "..."}) {...}`},
+			method{"yarpc::faults::remove", s.remove,
+				`remove({"name": "..."}) {"removed": false}`},
+		)
+	}
+	var r []transport.Procedure
+	for _, m := range methods {
+		p := json.Procedure(m.Name, m.Handler)[0]
+		p.Signature = m.Signature
+		r = append(r, p)
+	}
+	return r
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/internal/clock"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func TestRegister(t *testing.T) {
+	procedures := func(opts ...RegisterOption) []string {
+		disp := yarpc.NewDispatcher(yarpc.Config{Name: "myservice"})
+		Register(disp, NewInjector(), opts...)
+
+		var names []string
+		for _, p := range disp.Router().Procedures() {
+			if strings.HasPrefix(p.Name, "yarpc::faults") {
+				names = append(names, p.Name)
+			}
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"yarpc::faults"}, procedures(),
+		"only the listing should be registered by default")
+
+	allowAll := AllowChanges(func(context.Context) error { return nil })
+	assert.Equal(t,
+		[]string{"yarpc::faults", "yarpc::faults::remove", "yarpc::faults::set"},
+		procedures(allowAll))
+}
+
+func TestServiceAuthorize(t *testing.T) {
+	ctx := context.Background()
+	rule := &jsonRule{Name: "drop", Percentage: 100, Drop: true}
+
+	s := &service{injector: NewInjector()}
+	_, err := s.set(ctx, rule)
+	assert.True(t, yarpcerrors.IsPermissionDenied(err), "expected PermissionDenied, got %v", err)
+
+	s.authorize = func(context.Context) error { return errors.New("not an admin") }
+	_, err = s.set(ctx, rule)
+	assert.True(t, yarpcerrors.IsPermissionDenied(err), "expected PermissionDenied, got %v", err)
+	_, err = s.remove(ctx, &removeRequest{Name: "drop"})
+	assert.True(t, yarpcerrors.IsPermissionDenied(err), "expected PermissionDenied, got %v", err)
+
+	s.authorize = func(context.Context) error { return yarpcerrors.UnauthenticatedErrorf("who are you") }
+	_, err = s.set(ctx, rule)
+	assert.True(t, yarpcerrors.IsUnauthenticated(err), "expected Unauthenticated, got %v", err)
+
+	assert.Empty(t, s.injector.Rules())
+}
+
+func TestService(t *testing.T) {
+	fc := clock.NewFake()
+	s := &service{
+		injector:  NewInjector(withClock(fc)),
+		authorize: func(context.Context) error { return nil },
+	}
+	ctx := context.Background()
+
+	abort := yarpcerrors.CodeUnavailable
+	res, err := s.set(ctx, &jsonRule{
+		Name:       "slow-get",
+		Procedure:  "KeyValue::get*",
+		Percentage: 10,
+		Delay:      "500ms",
+		Abort:      &abort,
+		TTL:        "15m",
+	})
+	require.NoError(t, err)
+	expires := fc.Now().Add(15 * time.Minute)
+	want := jsonRule{
+		Name:       "slow-get",
+		Procedure:  "KeyValue::get*",
+		Percentage: 10,
+		Delay:      "500ms",
+		Abort:      &abort,
+		TTL:        "15m0s",
+		Expires:    &expires,
+	}
+	assert.Equal(t, &want, res)
+
+	list, err := s.list(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &rulesResponse{Rules: []jsonRule{want}}, list)
+
+	_, err = s.set(ctx, &jsonRule{Name: "bad", Delay: "soon"})
+	assert.True(t, yarpcerrors.IsInvalidArgument(err), "expected InvalidArgument, got %v", err)
+	_, err = s.set(ctx, &jsonRule{Name: "bad"})
+	assert.True(t, yarpcerrors.IsInvalidArgument(err), "expected InvalidArgument, got %v", err)
+
+	removed, err := s.remove(ctx, &removeRequest{Name: "slow-get"})
+	require.NoError(t, err)
+	assert.True(t, removed.Removed)
+
+	list, err = s.list(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, list.Rules)
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/glob"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Direction restricts rules to inbound requests or outbound calls.
+type Direction string
+
+const (
+	// Inbound rules apply to requests received by the service.
+	Inbound Direction = "inbound"
+
+	// Outbound rules apply to calls made by the service.
+	Outbound Direction = "outbound"
+)
+
+// Rule injects faults into a percentage of the requests it matches.
+type Rule struct {
+	// Name of the rule. Setting a rule replaces the rule with the same name.
+	Name string
+
+	// Restricts the rule to inbound requests or outbound calls. By default,
+	// the rule applies to both.
+	Direction Direction
+
+	// Patterns that the caller, service and procedure of requests must
+	// match, in which '*' matches any sequence of characters. Empty patterns
+	// match all requests.
+	Caller    string
+	Service   string
+	Procedure string
+
+	// Headers that requests must carry, with the given values.
+	Headers map[string]string
+
+	// Percentage of the matching requests into which faults are injected,
+	// between 0 and 100.
+	Percentage float64
+
+	// Delays requests by this duration before handling or sending them.
+	Delay time.Duration
+
+	// Fails requests with an error of this code, unless it is CodeOK.
+	Abort yarpcerrors.Code
+
+	// Drops oneway requests: inbound requests are not handled and outbound
+	// calls are not sent, but both succeed.
+	Drop bool
+
+	// Duration after which the rule expires. Defaults to the default TTL of
+	// the Injector.
+	TTL time.Duration
+}
+
+func (r *Rule) validate() error {
+	if r.Name == "" {
+		return errors.New("rule has no name")
+	}
+	if r.Direction != "" && r.Direction != Inbound && r.Direction != Outbound {
+		return fmt.Errorf("rule %q has invalid direction %q: must be %q or %q", r.Name, r.Direction, Inbound, Outbound)
+	}
+	if r.Percentage < 0 || r.Percentage > 100 {
+		return fmt.Errorf("rule %q has invalid percentage %v: must be between 0 and 100", r.Name, r.Percentage)
+	}
+	if r.Delay < 0 || r.TTL < 0 {
+		return fmt.Errorf("rule %q may not have negative durations", r.Name)
+	}
+	if r.Delay == 0 && r.Abort == yarpcerrors.CodeOK && !r.Drop {
+		return fmt.Errorf("rule %q injects no faults: it must delay, abort or drop requests", r.Name)
+	}
+	return nil
+}
+
+func (r *Rule) matches(dir Direction, req *transport.Request) bool {
+	if r.Direction != "" && r.Direction != dir {
+		return false
+	}
+	if !matchPattern(r.Caller, req.Caller) ||
+		!matchPattern(r.Service, req.Service) ||
+		!matchPattern(r.Procedure, req.Procedure) {
+		return false
+	}
+	for k, v := range r.Headers {
+		if got, ok := req.Headers.Get(k); !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPattern reports whether s matches the pattern. Every string matches
+// an empty pattern.
+func matchPattern(pattern, s string) bool {
+	return pattern == "" || glob.Match(pattern, s)
+}
+
+// ActiveRule is a rule that has been set on an Injector.
+type ActiveRule struct {
+	Rule
+
+	// Time at which the rule expires.
+	Expires time.Time
+}
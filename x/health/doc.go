@@ -0,0 +1,47 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package health provides EXPERIMENTAL health checking of dispatchers, for
+// load balancers and Kubernetes probes.
+//
+// A Server reports whether a dispatcher is serving, not serving or
+// draining, along with the health of the dependencies registered by the
+// application.
+//
+// 	healthServer := health.Register(dispatcher)
+// 	healthServer.AddCheck("database", func(ctx context.Context) error {
+// 		return db.PingContext(ctx)
+// 	})
+//
+// Its status is available over every transport through the "yarpc::health"
+// procedure, and over plain HTTP GET requests, which succeed only while the
+// dispatcher is serving.
+//
+// 	mux.Handle("/health", healthServer)
+//
+// The Server also implements the Check method of the standard
+// grpc.health.v1.Health service, so that stock gRPC tooling, like
+// grpc_health_probe, can check dispatchers with gRPC inbounds.
+//
+// Before stopping a dispatcher, mark it as draining so that load balancers
+// stop sending it requests.
+//
+// 	healthServer.SetStatus(health.Draining)
+package health
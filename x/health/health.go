@@ -0,0 +1,271 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc"
+)
+
+const _defaultCheckTimeout = time.Second
+
+// Status is the health status of a dispatcher.
+type Status int
+
+const (
+	// Serving dispatchers accept requests.
+	Serving Status = iota
+	// NotServing dispatchers shouldn't be sent requests, because they or
+	// one of their dependencies are unhealthy.
+	NotServing
+	// Draining dispatchers are shutting down, and shouldn't be sent new
+	// requests.
+	Draining
+)
+
+func (s Status) String() string {
+	switch s {
+	case Serving:
+		return "serving"
+	case NotServing:
+		return "not-serving"
+	case Draining:
+		return "draining"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Status) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "serving":
+		*s = Serving
+	case "not-serving":
+		*s = NotServing
+	case "draining":
+		*s = Draining
+	default:
+		return fmt.Errorf("unknown health status %q", text)
+	}
+	return nil
+}
+
+// Check checks the health of a dependency, returning an error if it's
+// unhealthy.
+//
+// Checks must return once their context is done. A check which doesn't is
+// reported as unhealthy after the check timeout, but isn't run again until
+// it returns.
+type Check func(context.Context) error
+
+// Report is the health of a dispatcher and its dependencies.
+type Report struct {
+	Service      string             `json:"service"`
+	Status       Status             `json:"status"`
+	Dependencies []DependencyReport `json:"dependencies,omitempty"`
+}
+
+// DependencyReport is the health of a dependency.
+type DependencyReport struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Option customizes a Server.
+type Option interface {
+	apply(*Server)
+}
+
+type optionFunc func(*Server)
+
+func (f optionFunc) apply(s *Server) { f(s) }
+
+// CheckTimeout bounds how long each dependency check may take. Checks which
+// take longer are unhealthy. Defaults to one second.
+func CheckTimeout(timeout time.Duration) Option {
+	return optionFunc(func(s *Server) {
+		s.timeout = timeout
+	})
+}
+
+// Server tracks the health of a dispatcher and its dependencies. Servers
+// start out serving.
+type Server struct {
+	service string
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	status Status
+	checks []*dependency
+}
+
+// dependency is a dependency registered with AddCheck. At most one run of
+// its check is in flight: concurrent health checks wait for the same run.
+type dependency struct {
+	name  string
+	check Check
+
+	mu  sync.Mutex
+	run *checkRun // nil unless a check is in flight
+}
+
+// checkRun is a run of the check of a dependency. Its error is set once done
+// is closed.
+type checkRun struct {
+	done chan struct{}
+	err  error
+}
+
+// Register builds a Server for the given dispatcher and registers its
+// procedures on the dispatcher.
+func Register(d *yarpc.Dispatcher, opts ...Option) *Server {
+	s := NewServer(d.Name(), opts...)
+	d.Register(s.Procedures())
+	return s
+}
+
+// NewServer builds a Server for the dispatcher of the given service. Its
+// procedures must be registered on the dispatcher.
+func NewServer(service string, opts ...Option) *Server {
+	s := &Server{
+		service: service,
+		timeout: _defaultCheckTimeout,
+	}
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+	return s
+}
+
+// SetStatus sets the status of the dispatcher. While it's serving, the
+// dispatcher is reported as not serving if any of its dependencies is
+// unhealthy.
+func (s *Server) SetStatus(status Status) {
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+}
+
+// AddCheck registers a check of the health of a dependency. Checks are
+// run on every health check, concurrently, unless the previous run of the
+// check is still in flight, in which case its result is awaited.
+func (s *Server) AddCheck(name string, check Check) {
+	s.mu.Lock()
+	s.checks = append(s.checks, &dependency{name: name, check: check})
+	s.mu.Unlock()
+}
+
+// Check checks the health of the dispatcher and of its dependencies.
+func (s *Server) Check(ctx context.Context) Report {
+	s.mu.RLock()
+	status := s.status
+	checks := s.checks
+	s.mu.RUnlock()
+
+	report := Report{
+		Service:      s.service,
+		Status:       status,
+		Dependencies: make([]DependencyReport, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, d *dependency) {
+			defer wg.Done()
+			report.Dependencies[i] = s.checkDependency(ctx, d)
+		}(i, c)
+	}
+	wg.Wait()
+
+	if report.Status == Serving {
+		for _, d := range report.Dependencies {
+			if !d.Healthy {
+				report.Status = NotServing
+				break
+			}
+		}
+	}
+	return report
+}
+
+// checkDependency checks the health of a single dependency.
+func (s *Server) checkDependency(ctx context.Context, d *dependency) DependencyReport {
+	d.mu.Lock()
+	run := d.run
+	if run == nil {
+		run = &checkRun{done: make(chan struct{})}
+		d.run = run
+		go s.runCheck(d, run)
+	}
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var err error
+	select {
+	case <-run.done:
+		err = run.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		return DependencyReport{Name: d.name, Error: err.Error()}
+	}
+	return DependencyReport{Name: d.name, Healthy: true}
+}
+
+// runCheck runs the check of a dependency. The run is shared by concurrent
+// health checks, so it isn't bound to the context of any of them.
+func (s *Server) runCheck(d *dependency, run *checkRun) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	run.err = d.check(ctx)
+
+	d.mu.Lock()
+	d.run = nil
+	d.mu.Unlock()
+	close(run.done)
+}
+
+// dependency returns the check of the named dependency, if any.
+func (s *Server) dependency(name string) (*dependency, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.checks {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	for _, s := range []Status{Serving, NotServing, Draining} {
+		text, err := s.MarshalText()
+		require.NoError(t, err)
+
+		var got Status
+		require.NoError(t, got.UnmarshalText(text))
+		assert.Equal(t, s, got)
+	}
+	assert.Equal(t, "Status(42)", Status(42).String())
+
+	var s Status
+	assert.Error(t, s.UnmarshalText([]byte("sleeping")))
+}
+
+func TestCheck(t *testing.T) {
+	s := NewServer("myservice", CheckTimeout(10*time.Millisecond))
+	assert.Equal(t, Report{
+		Service:      "myservice",
+		Status:       Serving,
+		Dependencies: []DependencyReport{},
+	}, s.Check(context.Background()))
+
+	s.AddCheck("cache", func(context.Context) error { return nil })
+	s.AddCheck("database", func(context.Context) error { return errors.New("connection refused") })
+	// Checks which ignore their context time out too.
+	unblock := make(chan struct{})
+	defer close(unblock)
+	s.AddCheck("stuck", func(context.Context) error {
+		<-unblock
+		return nil
+	})
+
+	wantDependencies := []DependencyReport{
+		{Name: "cache", Healthy: true},
+		{Name: "database", Error: "connection refused"},
+		{Name: "stuck", Error: context.DeadlineExceeded.Error()},
+	}
+	report := s.Check(context.Background())
+	assert.Equal(t, NotServing, report.Status)
+	assert.Equal(t, wantDependencies, report.Dependencies)
+
+	s.SetStatus(Draining)
+	report = s.Check(context.Background())
+	assert.Equal(t, Draining, report.Status, "Expected the status set by the application to take precedence.")
+	assert.Equal(t, wantDependencies, report.Dependencies)
+}
+
+func TestCheckStuck(t *testing.T) {
+	s := NewServer("myservice", CheckTimeout(10*time.Millisecond))
+	var runs int32
+	unblock := make(chan struct{})
+	s.AddCheck("stuck", func(context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		<-unblock
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		report := s.Check(context.Background())
+		assert.Equal(t, []DependencyReport{
+			{Name: "stuck", Error: context.DeadlineExceeded.Error()},
+		}, report.Dependencies)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs), "Expected a check in flight not to be run again.")
+
+	close(unblock)
+	assert.Equal(t, []DependencyReport{
+		{Name: "stuck", Healthy: true},
+	}, s.Check(context.Background()).Dependencies)
+}
+
+func TestReportJSON(t *testing.T) {
+	body, err := json.Marshal(Report{
+		Service:      "myservice",
+		Status:       NotServing,
+		Dependencies: []DependencyReport{{Name: "database", Error: "timeout"}},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"service": "myservice",
+		"status": "not-serving",
+		"dependencies": [{"name": "database", "healthy": false, "error": "timeout"}]
+	}`, string(body))
+}
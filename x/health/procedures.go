@@ -0,0 +1,136 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/yarpc/api/transport"
+	yarpcjson "go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/encoding/protobuf"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// _grpcHealthService is the name of the standard gRPC health checking
+	// service.
+	_grpcHealthService = "grpc.health.v1.Health"
+
+	// Serving statuses of grpc.health.v1.HealthCheckResponse.
+	_grpcServing    int32 = 1
+	_grpcNotServing int32 = 2
+)
+
+// Procedures returns the procedures to register on a dispatcher: the
+// "yarpc::health" JSON procedure, which returns the health report, and the
+// Check method of the grpc.health.v1.Health service.
+func (s *Server) Procedures() []transport.Procedure {
+	health := yarpcjson.Procedure("yarpc::health", s.health)[0]
+	health.Signature = `health() {"service": "...", "status": "serving", "dependencies": [{"name": "...", "healthy": true}]}`
+
+	procedures := []transport.Procedure{health}
+	return append(procedures, protobuf.BuildProcedures(protobuf.BuildProceduresParams{
+		ServiceName: _grpcHealthService,
+		UnaryHandlerParams: []protobuf.BuildProceduresUnaryHandlerParams{{
+			MethodName: "Check",
+			Handler: protobuf.NewUnaryHandler(protobuf.UnaryHandlerParams{
+				Handle:     s.grpcCheck,
+				NewRequest: func() proto.Message { return &healthCheckRequest{} },
+			}),
+		}},
+	})...)
+}
+
+// ServeHTTP serves the health report as JSON, for load balancers and
+// Kubernetes probes. Responses are 200 OK while the dispatcher is serving,
+// and 503 Service Unavailable otherwise.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := s.Check(r.Context())
+	body, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == Serving {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+func (s *Server) health(ctx context.Context, body interface{}) (*Report, error) {
+	report := s.Check(ctx)
+	return &report, nil
+}
+
+// grpcCheck implements the Check method of grpc.health.v1.Health. The
+// service of the request may be empty or the name of the dispatcher, to
+// check the dispatcher, or the name of a dependency.
+func (s *Server) grpcCheck(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	req, ok := msg.(*healthCheckRequest)
+	if !ok {
+		return nil, protobuf.CastError(&healthCheckRequest{}, msg)
+	}
+	if req.Service == "" || req.Service == s.service {
+		report := s.Check(ctx)
+		return &healthCheckResponse{Status: grpcServingStatus(report.Status == Serving)}, nil
+	}
+	c, ok := s.dependency(req.Service)
+	if !ok {
+		return nil, yarpcerrors.NotFoundErrorf("unknown service %q", req.Service)
+	}
+	d := s.checkDependency(ctx, c)
+	return &healthCheckResponse{Status: grpcServingStatus(d.Healthy)}, nil
+}
+
+func grpcServingStatus(serving bool) int32 {
+	if serving {
+		return _grpcServing
+	}
+	return _grpcNotServing
+}
+
+// healthCheckRequest is grpc.health.v1.HealthCheckRequest.
+type healthCheckRequest struct {
+	Service string `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+}
+
+func (m *healthCheckRequest) Reset()         { *m = healthCheckRequest{} }
+func (m *healthCheckRequest) String() string { return proto.CompactTextString(m) }
+func (*healthCheckRequest) ProtoMessage()    {}
+
+// healthCheckResponse is grpc.health.v1.HealthCheckResponse, whose status
+// is a grpc.health.v1.HealthCheckResponse.ServingStatus.
+type healthCheckResponse struct {
+	Status int32 `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+}
+
+func (m *healthCheckResponse) Reset()         { *m = healthCheckResponse{} }
+func (m *healthCheckResponse) String() string { return proto.CompactTextString(m) }
+func (*healthCheckResponse) ProtoMessage()    {}
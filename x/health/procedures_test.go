@@ -0,0 +1,142 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	yarpcjson "go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/encoding/protobuf"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+func TestServeHTTP(t *testing.T) {
+	s := NewServer("myservice")
+	s.AddCheck("database", func(context.Context) error { return nil })
+
+	responseRecorder := httptest.NewRecorder()
+	s.ServeHTTP(responseRecorder, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"service": "myservice",
+		"status": "serving",
+		"dependencies": [{"name": "database", "healthy": true}]
+	}`, responseRecorder.Body.String())
+
+	s.SetStatus(Draining)
+	responseRecorder = httptest.NewRecorder()
+	s.ServeHTTP(responseRecorder, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Code)
+
+	responseRecorder = httptest.NewRecorder()
+	s.ServeHTTP(responseRecorder, httptest.NewRequest("HEAD", "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, responseRecorder.Code)
+	assert.Empty(t, responseRecorder.Body.String())
+}
+
+func TestGRPCCheck(t *testing.T) {
+	s := NewServer("myservice")
+	s.AddCheck("cache", func(context.Context) error { return nil })
+	s.AddCheck("database", func(context.Context) error { return errors.New("connection refused") })
+
+	tests := []struct {
+		service    string
+		wantStatus int32
+		wantErr    bool
+	}{
+		{service: "", wantStatus: _grpcNotServing},
+		{service: "myservice", wantStatus: _grpcNotServing},
+		{service: "cache", wantStatus: _grpcServing},
+		{service: "database", wantStatus: _grpcNotServing},
+		{service: "other", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		res, err := s.grpcCheck(context.Background(), &healthCheckRequest{Service: tt.service})
+		if tt.wantErr {
+			assert.True(t, yarpcerrors.IsNotFound(err), "expected a not found error for %q", tt.service)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, &healthCheckResponse{Status: tt.wantStatus}, res, "unexpected status of %q", tt.service)
+	}
+
+	_, err := s.grpcCheck(context.Background(), &healthCheckResponse{})
+	assert.Error(t, err)
+}
+
+func TestGRPCMessages(t *testing.T) {
+	// The messages must be wire compatible with grpc/health/v1/health.proto.
+	b, err := proto.Marshal(&healthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x03, 's', 'v', 'c'}, b)
+
+	b, err = proto.Marshal(&healthCheckResponse{Status: _grpcServing})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x08, 0x01}, b)
+
+	var req healthCheckRequest
+	require.NoError(t, proto.Unmarshal([]byte{0x0a, 0x03, 's', 'v', 'c'}, &req))
+	assert.Equal(t, "svc", req.Service)
+}
+
+func TestRegister(t *testing.T) {
+	dispatcher := yarpc.NewDispatcher(yarpc.Config{Name: "myservice"})
+	s := Register(dispatcher)
+	s.SetStatus(Draining)
+
+	call := func(procedure string, encoding transport.Encoding, body []byte) []byte {
+		req := &transport.Request{
+			Caller:    "caller",
+			Service:   "myservice",
+			Procedure: procedure,
+			Encoding:  encoding,
+			Body:      bytes.NewReader(body),
+		}
+		spec, err := dispatcher.Router().Choose(context.Background(), req)
+		require.NoError(t, err)
+		rw := new(transporttest.FakeResponseWriter)
+		require.NoError(t, spec.Unary().Handle(context.Background(), req, rw))
+		return rw.Body.Bytes()
+	}
+
+	var report Report
+	require.NoError(t, json.Unmarshal(call("yarpc::health", yarpcjson.Encoding, []byte("{}")), &report))
+	assert.Equal(t, "myservice", report.Service)
+	assert.Equal(t, Draining, report.Status)
+
+	var res healthCheckResponse
+	require.NoError(t, proto.Unmarshal(call("grpc.health.v1.Health::Check", protobuf.Encoding, nil), &res))
+	assert.Equal(t, _grpcNotServing, res.Status)
+}
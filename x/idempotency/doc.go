@@ -0,0 +1,43 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package idempotency provides EXPERIMENTAL inbound middleware which
+// deduplicates requests carrying an idempotency key, so that retried
+// requests to non-idempotent procedures do not repeat their side effects.
+//
+// Clients set a unique key on the Idempotency-Key header of each logical
+// request, and reuse it when retrying the request.
+//
+// 	store := idempotency.NewLRUStore(10000)
+// 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+// 		Name: "myservice",
+// 		InboundMiddleware: yarpc.InboundMiddleware{
+// 			Unary: idempotency.NewInboundMiddleware(store, idempotency.TTL(time.Hour)),
+// 		},
+// 	})
+//
+// The first request with a key is handled normally, and its response is
+// stored. Repeats of the request receive the stored response, and repeats
+// received while the first request is in flight wait for it to complete.
+//
+// Responses are stored in a Store. The in-memory LRUStore only deduplicates
+// requests received by the same process; implement Store over a shared
+// database to deduplicate requests across instances of a service.
+package idempotency
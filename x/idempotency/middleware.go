@@ -0,0 +1,242 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/requestkey"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+)
+
+// DefaultHeader is the header which carries idempotency keys by default.
+const DefaultHeader = "Idempotency-Key"
+
+// MiddlewareOption customizes the behavior of an InboundMiddleware.
+type MiddlewareOption interface {
+	apply(*middlewareOptions)
+}
+
+type middlewareOptionFunc func(*middlewareOptions)
+
+func (f middlewareOptionFunc) apply(opts *middlewareOptions) { f(opts) }
+
+type middlewareOptions struct {
+	header string
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+var defaultMiddlewareOptions = middlewareOptions{
+	header: DefaultHeader,
+	ttl:    24 * time.Hour,
+	logger: zap.NewNop(),
+}
+
+// Header sets the name of the header which carries idempotency keys.
+// Defaults to DefaultHeader.
+func Header(name string) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.header = name
+	})
+}
+
+// TTL sets how long responses are stored. Repeats of a request received
+// after this are executed again. Defaults to 24 hours.
+func TTL(ttl time.Duration) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.ttl = ttl
+	})
+}
+
+// WithLogger sets a logger used to report responses which could not be
+// stored.
+func WithLogger(logger *zap.Logger) MiddlewareOption {
+	return middlewareOptionFunc(func(opts *middlewareOptions) {
+		opts.logger = logger
+	})
+}
+
+// InboundMiddleware deduplicates unary requests carrying the same
+// idempotency key.
+type InboundMiddleware struct {
+	store Store
+	opts  middlewareOptions
+
+	mu       sync.Mutex
+	inflight map[string]chan struct{}
+}
+
+var _ middleware.UnaryInbound = (*InboundMiddleware)(nil)
+
+// NewInboundMiddleware builds an inbound middleware which stores the
+// responses of requests carrying an idempotency key in the given store, and
+// responds to repeats of these requests with the stored responses, without
+// calling the handler again.
+//
+// Repeats received while the first request is still being handled wait for
+// it to complete. Requests which fail with an error other than an
+// application error are not stored, so that their repeats are handled
+// again.
+//
+// Idempotency keys are scoped to the caller, service and procedure of the
+// request.
+func NewInboundMiddleware(store Store, opts ...MiddlewareOption) *InboundMiddleware {
+	options := defaultMiddlewareOptions
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	return &InboundMiddleware{
+		store:    store,
+		opts:     options,
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Handle deduplicates a unary request.
+func (m *InboundMiddleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	idempotencyKey, ok := req.Headers.Get(m.opts.header)
+	if !ok || idempotencyKey == "" {
+		return h.Handle(ctx, req, resw)
+	}
+
+	key := storeKey(req, idempotencyKey)
+	release, err := m.acquire(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	res, ok, err := m.store.Get(ctx, key)
+	if err != nil {
+		return yarpcerrors.UnavailableErrorf("failed to look up idempotency key %q: %v", idempotencyKey, err)
+	}
+	if ok {
+		return replay(res, resw)
+	}
+
+	rw := recordingWriter{ResponseWriter: resw}
+	if err := h.Handle(ctx, req, &rw); err != nil {
+		return err
+	}
+
+	res = &Response{
+		Headers:          rw.headers,
+		Body:             rw.body.Bytes(),
+		ApplicationError: rw.isApplicationError,
+	}
+	if err := m.store.Put(ctx, key, res, m.opts.ttl); err != nil {
+		m.opts.logger.Warn("Failed to store response for idempotency key.",
+			zap.String("caller", req.Caller),
+			zap.String("service", req.Service),
+			zap.String("procedure", req.Procedure),
+			zap.String("idempotencyKey", idempotencyKey),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// storeKey returns the key under which the response to a request is stored.
+func storeKey(req *transport.Request, idempotencyKey string) string {
+	var key requestkey.Builder
+	key.Add(req.Caller, req.Service, req.Procedure, idempotencyKey)
+	return key.String()
+}
+
+// acquire waits until no other request with the given key is in flight, and
+// marks the key as in flight. The returned function must be called once the
+// request completes.
+func (m *InboundMiddleware) acquire(ctx context.Context, key string) (release func(), err error) {
+	for {
+		m.mu.Lock()
+		done, ok := m.inflight[key]
+		if !ok {
+			done = make(chan struct{})
+			m.inflight[key] = done
+			m.mu.Unlock()
+
+			return func() {
+				m.mu.Lock()
+				delete(m.inflight, key)
+				m.mu.Unlock()
+				close(done)
+			}, nil
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-done:
+			// The request in flight completed. Its response, if any, is now
+			// in the store.
+		case <-ctx.Done():
+			if ctx.Err() == context.Canceled {
+				return nil, yarpcerrors.CancelledErrorf(
+					"cancelled waiting for the request with the same idempotency key to complete")
+			}
+			return nil, yarpcerrors.DeadlineExceededErrorf(
+				"timed out waiting for the request with the same idempotency key to complete")
+		}
+	}
+}
+
+// replay writes a stored response.
+func replay(res *Response, resw transport.ResponseWriter) error {
+	if res.Headers.Len() > 0 {
+		resw.AddHeaders(res.Headers)
+	}
+	if res.ApplicationError {
+		resw.SetApplicationError()
+	}
+	_, err := resw.Write(res.Body)
+	return err
+}
+
+// recordingWriter records the response written to a ResponseWriter.
+type recordingWriter struct {
+	transport.ResponseWriter
+
+	headers            transport.Headers
+	body               bytes.Buffer
+	isApplicationError bool
+}
+
+func (w *recordingWriter) AddHeaders(h transport.Headers) {
+	for k, v := range h.Items() {
+		w.headers = w.headers.With(k, v)
+	}
+	w.ResponseWriter.AddHeaders(h)
+}
+
+func (w *recordingWriter) SetApplicationError() {
+	w.isApplicationError = true
+	w.ResponseWriter.SetApplicationError()
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
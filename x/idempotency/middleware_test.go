@@ -0,0 +1,251 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type handlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f handlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+func newRequest(key string) *transport.Request {
+	headers := transport.NewHeaders()
+	if key != "" {
+		headers = headers.With(DefaultHeader, key)
+	}
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Procedure: "procedure",
+		Headers:   headers,
+	}
+}
+
+type failingStore struct {
+	getErr, putErr error
+}
+
+func (s failingStore) Get(context.Context, string) (*Response, bool, error) {
+	return nil, false, s.getErr
+}
+
+func (s failingStore) Put(context.Context, string, *Response, time.Duration) error {
+	return s.putErr
+}
+
+func TestMiddlewareReplaysResponses(t *testing.T) {
+	calls := atomic.NewInt32(0)
+	h := handlerFunc(func(_ context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+		calls.Inc()
+		resw.AddHeaders(transport.NewHeaders().With("foo", "bar"))
+		resw.SetApplicationError()
+		_, err := resw.Write([]byte("hello"))
+		return err
+	})
+	m := NewInboundMiddleware(NewLRUStore(10))
+
+	for i := 0; i < 3; i++ {
+		resw := new(transporttest.FakeResponseWriter)
+		require.NoError(t, m.Handle(context.Background(), newRequest("key"), resw, h))
+		assert.Equal(t, "hello", resw.Body.String())
+		assert.True(t, resw.IsApplicationError)
+		assert.Equal(t, transport.NewHeaders().With("foo", "bar"), resw.Headers)
+	}
+	assert.Equal(t, int32(1), calls.Load(), "handler must be called once")
+
+	// Keys are scoped to the procedure.
+	req := newRequest("key")
+	req.Procedure = "other"
+	require.NoError(t, m.Handle(context.Background(), req, new(transporttest.FakeResponseWriter), h))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestStoreKeyFieldsCannotCollide(t *testing.T) {
+	a := newRequest("")
+	a.Caller = "a::b"
+	a.Service = "c"
+	b := newRequest("")
+	b.Caller = "a"
+	b.Service = "b::c"
+	assert.NotEqual(t, storeKey(a, "key"), storeKey(b, "key"))
+
+	c := newRequest("")
+	c.Procedure = "get::key"
+	d := newRequest("")
+	d.Procedure = "get"
+	assert.NotEqual(t, storeKey(c, "x"), storeKey(d, "key::x"))
+}
+
+func TestMiddlewareWithoutKey(t *testing.T) {
+	calls := atomic.NewInt32(0)
+	h := handlerFunc(func(context.Context, *transport.Request, transport.ResponseWriter) error {
+		calls.Inc()
+		return nil
+	})
+	m := NewInboundMiddleware(NewLRUStore(10))
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, m.Handle(context.Background(), newRequest(""), new(transporttest.FakeResponseWriter), h))
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddlewareDoesNotStoreErrors(t *testing.T) {
+	calls := atomic.NewInt32(0)
+	h := handlerFunc(func(context.Context, *transport.Request, transport.ResponseWriter) error {
+		if calls.Inc() == 1 {
+			return yarpcerrors.UnavailableErrorf("great sadness")
+		}
+		return nil
+	})
+	m := NewInboundMiddleware(NewLRUStore(10))
+
+	err := m.Handle(context.Background(), newRequest("key"), new(transporttest.FakeResponseWriter), h)
+	assert.True(t, yarpcerrors.IsUnavailable(err))
+
+	require.NoError(t, m.Handle(context.Background(), newRequest("key"), new(transporttest.FakeResponseWriter), h))
+	require.NoError(t, m.Handle(context.Background(), newRequest("key"), new(transporttest.FakeResponseWriter), h))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddlewareConcurrentDuplicatesWait(t *testing.T) {
+	calls := atomic.NewInt32(0)
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	h := handlerFunc(func(_ context.Context, _ *transport.Request, resw transport.ResponseWriter) error {
+		calls.Inc()
+		close(started)
+		<-unblock
+		_, err := resw.Write([]byte("hello"))
+		return err
+	})
+	m := NewInboundMiddleware(NewLRUStore(10))
+
+	var wg sync.WaitGroup
+	handle := func() {
+		defer wg.Done()
+		resw := new(transporttest.FakeResponseWriter)
+		assert.NoError(t, m.Handle(context.Background(), newRequest("key"), resw, h))
+		assert.Equal(t, "hello", resw.Body.String())
+	}
+
+	wg.Add(1)
+	go handle()
+	<-started
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go handle()
+	}
+
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load(), "handler must be called once")
+}
+
+func TestMiddlewareWaitTimesOut(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	h := handlerFunc(func(context.Context, *transport.Request, transport.ResponseWriter) error {
+		close(started)
+		<-unblock
+		return nil
+	})
+	m := NewInboundMiddleware(NewLRUStore(10))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, m.Handle(context.Background(), newRequest("key"), new(transporttest.FakeResponseWriter), h))
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := m.Handle(ctx, newRequest("key"), new(transporttest.FakeResponseWriter), h)
+	assert.True(t, yarpcerrors.IsDeadlineExceeded(err), "unexpected error: %v", err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = m.Handle(ctx, newRequest("key"), new(transporttest.FakeResponseWriter), h)
+	assert.True(t, yarpcerrors.IsCancelled(err), "unexpected error: %v", err)
+
+	close(unblock)
+	<-done
+}
+
+func TestMiddlewareStoreErrors(t *testing.T) {
+	h := handlerFunc(func(context.Context, *transport.Request, transport.ResponseWriter) error {
+		return nil
+	})
+
+	t.Run("get", func(t *testing.T) {
+		m := NewInboundMiddleware(failingStore{getErr: errors.New("great sadness")})
+		err := m.Handle(context.Background(), newRequest("key"), new(transporttest.FakeResponseWriter), h)
+		require.Error(t, err)
+		assert.True(t, yarpcerrors.IsUnavailable(err))
+		assert.Contains(t, err.Error(), "great sadness")
+	})
+
+	t.Run("put", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		m := NewInboundMiddleware(
+			failingStore{putErr: errors.New("great sadness")},
+			WithLogger(zap.New(core)),
+		)
+		require.NoError(t, m.Handle(context.Background(), newRequest("key"), new(transporttest.FakeResponseWriter), h))
+		assert.Equal(t, 1, logs.FilterMessage("Failed to store response for idempotency key.").Len())
+	})
+}
+
+func TestMiddlewareCustomHeader(t *testing.T) {
+	calls := atomic.NewInt32(0)
+	h := handlerFunc(func(context.Context, *transport.Request, transport.ResponseWriter) error {
+		calls.Inc()
+		return nil
+	})
+	m := NewInboundMiddleware(NewLRUStore(10), Header("x-request-id"), TTL(time.Minute))
+
+	for i := 0; i < 2; i++ {
+		req := newRequest("")
+		req.Headers = req.Headers.With("x-request-id", "key")
+		require.NoError(t, m.Handle(context.Background(), req, new(transporttest.FakeResponseWriter), h))
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
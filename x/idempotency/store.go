@@ -0,0 +1,135 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+)
+
+// Response is a completed response stored for an idempotency key.
+type Response struct {
+	Headers          transport.Headers
+	Body             []byte
+	ApplicationError bool
+}
+
+// Store stores the responses of completed requests by key.
+//
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the response stored for the given key, if any, and if it
+	// has not expired.
+	Get(ctx context.Context, key string) (*Response, bool, error)
+
+	// Put stores the response for the given key until the given TTL
+	// elapses.
+	Put(ctx context.Context, key string, res *Response, ttl time.Duration) error
+}
+
+var _ Store = (*LRUStore)(nil)
+
+// LRUStore is an in-memory Store which holds at most a fixed number of
+// responses, evicting the least recently used ones first.
+type LRUStore struct {
+	capacity int
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *lruEntry, most recently used first
+}
+
+type lruEntry struct {
+	key     string
+	res     *Response
+	expires time.Time
+}
+
+// NewLRUStore builds an in-memory Store holding at most capacity responses.
+func NewLRUStore(capacity int) *LRUStore {
+	return &LRUStore{
+		capacity: capacity,
+		clock:    clock.NewReal(),
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the response stored for the given key.
+func (s *LRUStore) Get(_ context.Context, key string) (*Response, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := e.Value.(*lruEntry)
+	if !s.clock.Now().Before(entry.expires) {
+		s.remove(e)
+		return nil, false, nil
+	}
+
+	s.order.MoveToFront(e)
+	return entry.res, true, nil
+}
+
+// Put stores the response for the given key, evicting the least recently
+// used response if the store is full.
+func (s *LRUStore) Put(_ context.Context, key string, res *Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires := s.clock.Now().Add(ttl)
+	if e, ok := s.entries[key]; ok {
+		entry := e.Value.(*lruEntry)
+		entry.res = res
+		entry.expires = expires
+		s.order.MoveToFront(e)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(&lruEntry{key: key, res: res, expires: expires})
+	for s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// Len returns the number of responses in the store, including expired
+// responses which have not been evicted yet.
+func (s *LRUStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+func (s *LRUStore) remove(e *list.Element) {
+	s.order.Remove(e)
+	delete(s.entries, e.Value.(*lruEntry).key)
+}
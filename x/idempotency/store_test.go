@@ -0,0 +1,109 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/internal/clock"
+)
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake()
+	s := NewLRUStore(2)
+	s.clock = clk
+
+	a := &Response{Body: []byte("a")}
+	b := &Response{Body: []byte("b")}
+	c := &Response{Body: []byte("c")}
+
+	_, ok, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok, "empty store")
+
+	require.NoError(t, s.Put(ctx, "a", a, time.Minute))
+	require.NoError(t, s.Put(ctx, "b", b, time.Minute))
+
+	// Using a makes b the least recently used response.
+	res, ok, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, a, res)
+
+	require.NoError(t, s.Put(ctx, "c", c, time.Minute))
+	assert.Equal(t, 2, s.Len())
+
+	_, ok, _ = s.Get(ctx, "b")
+	assert.False(t, ok, "b must have been evicted")
+	_, ok, _ = s.Get(ctx, "a")
+	assert.True(t, ok, "a must not have been evicted")
+
+	clk.Add(time.Minute)
+	_, ok, _ = s.Get(ctx, "c")
+	assert.False(t, ok, "c must have expired")
+	assert.Equal(t, 1, s.Len(), "expired responses are removed")
+}
+
+func TestLRUStoreReplace(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake()
+	s := NewLRUStore(2)
+	s.clock = clk
+
+	require.NoError(t, s.Put(ctx, "a", &Response{Body: []byte("old")}, time.Second))
+	require.NoError(t, s.Put(ctx, "a", &Response{Body: []byte("new")}, time.Minute))
+	assert.Equal(t, 1, s.Len())
+
+	clk.Add(time.Second)
+	res, ok, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+	require.True(t, ok, "TTL must have been extended")
+	assert.Equal(t, "new", string(res.Body))
+}
+
+func TestLRUStoreConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	s := NewLRUStore(10)
+
+	done := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			defer func() { done <- struct{}{} }()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprint((i + j) % 20)
+				assert.NoError(t, s.Put(ctx, key, &Response{Headers: transport.NewHeaders()}, time.Minute))
+				_, _, err := s.Get(ctx, key)
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+	assert.Equal(t, 10, s.Len())
+}
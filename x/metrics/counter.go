@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"fmt"
@@ -26,13 +26,11 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
 	promproto "github.com/prometheus/client_model/go"
-	"github.com/uber-go/tally"
 )
 
 type counter struct {
 	value
-	last  int64
-	tally tally.Counter
+	last int64
 }
 
 func newCounter(opts Opts) *counter {
@@ -56,18 +54,11 @@ func (c *counter) Collect(ch chan<- prometheus.Metric) {
 	ch <- c
 }
 
-func (c *counter) push(scope tally.Scope) {
-	if c.opts.DisableTally {
+func (c *counter) push(sink Sink) {
+	if sink = sinkFor(sink, c.opts); sink == nil {
 		return
 	}
-	if c.tally == nil {
-		labels := c.opts.copyLabels()
-		for i, key := range c.opts.VariableLabels {
-			labels[key] = c.variableLabelVals[i]
-		}
-		c.tally = scope.Tagged(labels).Counter(c.opts.Name)
-	}
-	c.tally.Inc(c.diff())
+	sink.Counter(c.opts.Name, c.sinkLabels, c.diff())
 }
 
 type counterVector vector
@@ -100,7 +91,7 @@ func (cv *counterVector) MustGet(variableLabelVals ...string) Counter {
 
 func (cv *counterVector) Describe(ch chan<- *prometheus.Desc) { (*vector)(cv).Describe(ch) }
 func (cv *counterVector) Collect(ch chan<- prometheus.Metric) { (*vector)(cv).Collect(ch) }
-func (cv *counterVector) push(scope tally.Scope)              { (*vector)(cv).push(scope) }
+func (cv *counterVector) push(sink Sink)                      { (*vector)(cv).push(sink) }
 
 func newDynamicCounter(opts Opts, desc *prometheus.Desc, variableLabelVals []string) metric {
 	scrubbed := scrubLabelValues(variableLabelVals)
@@ -109,5 +100,6 @@ func newDynamicCounter(opts Opts, desc *prometheus.Desc, variableLabelVals []str
 		desc:              desc,
 		variableLabelVals: scrubbed,
 		labelPairs:        opts.labelPairs(scrubbed),
+		sinkLabels:        opts.sinkLabels(scrubbed),
 	}}
 }
@@ -18,15 +18,15 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"go.uber.org/yarpc/internal/pally/pallytest"
 	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/x/metrics/metricstest"
 )
 
 func TestCounter(t *testing.T) {
@@ -60,7 +60,7 @@ func TestCounter(t *testing.T) {
 	}
 	export.Test(t, scope)
 
-	pallytest.AssertPrometheus(t, r, "# HELP test_counter Some help.\n"+
+	metricstest.AssertPrometheus(t, r, "# HELP test_counter Some help.\n"+
 		"# TYPE test_counter counter\n"+
 		`test_counter{foo="bar",service="users"} 4`)
 }
@@ -18,10 +18,13 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-// Package pally is a simple, atomic-based metrics library. It interoperates
-// seamlessly with both Prometheus and Tally, providing ready-to-use Prometheus
-// text and Protocol Buffer endpoints, differential updates to StatsD- or
-// M3-based systems, and excellent performance along the hot path.
+// Package metrics is Pally, a simple, atomic-based metrics library. It
+// interoperates seamlessly with both Prometheus and Tally, providing
+// ready-to-use Prometheus text and Protocol Buffer endpoints, differential
+// updates to StatsD- or M3-based systems, and excellent performance along the
+// hot path.
+//
+// This package is experimental: its API may change without notice.
 //
 // Metric Names
 //
@@ -71,4 +74,25 @@
 //   // probably use the safer Get variant).
 //   vec.MustGet("some_calling_service").Inc()
 //
-package pally
+// Sinks
+//
+// In addition to serving Prometheus scrapes, a Registry can periodically push
+// differential updates to any number of Sinks. Tally scopes are supported via
+// NewTallySink, and NewStatsDSink writes the StatsD line protocol (with
+// DogStatsD-style tags) to any io.Writer, typically a UDP connection.
+//
+//   conn, err := net.Dial("udp", "127.0.0.1:8125")
+//   if err != nil {
+//     log.Fatal(err)
+//   }
+//   stop, err := registry.PushTo(time.Second, NewStatsDSink(conn))
+//   if err != nil {
+//     log.Fatal(err)
+//   }
+//   defer stop()
+//
+// Bucket boundaries for histograms are normally fixed by the code that
+// creates them, but deployments can override them by name with the
+// LatencyBuckets and HistogramBuckets RegistryOptions.
+//
+package metrics
@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"fmt"
@@ -26,12 +26,10 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
 	promproto "github.com/prometheus/client_model/go"
-	"github.com/uber-go/tally"
 )
 
 type gauge struct {
 	value
-	tally tally.Gauge
 }
 
 func newGauge(opts Opts) *gauge {
@@ -48,18 +46,11 @@ func (g *gauge) Collect(ch chan<- prometheus.Metric) {
 	ch <- g
 }
 
-func (g *gauge) push(scope tally.Scope) {
-	if g.opts.DisableTally {
+func (g *gauge) push(sink Sink) {
+	if sink = sinkFor(sink, g.opts); sink == nil {
 		return
 	}
-	if g.tally == nil {
-		labels := g.opts.copyLabels()
-		for i, key := range g.opts.VariableLabels {
-			labels[key] = g.variableLabelVals[i]
-		}
-		g.tally = scope.Tagged(labels).Gauge(g.opts.Name)
-	}
-	g.tally.Update(float64(g.Load()))
+	sink.Gauge(g.opts.Name, g.sinkLabels, g.Load())
 }
 
 type gaugeVector vector
@@ -92,7 +83,7 @@ func (gv *gaugeVector) MustGet(variableLabelVals ...string) Gauge {
 
 func (gv *gaugeVector) Describe(ch chan<- *prometheus.Desc) { (*vector)(gv).Describe(ch) }
 func (gv *gaugeVector) Collect(ch chan<- prometheus.Metric) { (*vector)(gv).Collect(ch) }
-func (gv *gaugeVector) push(scope tally.Scope)              { (*vector)(gv).push(scope) }
+func (gv *gaugeVector) push(sink Sink)                      { (*vector)(gv).push(sink) }
 
 func newDynamicGauge(opts Opts, desc *prometheus.Desc, variableLabelVals []string) metric {
 	scrubbed := scrubLabelValues(variableLabelVals)
@@ -101,5 +92,6 @@ func newDynamicGauge(opts Opts, desc *prometheus.Desc, variableLabelVals []strin
 		desc:              desc,
 		variableLabelVals: scrubbed,
 		labelPairs:        opts.labelPairs(scrubbed),
+		sinkLabels:        opts.sinkLabels(scrubbed),
 	}}
 }
@@ -18,33 +18,33 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally_test
+package metrics_test
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
-	"go.uber.org/yarpc/internal/pally"
+	"go.uber.org/yarpc/x/metrics"
 )
 
 // For expvar-style usage (where all metrics are package-global), create a
-// package-global pally.Registry and use the Must* constructors. This
+// package-global metrics.Registry and use the Must* constructors. This
 // guarantees that all your metrics are unique.
 var (
-	_reg = pally.NewRegistry(
+	_reg = metrics.NewRegistry(
 		// Also register all metrics with the package-global Prometheus
 		// registry.
-		pally.Federated(prometheus.DefaultRegisterer),
+		metrics.Federated(prometheus.DefaultRegisterer),
 	)
-	_watches = _reg.MustGauge(pally.Opts{
+	_watches = _reg.MustGauge(metrics.Opts{
 		Name: "watch_count",
 		Help: "Current number of active service name watches.",
-		ConstLabels: pally.Labels{
+		ConstLabels: metrics.Labels{
 			"foo": "bar",
 		},
 	})
-	_resolvesPerName = _reg.MustCounterVector(pally.Opts{
+	_resolvesPerName = _reg.MustCounterVector(metrics.Opts{
 		Name: "resolve_count",
 		Help: "Total name resolves by service.",
-		ConstLabels: pally.Labels{
+		ConstLabels: metrics.Labels{
 			"foo": "bar",
 		},
 		VariableLabels: []string{"service"},
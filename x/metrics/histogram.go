@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"fmt"
@@ -29,7 +29,6 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
 	promproto "github.com/prometheus/client_model/go"
-	"github.com/uber-go/tally"
 	"go.uber.org/atomic"
 )
 
@@ -68,51 +67,47 @@ type histogram struct {
 	// Prometheus requires us to track the sum of all observed values.
 	sum atomic.Int64
 
-	opts              LatencyOpts
+	opts              histogramOpts
 	desc              *prometheus.Desc
-	tally             tally.Histogram
 	variableLabelVals []string
 	labelPairs        []*promproto.LabelPair
+	sinkLabels        Labels
+
+	// Reused on each push. Only the pushing goroutine uses it.
+	pushBuckets []Bucket
 }
 
-func newHistogram(opts LatencyOpts) *histogram {
+func newHistogram(opts histogramOpts) *histogram {
 	return &histogram{
 		buckets:    opts.buckets(),
 		opts:       opts,
 		desc:       opts.describe(),
 		labelPairs: opts.labelPairs(nil /* variable label vals */),
+		sinkLabels: opts.sinkLabels(nil /* variable label vals */),
 	}
 }
 
 func (h *histogram) Observe(d time.Duration) {
-	n := int64(d / h.opts.Unit)
+	h.observe(int64(d / h.opts.unit))
+}
+
+func (h *histogram) observe(n int64) {
 	bucket := h.buckets.get(n)
 	bucket.Inc()
 	h.sum.Add(n)
 }
 
-func (h *histogram) push(scope tally.Scope) {
-	if h.opts.DisableTally {
+func (h *histogram) push(sink Sink) {
+	if sink = sinkFor(sink, h.opts.Opts); sink == nil {
 		return
 	}
-	if h.tally == nil {
-		labels := h.opts.copyLabels()
-		for i, key := range h.opts.VariableLabels {
-			labels[key] = h.variableLabelVals[i]
-		}
-		h.tally = scope.Tagged(labels).Histogram(
-			h.opts.Name,
-			tally.DurationBuckets(h.opts.Buckets),
-		)
-	}
-	for _, bucket := range h.buckets {
-		diff := bucket.diff()
-		// TODO: either add a Tally API to observe multiple values or roll our
-		// own counter-based histogram implementation.
-		for i := int64(0); i < diff; i++ {
-			h.tally.RecordDuration(time.Duration(bucket.upper) * h.opts.Unit)
-		}
+	if h.pushBuckets == nil {
+		h.pushBuckets = make([]Bucket, len(h.buckets))
+	}
+	for i, bucket := range h.buckets {
+		h.pushBuckets[i] = Bucket{Upper: bucket.upper, Count: bucket.diff()}
 	}
+	sink.Histogram(h.opts.Name, h.sinkLabels, h.opts.unit, h.pushBuckets)
 }
 
 func (h *histogram) Desc() *prometheus.Desc {
@@ -151,8 +146,17 @@ func (h *histogram) Describe(ch chan<- *prometheus.Desc) {
 	ch <- h.desc
 }
 
+// valueHistogram is a histogram of plain values.
+type valueHistogram struct {
+	*histogram
+}
+
+func (h valueHistogram) Observe(n int64) {
+	h.observe(n)
+}
+
 type histogramVector struct {
-	opts LatencyOpts
+	opts histogramOpts
 	desc *prometheus.Desc
 
 	histogramsMu sync.RWMutex
@@ -160,7 +164,7 @@ type histogramVector struct {
 	histograms map[string]*histogram
 }
 
-func newHistogramVector(opts LatencyOpts) *histogramVector {
+func newHistogramVector(opts histogramOpts) *histogramVector {
 	return &histogramVector{
 		opts:       opts,
 		desc:       opts.describe(),
@@ -177,6 +181,14 @@ func (vec *histogramVector) MustGet(labels ...string) Latencies {
 }
 
 func (vec *histogramVector) Get(labels ...string) (Latencies, error) {
+	h, err := vec.get(labels...)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (vec *histogramVector) get(labels ...string) (*histogram, error) {
 	digester := newDigester()
 	for _, s := range labels {
 		digester.add(ScrubLabelValue(s))
@@ -213,6 +225,7 @@ func (vec *histogramVector) newHistogram(key []byte, variableLabelVals []string)
 		desc:              vec.desc,
 		variableLabelVals: scrubbed,
 		labelPairs:        vec.opts.labelPairs(scrubbed),
+		sinkLabels:        vec.opts.sinkLabels(scrubbed),
 	}
 	vec.histograms[string(key)] = m
 	return m, nil
@@ -230,10 +243,31 @@ func (vec *histogramVector) Collect(ch chan<- prometheus.Metric) {
 	vec.histogramsMu.RUnlock()
 }
 
-func (vec *histogramVector) push(scope tally.Scope) {
+func (vec *histogramVector) push(sink Sink) {
 	vec.histogramsMu.RLock()
 	for _, m := range vec.histograms {
-		m.push(scope)
+		m.push(sink)
 	}
 	vec.histogramsMu.RUnlock()
 }
+
+// valueHistogramVector is a vector of histograms of plain values.
+type valueHistogramVector struct {
+	*histogramVector
+}
+
+func (vec valueHistogramVector) MustGet(labels ...string) Histogram {
+	h, err := vec.Get(labels...)
+	if err != nil {
+		panic(fmt.Sprintf("failed to get Histogram with labels %v: %v", labels, err))
+	}
+	return h
+}
+
+func (vec valueHistogramVector) Get(labels ...string) (Histogram, error) {
+	h, err := vec.get(labels...)
+	if err != nil {
+		return nil, err
+	}
+	return valueHistogram{h}, nil
+}
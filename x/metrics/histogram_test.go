@@ -18,15 +18,16 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"math"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"go.uber.org/yarpc/internal/pally/pallytest"
+	"go.uber.org/yarpc/x/metrics/metricstest"
 )
 
 func TestLatencies(t *testing.T) {
@@ -69,7 +70,7 @@ func TestLatencies(t *testing.T) {
 	}
 	export.Test(t, scope)
 
-	pallytest.AssertPrometheus(t, r, "# HELP test_latency_ns Some help.\n"+
+	metricstest.AssertPrometheus(t, r, "# HELP test_latency_ns Some help.\n"+
 		"# TYPE test_latency_ns histogram\n"+
 		`test_latency_ns_bucket{foo="bar",service="users",le="10"} 3`+"\n"+
 		`test_latency_ns_bucket{foo="bar",service="users",le="50"} 3`+"\n"+
@@ -202,7 +203,7 @@ func TestLatenciesVector(t *testing.T) {
 			time.Sleep(5 * _tick)
 			tt.wantTally.Test(t, scope)
 
-			pallytest.AssertPrometheus(t, r, tt.wantProm)
+			metricstest.AssertPrometheus(t, r, tt.wantProm)
 		})
 
 	}
@@ -235,7 +236,7 @@ func TestLatenciesVectorIndependence(t *testing.T) {
 	x.Observe(time.Millisecond)
 	y.Observe(time.Millisecond)
 
-	pallytest.AssertPrometheus(t, r, "# HELP test_latency_ms Some help.\n"+
+	metricstest.AssertPrometheus(t, r, "# HELP test_latency_ms Some help.\n"+
 		"# TYPE test_latency_ms histogram\n"+
 		`test_latency_ms_bucket{var="x",le="1000"} 1`+"\n"+
 		`test_latency_ms_bucket{var="x",le="+Inf"} 1`+"\n"+
@@ -246,3 +247,102 @@ func TestLatenciesVectorIndependence(t *testing.T) {
 		`test_latency_ms_sum{var="y"} 1`+"\n"+
 		`test_latency_ms_count{var="y"} 1`)
 }
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry(Labeled(Labels{"service": "users"}))
+	h, err := r.NewHistogram(HistogramOpts{
+		Opts: Opts{
+			Name:        "test_size_bytes",
+			Help:        "Some help.",
+			ConstLabels: Labels{"foo": "bar"},
+		},
+		Buckets: []int64{10, 100},
+	})
+	require.NoError(t, err, "Unexpected error constructing histogram.")
+
+	scope := newTestScope()
+	stop, err := r.Push(scope, _tick)
+	require.NoError(t, err, "Unexpected error starting Tally push.")
+
+	h.Observe(0)
+	h.Observe(10)
+	h.Observe(75)
+	h.Observe(1024)
+
+	time.Sleep(5 * _tick)
+	stop()
+
+	histograms := scope.Snapshot().Histograms()
+	require.Len(t, histograms, 1, "Expected exactly one histogram in Tally snapshot.")
+	for _, s := range histograms {
+		assert.Equal(t, map[float64]int64{
+			10:              2,
+			100:             1,
+			math.MaxFloat64: 1,
+		}, withoutEmptyBuckets(s.Values()), "Tally histogram has unexpected observed values.")
+	}
+
+	metricstest.AssertPrometheus(t, r, "# HELP test_size_bytes Some help.\n"+
+		"# TYPE test_size_bytes histogram\n"+
+		`test_size_bytes_bucket{foo="bar",service="users",le="10"} 2`+"\n"+
+		`test_size_bytes_bucket{foo="bar",service="users",le="100"} 3`+"\n"+
+		`test_size_bytes_bucket{foo="bar",service="users",le="+Inf"} 4`+"\n"+
+		`test_size_bytes_sum{foo="bar",service="users"} 1109`+"\n"+
+		`test_size_bytes_count{foo="bar",service="users"} 4`)
+}
+
+func TestHistogramVector(t *testing.T) {
+	r := NewRegistry()
+	vec, err := r.NewHistogramVector(HistogramOpts{
+		Opts: Opts{
+			Name:           "test_size_bytes",
+			Help:           "Some help.",
+			VariableLabels: []string{"var"},
+		},
+		Buckets: []int64{10},
+	})
+	require.NoError(t, err, "Unexpected error constructing vector.")
+
+	x, err := vec.Get("x")
+	require.NoError(t, err, "Unexpected error calling Get.")
+	x.Observe(5)
+	vec.MustGet("y").Observe(50)
+
+	_, err = vec.Get("x", "y")
+	assert.Error(t, err, "Expected an error getting a histogram with the wrong number of labels.")
+
+	metricstest.AssertPrometheus(t, r, "# HELP test_size_bytes Some help.\n"+
+		"# TYPE test_size_bytes histogram\n"+
+		`test_size_bytes_bucket{var="x",le="10"} 1`+"\n"+
+		`test_size_bytes_bucket{var="x",le="+Inf"} 1`+"\n"+
+		`test_size_bytes_sum{var="x"} 5`+"\n"+
+		`test_size_bytes_count{var="x"} 1`+"\n"+
+		`test_size_bytes_bucket{var="y",le="10"} 0`+"\n"+
+		`test_size_bytes_bucket{var="y",le="+Inf"} 1`+"\n"+
+		`test_size_bytes_sum{var="y"} 50`+"\n"+
+		`test_size_bytes_count{var="y"} 1`)
+}
+
+func TestHistogramOptsValidation(t *testing.T) {
+	r := NewRegistry()
+	opts := Opts{Name: "test_size_bytes", Help: "Some help."}
+
+	_, err := r.NewHistogram(HistogramOpts{Opts: opts})
+	assert.Error(t, err, "Expected an error without buckets.")
+
+	_, err = r.NewHistogram(HistogramOpts{Opts: opts, Buckets: []int64{10, 5}})
+	assert.Error(t, err, "Expected an error with unsorted buckets.")
+
+	_, err = r.NewHistogramVector(HistogramOpts{Opts: opts, Buckets: []int64{10}})
+	assert.Error(t, err, "Expected an error constructing a vector without variable labels.")
+}
+
+func withoutEmptyBuckets(values map[float64]int64) map[float64]int64 {
+	m := make(map[float64]int64, len(values))
+	for v, n := range values {
+		if n > 0 {
+			m[v] = n
+		}
+	}
+	return m
+}
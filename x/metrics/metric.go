@@ -18,13 +18,12 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/uber-go/tally"
 )
 
 // A Counter is a monotonically increasing value, like a car's odometer.
@@ -85,8 +84,26 @@ type LatenciesVector interface {
 	MustGet(...string) Latencies
 }
 
+// Histogram approximates the distribution of a value, like the size of
+// requests, with a histogram.
+//
+// Histograms are exported to both Prometheus and Tally using their native
+// histogram types.
+type Histogram interface {
+	Observe(int64)
+}
+
+// A HistogramVector is a collection of Histograms that share a name and some
+// constant labels, but also have an enumerated set of variable labels.
+type HistogramVector interface {
+	// For a description of Get, MustGet, and vector types in general, see the
+	// package-level documentation on vectors.
+	Get(...string) (Histogram, error)
+	MustGet(...string) Histogram
+}
+
 type metric interface {
 	prometheus.Collector
 
-	push(tally.Scope)
+	push(Sink)
 }
@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import "time"
 
@@ -30,6 +30,8 @@ var (
 	_nopGaugeVector     GaugeVector     = nopGaugeVec{}
 	_nopLatencies       Latencies       = nop{}
 	_nopLatenciesVector LatenciesVector = nopLatenciesVec{}
+	_nopHistogram       Histogram       = nopHistogram{}
+	_nopHistogramVector HistogramVector = nopHistogramVec{}
 )
 
 // NewNopCounter returns a no-op Counter.
@@ -50,6 +52,12 @@ func NewNopLatencies() Latencies { return _nopLatencies }
 // NewNopLatenciesVector returns a no-op LatenciesVector.
 func NewNopLatenciesVector() LatenciesVector { return _nopLatenciesVector }
 
+// NewNopHistogram returns a no-op Histogram.
+func NewNopHistogram() Histogram { return _nopHistogram }
+
+// NewNopHistogramVector returns a no-op HistogramVector.
+func NewNopHistogramVector() HistogramVector { return _nopHistogramVector }
+
 type nop struct{}
 
 func (nop) Inc() int64              { return 0 }
@@ -74,3 +82,12 @@ type nopLatenciesVec struct{}
 
 func (nopLatenciesVec) Get(...string) (Latencies, error) { return NewNopLatencies(), nil }
 func (nopLatenciesVec) MustGet(...string) Latencies      { return NewNopLatencies() }
+
+type nopHistogram struct{}
+
+func (nopHistogram) Observe(_ int64) {}
+
+type nopHistogramVec struct{}
+
+func (nopHistogramVec) Get(...string) (Histogram, error) { return NewNopHistogram(), nil }
+func (nopHistogramVec) MustGet(...string) Histogram      { return NewNopHistogram() }
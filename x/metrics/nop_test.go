@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"testing"
@@ -86,3 +86,15 @@ func assertNopLatencies(t testing.TB, lat Latencies) {
 		"Unexpected panic using no-op latencies.",
 	)
 }
+
+func TestNopHistogram(t *testing.T) {
+	assert.NotPanics(t, func() { NewNopHistogram().Observe(42) }, "Failed Observe on no-op Histogram.")
+}
+
+func TestNopHistogramVector(t *testing.T) {
+	vec := NewNopHistogramVector()
+	h, err := vec.Get("foo", "bar")
+	require.NoError(t, err, "Failed Get from no-op HistogramVector.")
+	assert.NotPanics(t, func() { vec.MustGet("foo", "bar") }, "Failed MustGet from no-op HistogramVector.")
+	assert.NotPanics(t, func() { h.Observe(42) }, "Failed Observe on no-op Histogram.")
+}
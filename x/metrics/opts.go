@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"errors"
@@ -37,7 +37,8 @@ type Opts struct {
 	Help           string
 	ConstLabels    Labels
 	VariableLabels []string // only meaningful for vectors
-	DisableTally   bool
+	DisableTally   bool     // skip pushes to Tally sinks
+	DisableSinks   bool     // skip pushes to all Sinks, including Tally
 }
 
 func (o Opts) describe() *prometheus.Desc {
@@ -105,6 +106,17 @@ func (o Opts) validateVector() error {
 	return nil
 }
 
+// sinkLabels returns the labels with which a metric is pushed to sinks.
+func (o Opts) sinkLabels(variableLabelVals []string) Labels {
+	labels := Labels(o.copyLabels())
+	for i, key := range o.VariableLabels {
+		if i < len(variableLabelVals) {
+			labels[key] = variableLabelVals[i]
+		}
+	}
+	return labels
+}
+
 func (o Opts) copyLabels() map[string]string {
 	l := make(map[string]string, len(o.ConstLabels)+len(o.VariableLabels))
 	for k, v := range o.ConstLabels {
@@ -128,15 +140,20 @@ type LatencyOpts struct {
 	Buckets []time.Duration
 }
 
-func (l LatencyOpts) buckets() buckets {
-	bs := make(buckets, 0, len(l.Buckets)+1)
-	for _, upper := range l.Buckets {
-		bs = append(bs, &bucket{upper: int64(upper / l.Unit)})
+func (l LatencyOpts) histogramOpts() histogramOpts {
+	bounds := make([]int64, len(l.Buckets))
+	for i, upper := range l.Buckets {
+		bounds[i] = int64(upper / l.Unit)
+		if upper == time.Duration(math.MaxInt64) {
+			// Keep the catch-all bucket as such.
+			bounds[i] = math.MaxInt64
+		}
 	}
-	if l.Buckets[len(l.Buckets)-1] != time.Duration(math.MaxInt64) {
-		bs = append(bs, &bucket{upper: math.MaxInt64})
+	return histogramOpts{
+		Opts:   l.Opts,
+		unit:   l.Unit,
+		bounds: bounds,
 	}
-	return bs
 }
 
 func (l LatencyOpts) validate() error {
@@ -169,3 +186,69 @@ func (l LatencyOpts) validateLatencies() error {
 	}
 	return nil
 }
+
+// HistogramOpts configure Histograms and HistogramVectors.
+type HistogramOpts struct {
+	Opts
+
+	// Upper bounds for the histogram buckets. A catch-all bucket for large
+	// observations is automatically created, if necessary.
+	Buckets []int64
+}
+
+func (h HistogramOpts) histogramOpts() histogramOpts {
+	return histogramOpts{
+		Opts:   h.Opts,
+		bounds: h.Buckets,
+	}
+}
+
+func (h HistogramOpts) validate() error {
+	if err := h.validateBuckets(); err != nil {
+		return err
+	}
+	return h.Opts.validate()
+}
+
+func (h HistogramOpts) validateVector() error {
+	if err := h.validateBuckets(); err != nil {
+		return err
+	}
+	return h.Opts.validateVector()
+}
+
+func (h HistogramOpts) validateBuckets() error {
+	if len(h.Buckets) == 0 {
+		return fmt.Errorf("must specify some buckets")
+	}
+	prev := int64(math.MinInt64)
+	for _, upper := range h.Buckets {
+		if upper <= prev {
+			return fmt.Errorf("bucket upper bounds must be sorted in increasing order")
+		}
+		prev = upper
+	}
+	return nil
+}
+
+// histogramOpts are the options of both Latencies and Histograms.
+type histogramOpts struct {
+	Opts
+
+	// Granularity of latency observations, or zero for histograms of plain
+	// values.
+	unit time.Duration
+
+	bounds []int64
+}
+
+func (o histogramOpts) buckets() buckets {
+	bs := make(buckets, 0, len(o.bounds)+1)
+	for _, upper := range o.bounds {
+		bs = append(bs, &bucket{upper: upper})
+	}
+	if o.bounds[len(o.bounds)-1] != math.MaxInt64 {
+		bs = append(bs, &bucket{upper: math.MaxInt64})
+	}
+	return bs
+}
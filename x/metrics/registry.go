@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"context"
@@ -52,15 +52,19 @@ type Registry struct {
 	federated []prometheus.Registerer
 	handler   http.Handler
 
-	// Registries can only push to a single Tally scope. Since Tally scopes
-	// support tee'ing to multiple backends, this isn't a problem in practice.
+	// Buckets overriding those of histograms, by metric name.
+	latencyBuckets   map[string][]time.Duration
+	histogramBuckets map[string][]int64
+
+	// Registries can only push once, to any number of sinks, so that the
+	// changes of counters and histograms are computed once for all of them.
 	pushing atomic.Bool
 }
 
 // A RegistryOption configures a Registry.
 type RegistryOption func(*Registry)
 
-// Federated links a pally.Registry with a prometheus.Registerer, so that all
+// Federated links a metrics.Registry with a prometheus.Registerer, so that all
 // metrics created in one also appear in the other.
 func Federated(prom prometheus.Registerer) RegistryOption {
 	return func(r *Registry) {
@@ -84,6 +88,22 @@ func Labeled(ls Labels) RegistryOption {
 	}
 }
 
+// LatencyBuckets sets the upper bounds of the buckets of the Latencies and
+// LatenciesVectors with the given name, overriding those of their options.
+func LatencyBuckets(name string, buckets []time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.latencyBuckets[name] = buckets
+	}
+}
+
+// HistogramBuckets sets the upper bounds of the buckets of the Histograms and
+// HistogramVectors with the given name, overriding those of their options.
+func HistogramBuckets(name string, buckets []int64) RegistryOption {
+	return func(r *Registry) {
+		r.histogramBuckets[name] = buckets
+	}
+}
+
 // NewRegistry constructs a new Registry.
 func NewRegistry(opts ...RegistryOption) *Registry {
 	prom := prometheus.NewRegistry()
@@ -96,8 +116,10 @@ func NewRegistry(opts ...RegistryOption) *Registry {
 		constLabels: make(Labels),
 		prom:        prom,
 		// Assume that we'll be federated with the global prometheus Registry.
-		federated: make([]prometheus.Registerer, 0, 1),
-		handler:   handler,
+		federated:        make([]prometheus.Registerer, 0, 1),
+		handler:          handler,
+		latencyBuckets:   make(map[string][]time.Duration),
+		histogramBuckets: make(map[string][]int64),
 	}
 	for _, opt := range opts {
 		opt(r)
@@ -106,16 +128,25 @@ func NewRegistry(opts ...RegistryOption) *Registry {
 }
 
 // Push starts a goroutine that periodically exports all registered metrics to
-// a Tally scope. Each Registry can only push to a single Scope; calling Push a
-// second time returns an error.
-//
-// In practice, this isn't a problem because Tally scopes natively support
-// tee'ing to multiple backends.
+// a Tally scope. It is equivalent to PushTo with a Tally sink.
 func (r *Registry) Push(scope tally.Scope, tick time.Duration) (context.CancelFunc, error) {
+	return r.PushTo(tick, NewTallySink(scope))
+}
+
+// PushTo starts a goroutine that periodically exports all registered metrics
+// to the given sinks. Each Registry can only push once; calling Push or
+// PushTo a second time returns an error.
+//
+// Counters and histograms are pushed as their changes since the previous
+// push, computed once for all sinks.
+func (r *Registry) PushTo(tick time.Duration, sinks ...Sink) (context.CancelFunc, error) {
+	if len(sinks) == 0 {
+		return nil, errors.New("no sinks to push metrics to")
+	}
 	if r.pushing.Swap(true) {
-		return nil, errors.New("already pushing to Tally")
+		return nil, errors.New("already pushing metrics")
 	}
-	pusher := newPusher(r, scope, tick)
+	pusher := newPusher(r, newPushSink(sinks), tick)
 	go pusher.Start()
 	return pusher.Stop, nil
 }
@@ -167,10 +198,13 @@ func (r *Registry) MustGauge(opts Opts) Gauge {
 // NewLatencies constructs a new Latencies.
 func (r *Registry) NewLatencies(opts LatencyOpts) (Latencies, error) {
 	opts.Opts = r.addConstLabels(opts.Opts)
+	if buckets, ok := r.latencyBuckets[opts.Name]; ok {
+		opts.Buckets = buckets
+	}
 	if err := opts.validate(); err != nil {
 		return nil, err
 	}
-	h := newHistogram(opts)
+	h := newHistogram(opts.histogramOpts())
 	if err := r.register(h); err != nil {
 		return nil, err
 	}
@@ -235,10 +269,13 @@ func (r *Registry) MustGaugeVector(opts Opts) GaugeVector {
 // NewLatenciesVector constructs a new LatenciesVector.
 func (r *Registry) NewLatenciesVector(opts LatencyOpts) (LatenciesVector, error) {
 	opts.Opts = r.addConstLabels(opts.Opts)
+	if buckets, ok := r.latencyBuckets[opts.Name]; ok {
+		opts.Buckets = buckets
+	}
 	if err := opts.validateVector(); err != nil {
 		return nil, err
 	}
-	vec := newHistogramVector(opts)
+	vec := newHistogramVector(opts.histogramOpts())
 	if err := r.register(vec); err != nil {
 		return nil, err
 	}
@@ -255,6 +292,58 @@ func (r *Registry) MustLatenciesVector(opts LatencyOpts) LatenciesVector {
 	return vec
 }
 
+// NewHistogram constructs a new Histogram.
+func (r *Registry) NewHistogram(opts HistogramOpts) (Histogram, error) {
+	opts.Opts = r.addConstLabels(opts.Opts)
+	if buckets, ok := r.histogramBuckets[opts.Name]; ok {
+		opts.Buckets = buckets
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	h := newHistogram(opts.histogramOpts())
+	if err := r.register(h); err != nil {
+		return nil, err
+	}
+	return valueHistogram{h}, nil
+}
+
+// MustHistogram constructs a new Histogram. It panics if it encounters an
+// error.
+func (r *Registry) MustHistogram(opts HistogramOpts) Histogram {
+	h, err := r.NewHistogram(opts)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create Histogram with options %+v: %v", opts, err))
+	}
+	return h
+}
+
+// NewHistogramVector constructs a new HistogramVector.
+func (r *Registry) NewHistogramVector(opts HistogramOpts) (HistogramVector, error) {
+	opts.Opts = r.addConstLabels(opts.Opts)
+	if buckets, ok := r.histogramBuckets[opts.Name]; ok {
+		opts.Buckets = buckets
+	}
+	if err := opts.validateVector(); err != nil {
+		return nil, err
+	}
+	vec := newHistogramVector(opts.histogramOpts())
+	if err := r.register(vec); err != nil {
+		return nil, err
+	}
+	return valueHistogramVector{vec}, nil
+}
+
+// MustHistogramVector constructs a new HistogramVector. It panics if it
+// encounters an error.
+func (r *Registry) MustHistogramVector(opts HistogramOpts) HistogramVector {
+	vec, err := r.NewHistogramVector(opts)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create HistogramVector with options %+v: %v", opts, err))
+	}
+	return vec
+}
+
 // ServeHTTP implements http.Handler.
 func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.handler.ServeHTTP(w, req)
@@ -291,12 +380,13 @@ func (r *Registry) addConstLabels(opts Opts) Opts {
 	return opts
 }
 
-func (r *Registry) push(scope tally.Scope) {
+func (r *Registry) push(sink Sink) {
 	r.metricsMu.RLock()
 	for _, m := range r.metrics {
-		m.push(scope)
+		m.push(sink)
 	}
 	r.metricsMu.RUnlock()
+	sink.Flush()
 }
 
 type pusher struct {
@@ -304,30 +394,30 @@ type pusher struct {
 	stop    chan struct{}
 	stopped chan struct{}
 	ticker  *time.Ticker
-	scope   tally.Scope
+	sink    Sink
 }
 
-func newPusher(r *Registry, scope tally.Scope, tick time.Duration) *pusher {
+func newPusher(r *Registry, sink Sink, tick time.Duration) *pusher {
 	return &pusher{
 		reg:     r,
 		stop:    make(chan struct{}),
 		stopped: make(chan struct{}),
 		ticker:  time.NewTicker(tick),
-		scope:   scope,
+		sink:    sink,
 	}
 }
 
 func (p *pusher) Start() {
 	defer close(p.stopped)
 	// When stopping, do one last export to catch any stragglers.
-	defer p.reg.push(p.scope)
+	defer p.reg.push(p.sink)
 
 	for {
 		select {
 		case <-p.stop:
 			return
 		case <-p.ticker.C:
-			p.reg.push(p.scope)
+			p.reg.push(p.sink)
 		}
 	}
 }
@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"context"
@@ -34,7 +34,7 @@ import (
 	"github.com/uber-go/tally"
 	"github.com/uber-go/tally/m3"
 	"go.uber.org/atomic"
-	"go.uber.org/yarpc/internal/pally/pallytest"
+	"go.uber.org/yarpc/x/metrics/metricstest"
 )
 
 func TestSimpleMetricDuplicates(t *testing.T) {
@@ -101,7 +101,7 @@ func TestFederatedMetrics(t *testing.T) {
 		"# TYPE foo counter\n" +
 		"foo 1"
 
-	pallytest.AssertPrometheus(t, promhttp.HandlerFor(prom, promhttp.HandlerOpts{}), expected)
+	metricstest.AssertPrometheus(t, promhttp.HandlerFor(prom, promhttp.HandlerOpts{}), expected)
 }
 
 func TestConstLabelValidation(t *testing.T) {
@@ -115,7 +115,7 @@ func TestConstLabelValidation(t *testing.T) {
 		Help: "help",
 	})
 	require.NoError(t, err, "Unexpected error creating a counter.")
-	pallytest.AssertPrometheus(t, r, "# HELP test help\n"+
+	metricstest.AssertPrometheus(t, r, "# HELP test help\n"+
 		"# TYPE test counter\n"+
 		`test{ok="yes"} 0`)
 }
@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally_test
+package metrics_test
 
 import (
 	"context"
@@ -26,31 +26,31 @@ import (
 	"time"
 
 	"github.com/uber-go/tally"
-	"go.uber.org/yarpc/internal/pally"
+	"go.uber.org/yarpc/x/metrics"
 )
 
 // If you'd prefer to use pure dependency injection and scope your metrics
-// to a single struct, create a new pally.Registry in your struct's
+// to a single struct, create a new metrics.Registry in your struct's
 // constructor. In this case, we're also exporting our metrics to a Tally
 // scope, which can report to StatsD- or M3-aware systems.
 type Resolver struct {
-	registry        *pally.Registry
-	watches         pally.Gauge
-	resolves        pally.CounterVector
+	registry        *metrics.Registry
+	watches         metrics.Gauge
+	resolves        metrics.CounterVector
 	stopTallyExport context.CancelFunc
 }
 
 func NewResolver(scope tally.Scope) (*Resolver, error) {
-	reg := pally.NewRegistry()
+	reg := metrics.NewRegistry()
 	stop, err := reg.Push(scope, time.Second)
 	if err != nil {
 		return nil, err
 	}
 
-	watches, err := _reg.NewGauge(pally.Opts{
+	watches, err := _reg.NewGauge(metrics.Opts{
 		Name: "watch_count",
 		Help: "Current number of active service name watches.",
-		ConstLabels: pally.Labels{
+		ConstLabels: metrics.Labels{
 			"foo": "bar",
 		},
 	})
@@ -58,10 +58,10 @@ func NewResolver(scope tally.Scope) (*Resolver, error) {
 		return nil, err
 	}
 
-	resolves, err := _reg.NewCounterVector(pally.Opts{
+	resolves, err := _reg.NewCounterVector(metrics.Opts{
 		Name: "resolve_count",
 		Help: "Total name resolves by path.",
-		ConstLabels: pally.Labels{
+		ConstLabels: metrics.Labels{
 			"foo": "bar",
 		},
 		VariableLabels: []string{"service"},
@@ -0,0 +1,238 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// A Sink receives the metrics of a Registry each time they are pushed. See
+// Registry.PushTo.
+//
+// Sinks are only called from the goroutine pushing metrics, so they need not
+// be safe for concurrent use. They must not modify nor retain the labels and
+// buckets they receive.
+type Sink interface {
+	// Counter receives the increase of a counter since the previous push.
+	Counter(name string, labels Labels, delta int64)
+
+	// Gauge receives the current value of a gauge.
+	Gauge(name string, labels Labels, value int64)
+
+	// Histogram receives the number of observations in each bucket of a
+	// histogram since the previous push. Latency histograms have the unit of
+	// their observations, so that the upper bound of a bucket is its Upper
+	// multiplied by the unit. Histograms of plain values have a zero unit.
+	Histogram(name string, labels Labels, unit time.Duration, buckets []Bucket)
+
+	// Flush is called once all the metrics of a push have been received.
+	Flush()
+}
+
+// A Bucket of a histogram.
+type Bucket struct {
+	// Upper bound of the bucket, inclusive. The final, catch-all bucket of a
+	// histogram has an upper bound of math.MaxInt64.
+	Upper int64
+
+	// Number of observations in the bucket.
+	Count int64
+}
+
+type multiSink []Sink
+
+func (ms multiSink) Counter(name string, labels Labels, delta int64) {
+	for _, s := range ms {
+		s.Counter(name, labels, delta)
+	}
+}
+
+func (ms multiSink) Gauge(name string, labels Labels, value int64) {
+	for _, s := range ms {
+		s.Gauge(name, labels, value)
+	}
+}
+
+func (ms multiSink) Histogram(name string, labels Labels, unit time.Duration, buckets []Bucket) {
+	for _, s := range ms {
+		s.Histogram(name, labels, unit, buckets)
+	}
+}
+
+func (ms multiSink) Flush() {
+	for _, s := range ms {
+		s.Flush()
+	}
+}
+
+// pushSink is the sink to which a Registry pushes its metrics. Metrics with
+// DisableTally are only pushed to its sinks other than Tally.
+type pushSink struct {
+	Sink
+
+	// Sinks other than Tally, or nil if there are none.
+	nonTally Sink
+}
+
+func newPushSink(sinks []Sink) pushSink {
+	var nonTally []Sink
+	for _, s := range sinks {
+		if _, ok := s.(*tallySink); !ok {
+			nonTally = append(nonTally, s)
+		}
+	}
+	return pushSink{Sink: combineSinks(sinks), nonTally: combineSinks(nonTally)}
+}
+
+// combineSinks returns a sink pushing to all the given sinks, or nil if
+// there are none.
+func combineSinks(sinks []Sink) Sink {
+	switch len(sinks) {
+	case 0:
+		return nil
+	case 1:
+		return sinks[0]
+	default:
+		return multiSink(sinks)
+	}
+}
+
+// sinkFor returns the sink to which a metric with the given options is
+// pushed, or nil if it isn't pushed.
+func sinkFor(sink Sink, opts Opts) Sink {
+	switch {
+	case opts.DisableSinks:
+		return nil
+	case !opts.DisableTally:
+		return sink
+	}
+	switch s := sink.(type) {
+	case pushSink:
+		return s.nonTally
+	case *tallySink:
+		return nil
+	default:
+		return sink
+	}
+}
+
+// NewTallySink returns a Sink which reports metrics to a Tally scope, using
+// Tally's native histograms.
+func NewTallySink(scope tally.Scope) Sink {
+	return &tallySink{
+		scope:      scope,
+		counters:   make(map[string]tally.Counter),
+		gauges:     make(map[string]tally.Gauge),
+		histograms: make(map[string]tally.Histogram),
+	}
+}
+
+type tallySink struct {
+	scope tally.Scope
+
+	counters   map[string]tally.Counter
+	gauges     map[string]tally.Gauge
+	histograms map[string]tally.Histogram
+}
+
+func (s *tallySink) Counter(name string, labels Labels, delta int64) {
+	key := sinkKey(name, labels)
+	c, ok := s.counters[key]
+	if !ok {
+		c = s.scope.Tagged(labels).Counter(name)
+		s.counters[key] = c
+	}
+	c.Inc(delta)
+}
+
+func (s *tallySink) Gauge(name string, labels Labels, value int64) {
+	key := sinkKey(name, labels)
+	g, ok := s.gauges[key]
+	if !ok {
+		g = s.scope.Tagged(labels).Gauge(name)
+		s.gauges[key] = g
+	}
+	g.Update(float64(value))
+}
+
+func (s *tallySink) Histogram(name string, labels Labels, unit time.Duration, buckets []Bucket) {
+	key := sinkKey(name, labels)
+	h, ok := s.histograms[key]
+	if !ok {
+		h = s.scope.Tagged(labels).Histogram(name, tallyBuckets(unit, buckets))
+		s.histograms[key] = h
+	}
+	for _, b := range buckets {
+		// TODO: either add a Tally API to observe multiple values or roll our
+		// own counter-based histogram implementation.
+		for i := int64(0); i < b.Count; i++ {
+			if unit == 0 {
+				h.RecordValue(float64(b.Upper))
+			} else {
+				h.RecordDuration(time.Duration(b.Upper) * unit)
+			}
+		}
+	}
+}
+
+func (s *tallySink) Flush() {}
+
+func tallyBuckets(unit time.Duration, buckets []Bucket) tally.Buckets {
+	if unit == 0 {
+		values := make(tally.ValueBuckets, 0, len(buckets))
+		for _, b := range buckets {
+			if b.Upper != math.MaxInt64 {
+				values = append(values, float64(b.Upper))
+			}
+		}
+		return values
+	}
+	durations := make(tally.DurationBuckets, 0, len(buckets))
+	for _, b := range buckets {
+		if b.Upper != math.MaxInt64 {
+			durations = append(durations, time.Duration(b.Upper)*unit)
+		}
+	}
+	return durations
+}
+
+// sinkKey identifies a metric by its name and labels.
+func sinkKey(name string, labels Labels) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	d := newDigester()
+	d.add(name)
+	for _, k := range keys {
+		d.add(k)
+		d.add(labels[k])
+	}
+	key := string(d.digest())
+	d.free()
+	return key
+}
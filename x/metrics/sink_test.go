@@ -0,0 +1,211 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+type recordedHistogram struct {
+	Unit    time.Duration
+	Buckets []Bucket
+}
+
+// recordingSink records the metrics pushed to it, by name.
+type recordingSink struct {
+	counters   map[string]int64
+	gauges     map[string]int64
+	histograms map[string]recordedHistogram
+	labels     map[string]Labels
+	flushes    int
+}
+
+func newRecordingSink() *recordingSink {
+	return &recordingSink{
+		counters:   make(map[string]int64),
+		gauges:     make(map[string]int64),
+		histograms: make(map[string]recordedHistogram),
+		labels:     make(map[string]Labels),
+	}
+}
+
+func (s *recordingSink) Counter(name string, labels Labels, delta int64) {
+	s.counters[name] += delta
+	s.labels[name] = labels
+}
+
+func (s *recordingSink) Gauge(name string, labels Labels, value int64) {
+	s.gauges[name] = value
+	s.labels[name] = labels
+}
+
+func (s *recordingSink) Histogram(name string, labels Labels, unit time.Duration, buckets []Bucket) {
+	h := s.histograms[name]
+	h.Unit = unit
+	if h.Buckets == nil {
+		h.Buckets = make([]Bucket, len(buckets))
+	}
+	for i, b := range buckets {
+		h.Buckets[i].Upper = b.Upper
+		h.Buckets[i].Count += b.Count
+	}
+	s.histograms[name] = h
+	s.labels[name] = labels
+}
+
+func (s *recordingSink) Flush() { s.flushes++ }
+
+func TestPushToSinks(t *testing.T) {
+	r := NewRegistry(Labeled(Labels{"service": "users"}))
+	counter := r.MustCounter(Opts{Name: "test_counter", Help: "Some help."})
+	gauge := r.MustGauge(Opts{Name: "test_gauge", Help: "Some help."})
+	vec := r.MustLatenciesVector(LatencyOpts{
+		Opts: Opts{
+			Name:           "test_latency_ms",
+			Help:           "Some help.",
+			VariableLabels: []string{"var"},
+		},
+		Unit:    time.Millisecond,
+		Buckets: []time.Duration{time.Second},
+	})
+	r.MustCounter(Opts{Name: "test_hidden", Help: "Some help.", DisableSinks: true}).Inc()
+
+	first, second := newRecordingSink(), newRecordingSink()
+	stop, err := r.PushTo(_tick, first, second)
+	require.NoError(t, err, "Unexpected error starting push.")
+
+	_, err = r.PushTo(_tick, newRecordingSink())
+	assert.Error(t, err, "Expected an error pushing twice.")
+
+	counter.Add(3)
+	gauge.Store(42)
+	vec.MustGet("x").Observe(time.Millisecond)
+	vec.MustGet("x").Observe(time.Minute)
+	time.Sleep(5 * _tick)
+	counter.Inc()
+	stop()
+
+	for _, s := range []*recordingSink{first, second} {
+		assert.Equal(t, map[string]int64{"test_counter": 4}, s.counters)
+		assert.Equal(t, map[string]int64{"test_gauge": 42}, s.gauges)
+		assert.Equal(t, map[string]recordedHistogram{
+			"test_latency_ms": {
+				Unit: time.Millisecond,
+				Buckets: []Bucket{
+					{Upper: 1000, Count: 1},
+					{Upper: math.MaxInt64, Count: 1},
+				},
+			},
+		}, s.histograms)
+		assert.Equal(t, Labels{"service": "users"}, s.labels["test_counter"])
+		assert.Equal(t, Labels{"service": "users", "var": "x"}, s.labels["test_latency_ms"])
+		assert.True(t, s.flushes > 1, "Expected a flush after each push.")
+	}
+}
+
+func TestPushDisabledMetrics(t *testing.T) {
+	r := NewRegistry()
+	r.MustCounter(Opts{Name: "test_counter", Help: "Some help."}).Inc()
+	r.MustCounter(Opts{Name: "test_no_tally", Help: "Some help.", DisableTally: true}).Inc()
+	r.MustCounterVector(Opts{
+		Name:           "test_no_sinks",
+		Help:           "Some help.",
+		VariableLabels: []string{"var"},
+		DisableSinks:   true,
+	}).MustGet("x").Inc()
+
+	scope := tally.NewTestScope("", nil)
+	sink := newRecordingSink()
+	stop, err := r.PushTo(_tick, NewTallySink(scope), sink)
+	require.NoError(t, err, "Unexpected error starting push.")
+	stop()
+
+	assert.Equal(t, map[string]int64{"test_counter": 1, "test_no_tally": 1}, sink.counters)
+	tallyCounters := make(map[string]int64)
+	for _, c := range scope.Snapshot().Counters() {
+		tallyCounters[c.Name()] = c.Value()
+	}
+	assert.Equal(t, map[string]int64{"test_counter": 1}, tallyCounters)
+}
+
+func TestPushToWithoutSinks(t *testing.T) {
+	_, err := NewRegistry().PushTo(_tick)
+	assert.Error(t, err)
+}
+
+func TestBucketOverrides(t *testing.T) {
+	r := NewRegistry(
+		LatencyBuckets("test_latency_ms", []time.Duration{time.Second, time.Minute}),
+		HistogramBuckets("test_size_bytes", []int64{1024}),
+	)
+	lat := r.MustLatencies(LatencyOpts{
+		Opts:    Opts{Name: "test_latency_ms", Help: "Some help."},
+		Unit:    time.Millisecond,
+		Buckets: []time.Duration{time.Millisecond},
+	})
+	size := r.MustHistogramVector(HistogramOpts{
+		Opts:    Opts{Name: "test_size_bytes", Help: "Some help.", VariableLabels: []string{"var"}},
+		Buckets: []int64{1, 2, 3},
+	})
+	other := r.MustLatencies(LatencyOpts{
+		Opts:    Opts{Name: "test_other_ms", Help: "Some help."},
+		Unit:    time.Millisecond,
+		Buckets: []time.Duration{time.Millisecond},
+	})
+
+	sink := newRecordingSink()
+	r.push(sink)
+
+	lat.Observe(time.Second)
+	size.MustGet("x").Observe(10)
+	other.Observe(time.Millisecond)
+	r.push(sink)
+
+	assert.Equal(t, map[string]recordedHistogram{
+		"test_latency_ms": {
+			Unit: time.Millisecond,
+			Buckets: []Bucket{
+				{Upper: 1000, Count: 1},
+				{Upper: 60000},
+				{Upper: math.MaxInt64},
+			},
+		},
+		"test_size_bytes": {
+			Buckets: []Bucket{
+				{Upper: 1024, Count: 1},
+				{Upper: math.MaxInt64},
+			},
+		},
+		"test_other_ms": {
+			Unit: time.Millisecond,
+			Buckets: []Bucket{
+				{Upper: 1, Count: 1},
+				{Upper: math.MaxInt64},
+			},
+		},
+	}, sink.histograms)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// _defaultStatsDPacketSize fits StatsD packets in the MTU of most networks.
+const _defaultStatsDPacketSize = 1432
+
+// StatsDOption customizes a StatsD sink.
+type StatsDOption func(*statsDSink)
+
+// StatsDPrefix prefixes the names of all metrics written by a StatsD sink,
+// like "myservice.".
+func StatsDPrefix(prefix string) StatsDOption {
+	return func(s *statsDSink) {
+		s.prefix = prefix
+	}
+}
+
+// StatsDPacketSize sets the maximum size in bytes of the writes of a StatsD
+// sink, each of which holds as many lines as fit. Defaults to 1432 bytes.
+func StatsDPacketSize(size int) StatsDOption {
+	return func(s *statsDSink) {
+		s.packetSize = size
+	}
+}
+
+// NewStatsDSink returns a Sink which writes metrics in the StatsD line
+// protocol, usually to a UDP connection to a StatsD server:
+//
+// 	conn, err := net.Dial("udp", "127.0.0.1:8125")
+// 	if err != nil {
+// 		log.Fatal(err)
+// 	}
+// 	sink := metrics.NewStatsDSink(conn, metrics.StatsDPrefix("myservice."))
+//
+// Labels are written as tags in the DogStatsD format, like
+// "calls:1|c|#dest:users,source:frontend". Counters are written only when
+// they changed since the previous push. Histograms are written as a counter
+// per non-empty bucket, named after the histogram with a "_bucket" suffix
+// and tagged with the upper bound of the bucket as "le", like Prometheus
+// histograms.
+//
+// Like UDP packets, lines which fail to be written are dropped.
+func NewStatsDSink(w io.Writer, opts ...StatsDOption) Sink {
+	s := &statsDSink{w: w, packetSize: _defaultStatsDPacketSize}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type statsDSink struct {
+	w          io.Writer
+	prefix     string
+	packetSize int
+
+	// Lines not yet written, and the line being built.
+	packet bytes.Buffer
+	line   []byte
+	keys   []string
+}
+
+func (s *statsDSink) Counter(name string, labels Labels, delta int64) {
+	if delta == 0 {
+		return
+	}
+	s.write(name, labels, "", "", delta, "c")
+}
+
+func (s *statsDSink) Gauge(name string, labels Labels, value int64) {
+	s.write(name, labels, "", "", value, "g")
+}
+
+func (s *statsDSink) Histogram(name string, labels Labels, _ time.Duration, buckets []Bucket) {
+	name += "_bucket"
+	for _, b := range buckets {
+		if b.Count == 0 {
+			continue
+		}
+		le := "+Inf"
+		if b.Upper != math.MaxInt64 {
+			le = strconv.FormatInt(b.Upper, 10)
+		}
+		s.write(name, labels, "le", le, b.Count, "c")
+	}
+}
+
+func (s *statsDSink) Flush() {
+	if s.packet.Len() > 0 {
+		s.w.Write(s.packet.Bytes())
+		s.packet.Reset()
+	}
+}
+
+// write adds a line to the current packet, writing the packet first if the
+// line doesn't fit in it. An extra tag is added if extraKey isn't empty.
+func (s *statsDSink) write(name string, labels Labels, extraKey, extraValue string, value int64, kind string) {
+	line := append(s.line[:0], s.prefix...)
+	line = append(line, name...)
+	line = append(line, ':')
+	line = strconv.AppendInt(line, value, 10)
+	line = append(line, '|')
+	line = append(line, kind...)
+
+	keys := s.keys[:0]
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	s.keys = keys
+
+	for i, k := range keys {
+		line = appendTag(line, i == 0, k, labels[k])
+	}
+	if extraKey != "" {
+		line = appendTag(line, len(keys) == 0, extraKey, extraValue)
+	}
+	line = append(line, '\n')
+	s.line = line
+
+	if s.packet.Len() > 0 && s.packet.Len()+len(line) > s.packetSize {
+		s.Flush()
+	}
+	s.packet.Write(line)
+}
+
+func appendTag(line []byte, first bool, k, v string) []byte {
+	if first {
+		line = append(line, '|', '#')
+	} else {
+		line = append(line, ',')
+	}
+	line = append(line, k...)
+	line = append(line, ':')
+	return append(line, v...)
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package metrics
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsDSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err, "Failed to listen for UDP packets.")
+	defer conn.Close()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err, "Failed to dial UDP listener.")
+	defer client.Close()
+
+	r := NewRegistry(Labeled(Labels{"service": "users"}))
+	counter := r.MustCounter(Opts{Name: "calls", Help: "Some help."})
+	gauge := r.MustGaugeVector(Opts{Name: "pending", Help: "Some help.", VariableLabels: []string{"peer"}})
+	lat := r.MustLatencies(LatencyOpts{
+		Opts:    Opts{Name: "latency_ms", Help: "Some help."},
+		Unit:    time.Millisecond,
+		Buckets: []time.Duration{10 * time.Millisecond},
+	})
+
+	counter.Add(2)
+	gauge.MustGet("127.0.0.1:8080").Store(5)
+	lat.Observe(time.Millisecond)
+	lat.Observe(time.Second)
+	lat.Observe(time.Second)
+
+	r.push(NewStatsDSink(client, StatsDPrefix("myservice.")))
+
+	buf := make([]byte, 64*1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err, "Failed to read UDP packet.")
+
+	lines := strings.Split(strings.TrimSpace(string(buf[:n])), "\n")
+	sort.Strings(lines)
+	assert.Equal(t, []string{
+		"myservice.calls:2|c|#service:users",
+		"myservice.latency_ms_bucket:1|c|#service:users,le:10",
+		"myservice.latency_ms_bucket:2|c|#service:users,le:+Inf",
+		"myservice.pending:5|g|#peer:127.0.0.1-8080,service:users",
+	}, lines)
+}
+
+func TestStatsDSinkPackets(t *testing.T) {
+	var writes []string
+	w := writerFunc(func(p []byte) (int, error) {
+		writes = append(writes, string(p))
+		return len(p), nil
+	})
+
+	sink := NewStatsDSink(w, StatsDPacketSize(30))
+	sink.Counter("first", nil, 1)
+	sink.Counter("unchanged", nil, 0)
+	sink.Gauge("second", Labels{"a": "b"}, 2)
+	sink.Counter("third", nil, 3)
+	assert.Len(t, writes, 1, "Expected a write once a packet is full.")
+
+	sink.Flush()
+	sink.Flush()
+	assert.Equal(t, []string{
+		"first:1|c\nsecond:2|g|#a:b\n",
+		"third:3|c\n",
+	}, writes)
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func BenchmarkStatsDSink(b *testing.B) {
+	var buf bytes.Buffer
+	sink := NewStatsDSink(&buf)
+	labels := Labels{"source": "frontend", "dest": "users", "procedure": "get"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sink.Counter("calls", labels, 1)
+		sink.Flush()
+		buf.Reset()
+	}
+}
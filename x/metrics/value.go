@@ -18,14 +18,13 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	promproto "github.com/prometheus/client_model/go"
-	"github.com/uber-go/tally"
 	"go.uber.org/atomic"
 )
 
@@ -40,6 +39,7 @@ type value struct {
 	desc              *prometheus.Desc
 	variableLabelVals []string
 	labelPairs        []*promproto.LabelPair
+	sinkLabels        Labels
 }
 
 func newValue(opts Opts) value {
@@ -47,6 +47,7 @@ func newValue(opts Opts) value {
 		opts:       opts,
 		desc:       opts.describe(),
 		labelPairs: opts.labelPairs(nil /* variable label vals */),
+		sinkLabels: opts.sinkLabels(nil /* variable label vals */),
 	}
 }
 
@@ -120,10 +121,10 @@ func (vec *vector) Collect(ch chan<- prometheus.Metric) {
 	vec.metricsMu.RUnlock()
 }
 
-func (vec *vector) push(scope tally.Scope) {
+func (vec *vector) push(sink Sink) {
 	vec.metricsMu.RLock()
 	for _, m := range vec.metrics {
-		m.push(scope)
+		m.push(sink)
 	}
 	vec.metricsMu.RUnlock()
 }
@@ -18,7 +18,7 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package pally
+package metrics
 
 import (
 	"testing"
@@ -26,7 +26,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"go.uber.org/yarpc/internal/pally/pallytest"
+	"go.uber.org/yarpc/x/metrics/metricstest"
 )
 
 func TestSimpleVectors(t *testing.T) {
@@ -187,7 +187,7 @@ func TestSimpleVectors(t *testing.T) {
 			time.Sleep(10 * _tick)
 			tt.wantTally.Test(t, scope)
 
-			pallytest.AssertPrometheus(t, r, tt.wantProm)
+			metricstest.AssertPrometheus(t, r, tt.wantProm)
 		})
 	}
 }
@@ -0,0 +1,186 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package payloadlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/thriftrw/protocol"
+	"go.uber.org/thriftrw/wire"
+	"go.uber.org/yarpc/api/transport"
+)
+
+// Encodings of the bodies that may be decoded. These are spelled out rather
+// than imported from the encoding packages to avoid depending on them.
+const (
+	_jsonEncoding     transport.Encoding = "json"
+	_thriftEncoding   transport.Encoding = "thrift"
+	_protobufEncoding transport.Encoding = "proto"
+)
+
+var _protobufMarshaler = &jsonpb.Marshaler{OrigName: true}
+
+// ThriftValue is implemented by the types generated by thriftrw.
+type ThriftValue interface {
+	FromWire(wire.Value) error
+}
+
+// bodyTypes holds the types into which a body is decoded, if any.
+type bodyTypes struct {
+	thrift   func() ThriftValue
+	protobuf func() proto.Message
+}
+
+// decode decodes a body of the given encoding into a tree of maps, lists and
+// scalar values, which may be redacted. It returns an error if the body
+// cannot be decoded.
+func decode(encoding transport.Encoding, body []byte, types bodyTypes) (interface{}, error) {
+	switch encoding {
+	case _jsonEncoding:
+		return decodeJSON(body)
+	case _thriftEncoding:
+		return decodeThrift(body, types.thrift)
+	case _protobufEncoding:
+		if types.protobuf == nil {
+			return nil, fmt.Errorf("protobuf types are not configured")
+		}
+		return decodeProtobuf(body, types.protobuf())
+	default:
+		return nil, fmt.Errorf("cannot decode %q bodies", encoding)
+	}
+}
+
+// rawBody returns a value that logs the body as a string if it is valid
+// UTF-8, and in base64 otherwise.
+func rawBody(body []byte) interface{} {
+	if utf8.Valid(body) {
+		return string(body)
+	}
+	return body
+}
+
+func decodeJSON(body []byte) (interface{}, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func decodeThrift(body []byte, newValue func() ThriftValue) (interface{}, error) {
+	w, err := protocol.Binary.Decode(bytes.NewReader(body), wire.TStruct)
+	if err != nil {
+		// The body may be enveloped.
+		e, envErr := protocol.Binary.DecodeEnveloped(bytes.NewReader(body))
+		if envErr != nil {
+			return nil, err
+		}
+		w = e.Value
+	}
+
+	if newValue == nil {
+		return fromWire(w), nil
+	}
+
+	v := newValue()
+	if err := v.FromWire(w); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(b)
+}
+
+// fromWire converts a Thrift value to a tree of maps, lists and scalar
+// values, keying struct fields by field ID.
+func fromWire(w wire.Value) interface{} {
+	switch w.Type() {
+	case wire.TBool:
+		return w.GetBool()
+	case wire.TI8:
+		return w.GetI8()
+	case wire.TDouble:
+		return w.GetDouble()
+	case wire.TI16:
+		return w.GetI16()
+	case wire.TI32:
+		return w.GetI32()
+	case wire.TI64:
+		return w.GetI64()
+	case wire.TBinary:
+		return rawBody(w.GetBinary())
+	case wire.TStruct:
+		fields := make(map[string]interface{}, len(w.GetStruct().Fields))
+		for _, f := range w.GetStruct().Fields {
+			fields[strconv.Itoa(int(f.ID))] = fromWire(f.Value)
+		}
+		return fields
+	case wire.TMap:
+		items := make([]interface{}, 0, w.GetMap().Size())
+		_ = w.GetMap().ForEach(func(item wire.MapItem) error {
+			items = append(items, map[string]interface{}{
+				"key":   fromWire(item.Key),
+				"value": fromWire(item.Value),
+			})
+			return nil
+		})
+		return items
+	case wire.TSet:
+		return fromWireList(w.GetSet())
+	case wire.TList:
+		return fromWireList(w.GetList())
+	default:
+		return nil
+	}
+}
+
+func fromWireList(l wire.ValueList) interface{} {
+	items := make([]interface{}, 0, l.Size())
+	_ = l.ForEach(func(item wire.Value) error {
+		items = append(items, fromWire(item))
+		return nil
+	})
+	return items
+}
+
+func decodeProtobuf(body []byte, message proto.Message) (interface{}, error) {
+	if err := proto.Unmarshal(body, message); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := _protobufMarshaler.Marshal(&buf, message); err != nil {
+		return nil, err
+	}
+	return decodeJSON(buf.Bytes())
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package payloadlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/thriftrw/protocol"
+	"go.uber.org/thriftrw/wire"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/yarpcproto"
+)
+
+// user is a hand-written equivalent of the type thriftrw generates for
+//
+// 	struct User {
+// 		1: required string name
+// 		2: optional list<string> tags
+// 	}
+type user struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+func (u *user) FromWire(w wire.Value) error {
+	for _, f := range w.GetStruct().Fields {
+		switch f.ID {
+		case 1:
+			u.Name = f.Value.GetString()
+		case 2:
+			_ = f.Value.GetList().ForEach(func(v wire.Value) error {
+				u.Tags = append(u.Tags, v.GetString())
+				return nil
+			})
+		}
+	}
+	return nil
+}
+
+func userWire() wire.Value {
+	return wire.NewValueStruct(wire.Struct{Fields: []wire.Field{
+		{ID: 1, Value: wire.NewValueString("alice")},
+		{ID: 2, Value: wire.NewValueList(wire.ValueListFromSlice(wire.TBinary, []wire.Value{
+			wire.NewValueString("admin"),
+		}))},
+		{ID: 3, Value: wire.NewValueMap(wire.MapItemListFromSlice(wire.TI32, wire.TBinary, []wire.MapItem{
+			{Key: wire.NewValueI32(1), Value: wire.NewValueBinary([]byte{0xff, 0xfe})},
+		}))},
+		{ID: 4, Value: wire.NewValueBool(true)},
+	}})
+}
+
+func encodeThrift(t *testing.T, w wire.Value) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, protocol.Binary.Encode(w, &buf))
+	return buf.Bytes()
+}
+
+func encodeThriftEnveloped(t *testing.T, w wire.Value) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, protocol.Binary.EncodeEnveloped(wire.Envelope{
+		Name:  "getUser",
+		Type:  wire.Call,
+		SeqID: 1,
+		Value: w,
+	}, &buf))
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	wantThrift := map[string]interface{}{
+		"1": "alice",
+		"2": []interface{}{"admin"},
+		"3": []interface{}{
+			map[string]interface{}{"key": int32(1), "value": []byte{0xff, 0xfe}},
+		},
+		"4": true,
+	}
+	protobufBody, err := proto.Marshal(&yarpcproto.Oneway{Ack: true})
+	require.NoError(t, err)
+
+	tests := []struct {
+		msg      string
+		encoding transport.Encoding
+		body     []byte
+		types    bodyTypes
+		want     interface{}
+		wantErr  string
+	}{
+		{
+			msg:      "json",
+			encoding: "json",
+			body:     []byte(`{"user": {"name": "alice", "age": 42}}`),
+			want: map[string]interface{}{
+				"user": map[string]interface{}{"name": "alice", "age": json.Number("42")},
+			},
+		},
+		{
+			msg:      "empty json",
+			encoding: "json",
+		},
+		{
+			msg:      "invalid json",
+			encoding: "json",
+			body:     []byte(`{`),
+			wantErr:  "unexpected EOF",
+		},
+		{
+			msg:      "thrift",
+			encoding: "thrift",
+			body:     encodeThrift(t, userWire()),
+			want:     wantThrift,
+		},
+		{
+			msg:      "enveloped thrift",
+			encoding: "thrift",
+			body:     encodeThriftEnveloped(t, userWire()),
+			want:     wantThrift,
+		},
+		{
+			msg:      "typed thrift",
+			encoding: "thrift",
+			body:     encodeThrift(t, userWire()),
+			types:    bodyTypes{thrift: func() ThriftValue { return &user{} }},
+			want: map[string]interface{}{
+				"name": "alice",
+				"tags": []interface{}{"admin"},
+			},
+		},
+		{
+			msg:      "invalid thrift",
+			encoding: "thrift",
+			body:     []byte{0xff},
+			wantErr:  "unexpected EOF",
+		},
+		{
+			msg:      "protobuf",
+			encoding: "proto",
+			body:     protobufBody,
+			types:    bodyTypes{protobuf: func() proto.Message { return &yarpcproto.Oneway{} }},
+			want:     map[string]interface{}{"ack": true},
+		},
+		{
+			msg:      "untyped protobuf",
+			encoding: "proto",
+			body:     protobufBody,
+			wantErr:  "protobuf types are not configured",
+		},
+		{
+			msg:      "raw",
+			encoding: "raw",
+			body:     []byte("hello"),
+			wantErr:  `cannot decode "raw" bodies`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := decode(tt.encoding, tt.body, tt.types)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRawBody(t *testing.T) {
+	assert.Equal(t, "hello", rawBody([]byte("hello")))
+	assert.Equal(t, []byte{0xff}, rawBody([]byte{0xff}))
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package payloadlog provides EXPERIMENTAL middleware that logs the headers
+// and bodies of requests and responses, to debug services in production.
+//
+// Payloads are only logged for the procedures configured with Procedure, at
+// the configured sample rate. Header values and body fields that may contain
+// sensitive information should be redacted with RedactHeaders and
+// RedactFields.
+//
+// 	mw := payloadlog.NewMiddleware(
+// 		payloadlog.Logger(logger),
+// 		payloadlog.Procedure("Users::get", payloadlog.SampleRate(0.01)),
+// 		payloadlog.RedactHeaders("authorization"),
+// 		payloadlog.RedactFields("user.password", "user.cards.*.number"),
+// 	)
+// 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+// 		InboundMiddleware: yarpc.InboundMiddleware{Unary: mw},
+// 		...
+// 	})
+//
+// JSON bodies are decoded as is. Thrift bodies are decoded with the thriftrw
+// wire representation, keying fields by ID, or with the types configured
+// with ThriftTypes. Protobuf bodies are decoded with the types configured
+// with ProtobufTypes. Other bodies are logged verbatim, as strings or in
+// base64, unless fields are redacted.
+package payloadlog
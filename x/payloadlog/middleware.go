@@ -0,0 +1,280 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package payloadlog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// _omitted replaces bodies that are too large or cannot be redacted.
+const _omitted = "[OMITTED]"
+
+// Middleware logs the headers and bodies of requests and responses.
+type Middleware struct {
+	opts options
+}
+
+var (
+	_ middleware.UnaryInbound   = (*Middleware)(nil)
+	_ middleware.OnewayInbound  = (*Middleware)(nil)
+	_ middleware.UnaryOutbound  = (*Middleware)(nil)
+	_ middleware.OnewayOutbound = (*Middleware)(nil)
+)
+
+// NewMiddleware builds a middleware that logs the payloads of calls to the
+// configured procedures.
+func NewMiddleware(opts ...Option) *Middleware {
+	options := newOptions()
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+	return &Middleware{opts: options}
+}
+
+// Handle logs the payloads of a unary inbound request.
+func (m *Middleware) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter, h transport.UnaryHandler) error {
+	p := m.sample(req)
+	if p == nil {
+		return h.Handle(ctx, req, resw)
+	}
+
+	req, reqBody, err := readRequest(req)
+	if err != nil {
+		return err
+	}
+	w := &recordingWriter{ResponseWriter: resw, max: m.opts.maxBodySize}
+	err = h.Handle(ctx, req, w)
+
+	fields := m.requestFields(req, reqBody, p)
+	fields = append(fields, zap.Object("responseHeaders", m.headers(w.headers)))
+	fields = append(fields, m.bodyFields("responseBody", req.Encoding, w.body.Bytes(), w.size, bodyTypes{
+		thrift:   p.thriftResponse,
+		protobuf: p.protobufResponse,
+	})...)
+	fields = append(fields, zap.Bool("applicationError", w.isApplicationError), zap.Error(err))
+	m.opts.logger.Info("Inbound request payload.", fields...)
+	return err
+}
+
+// HandleOneway logs the payloads of a oneway inbound request.
+func (m *Middleware) HandleOneway(ctx context.Context, req *transport.Request, h transport.OnewayHandler) error {
+	p := m.sample(req)
+	if p == nil {
+		return h.HandleOneway(ctx, req)
+	}
+
+	req, reqBody, err := readRequest(req)
+	if err != nil {
+		return err
+	}
+	err = h.HandleOneway(ctx, req)
+
+	fields := append(m.requestFields(req, reqBody, p), zap.Error(err))
+	m.opts.logger.Info("Inbound request payload.", fields...)
+	return err
+}
+
+// Call logs the payloads of a unary outbound request.
+func (m *Middleware) Call(ctx context.Context, req *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	p := m.sample(req)
+	if p == nil {
+		return out.Call(ctx, req)
+	}
+
+	req, reqBody, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := out.Call(ctx, req)
+
+	fields := m.requestFields(req, reqBody, p)
+	if res != nil {
+		var resBody []byte
+		if res.Body != nil {
+			var readErr error
+			resBody, readErr = ioutil.ReadAll(res.Body)
+			if closeErr := res.Body.Close(); readErr == nil {
+				readErr = closeErr
+			}
+			if readErr != nil {
+				return nil, readErr
+			}
+			r := *res
+			r.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+			res = &r
+		}
+
+		fields = append(fields, zap.Object("responseHeaders", m.headers(res.Headers)))
+		fields = append(fields, m.bodyFields("responseBody", req.Encoding, resBody, len(resBody), bodyTypes{
+			thrift:   p.thriftResponse,
+			protobuf: p.protobufResponse,
+		})...)
+		fields = append(fields, zap.Bool("applicationError", res.ApplicationError))
+	}
+	fields = append(fields, zap.Error(err))
+	m.opts.logger.Info("Outbound request payload.", fields...)
+	return res, err
+}
+
+// CallOneway logs the payloads of a oneway outbound request.
+func (m *Middleware) CallOneway(ctx context.Context, req *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	p := m.sample(req)
+	if p == nil {
+		return out.CallOneway(ctx, req)
+	}
+
+	req, reqBody, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	ack, err := out.CallOneway(ctx, req)
+
+	fields := append(m.requestFields(req, reqBody, p), zap.Error(err))
+	m.opts.logger.Info("Outbound request payload.", fields...)
+	return ack, err
+}
+
+// sample returns the options of the procedure of the request if its
+// payloads should be logged, and nil otherwise.
+func (m *Middleware) sample(req *transport.Request) *procedureOptions {
+	p, ok := m.opts.procedures[req.Procedure]
+	if !ok {
+		p, ok = m.opts.procedures[_allProcedures]
+	}
+	if !ok || p.sampleRate <= 0 {
+		return nil
+	}
+	if p.sampleRate < 1 && m.opts.randFloat64() >= p.sampleRate {
+		return nil
+	}
+	return p
+}
+
+// readRequest returns a copy of the request whose body may be read again,
+// and the contents of the body.
+func readRequest(req *transport.Request) (*transport.Request, []byte, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, nil, err
+		}
+	}
+	r := *req
+	r.Body = bytes.NewReader(body)
+	return &r, body, nil
+}
+
+func (m *Middleware) requestFields(req *transport.Request, body []byte, p *procedureOptions) []zapcore.Field {
+	fields := make([]zapcore.Field, 0, 10)
+	fields = append(fields, zap.Object("request", req))
+	fields = append(fields, zap.Object("requestHeaders", m.headers(req.Headers)))
+	return append(fields, m.bodyFields("requestBody", req.Encoding, body, len(body), bodyTypes{
+		thrift:   p.thriftRequest,
+		protobuf: p.protobufRequest,
+	})...)
+}
+
+// bodyFields returns the fields logging a body of the given encoding and
+// total size, redacting it or omitting it as configured.
+func (m *Middleware) bodyFields(key string, encoding transport.Encoding, body []byte, size int, types bodyTypes) []zapcore.Field {
+	sizeField := zap.Int(key+"Size", size)
+	if size > m.opts.maxBodySize {
+		return []zapcore.Field{sizeField, zap.String(key, _omitted)}
+	}
+
+	v, err := decode(encoding, body, types)
+	if err != nil {
+		if len(m.opts.fields) > 0 {
+			return []zapcore.Field{sizeField, zap.String(key, _omitted)}
+		}
+		return []zapcore.Field{sizeField, zap.Any(key, rawBody(body))}
+	}
+
+	for _, path := range m.opts.fields {
+		v = redact(v, path)
+	}
+	return []zapcore.Field{sizeField, zap.Reflect(key, v)}
+}
+
+func (m *Middleware) headers(h transport.Headers) zapcore.ObjectMarshaler {
+	return redactedHeaders{headers: h, redacted: m.opts.headers}
+}
+
+// redactedHeaders logs headers, redacting the values of some of them.
+type redactedHeaders struct {
+	headers  transport.Headers
+	redacted map[string]struct{}
+}
+
+func (r redactedHeaders) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for k, v := range r.headers.Items() {
+		if _, ok := r.redacted[k]; ok {
+			v = _redacted
+		}
+		enc.AddString(k, v)
+	}
+	return nil
+}
+
+// recordingWriter records the headers and the start of the body of a
+// response.
+type recordingWriter struct {
+	transport.ResponseWriter
+
+	max                int
+	headers            transport.Headers
+	body               bytes.Buffer
+	size               int
+	isApplicationError bool
+}
+
+func (w *recordingWriter) AddHeaders(h transport.Headers) {
+	for k, v := range h.Items() {
+		w.headers = w.headers.With(k, v)
+	}
+	w.ResponseWriter.AddHeaders(h)
+}
+
+func (w *recordingWriter) SetApplicationError() {
+	w.isApplicationError = true
+	w.ResponseWriter.SetApplicationError()
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.size += len(p)
+	// Bodies larger than the maximum are not logged, so there's no need to
+	// record more than one byte past it.
+	if remaining := w.max + 1 - w.body.Len(); remaining > 0 {
+		if remaining > len(p) {
+			remaining = len(p)
+		}
+		w.body.Write(p[:remaining])
+	}
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package payloadlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type handlerFunc func(context.Context, *transport.Request, transport.ResponseWriter) error
+
+func (f handlerFunc) Handle(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	return f(ctx, req, resw)
+}
+
+func jsonRequest(body string) *transport.Request {
+	return &transport.Request{
+		Caller:    "caller",
+		Service:   "users",
+		Encoding:  "json",
+		Procedure: "getUser",
+		Headers:   transport.NewHeaders().With("authorization", "Bearer foo").With("x-trace", "1"),
+		Body:      bytes.NewReader([]byte(body)),
+	}
+}
+
+// echo is a handler that responds with the request headers and body.
+var echo = handlerFunc(func(ctx context.Context, req *transport.Request, resw transport.ResponseWriter) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	resw.AddHeaders(req.Headers)
+	_, err = resw.Write(body)
+	return err
+})
+
+func TestHandle(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	m := NewMiddleware(
+		Logger(zap.New(core)),
+		Procedure("getUser"),
+		RedactHeaders("Authorization"),
+		RedactFields("user.password"),
+	)
+
+	resw := new(transporttest.FakeResponseWriter)
+	require.NoError(t, m.Handle(context.Background(),
+		jsonRequest(`{"user": {"name": "alice", "password": "hunter2"}}`), resw, echo))
+	assert.Equal(t, `{"user": {"name": "alice", "password": "hunter2"}}`, resw.Body.String(),
+		"handler should read the full body")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Inbound request payload.", entries[0].Message)
+
+	fields := entries[0].ContextMap()
+	wantUser := map[string]interface{}{"name": "alice", "password": _redacted}
+	wantHeaders := map[string]interface{}{"authorization": _redacted, "x-trace": "1"}
+	assert.Equal(t, wantHeaders, fields["requestHeaders"])
+	assert.Equal(t, map[string]interface{}{"user": wantUser}, fields["requestBody"])
+	assert.Equal(t, int64(50), fields["requestBodySize"])
+	assert.Equal(t, wantHeaders, fields["responseHeaders"])
+	assert.Equal(t, map[string]interface{}{"user": wantUser}, fields["responseBody"])
+	assert.Equal(t, false, fields["applicationError"])
+}
+
+func TestHandleSkipsProcedures(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	sample := 0.5
+	m := NewMiddleware(
+		Logger(zap.New(core)),
+		Procedure("sampled", SampleRate(0.1)),
+		Procedure("never", SampleRate(0)),
+		withRand(func() float64 { return sample }),
+	)
+
+	for _, procedure := range []string{"unconfigured", "sampled", "never"} {
+		req := jsonRequest(`{}`)
+		req.Procedure = procedure
+		require.NoError(t, m.Handle(context.Background(), req, new(transporttest.FakeResponseWriter), echo))
+	}
+	assert.Equal(t, 0, logs.Len(), "expected no payloads to be logged")
+
+	sample = 0.05
+	req := jsonRequest(`{}`)
+	req.Procedure = "sampled"
+	require.NoError(t, m.Handle(context.Background(), req, new(transporttest.FakeResponseWriter), echo))
+	assert.Equal(t, 1, logs.Len(), "expected sampled payload to be logged")
+}
+
+func TestBodyOmitted(t *testing.T) {
+	tests := []struct {
+		msg      string
+		opts     []Option
+		encoding transport.Encoding
+		body     string
+		want     interface{}
+	}{
+		{
+			msg:      "too large",
+			opts:     []Option{MaxBodySize(4)},
+			encoding: "json",
+			body:     `"hello"`,
+			want:     _omitted,
+		},
+		{
+			msg:      "raw",
+			encoding: "raw",
+			body:     "hello",
+			want:     "hello",
+		},
+		{
+			msg:      "raw with redacted fields",
+			opts:     []Option{RedactFields("password")},
+			encoding: "raw",
+			body:     "hello",
+			want:     _omitted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			core, logs := observer.New(zapcore.InfoLevel)
+			m := NewMiddleware(append(tt.opts, Logger(zap.New(core)), Procedure("*"))...)
+
+			req := jsonRequest(tt.body)
+			req.Encoding = tt.encoding
+			resw := new(transporttest.FakeResponseWriter)
+			require.NoError(t, m.Handle(context.Background(), req, resw, echo))
+			assert.Equal(t, tt.body, resw.Body.String())
+
+			entries := logs.AllUntimed()
+			require.Len(t, entries, 1)
+			fields := entries[0].ContextMap()
+			assert.Equal(t, tt.want, fields["requestBody"])
+			assert.Equal(t, tt.want, fields["responseBody"])
+			assert.Equal(t, int64(len(tt.body)), fields["responseBodySize"])
+		})
+	}
+}
+
+func TestCall(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	m := NewMiddleware(Logger(zap.New(core)), Procedure("*"), RedactFields("token"))
+
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Do(func(_ context.Context, req *transport.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"id": 1}`, string(body))
+	}).Return(&transport.Response{
+		Headers: transport.NewHeaders().With("foo", "bar"),
+		Body:    ioutil.NopCloser(bytes.NewReader([]byte(`{"token": "secret"}`))),
+	}, nil)
+
+	res, err := m.Call(context.Background(), jsonRequest(`{"id": 1}`), out)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"token": "secret"}`, string(body), "response body should be readable")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Outbound request payload.", entries[0].Message)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, map[string]interface{}{"id": json.Number("1")}, fields["requestBody"])
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, fields["responseHeaders"])
+	assert.Equal(t, map[string]interface{}{"token": _redacted}, fields["responseBody"])
+}
+
+func TestCallResponseWithError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	m := NewMiddleware(Logger(zap.New(core)), Procedure("*"))
+
+	// Outbounds may return both a response and an error, which must not be
+	// lost when the response body is read.
+	callErr := errors.New("great sadness")
+	out := transporttest.NewMockUnaryOutbound(mockCtrl)
+	out.EXPECT().Call(gomock.Any(), gomock.Any()).Return(&transport.Response{
+		Body:             ioutil.NopCloser(bytes.NewReader([]byte(`{"error": "sad"}`))),
+		ApplicationError: true,
+	}, callErr)
+
+	res, err := m.Call(context.Background(), jsonRequest(`{"id": 1}`), out)
+	assert.Equal(t, callErr, err)
+	require.NotNil(t, res)
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"error": "sad"}`, string(body), "response body should be readable")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, map[string]interface{}{"error": "sad"}, fields["responseBody"])
+	assert.Equal(t, true, fields["applicationError"])
+	assert.Equal(t, "great sadness", fields["error"])
+}
+
+func TestOneway(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	m := NewMiddleware(Logger(zap.New(core)), Procedure("*"))
+
+	handler := transporttest.NewMockOnewayHandler(mockCtrl)
+	handler.EXPECT().HandleOneway(gomock.Any(), gomock.Any()).Return(nil)
+	require.NoError(t, m.HandleOneway(context.Background(), jsonRequest(`"in"`), handler))
+
+	out := transporttest.NewMockOnewayOutbound(mockCtrl)
+	out.EXPECT().CallOneway(gomock.Any(), gomock.Any()).Return(nil, nil)
+	_, err := m.CallOneway(context.Background(), jsonRequest(`"out"`), out)
+	require.NoError(t, err)
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "Inbound request payload.", entries[0].Message)
+	assert.Equal(t, "in", entries[0].ContextMap()["requestBody"])
+	assert.Equal(t, "Outbound request payload.", entries[1].Message)
+	assert.Equal(t, "out", entries[1].ContextMap()["requestBody"])
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package payloadlog
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+// _allProcedures is the name under which procedures without their own
+// configuration are configured.
+const _allProcedures = "*"
+
+// Option customizes the behavior of a payload logging middleware.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(opts *options) { f(opts) }
+
+type options struct {
+	logger      *zap.Logger
+	procedures  map[string]*procedureOptions
+	headers     map[string]struct{}
+	fields      [][]string
+	maxBodySize int
+	randFloat64 func() float64
+}
+
+func newOptions() options {
+	return options{
+		logger:      zap.NewNop(),
+		procedures:  make(map[string]*procedureOptions),
+		headers:     make(map[string]struct{}),
+		maxBodySize: 64 * 1024,
+		randFloat64: rand.Float64,
+	}
+}
+
+// Logger sets the logger to which payloads are written. Payloads are logged
+// at the Info level. By default, nothing is logged.
+func Logger(logger *zap.Logger) Option {
+	return optionFunc(func(opts *options) {
+		opts.logger = logger
+	})
+}
+
+// Procedure enables payload logging for the procedure with the given name.
+// The name "*" configures all procedures that are not configured by name.
+//
+// No payloads are logged for procedures that are not configured.
+func Procedure(name string, opts ...ProcedureOption) Option {
+	return optionFunc(func(o *options) {
+		p := &procedureOptions{sampleRate: 1}
+		for _, opt := range opts {
+			opt.apply(p)
+		}
+		o.procedures[name] = p
+	})
+}
+
+// RedactHeaders replaces the values of the headers with the given names by
+// "[REDACTED]" in logs. Header names are case-insensitive.
+func RedactHeaders(names ...string) Option {
+	return optionFunc(func(opts *options) {
+		for _, name := range names {
+			opts.headers[strings.ToLower(name)] = struct{}{}
+		}
+	})
+}
+
+// RedactFields replaces the values at the given paths of decoded bodies by
+// "[REDACTED]" in logs.
+//
+// Paths are dot-separated lists of object keys or list indexes, in which "*"
+// matches any key or index. For example, "user.password" redacts the
+// password of the user object at the root of the body, and "cards.*.number"
+// redacts the number of every card in a list.
+//
+// Thrift bodies are decoded without their IDL unless their types are
+// configured with ThriftTypes, so their fields are keyed by field ID, and map
+// items are lists of objects with "key" and "value" fields.
+//
+// Bodies that cannot be decoded, such as raw bodies, are omitted from logs
+// when fields are redacted, because they may contain these fields.
+func RedactFields(paths ...string) Option {
+	return optionFunc(func(opts *options) {
+		for _, path := range paths {
+			opts.fields = append(opts.fields, strings.Split(path, "."))
+		}
+	})
+}
+
+// MaxBodySize sets the size in bytes above which bodies are not logged.
+// Defaults to 64 KiB.
+func MaxBodySize(n int) Option {
+	return optionFunc(func(opts *options) {
+		opts.maxBodySize = n
+	})
+}
+
+// withRand replaces the source of randomness used to sample requests.
+func withRand(f func() float64) Option {
+	return optionFunc(func(opts *options) {
+		opts.randFloat64 = f
+	})
+}
+
+// ProcedureOption customizes payload logging for a procedure.
+type ProcedureOption interface {
+	apply(*procedureOptions)
+}
+
+type procedureOptionFunc func(*procedureOptions)
+
+func (f procedureOptionFunc) apply(opts *procedureOptions) { f(opts) }
+
+type procedureOptions struct {
+	sampleRate float64
+
+	// Build the values into which typed Thrift or Protobuf bodies are
+	// decoded, if any.
+	thriftRequest    func() ThriftValue
+	thriftResponse   func() ThriftValue
+	protobufRequest  func() proto.Message
+	protobufResponse func() proto.Message
+}
+
+// SampleRate sets the fraction of calls to the procedure, between 0 and 1,
+// whose payloads are logged. Defaults to 1.
+func SampleRate(rate float64) ProcedureOption {
+	return procedureOptionFunc(func(opts *procedureOptions) {
+		opts.sampleRate = rate
+	})
+}
+
+// ThriftTypes decodes the Thrift request and response bodies of the
+// procedure with the given thriftrw-generated types, usually its Args and
+// Result types, so that their fields are keyed by name.
+//
+//	payloadlog.Procedure("KeyValue::getValue", payloadlog.ThriftTypes(
+//		func() payloadlog.ThriftValue { return &kv.KeyValue_GetValue_Args{} },
+//		func() payloadlog.ThriftValue { return &kv.KeyValue_GetValue_Result{} },
+//	))
+func ThriftTypes(request, response func() ThriftValue) ProcedureOption {
+	return procedureOptionFunc(func(opts *procedureOptions) {
+		opts.thriftRequest = request
+		opts.thriftResponse = response
+	})
+}
+
+// ProtobufTypes decodes the Protobuf request and response bodies of the
+// procedure into the given messages. Protobuf bodies cannot be decoded
+// without their types, so they are otherwise logged like raw bodies.
+func ProtobufTypes(request, response func() proto.Message) ProcedureOption {
+	return procedureOptionFunc(func(opts *procedureOptions) {
+		opts.protobufRequest = request
+		opts.protobufResponse = response
+	})
+}
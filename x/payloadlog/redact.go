@@ -0,0 +1,54 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package payloadlog
+
+import "strconv"
+
+// _redacted replaces the values of redacted headers and fields.
+const _redacted = "[REDACTED]"
+
+// redact replaces the values at the given path of a decoded body by
+// _redacted, and returns the body.
+func redact(v interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return _redacted
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if path[0] == "*" {
+			for k, item := range v {
+				v[k] = redact(item, path[1:])
+			}
+		} else if item, ok := v[path[0]]; ok {
+			v[path[0]] = redact(item, path[1:])
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for i, item := range v {
+				v[i] = redact(item, path[1:])
+			}
+		} else if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(v) {
+			v[i] = redact(v[i], path[1:])
+		}
+	}
+	return v
+}
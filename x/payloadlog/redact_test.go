@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package payloadlog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	body := func() interface{} {
+		return map[string]interface{}{
+			"user": map[string]interface{}{
+				"name":     "alice",
+				"password": "hunter2",
+				"cards": []interface{}{
+					map[string]interface{}{"number": "4111", "expiry": "12/20"},
+					map[string]interface{}{"number": "5500", "expiry": "01/21"},
+				},
+			},
+			"token": "secret",
+		}
+	}
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{
+			path: "user.password",
+			want: map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":     "alice",
+					"password": _redacted,
+					"cards": []interface{}{
+						map[string]interface{}{"number": "4111", "expiry": "12/20"},
+						map[string]interface{}{"number": "5500", "expiry": "01/21"},
+					},
+				},
+				"token": "secret",
+			},
+		},
+		{
+			path: "user.cards.*.number",
+			want: map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":     "alice",
+					"password": "hunter2",
+					"cards": []interface{}{
+						map[string]interface{}{"number": _redacted, "expiry": "12/20"},
+						map[string]interface{}{"number": _redacted, "expiry": "01/21"},
+					},
+				},
+				"token": "secret",
+			},
+		},
+		{
+			path: "user.cards.1",
+			want: map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":     "alice",
+					"password": "hunter2",
+					"cards": []interface{}{
+						map[string]interface{}{"number": "4111", "expiry": "12/20"},
+						_redacted,
+					},
+				},
+				"token": "secret",
+			},
+		},
+		{
+			path: "*.name",
+			want: map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":     _redacted,
+					"password": "hunter2",
+					"cards": []interface{}{
+						map[string]interface{}{"number": "4111", "expiry": "12/20"},
+						map[string]interface{}{"number": "5500", "expiry": "01/21"},
+					},
+				},
+				"token": "secret",
+			},
+		},
+		{
+			path: "user.cards.5.number",
+			want: body(),
+		},
+		{
+			path: "token.value",
+			want: body(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, redact(body(), strings.Split(tt.path, ".")))
+		})
+	}
+}
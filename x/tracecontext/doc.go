@@ -0,0 +1,44 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package tracecontext provides EXPERIMENTAL support for the W3C Trace
+// Context and Baggage propagation formats, carried in the traceparent,
+// tracestate and baggage headers.
+//
+// Propagation is implemented by an opentracing.Tracer, so that every
+// transport injects and extracts W3C headers through the tracer it is
+// configured with: HTTP and gRPC carry them as headers, TChannel as tracing
+// headers, and the serialize package in its span context bytes.
+//
+// The tracer may stand alone, for services which only propagate the trace
+// context of their callers without recording spans,
+//
+// 	tracer := tracecontext.NewTracer()
+// 	transport := http.NewTransport(http.Tracer(tracer))
+//
+// or bridge into another tracer, whose spans it wraps and whose own headers
+// it propagates alongside the W3C ones.
+//
+// 	tracer := tracecontext.NewTracer(tracecontext.Bridge(jaegerTracer))
+// 	opentracing.SetGlobalTracer(tracer)
+//
+// The trace context of the current span is available with FromContext, for
+// example to log trace IDs.
+package tracecontext
@@ -0,0 +1,316 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracecontext
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Names of the headers of the W3C Trace Context and Baggage formats.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+	BaggageHeader     = "baggage"
+)
+
+const (
+	_version         = "00"
+	_traceParentLen  = 55
+	_maxStateMembers = 32
+	_maxStateKeyLen  = 256
+	_maxStateValLen  = 256
+	_maxBaggageLen   = 8192
+)
+
+// TraceID identifies a trace. The zero TraceID is invalid.
+type TraceID [16]byte
+
+// IsValid returns true if the TraceID is not all zeroes.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a span within a trace. The zero SpanID is invalid.
+type SpanID [8]byte
+
+// IsValid returns true if the SpanID is not all zeroes.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// Flags are the trace flags of a trace context.
+type Flags byte
+
+// FlagSampled indicates that the caller may have recorded the trace.
+const FlagSampled Flags = 1
+
+// IsSampled returns true if the sampled flag is set.
+func (f Flags) IsSampled() bool { return f&FlagSampled != 0 }
+
+// SpanContext is a W3C trace context, with its baggage.
+//
+// SpanContexts are immutable: their Baggage must not be modified.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   Flags
+
+	// Vendor-specific trace state, as a normalized tracestate header value.
+	State string
+
+	Baggage map[string]string
+}
+
+// IsValid returns true if the SpanContext has valid trace and span IDs.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// ForeachBaggageItem calls the given function for each baggage item, until
+// it returns false. Items are visited in no particular order.
+func (sc SpanContext) ForeachBaggageItem(f func(k, v string) bool) {
+	for k, v := range sc.Baggage {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// TraceParent returns the traceparent header value of the SpanContext.
+//
+// Only the sampled flag is propagated, as required for version 00 of the
+// format.
+func (sc SpanContext) TraceParent() string {
+	return fmt.Sprintf("%s-%s-%s-%02x", _version, sc.TraceID, sc.SpanID, byte(sc.Flags&FlagSampled))
+}
+
+// ParseTraceParent parses a traceparent header value.
+//
+// Values of future versions of the format are accepted as long as they
+// start with the fields of version 00.
+func ParseTraceParent(v string) (SpanContext, error) {
+	var sc SpanContext
+	v = strings.Trim(v, " \t")
+	if len(v) < _traceParentLen {
+		return sc, errors.New("traceparent is too short")
+	}
+
+	version, err := decodeHex(v[0:2])
+	if err != nil {
+		return sc, fmt.Errorf("invalid traceparent version: %v", err)
+	}
+	switch {
+	case version[0] == 0xff:
+		return sc, errors.New("invalid traceparent version ff")
+	case version[0] == 0 && len(v) != _traceParentLen:
+		return sc, errors.New("traceparent of version 00 is too long")
+	case len(v) > _traceParentLen && v[_traceParentLen] != '-':
+		return sc, errors.New("traceparent fields must be separated by dashes")
+	}
+	if v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return sc, errors.New("traceparent fields must be separated by dashes")
+	}
+
+	traceID, err := decodeHex(v[3:35])
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace ID: %v", err)
+	}
+	spanID, err := decodeHex(v[36:52])
+	if err != nil {
+		return sc, fmt.Errorf("invalid parent ID: %v", err)
+	}
+	flags, err := decodeHex(v[53:55])
+	if err != nil {
+		return sc, fmt.Errorf("invalid trace flags: %v", err)
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = Flags(flags[0])
+	if !sc.TraceID.IsValid() {
+		return sc, errors.New("trace ID must not be all zeroes")
+	}
+	if !sc.SpanID.IsValid() {
+		return sc, errors.New("parent ID must not be all zeroes")
+	}
+	return sc, nil
+}
+
+// decodeHex decodes lower-case hexadecimal strings only.
+func decodeHex(s string) ([]byte, error) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return nil, fmt.Errorf("%q is not lower-case hexadecimal", s)
+		}
+	}
+	return hex.DecodeString(s)
+}
+
+// ParseTraceState validates and normalizes a tracestate header value.
+// Multiple tracestate headers must be joined with commas first.
+//
+// Empty list members are dropped. A tracestate with invalid or duplicate
+// members, or more than 32 members, is rejected as a whole.
+func ParseTraceState(v string) (string, error) {
+	var members []string
+	keys := make(map[string]struct{})
+	for _, m := range strings.Split(v, ",") {
+		m = strings.Trim(m, " \t")
+		if m == "" {
+			continue
+		}
+		i := strings.IndexByte(m, '=')
+		if i < 0 {
+			return "", fmt.Errorf("tracestate member %q has no value", m)
+		}
+		key, value := m[:i], m[i+1:]
+		if !isValidStateKey(key) {
+			return "", fmt.Errorf("invalid tracestate key %q", key)
+		}
+		if !isValidStateValue(value) {
+			return "", fmt.Errorf("invalid tracestate value %q", value)
+		}
+		if _, ok := keys[key]; ok {
+			return "", fmt.Errorf("duplicate tracestate key %q", key)
+		}
+		keys[key] = struct{}{}
+		members = append(members, m)
+	}
+	if len(members) > _maxStateMembers {
+		return "", fmt.Errorf("tracestate has more than %d members", _maxStateMembers)
+	}
+	return strings.Join(members, ","), nil
+}
+
+func isValidStateKey(key string) bool {
+	if len(key) == 0 || len(key) > _maxStateKeyLen {
+		return false
+	}
+	tenant, system := key, ""
+	if i := strings.IndexByte(key, '@'); i >= 0 {
+		tenant, system = key[:i], key[i+1:]
+		// Multi-tenant keys: up to 241 characters for the tenant, which may
+		// start with a digit, and up to 14 for the system.
+		if len(tenant) == 0 || len(tenant) > 241 || len(system) == 0 || len(system) > 14 {
+			return false
+		}
+		if !isLowerAlpha(system[0]) || !isStateKeyChars(system) {
+			return false
+		}
+		return isStateKeyChars(tenant) && (isLowerAlpha(tenant[0]) || isDigit(tenant[0]))
+	}
+	return isLowerAlpha(tenant[0]) && isStateKeyChars(tenant)
+}
+
+func isStateKeyChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isLowerAlpha(c) && !isDigit(c) && c != '_' && c != '-' && c != '*' && c != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidStateValue(v string) bool {
+	if len(v) == 0 || len(v) > _maxStateValLen || v[len(v)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLowerAlpha(c byte) bool { return 'a' <= c && c <= 'z' }
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// ParseBaggage parses a baggage header value. Multiple baggage headers must
+// be joined with commas first.
+//
+// Values are percent-decoded and member properties are ignored. Invalid
+// members are dropped.
+func ParseBaggage(v string) map[string]string {
+	if len(v) > _maxBaggageLen {
+		return nil
+	}
+	var baggage map[string]string
+	for _, m := range strings.Split(v, ",") {
+		if i := strings.IndexByte(m, ';'); i >= 0 {
+			m = m[:i]
+		}
+		i := strings.IndexByte(m, '=')
+		if i < 0 {
+			continue
+		}
+		key := strings.Trim(m[:i], " \t")
+		value, err := url.PathUnescape(strings.Trim(m[i+1:], " \t"))
+		if err != nil || !isToken(key) {
+			continue
+		}
+		if baggage == nil {
+			baggage = make(map[string]string)
+		}
+		baggage[key] = value
+	}
+	return baggage
+}
+
+// FormatBaggage returns the baggage header value for the given items, sorted
+// by key, with percent-encoded values. Items whose key is not a valid token
+// are skipped.
+func FormatBaggage(baggage map[string]string) string {
+	keys := make([]string, 0, len(baggage))
+	for k := range baggage {
+		if isToken(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	members := make([]string, len(keys))
+	for i, k := range keys {
+		members[i] = k + "=" + url.PathEscape(baggage[k])
+	}
+	return strings.Join(members, ",")
+}
+
+// isToken returns true if s is an RFC 7230 token.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= 0x20 || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
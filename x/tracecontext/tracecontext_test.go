@@ -0,0 +1,251 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracecontext
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	_traceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	_traceID     = "0af7651916cd43dd8448eb211c80319c"
+	_spanID      = "b7ad6b7169203331"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	// Vectors from the W3C Trace Context test suite.
+	tests := []struct {
+		give      string
+		wantFlags Flags
+		wantErr   bool
+	}{
+		{give: _traceParent, wantFlags: FlagSampled},
+		{give: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00"},
+		{give: " \t" + _traceParent + " ", wantFlags: FlagSampled},
+		{give: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-09", wantFlags: 9},
+		{give: "01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", wantFlags: FlagSampled},
+		{
+			give:      "cc-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-what-the-future-will-be-like",
+			wantFlags: FlagSampled,
+		},
+		{give: "", wantErr: true},
+		{give: "ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", wantErr: true},
+		{give: "0x-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", wantErr: true},
+		{give: "00-0AF7651916CD43DD8448EB211C80319C-B7AD6B7169203331-01", wantErr: true},
+		{give: "00-00000000000000000000000000000000-b7ad6b7169203331-01", wantErr: true},
+		{give: "00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01", wantErr: true},
+		{give: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-", wantErr: true},
+		{give: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-1", wantErr: true},
+		{give: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b716920333-01", wantErr: true},
+		{give: "00_0af7651916cd43dd8448eb211c80319c_b7ad6b7169203331_01", wantErr: true},
+		{give: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-0g", wantErr: true},
+		{give: "cc-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01.what", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			sc, err := ParseTraceParent(tt.give)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, _traceID, sc.TraceID.String())
+			assert.Equal(t, _spanID, sc.SpanID.String())
+			assert.Equal(t, tt.wantFlags, sc.Flags)
+		})
+	}
+}
+
+func TestTraceParent(t *testing.T) {
+	sc, err := ParseTraceParent("cc-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-ff-future")
+	require.NoError(t, err)
+	assert.Equal(t, _traceParent, sc.TraceParent(), "expected version 00 with only the sampled flag")
+}
+
+func TestParseTraceState(t *testing.T) {
+	tooMany := make([]string, 33)
+	for i := range tooMany {
+		tooMany[i] = "k" + strings.Repeat("a", i) + "=v"
+	}
+
+	tests := []struct {
+		give    string
+		want    string
+		wantErr bool
+	}{
+		{give: "", want: ""},
+		{give: "foo=1,bar=2", want: "foo=1,bar=2"},
+		{give: " foo=1 ,, \tbar=2 , ", want: "foo=1,bar=2"},
+		{give: "foo=a b,bar=!~", want: "foo=a b,bar=!~"},
+		{give: "tenant@vendor=x,1tenant@vendor=y", want: "tenant@vendor=x,1tenant@vendor=y"},
+		{give: "a_-*/9=x", want: "a_-*/9=x"},
+		{give: strings.Join(tooMany[:32], ","), want: strings.Join(tooMany[:32], ",")},
+		{give: strings.Join(tooMany, ","), wantErr: true},
+		{give: "foo=1,foo=2", wantErr: true},
+		{give: "FOO=1", wantErr: true},
+		{give: "1foo=1", wantErr: true},
+		{give: "foo@1vendor=1", wantErr: true},
+		{give: "foo@=1", wantErr: true},
+		{give: "foo=", wantErr: true},
+		{give: "foo", wantErr: true},
+		{give: "foo=a=b", wantErr: true},
+		{give: "foo=\x01", wantErr: true},
+		{give: "foo=" + strings.Repeat("a", 257), wantErr: true},
+		{give: strings.Repeat("a", 257) + "=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			state, err := ParseTraceState(tt.give)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, state)
+		})
+	}
+}
+
+func TestParseBaggage(t *testing.T) {
+	tests := []struct {
+		give string
+		want map[string]string
+	}{
+		{give: ""},
+		{
+			give: "userId=alice,serverNode=DF%2028,isProduction=false",
+			want: map[string]string{"userId": "alice", "serverNode": "DF 28", "isProduction": "false"},
+		},
+		{
+			give: " key1 = value1 ;property1;p2=v2, key2=value2",
+			want: map[string]string{"key1": "value1", "key2": "value2"},
+		},
+		{
+			give: "novalue,a b=c,bad=%zz,ok=1",
+			want: map[string]string{"ok": "1"},
+		},
+		{give: "big=" + strings.Repeat("a", 8192)},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseBaggage(tt.give), "ParseBaggage(%q)", tt.give)
+	}
+}
+
+func TestFormatBaggage(t *testing.T) {
+	baggage := map[string]string{
+		"weapon":  "knife, fork",
+		"name":    "100% café",
+		"bad key": "skipped",
+	}
+	formatted := FormatBaggage(baggage)
+	assert.Equal(t, "name=100%25%20caf%C3%A9,weapon=knife%2C%20fork", formatted)
+
+	delete(baggage, "bad key")
+	assert.Equal(t, baggage, ParseBaggage(formatted))
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		desc        string
+		give        http.Header
+		wantValid   bool
+		wantState   string
+		wantBaggage map[string]string
+	}{
+		{desc: "empty", give: http.Header{}},
+		{
+			desc: "trace context",
+			give: http.Header{
+				"Traceparent": {_traceParent},
+				"Tracestate":  {"foo=1", "bar=2"},
+				"Baggage":     {"a=1", "b=2"},
+			},
+			wantValid:   true,
+			wantState:   "foo=1,bar=2",
+			wantBaggage: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			desc: "invalid tracestate",
+			give: http.Header{
+				"Traceparent": {_traceParent},
+				"Tracestate":  {"foo=1", "foo=2"},
+			},
+			wantValid: true,
+		},
+		{
+			desc: "invalid traceparent",
+			give: http.Header{
+				"Traceparent": {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331"},
+				"Tracestate":  {"foo=1"},
+				"Baggage":     {"a=1"},
+			},
+			wantBaggage: map[string]string{"a": "1"},
+		},
+		{
+			desc: "several traceparents",
+			give: http.Header{
+				"Traceparent": {_traceParent, _traceParent},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			sc, err := Extract(opentracing.HTTPHeadersCarrier(tt.give))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValid, sc.IsValid())
+			if tt.wantValid {
+				assert.Equal(t, _traceID, sc.TraceID.String())
+				assert.Equal(t, _spanID, sc.SpanID.String())
+			}
+			assert.Equal(t, tt.wantState, sc.State)
+			assert.Equal(t, tt.wantBaggage, sc.Baggage)
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	sc, err := ParseTraceParent(_traceParent)
+	require.NoError(t, err)
+	sc.State = "foo=1"
+	sc.Baggage = map[string]string{"a": "1"}
+
+	headers := make(http.Header)
+	Inject(sc, opentracing.HTTPHeadersCarrier(headers))
+	assert.Equal(t, http.Header{
+		"Traceparent": {_traceParent},
+		"Tracestate":  {"foo=1"},
+		"Baggage":     {"a=1"},
+	}, headers)
+
+	headers = make(http.Header)
+	Inject(SpanContext{}, opentracing.HTTPHeadersCarrier(headers))
+	assert.Empty(t, headers, "invalid contexts should not be injected")
+}
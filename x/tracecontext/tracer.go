@@ -0,0 +1,529 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracecontext
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// Spans serialized in the Binary format start with this magic number,
+// followed by the length of the W3C headers and the headers themselves. The
+// binary span context of a bridged tracer follows.
+var _binaryMagic = []byte("W3TC")
+
+// _maxBinaryHeaderSize bounds the length of each header of a span context in
+// the Binary format, so that a corrupted length cannot make us allocate an
+// arbitrary amount of memory.
+const _maxBinaryHeaderSize = 8 * 1024
+
+// TracerOption customizes the behavior of a Tracer.
+type TracerOption interface {
+	apply(*Tracer)
+}
+
+type tracerOptionFunc func(*Tracer)
+
+func (f tracerOptionFunc) apply(t *Tracer) { f(t) }
+
+// Bridge wraps the given tracer: spans are started with it, and its own
+// propagation headers are injected and extracted alongside the W3C ones.
+//
+// By default, the Tracer stands alone and does not record spans.
+func Bridge(tracer opentracing.Tracer) TracerOption {
+	return tracerOptionFunc(func(t *Tracer) {
+		t.bridged = tracer
+	})
+}
+
+// Tracer is an opentracing.Tracer which propagates W3C trace contexts.
+//
+// It supports the TextMap and HTTPHeaders formats, carrying the traceparent,
+// tracestate and baggage headers, and the Binary format. Other formats are
+// delegated to the bridged tracer, if any.
+type Tracer struct {
+	bridged opentracing.Tracer
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+var _ opentracing.Tracer = (*Tracer)(nil)
+
+// NewTracer builds a new Tracer.
+func NewTracer(opts ...TracerOption) *Tracer {
+	t := &Tracer{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	for _, o := range opts {
+		o.apply(t)
+	}
+	return t
+}
+
+// FromContext returns the W3C trace context of the span attached to the
+// given context, if the span was started by a Tracer.
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := span.Context().(*spanContext)
+	if !ok {
+		return SpanContext{}, false
+	}
+	return sc.w3c, true
+}
+
+// spanContext is the span context of spans started by a Tracer. The W3C
+// context may be invalid if only the bridged tracer's headers were
+// extracted.
+type spanContext struct {
+	w3c     SpanContext
+	bridged opentracing.SpanContext // nil if standalone
+}
+
+func (sc *spanContext) ForeachBaggageItem(f func(k, v string) bool) {
+	sc.w3c.ForeachBaggageItem(f)
+}
+
+// TraceID returns the W3C trace ID of the span context, or an empty string
+// if it has none. YARPC shows it with the requests it introspects.
+func (sc *spanContext) TraceID() string {
+	if !sc.w3c.TraceID.IsValid() {
+		return ""
+	}
+	return sc.w3c.TraceID.String()
+}
+
+// StartSpan starts a span. Its trace context continues the trace of the
+// first parent started or extracted by this Tracer, if any, and starts a new
+// trace otherwise.
+func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var options opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&options)
+	}
+
+	var (
+		parent     *spanContext
+		references []opentracing.StartSpanOption
+		baggage    map[string]string
+	)
+	for _, ref := range options.References {
+		sc, ok := ref.ReferencedContext.(*spanContext)
+		if !ok {
+			continue
+		}
+		if parent == nil && sc.w3c.IsValid() {
+			parent = sc
+		}
+		for k, v := range sc.w3c.Baggage {
+			if baggage == nil {
+				baggage = make(map[string]string)
+			}
+			baggage[k] = v
+		}
+		if sc.bridged != nil {
+			references = append(references, opentracing.SpanReference{
+				Type:              ref.Type,
+				ReferencedContext: sc.bridged,
+			})
+		}
+	}
+
+	s := &span{tracer: t}
+	if t.bridged != nil {
+		bridgedOpts := append(references,
+			opentracing.StartTime(options.StartTime),
+			opentracing.Tags(options.Tags))
+		s.bridged = t.bridged.StartSpan(operationName, bridgedOpts...)
+		for k, v := range baggage {
+			if s.bridged.BaggageItem(k) != v {
+				s.bridged.SetBaggageItem(k, v)
+			}
+		}
+	}
+
+	sc := SpanContext{Baggage: baggage}
+	if parent != nil {
+		sc.TraceID = parent.w3c.TraceID
+		sc.Flags = parent.w3c.Flags
+		sc.State = parent.w3c.State
+	} else {
+		sc.TraceID = t.newTraceID()
+		if s.bridged != nil && isSampled(s.bridged.Context()) {
+			sc.Flags = FlagSampled
+		}
+	}
+	sc.SpanID = t.newSpanID()
+	s.context = sc
+	return s
+}
+
+// isSampled returns true if a bridged span context, like Jaeger's, reports
+// that it is sampled.
+func isSampled(sc opentracing.SpanContext) bool {
+	s, ok := sc.(interface {
+		IsSampled() bool
+	})
+	return ok && s.IsSampled()
+}
+
+func (t *Tracer) newTraceID() (id TraceID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for !id.IsValid() {
+		t.rand.Read(id[:])
+	}
+	return id
+}
+
+func (t *Tracer) newSpanID() (id SpanID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for !id.IsValid() {
+		t.rand.Read(id[:])
+	}
+	return id
+}
+
+// Inject injects the span context into the given carrier.
+func (t *Tracer) Inject(sm opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	sc, ok := sm.(*spanContext)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+
+	switch format {
+	case opentracing.TextMap, opentracing.HTTPHeaders:
+		w, ok := carrier.(opentracing.TextMapWriter)
+		if !ok {
+			return opentracing.ErrInvalidCarrier
+		}
+		Inject(sc.w3c, w)
+	case opentracing.Binary:
+		w, ok := carrier.(io.Writer)
+		if !ok {
+			return opentracing.ErrInvalidCarrier
+		}
+		if err := injectBinary(sc.w3c, w); err != nil {
+			return err
+		}
+	default:
+		if t.bridged == nil || sc.bridged == nil {
+			return opentracing.ErrUnsupportedFormat
+		}
+		return t.bridged.Inject(sc.bridged, format, carrier)
+	}
+
+	if t.bridged == nil || sc.bridged == nil {
+		return nil
+	}
+	// The W3C headers were injected, so bridged tracers which do not support
+	// this format are not an error.
+	if err := t.bridged.Inject(sc.bridged, format, carrier); err != opentracing.ErrUnsupportedFormat {
+		return err
+	}
+	return nil
+}
+
+// Extract extracts a span context from the given carrier. It fails with
+// opentracing.ErrSpanContextNotFound if the carrier has neither a valid W3C
+// trace context nor a span context of the bridged tracer.
+func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	var (
+		sc  spanContext
+		err error
+	)
+
+	switch format {
+	case opentracing.TextMap, opentracing.HTTPHeaders:
+		r, ok := carrier.(opentracing.TextMapReader)
+		if !ok {
+			return nil, opentracing.ErrInvalidCarrier
+		}
+		if sc.w3c, err = Extract(r); err != nil {
+			return nil, err
+		}
+	case opentracing.Binary:
+		r, ok := carrier.(io.Reader)
+		if !ok {
+			return nil, opentracing.ErrInvalidCarrier
+		}
+		if sc.w3c, r, err = extractBinary(r); err != nil {
+			return nil, err
+		}
+		carrier = r
+	default:
+		if t.bridged == nil {
+			return nil, opentracing.ErrUnsupportedFormat
+		}
+	}
+
+	if t.bridged != nil {
+		bridged, err := t.bridged.Extract(format, carrier)
+		switch err {
+		case nil:
+			sc.bridged = bridged
+			bridged.ForeachBaggageItem(func(k, v string) bool {
+				if _, ok := sc.w3c.Baggage[k]; !ok {
+					if sc.w3c.Baggage == nil {
+						sc.w3c.Baggage = make(map[string]string)
+					}
+					sc.w3c.Baggage[k] = v
+				}
+				return true
+			})
+		case opentracing.ErrSpanContextNotFound:
+		case opentracing.ErrUnsupportedFormat:
+			if !isW3CFormat(format) {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+	}
+
+	if !sc.w3c.IsValid() && sc.bridged == nil {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	return &sc, nil
+}
+
+func isW3CFormat(format interface{}) bool {
+	switch format {
+	case opentracing.TextMap, opentracing.HTTPHeaders, opentracing.Binary:
+		return true
+	default:
+		return false
+	}
+}
+
+// Inject writes the traceparent, tracestate and baggage headers of the given
+// SpanContext into the carrier. Nothing is written for invalid contexts.
+func Inject(sc SpanContext, carrier opentracing.TextMapWriter) {
+	if sc.IsValid() {
+		carrier.Set(TraceParentHeader, sc.TraceParent())
+		if sc.State != "" {
+			carrier.Set(TraceStateHeader, sc.State)
+		}
+	}
+	if baggage := FormatBaggage(sc.Baggage); baggage != "" {
+		carrier.Set(BaggageHeader, baggage)
+	}
+}
+
+// Extract reads a SpanContext from the traceparent, tracestate and baggage
+// headers of the carrier. Header names are matched case-insensitively, and
+// repeated tracestate and baggage headers are combined.
+//
+// A missing or invalid traceparent yields an invalid SpanContext, which may
+// still carry baggage; it is not an error. Errors are only returned by the
+// carrier.
+func Extract(carrier opentracing.TextMapReader) (SpanContext, error) {
+	var (
+		sc                   SpanContext
+		parents              []string
+		states, baggageItems []string
+	)
+	err := carrier.ForeachKey(func(k, v string) error {
+		switch strings.ToLower(k) {
+		case TraceParentHeader:
+			parents = append(parents, v)
+		case TraceStateHeader:
+			states = append(states, v)
+		case BaggageHeader:
+			baggageItems = append(baggageItems, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return sc, err
+	}
+
+	// Requests with several traceparent headers are treated as if they had
+	// none.
+	if len(parents) == 1 {
+		if parsed, err := ParseTraceParent(parents[0]); err == nil {
+			sc = parsed
+			sc.State, _ = ParseTraceState(strings.Join(states, ","))
+		}
+	}
+	sc.Baggage = ParseBaggage(strings.Join(baggageItems, ","))
+	return sc, nil
+}
+
+func injectBinary(sc SpanContext, w io.Writer) error {
+	headers := make(opentracing.TextMapCarrier)
+	Inject(sc, headers)
+
+	var buf bytes.Buffer
+	buf.Write(_binaryMagic)
+	for _, k := range []string{TraceParentHeader, TraceStateHeader, BaggageHeader} {
+		v := headers[k]
+		var n [binary.MaxVarintLen64]byte
+		buf.Write(n[:binary.PutUvarint(n[:], uint64(len(v)))])
+		buf.WriteString(v)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// extractBinary extracts a SpanContext written by injectBinary, and returns
+// a reader of the remaining bytes. Readers which do not start with the magic
+// number are returned as-is, with an invalid SpanContext.
+func extractBinary(r io.Reader) (SpanContext, io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(_binaryMagic))
+	if err != nil || !bytes.Equal(magic, _binaryMagic) {
+		return SpanContext{}, br, nil
+	}
+	if _, err := br.Discard(len(_binaryMagic)); err != nil {
+		return SpanContext{}, nil, err
+	}
+
+	headers := make(opentracing.TextMapCarrier)
+	for _, k := range []string{TraceParentHeader, TraceStateHeader, BaggageHeader} {
+		n, err := binary.ReadUvarint(br)
+		if err != nil || n > _maxBinaryHeaderSize {
+			return SpanContext{}, nil, opentracing.ErrSpanContextCorrupted
+		}
+		v := make([]byte, n)
+		if _, err := io.ReadFull(br, v); err != nil {
+			return SpanContext{}, nil, opentracing.ErrSpanContextCorrupted
+		}
+		if len(v) > 0 {
+			headers[k] = string(v)
+		}
+	}
+
+	sc, err := Extract(headers)
+	return sc, br, err
+}
+
+// span is a span started by a Tracer. Standalone spans are not recorded.
+type span struct {
+	tracer  *Tracer
+	bridged opentracing.Span // nil if standalone
+
+	mu      sync.Mutex
+	context SpanContext
+}
+
+func (s *span) Finish() {
+	if s.bridged != nil {
+		s.bridged.Finish()
+	}
+}
+
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	if s.bridged != nil {
+		s.bridged.FinishWithOptions(opts)
+	}
+}
+
+func (s *span) Context() opentracing.SpanContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc := &spanContext{w3c: s.context}
+	if s.bridged != nil {
+		sc.bridged = s.bridged.Context()
+	}
+	return sc
+}
+
+func (s *span) SetOperationName(operationName string) opentracing.Span {
+	if s.bridged != nil {
+		s.bridged.SetOperationName(operationName)
+	}
+	return s
+}
+
+func (s *span) SetTag(key string, value interface{}) opentracing.Span {
+	if s.bridged != nil {
+		s.bridged.SetTag(key, value)
+	}
+	return s
+}
+
+func (s *span) LogFields(fields ...log.Field) {
+	if s.bridged != nil {
+		s.bridged.LogFields(fields...)
+	}
+}
+
+func (s *span) LogKV(alternatingKeyValues ...interface{}) {
+	if s.bridged != nil {
+		s.bridged.LogKV(alternatingKeyValues...)
+	}
+}
+
+func (s *span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	if s.bridged != nil {
+		s.bridged.SetBaggageItem(restrictedKey, value)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// SpanContexts handed out are immutable, so the baggage is copied.
+	baggage := make(map[string]string, len(s.context.Baggage)+1)
+	for k, v := range s.context.Baggage {
+		baggage[k] = v
+	}
+	baggage[restrictedKey] = value
+	s.context.Baggage = baggage
+	return s
+}
+
+func (s *span) BaggageItem(restrictedKey string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.context.Baggage[restrictedKey]
+}
+
+func (s *span) Tracer() opentracing.Tracer { return s.tracer }
+
+func (s *span) LogEvent(event string) {
+	if s.bridged != nil {
+		s.bridged.LogEvent(event)
+	}
+}
+
+func (s *span) LogEventWithPayload(event string, payload interface{}) {
+	if s.bridged != nil {
+		s.bridged.LogEventWithPayload(event, payload)
+	}
+}
+
+func (s *span) Log(data opentracing.LogData) {
+	if s.bridged != nil {
+		s.bridged.Log(data)
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tracecontext
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/encoding/json"
+	"go.uber.org/yarpc/internal/testtime"
+	"go.uber.org/yarpc/serialize"
+	"go.uber.org/yarpc/transport/http"
+)
+
+func extractHeaders(t *testing.T, tracer opentracing.Tracer, headers nethttp.Header) opentracing.SpanContext {
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers))
+	require.NoError(t, err, "failed to extract span context")
+	return sc
+}
+
+func TestStandaloneTracer(t *testing.T) {
+	tracer := NewTracer()
+
+	parent := extractHeaders(t, tracer, nethttp.Header{
+		"Traceparent": {_traceParent},
+		"Tracestate":  {"foo=1"},
+		"Baggage":     {"weapon=knife"},
+	})
+	span := tracer.StartSpan("test", ext.RPCServerOption(parent))
+	defer span.Finish()
+	assert.Equal(t, "knife", span.BaggageItem("weapon"))
+	span.SetBaggageItem("fruit", "apple")
+
+	sc, ok := FromContext(opentracing.ContextWithSpan(context.Background(), span))
+	require.True(t, ok, "expected a W3C span context")
+	assert.Equal(t, _traceID, sc.TraceID.String(), "trace ID must be continued")
+	assert.NotEqual(t, _spanID, sc.SpanID.String(), "span ID must be new")
+	assert.True(t, sc.Flags.IsSampled())
+
+	headers := make(nethttp.Header)
+	require.NoError(t, tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers)))
+	assert.Equal(t, nethttp.Header{
+		"Traceparent": {sc.TraceParent()},
+		"Tracestate":  {"foo=1"},
+		"Baggage":     {"fruit=apple,weapon=knife"},
+	}, headers)
+
+	root := tracer.StartSpan("root")
+	rootContext := root.Context().(*spanContext).w3c
+	assert.True(t, rootContext.IsValid(), "root spans start a new trace")
+	assert.NotEqual(t, _traceID, rootContext.TraceID.String())
+	assert.False(t, rootContext.Flags.IsSampled())
+}
+
+func TestStandaloneTracerErrors(t *testing.T) {
+	tracer := NewTracer()
+
+	_, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(nethttp.Header{}))
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	_, err = tracer.Extract("unknown", nil)
+	assert.Equal(t, opentracing.ErrUnsupportedFormat, err)
+
+	_, err = tracer.Extract(opentracing.Binary, "not a reader")
+	assert.Equal(t, opentracing.ErrInvalidCarrier, err)
+
+	span := tracer.StartSpan("test")
+	assert.Equal(t, opentracing.ErrUnsupportedFormat, tracer.Inject(span.Context(), "unknown", nil))
+	assert.Equal(t, opentracing.ErrInvalidCarrier, tracer.Inject(span.Context(), opentracing.TextMap, "not a writer"))
+
+	other := mocktracer.New().StartSpan("test")
+	assert.Equal(t, opentracing.ErrInvalidSpanContext,
+		tracer.Inject(other.Context(), opentracing.TextMap, opentracing.TextMapCarrier{}))
+}
+
+func TestBinary(t *testing.T) {
+	tracer := NewTracer()
+	span := tracer.StartSpan("test")
+	span.SetBaggageItem("weapon", "knife")
+
+	var buf bytes.Buffer
+	require.NoError(t, tracer.Inject(span.Context(), opentracing.Binary, &buf))
+
+	sc, err := tracer.Extract(opentracing.Binary, bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, span.Context().(*spanContext).w3c, sc.(*spanContext).w3c)
+
+	_, err = tracer.Extract(opentracing.Binary, bytes.NewReader(nil))
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	_, err = tracer.Extract(opentracing.Binary, bytes.NewReader(buf.Bytes()[:10]))
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+
+	// Lengths beyond the limit are rejected before anything is allocated.
+	var huge bytes.Buffer
+	huge.Write(_binaryMagic)
+	lenBuf := make([]byte, binary.MaxVarintLen64)
+	huge.Write(lenBuf[:binary.PutUvarint(lenBuf, math.MaxUint64)])
+	_, err = tracer.Extract(opentracing.Binary, &huge)
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+
+	huge.Reset()
+	huge.Write(_binaryMagic)
+	huge.Write(lenBuf[:binary.PutUvarint(lenBuf, _maxBinaryHeaderSize+1)])
+	huge.Write(make([]byte, _maxBinaryHeaderSize+1))
+	_, err = tracer.Extract(opentracing.Binary, &huge)
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+}
+
+func TestSpanContextTraceID(t *testing.T) {
+	span := NewTracer().StartSpan("test")
+	sc, ok := FromContext(opentracing.ContextWithSpan(context.Background(), span))
+	require.True(t, ok)
+
+	// YARPC introspection looks the trace ID up with this method.
+	withTraceID, ok := span.Context().(interface {
+		TraceID() string
+	})
+	require.True(t, ok)
+	assert.Equal(t, sc.TraceID.String(), withTraceID.TraceID())
+
+	assert.Empty(t, (&spanContext{}).TraceID())
+}
+
+func TestBridgedTracer(t *testing.T) {
+	mock := mocktracer.New()
+	tracer := NewTracer(Bridge(mock))
+
+	// A caller which only speaks W3C.
+	parent := extractHeaders(t, tracer, nethttp.Header{
+		"Traceparent": {_traceParent},
+		"Baggage":     {"weapon=knife"},
+	})
+	server := tracer.StartSpan("server", ext.RPCServerOption(parent), opentracing.Tag{Key: "k", Value: "v"})
+	assert.Equal(t, "knife", server.BaggageItem("weapon"))
+	assert.Equal(t, "knife", server.(*span).bridged.BaggageItem("weapon"),
+		"W3C baggage should be visible to the bridged tracer")
+
+	child := tracer.StartSpan("client", opentracing.ChildOf(server.Context()))
+	child.SetBaggageItem("fruit", "apple")
+	headers := make(nethttp.Header)
+	require.NoError(t, tracer.Inject(child.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(headers)))
+	child.Finish()
+	server.Finish()
+
+	childContext := child.Context().(*spanContext).w3c
+	assert.Equal(t, _traceID, childContext.TraceID.String())
+	assert.Equal(t, childContext.TraceParent(), headers.Get("traceparent"))
+	assert.Equal(t, "fruit=apple,weapon=knife", headers.Get("baggage"))
+	assert.NotEmpty(t, headers.Get("mockpfx-ids-traceid"), "bridged tracer headers should be injected")
+
+	finished := mock.FinishedSpans()
+	require.Len(t, finished, 2)
+	assert.Equal(t, "client", finished[0].OperationName)
+	assert.Equal(t, "server", finished[1].OperationName)
+	assert.Equal(t, finished[1].SpanContext.SpanID, finished[0].ParentID)
+	assert.Equal(t, "v", finished[1].Tag("k"))
+
+	// A caller which only speaks the bridged tracer's format.
+	headers.Del("traceparent")
+	headers.Del("baggage")
+	sc := extractHeaders(t, tracer, headers).(*spanContext)
+	assert.False(t, sc.w3c.IsValid())
+	assert.Equal(t, map[string]string{"fruit": "apple", "weapon": "knife"}, sc.w3c.Baggage)
+
+	next := tracer.StartSpan("next", ext.RPCServerOption(sc))
+	next.Finish()
+	assert.Equal(t, finished[0].SpanContext.TraceID, mock.FinishedSpans()[2].SpanContext.TraceID,
+		"bridged trace should be continued")
+	assert.True(t, next.Context().(*spanContext).w3c.IsValid(), "a new W3C trace should be started")
+
+	// Mock tracers do not support the Binary format, which is not an error.
+	var buf bytes.Buffer
+	require.NoError(t, tracer.Inject(child.Context(), opentracing.Binary, &buf))
+	binary, err := tracer.Extract(opentracing.Binary, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, childContext.TraceID, binary.(*spanContext).w3c.TraceID)
+}
+
+func TestSerialize(t *testing.T) {
+	tracer := NewTracer()
+	span := tracer.StartSpan("test")
+
+	req := &transport.Request{
+		Caller:    "caller",
+		Service:   "service",
+		Encoding:  "raw",
+		Procedure: "procedure",
+		Body:      bytes.NewReader([]byte("body")),
+	}
+	b, err := serialize.ToBytes(tracer, span.Context(), req)
+	require.NoError(t, err)
+
+	sc, _, err := serialize.FromBytes(tracer, b)
+	require.NoError(t, err)
+	assert.Equal(t, span.Context().(*spanContext).w3c, sc.(*spanContext).w3c)
+}
+
+func TestHTTPTransport(t *testing.T) {
+	tracer := NewTracer()
+
+	// Record what a W3C-aware server receives from the HTTP outbound.
+	received := make(chan nethttp.Header, 1)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		received <- r.Header
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	trans := http.NewTransport(http.Tracer(tracer))
+	inbound := trans.NewInbound("127.0.0.1:0")
+	dispatcher := yarpc.NewDispatcher(yarpc.Config{
+		Name:     "server",
+		Inbounds: yarpc.Inbounds{inbound},
+		Outbounds: yarpc.Outbounds{
+			"backend": {Unary: trans.NewSingleOutbound(server.URL)},
+		},
+	})
+	client := json.New(dispatcher.ClientConfig("backend"))
+
+	var handled SpanContext
+	dispatcher.Register(json.Procedure("echo", func(ctx context.Context, body map[string]string) (map[string]string, error) {
+		handled, _ = FromContext(ctx)
+		var res map[string]string
+		return body, client.Call(ctx, "backend", body, &res)
+	}))
+	require.NoError(t, dispatcher.Start())
+	defer dispatcher.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+	defer cancel()
+
+	req, err := nethttp.NewRequest("POST", "http://"+inbound.Addr().String(), bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	req.Header.Set(http.CallerHeader, "caller")
+	req.Header.Set(http.ServiceHeader, "server")
+	req.Header.Set(http.ProcedureHeader, "echo")
+	req.Header.Set(http.EncodingHeader, "json")
+	req.Header.Set(http.TTLMSHeader, "1000")
+	req.Header.Set("traceparent", _traceParent)
+	req.Header.Set("tracestate", "foo=1")
+	res, err := nethttp.DefaultClient.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, nethttp.StatusOK, res.StatusCode)
+
+	assert.Equal(t, _traceID, handled.TraceID.String(), "inbound should continue the trace")
+
+	headers := <-received
+	outbound, err := ParseTraceParent(headers.Get("traceparent"))
+	require.NoError(t, err, "outbound should send a valid traceparent")
+	assert.Equal(t, _traceID, outbound.TraceID.String())
+	assert.NotEqual(t, _spanID, outbound.SpanID.String())
+	assert.NotEqual(t, handled.SpanID, outbound.SpanID, "outbound calls get their own span")
+	assert.Equal(t, "foo=1", headers.Get("tracestate"))
+}
@@ -54,6 +54,52 @@ func (m *service) procs(ctx context.Context, body interface{}) (*procsResponse,
 	}, nil
 }
 
+type peersResponse struct {
+	Service   string          `json:"service"`
+	Outbounds []outboundPeers `json:"outbounds"`
+}
+
+type outboundPeers struct {
+	OutboundKey string                     `json:"outboundKey"`
+	Service     string                     `json:"service"`
+	RPCType     string                     `json:"rpcType"`
+	Chooser     string                     `json:"chooser"`
+	Peers       []introspection.PeerStatus `json:"peers"`
+}
+
+func (m *service) peers(ctx context.Context, body interface{}) (*peersResponse, error) {
+	status := m.disp.Introspect()
+	outbounds := make([]outboundPeers, 0, len(status.Outbounds))
+	for _, o := range status.Outbounds {
+		outbounds = append(outbounds, outboundPeers{
+			OutboundKey: o.OutboundKey,
+			Service:     o.Service,
+			RPCType:     o.RPCType,
+			Chooser:     o.Chooser.Name,
+			Peers:       o.Chooser.Peers,
+		})
+	}
+	return &peersResponse{
+		Service:   m.disp.Name(),
+		Outbounds: outbounds,
+	}, nil
+}
+
+type requestsResponse struct {
+	Service         string                             `json:"service"`
+	RecentFailures  []introspection.RequestStatus      `json:"recentFailures"`
+	SlowestRequests []introspection.SlowRequestsStatus `json:"slowestRequests"`
+}
+
+func (m *service) requests(ctx context.Context, body interface{}) (*requestsResponse, error) {
+	status := m.disp.Introspect()
+	return &requestsResponse{
+		Service:         m.disp.Name(),
+		RecentFailures:  status.RecentFailures,
+		SlowestRequests: status.SlowestRequests,
+	}, nil
+}
+
 func (m *service) introspect(ctx context.Context, body interface{}) (*introspection.DispatcherStatus, error) {
 	status := m.disp.Introspect()
 	return &status, nil
@@ -70,6 +116,10 @@ func (m *service) Procedures() []transport.Procedure {
 			`procedures() {"service": "...", "procedures": [{"name": "..."}]}`},
 		{"yarpc::introspect", m.introspect,
 			`introspect() {...}`},
+		{"yarpc::peers", m.peers,
+			`peers() {"service": "...", "outbounds": [{"outboundKey": "...", "peers": [{"identifier": "...", "stats": {...}}]}]}`},
+		{"yarpc::requests", m.requests,
+			`requests() {"service": "...", "recentFailures": [{"procedure": "...", "code": "..."}], "slowestRequests": [{"procedure": "...", "requests": [...]}]}`},
 	}
 	var r []transport.Procedure
 	for _, m := range methods {
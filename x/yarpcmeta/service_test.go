@@ -22,12 +22,17 @@ package yarpcmeta
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
 	"go.uber.org/yarpc/encoding/json"
+	yarpchttp "go.uber.org/yarpc/transport/http"
+	"go.uber.org/yarpc/yarpcerrors"
 )
 
 func TestProcedures(t *testing.T) {
@@ -65,3 +70,60 @@ func TestProcedures(t *testing.T) {
 	}
 	assert.True(t, found)
 }
+
+func TestPeers(t *testing.T) {
+	httpTransport := yarpchttp.NewTransport()
+	disp := yarpc.NewDispatcher(yarpc.Config{
+		Name: "myservice",
+		Outbounds: yarpc.Outbounds{
+			"other": {
+				Unary: httpTransport.NewSingleOutbound("http://127.0.0.1:1234"),
+			},
+		},
+	})
+	ms := &service{disp}
+
+	r, err := ms.peers(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "myservice", r.Service)
+	require.Len(t, r.Outbounds, 1)
+	assert.Equal(t, "other", r.Outbounds[0].OutboundKey)
+	assert.Equal(t, "unary", r.Outbounds[0].RPCType)
+	assert.Equal(t, "Single", r.Outbounds[0].Chooser)
+	require.Len(t, r.Outbounds[0].Peers, 1)
+	assert.Equal(t, "127.0.0.1:1234", r.Outbounds[0].Peers[0].Identifier)
+}
+
+func TestRequests(t *testing.T) {
+	disp := yarpc.NewDispatcher(yarpc.Config{
+		Name: "myservice",
+	})
+	disp.Register(json.Procedure("fail",
+		func(context.Context, interface{}) (interface{}, error) {
+			return nil, yarpcerrors.NotFoundErrorf("no such thing")
+		}))
+	ms := &service{disp}
+
+	req := &transport.Request{
+		Caller:    "caller",
+		Service:   "myservice",
+		Encoding:  json.Encoding,
+		Procedure: "fail",
+		Body:      strings.NewReader("{}"),
+	}
+	spec, err := disp.Router().Choose(context.Background(), req)
+	require.NoError(t, err)
+	err = spec.Unary().Handle(context.Background(), req, &transporttest.FakeResponseWriter{})
+	require.Error(t, err)
+
+	r, err := ms.requests(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "myservice", r.Service)
+	require.Len(t, r.RecentFailures, 1)
+	assert.Equal(t, "inbound", r.RecentFailures[0].Direction)
+	assert.Equal(t, "fail", r.RecentFailures[0].Procedure)
+	assert.Equal(t, "not-found", r.RecentFailures[0].Code)
+	require.Len(t, r.SlowestRequests, 1)
+	assert.Equal(t, "fail", r.SlowestRequests[0].Procedure)
+	assert.Len(t, r.SlowestRequests[0].Requests, 1)
+}
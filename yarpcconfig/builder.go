@@ -21,12 +21,14 @@
 package yarpcconfig
 
 import (
+	"errors"
 	"fmt"
 
 	"go.uber.org/multierr"
 	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/internal/config"
+	"go.uber.org/yarpc/transport/x/split"
 )
 
 type buildableOutbounds struct {
@@ -43,12 +45,34 @@ type buildableInbound struct {
 type buildableOutbound struct {
 	TransportSpec *compiledTransportSpec
 	Value         *buildable
+
+	// Set instead of TransportSpec and Value for outbounds which split
+	// traffic between weighted child outbounds.
+	Split []buildableSplitChild
+}
+
+type buildableSplitChild struct {
+	Name     string
+	Weight   int
+	Outbound *buildableOutbound
+}
+
+// splitChildSpec is a child outbound of a split outbound whose transport
+// has been resolved.
+type splitChildSpec struct {
+	Name       string
+	Weight     int
+	Spec       *compiledTransportSpec
+	Attributes config.AttributeMap
 }
 
 type builder struct {
 	Name string
 	kit  *Kit
 
+	// TTLs of outbound requests.
+	TTLs yarpc.TTLConfig
+
 	// Transports that we actually need and their specs. We need a transport
 	// only if we have at least one inbound or outbound using it.
 	needTransports map[string]*compiledTransportSpec
@@ -71,7 +95,7 @@ func newBuilder(name string, kit *Kit) *builder {
 func (b *builder) Build() (yarpc.Config, error) {
 	var (
 		transports = make(map[string]transport.Transport)
-		cfg        = yarpc.Config{Name: b.Name}
+		cfg        = yarpc.Config{Name: b.Name, TTLs: b.TTLs}
 		errs       error
 	)
 
@@ -112,14 +136,14 @@ func (b *builder) Build() (yarpc.Config, error) {
 		}
 
 		if o := c.Unary; o != nil {
-			ob.Unary, err = buildUnaryOutbound(o, transports[o.TransportSpec.Name], b.kit)
+			ob.Unary, err = buildUnaryOutbound(o, transports, b.kit)
 			if err != nil {
 				errs = multierr.Append(errs, fmt.Errorf(`failed to configure unary outbound for %q: %v`, ccname, err))
 				continue
 			}
 		}
 		if o := c.Oneway; o != nil {
-			ob.Oneway, err = buildOnewayOutbound(o, transports[o.TransportSpec.Name], b.kit)
+			ob.Oneway, err = buildOnewayOutbound(o, transports, b.kit)
 			if err != nil {
 				errs = multierr.Append(errs, fmt.Errorf(`failed to configure oneway outbound for %q: %v`, ccname, err))
 				continue
@@ -157,8 +181,20 @@ func buildInbound(cv *buildable, t transport.Transport, k *Kit) (transport.Inbou
 
 // buildUnaryOutbound builds an UnaryOutbound from the given value. This will panic
 // if the output type for this is not transport.UnaryOutbound.
-func buildUnaryOutbound(o *buildableOutbound, t transport.Transport, k *Kit) (transport.UnaryOutbound, error) {
-	result, err := o.Value.Build(t, k.withTransportSpec(o.TransportSpec))
+func buildUnaryOutbound(o *buildableOutbound, transports map[string]transport.Transport, k *Kit) (transport.UnaryOutbound, error) {
+	if o.Split != nil {
+		children := make([]split.UnaryChild, len(o.Split))
+		for i, c := range o.Split {
+			child, err := buildUnaryOutbound(c.Outbound, transports, k)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure split outbound %q: %v", c.Name, err)
+			}
+			children[i] = split.UnaryChild{Name: c.Name, Weight: c.Weight, Outbound: child}
+		}
+		return split.NewUnaryOutbound(children...)
+	}
+
+	result, err := o.Value.Build(transports[o.TransportSpec.Name], k.withTransportSpec(o.TransportSpec))
 	if err != nil {
 		return nil, err
 	}
@@ -167,8 +203,20 @@ func buildUnaryOutbound(o *buildableOutbound, t transport.Transport, k *Kit) (tr
 
 // buildOnewayOutbound builds an OnewayOutbound from the given value. This will
 // panic if the output type for this is not transport.OnewayOutbound.
-func buildOnewayOutbound(o *buildableOutbound, t transport.Transport, k *Kit) (transport.OnewayOutbound, error) {
-	result, err := o.Value.Build(t, k.withTransportSpec(o.TransportSpec))
+func buildOnewayOutbound(o *buildableOutbound, transports map[string]transport.Transport, k *Kit) (transport.OnewayOutbound, error) {
+	if o.Split != nil {
+		children := make([]split.OnewayChild, len(o.Split))
+		for i, c := range o.Split {
+			child, err := buildOnewayOutbound(c.Outbound, transports, k)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure split outbound %q: %v", c.Name, err)
+			}
+			children[i] = split.OnewayChild{Name: c.Name, Weight: c.Weight, Outbound: child}
+		}
+		return split.NewOnewayOutbound(children...)
+	}
+
+	result, err := o.Value.Build(transports[o.TransportSpec.Name], k.withTransportSpec(o.TransportSpec))
 	if err != nil {
 		return nil, err
 	}
@@ -233,47 +281,113 @@ func (b *builder) AddImplicitOutbound(
 func (b *builder) AddUnaryOutbound(
 	spec *compiledTransportSpec, outboundKey, service string, attrs config.AttributeMap,
 ) error {
-	if spec.UnaryOutbound == nil {
-		return fmt.Errorf("transport %q does not support unary outbound requests", spec.Name)
+	o, err := b.decodeUnaryOutbound(spec, attrs)
+	if err != nil {
+		return err
 	}
 
-	b.needTransport(spec)
-	cv, err := spec.UnaryOutbound.Decode(attrs, config.InterpolateWith(b.kit.resolver))
+	b.client(outboundKey, service).Unary = o
+	return nil
+}
+
+func (b *builder) AddOnewayOutbound(
+	spec *compiledTransportSpec, outboundKey, service string, attrs config.AttributeMap,
+) error {
+	o, err := b.decodeOnewayOutbound(spec, attrs)
 	if err != nil {
-		return fmt.Errorf("failed to decode unary outbound configuration: %v", err)
+		return err
 	}
 
-	cc, ok := b.clients[outboundKey]
-	if !ok {
-		cc = &buildableOutbounds{Service: service}
-		b.clients[outboundKey] = cc
+	b.client(outboundKey, service).Oneway = o
+	return nil
+}
+
+func (b *builder) AddImplicitSplitOutbound(children []splitChildSpec, outboundKey, service string) error {
+	supportsUnary, supportsOneway := true, true
+	for _, c := range children {
+		supportsUnary = supportsUnary && c.Spec.SupportsUnaryOutbound()
+		supportsOneway = supportsOneway && c.Spec.SupportsOnewayOutbound()
+	}
+
+	var errs error
+	if supportsUnary {
+		errs = multierr.Append(errs, b.AddUnarySplitOutbound(children, outboundKey, service))
+	}
+	if supportsOneway {
+		errs = multierr.Append(errs, b.AddOnewaySplitOutbound(children, outboundKey, service))
+	}
+
+	if !supportsUnary && !supportsOneway {
+		return errors.New("the transports of split outbounds do not support a common RPC type")
+	}
+
+	return errs
+}
+
+func (b *builder) AddUnarySplitOutbound(children []splitChildSpec, outboundKey, service string) error {
+	o := &buildableOutbound{Split: make([]buildableSplitChild, len(children))}
+	for i, c := range children {
+		child, err := b.decodeUnaryOutbound(c.Spec, c.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to add split outbound %q: %v", c.Name, err)
+		}
+		o.Split[i] = buildableSplitChild{Name: c.Name, Weight: c.Weight, Outbound: child}
 	}
 
-	cc.Unary = &buildableOutbound{TransportSpec: spec, Value: cv}
+	b.client(outboundKey, service).Unary = o
 	return nil
 }
 
-func (b *builder) AddOnewayOutbound(
-	spec *compiledTransportSpec, outboundKey, service string, attrs config.AttributeMap,
-) error {
+func (b *builder) AddOnewaySplitOutbound(children []splitChildSpec, outboundKey, service string) error {
+	o := &buildableOutbound{Split: make([]buildableSplitChild, len(children))}
+	for i, c := range children {
+		child, err := b.decodeOnewayOutbound(c.Spec, c.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to add split outbound %q: %v", c.Name, err)
+		}
+		o.Split[i] = buildableSplitChild{Name: c.Name, Weight: c.Weight, Outbound: child}
+	}
+
+	b.client(outboundKey, service).Oneway = o
+	return nil
+}
+
+func (b *builder) decodeUnaryOutbound(spec *compiledTransportSpec, attrs config.AttributeMap) (*buildableOutbound, error) {
+	if spec.UnaryOutbound == nil {
+		return nil, fmt.Errorf("transport %q does not support unary outbound requests", spec.Name)
+	}
+
+	b.needTransport(spec)
+	cv, err := spec.UnaryOutbound.Decode(attrs, config.InterpolateWith(b.kit.resolver))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode unary outbound configuration: %v", err)
+	}
+
+	return &buildableOutbound{TransportSpec: spec, Value: cv}, nil
+}
+
+func (b *builder) decodeOnewayOutbound(spec *compiledTransportSpec, attrs config.AttributeMap) (*buildableOutbound, error) {
 	if spec.OnewayOutbound == nil {
-		return fmt.Errorf("transport %q does not support oneway outbound requests", spec.Name)
+		return nil, fmt.Errorf("transport %q does not support oneway outbound requests", spec.Name)
 	}
 
 	b.needTransport(spec)
 	cv, err := spec.OnewayOutbound.Decode(attrs, config.InterpolateWith(b.kit.resolver))
 	if err != nil {
-		return fmt.Errorf("failed to decode oneway outbound configuration: %v", err)
+		return nil, fmt.Errorf("failed to decode oneway outbound configuration: %v", err)
 	}
 
+	return &buildableOutbound{TransportSpec: spec, Value: cv}, nil
+}
+
+// client returns the outbounds with the given key, adding them if needed.
+func (b *builder) client(outboundKey, service string) *buildableOutbounds {
 	cc, ok := b.clients[outboundKey]
 	if !ok {
 		cc = &buildableOutbounds{Service: service}
 		b.clients[outboundKey] = cc
 	}
-
-	cc.Oneway = &buildableOutbound{TransportSpec: spec, Value: cv}
-	return nil
+	return cc
 }
 
 func (b *builder) needTransport(spec *compiledTransportSpec) {
@@ -21,13 +21,16 @@
 package yarpcconfig
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"go.uber.org/yarpc/api/peer"
 	"go.uber.org/yarpc/internal/config"
 	peerbind "go.uber.org/yarpc/peer"
+	"go.uber.org/yarpc/peer/x/failover"
 )
 
 // PeerChooser facilitates decoding and building peer choosers. A peer chooser
@@ -39,7 +42,7 @@ import (
 // Format
 //
 // Peer chooser configuration may define only one of the following keys:
-// `peer`, `with`, or the name of any registered PeerListSpec.
+// `peer`, `with`, `failover`, or the name of any registered PeerListSpec.
 //
 // `peer` indicates that requests must be sent to a single peer.
 //
@@ -69,6 +72,29 @@ import (
 // robin peer list. The only remaining key is the name of the peer list
 // updater: `peers` which is just a static list of peers.
 //
+// `failover` combines an ordered list of peer choosers, each with its own
+// peer list updater. Requests are sent to the first healthy chooser. A
+// chooser is unhealthy when it produces no peer within `chooseTimeout`, or
+// when at least `errorThreshold` of the requests it served in a `window` of
+// time failed (once it served at least `minRequests`). Unhealthy choosers
+// are skipped for `failbackAfter`, after which they are tried again. The
+// last chooser is always used as a last resort.
+//
+// 	failover:
+// 	  chooseTimeout: 250ms
+// 	  errorThreshold: 0.5
+// 	  minRequests: 10
+// 	  window: 10s
+// 	  failbackAfter: 30s
+// 	  choosers:
+// 	    - round-robin:
+// 	        peers:
+// 	          - 127.0.0.1:8080
+// 	    - peer: 10.0.0.1:8080
+//
+// All attributes except `choosers` are optional. See the
+// go.uber.org/yarpc/peer/x/failover package for their defaults.
+//
 // Integration
 //
 // To integrate peer choosers with your transport, embed this struct into your
@@ -105,9 +131,20 @@ type PeerChooser struct {
 // peerChooser is the private representation of PeerChooser that captures
 // decoded configuration without revealing it on the public type.
 type peerChooser struct {
-	Peer   string              `config:"peer,interpolate"`
-	Preset string              `config:"with,interpolate"`
-	Etc    config.AttributeMap `config:",squash"`
+	Peer     string              `config:"peer,interpolate"`
+	Preset   string              `config:"with,interpolate"`
+	Failover *failoverChooser    `config:"failover"`
+	Etc      config.AttributeMap `config:",squash"`
+}
+
+// failoverChooser is the configuration of a failover peer chooser.
+type failoverChooser struct {
+	ChooseTimeout  time.Duration `config:"chooseTimeout"`
+	ErrorThreshold float64       `config:"errorThreshold"`
+	MinRequests    int           `config:"minRequests"`
+	Window         time.Duration `config:"window"`
+	FailbackAfter  time.Duration `config:"failbackAfter"`
+	Choosers       []PeerChooser `config:"choosers"`
 }
 
 // Empty returns true if the PeerChooser is empty, i.e., it does not have any
@@ -117,7 +154,7 @@ type peerChooser struct {
 // configuration is specified in a different way than the standard peer
 // configuration.
 func (pc PeerChooser) Empty() bool {
-	return pc.Peer == "" && pc.Preset == "" && len(pc.Etc) == 0
+	return pc.Peer == "" && pc.Preset == "" && pc.Failover == nil && len(pc.Etc) == 0
 }
 
 // BuildPeerChooser translates the decoded configuration into a peer.Chooser.
@@ -154,6 +191,17 @@ func (pc PeerChooser) BuildPeerChooser(transport peer.Transport, identify func(s
 		}
 
 		return preset.Build(transport, kit)
+	case pc.Failover != nil:
+		// myoutbound:
+		//   outboundopt1: ...
+		//   outboundopt2: ...
+		//   failover:
+		//     choosers:
+		//       - ...
+		if len(pc.Etc) > 0 {
+			return nil, fmt.Errorf("unrecognized attributes in outbound config: %+v", pc.Etc)
+		}
+		return pc.Failover.build(transport, identify, kit)
 	default:
 		// myoutbound:
 		//   outboundopt1: ...
@@ -203,6 +251,39 @@ func (pc PeerChooser) buildPeerChooser(transport peer.Transport, identify func(s
 	return peerbind.Bind(peerChooser, peerListUpdater), nil
 }
 
+func (fc *failoverChooser) build(transport peer.Transport, identify func(string) peer.Identifier, kit *Kit) (peer.Chooser, error) {
+	if len(fc.Choosers) == 0 {
+		return nil, errors.New("failover peer chooser requires at least one chooser")
+	}
+
+	choosers := make([]peer.Chooser, len(fc.Choosers))
+	for i, c := range fc.Choosers {
+		chooser, err := c.BuildPeerChooser(transport, identify, kit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build failover chooser %d: %v", i, err)
+		}
+		choosers[i] = chooser
+	}
+
+	var opts []failover.ChooserOption
+	if fc.ChooseTimeout > 0 {
+		opts = append(opts, failover.ChooseTimeout(fc.ChooseTimeout))
+	}
+	if fc.ErrorThreshold > 0 {
+		opts = append(opts, failover.ErrorThreshold(fc.ErrorThreshold))
+	}
+	if fc.MinRequests > 0 {
+		opts = append(opts, failover.MinRequests(fc.MinRequests))
+	}
+	if fc.Window > 0 {
+		opts = append(opts, failover.Window(fc.Window))
+	}
+	if fc.FailbackAfter > 0 {
+		opts = append(opts, failover.FailbackAfter(fc.FailbackAfter))
+	}
+	return failover.New(choosers, opts...), nil
+}
+
 // getPeerListInfo extracts the peer list entry from the given attribute map. It
 // must be the only remaining entry.
 //
@@ -41,6 +41,7 @@ import (
 	"go.uber.org/yarpc/peer"
 	"go.uber.org/yarpc/peer/hostport"
 	"go.uber.org/yarpc/peer/roundrobin"
+	"go.uber.org/yarpc/peer/x/failover"
 	"go.uber.org/yarpc/peer/x/peerheap"
 	"go.uber.org/yarpc/transport/http"
 	"go.uber.org/yarpc/transport/tchannel"
@@ -322,6 +323,97 @@ func TestChooserConfigurator(t *testing.T) {
 				_ = list
 			},
 		},
+		{
+			desc: "use failover chooser",
+			given: whitespace.Expand(`
+				outbounds:
+					their-service:
+						unary:
+							fake-transport:
+								failover:
+									chooseTimeout: 50ms
+									errorThreshold: 0.25
+									failbackAfter: 1m
+									choosers:
+										- round-robin:
+												peers:
+													- 127.0.0.1:8080
+										- peer: 127.0.0.1:9090
+			`),
+			test: func(t *testing.T, c yarpc.Config) {
+				outbound := c.Outbounds["their-service"]
+				unary := outbound.Unary.(*yarpctest.FakeOutbound)
+				chooser, ok := unary.Chooser().(*failover.Chooser)
+				require.True(t, ok, "use failover chooser")
+
+				dispatcher := yarpc.NewDispatcher(c)
+				require.NoError(t, dispatcher.Start(), "error starting dispatcher")
+				defer func() {
+					require.NoError(t, dispatcher.Stop(), "error stopping dispatcher")
+				}()
+
+				ctx, cancel := context.WithTimeout(context.Background(), testtime.Second)
+				defer cancel()
+				peer, onFinish, err := chooser.Choose(ctx, nil)
+				require.NoError(t, err, "error choosing peer")
+				defer onFinish(nil)
+
+				assert.Equal(t, "127.0.0.1:8080", peer.Identifier(), "chooses from the primary chooser")
+			},
+		},
+		{
+			desc: "failover chooser without choosers",
+			given: whitespace.Expand(`
+				outbounds:
+					their-service:
+						unary:
+							fake-transport:
+								failover:
+									failbackAfter: 1m
+			`),
+			wantErr: []string{
+				`failed to configure unary outbound for "their-service": `,
+				`failover peer chooser requires at least one chooser`,
+			},
+		},
+		{
+			desc: "failover chooser with invalid chooser",
+			given: whitespace.Expand(`
+				outbounds:
+					their-service:
+						unary:
+							fake-transport:
+								failover:
+									choosers:
+										- peer: 127.0.0.1:8080
+										- invalid-list:
+												peers:
+													- 127.0.0.1:9090
+			`),
+			wantErr: []string{
+				`failed to configure unary outbound for "their-service": `,
+				`failed to build failover chooser 1: `,
+				`could not create invalid-list`,
+			},
+		},
+		{
+			desc: "extraneous config in combination with failover",
+			given: whitespace.Expand(`
+				outbounds:
+					their-service:
+						unary:
+							fake-transport:
+								failover:
+									choosers:
+										- peer: 127.0.0.1:8080
+								conspicuously: present
+			`),
+			wantErr: []string{
+				`failed to configure unary outbound for "their-service": `,
+				`unrecognized attributes in outbound config: `,
+				`conspicuously`,
+			},
+		},
 		{
 			desc: "HTTP single peer implied by URL",
 			given: whitespace.Expand(`
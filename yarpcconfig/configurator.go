@@ -214,6 +214,7 @@ func (c *Configurator) NewDispatcher(serviceName string, data interface{}) (*yar
 
 func (c *Configurator) load(serviceName string, cfg *yarpcConfig) (_ yarpc.Config, err error) {
 	b := newBuilder(serviceName, &Kit{name: serviceName, c: c, resolver: c.resolver})
+	b.TTLs = cfg.TTLs.ttls()
 
 	for _, inbound := range cfg.Inbounds {
 		if e := c.loadInboundInto(b, inbound); e != nil {
@@ -225,6 +226,12 @@ func (c *Configurator) load(serviceName string, cfg *yarpcConfig) (_ yarpc.Confi
 		if e := c.loadOutboundInto(b, name, outboundConfig); e != nil {
 			err = multierr.Append(err, e)
 		}
+		if t := outboundConfig.TTL; t != nil {
+			if b.TTLs.Outbounds == nil {
+				b.TTLs.Outbounds = make(map[string]yarpc.ServiceTTLConfig)
+			}
+			b.TTLs.Outbounds[name] = t.ttls()
+		}
 	}
 
 	for name, attrs := range cfg.Transports {
@@ -261,7 +268,24 @@ func (c *Configurator) loadOutboundInto(b *builder, name string, cfg outbounds)
 	// AddUnaryOutbound and AddOnewayOutbound
 	type adder func(*compiledTransportSpec, string, string, config.AttributeMap) error
 
-	loadUsing := func(o *outbound, adder adder) error {
+	// This matches the signature of builder.AddImplicitSplitOutbound,
+	// AddUnarySplitOutbound and AddOnewaySplitOutbound
+	type splitAdder func([]splitChildSpec, string, string) error
+
+	loadUsing := func(o *outbound, adder adder, splitAdder splitAdder) error {
+		if o.Type == _splitOutboundType {
+			children, err := c.splitChildren(o.Attributes)
+			if err != nil {
+				return fmt.Errorf("failed to load configuration for outbound %q: %v", name, err)
+			}
+
+			if err := splitAdder(children, name, cfg.Service); err != nil {
+				return fmt.Errorf("failed to add outbound %q: %v", name, err)
+			}
+
+			return nil
+		}
+
 		spec, err := c.spec(o.Type)
 		if err != nil {
 			return fmt.Errorf("failed to load configuration for outbound %q: %v", name, err)
@@ -275,17 +299,17 @@ func (c *Configurator) loadOutboundInto(b *builder, name string, cfg outbounds)
 	}
 
 	if implicit := cfg.Implicit; implicit != nil {
-		return loadUsing(implicit, b.AddImplicitOutbound)
+		return loadUsing(implicit, b.AddImplicitOutbound, b.AddImplicitSplitOutbound)
 	}
 
 	if unary := cfg.Unary; unary != nil {
-		if err := loadUsing(unary, b.AddUnaryOutbound); err != nil {
+		if err := loadUsing(unary, b.AddUnaryOutbound, b.AddUnarySplitOutbound); err != nil {
 			return err
 		}
 	}
 
 	if oneway := cfg.Oneway; oneway != nil {
-		if err := loadUsing(oneway, b.AddOnewayOutbound); err != nil {
+		if err := loadUsing(oneway, b.AddOnewayOutbound, b.AddOnewaySplitOutbound); err != nil {
 			return err
 		}
 	}
@@ -293,6 +317,38 @@ func (c *Configurator) loadOutboundInto(b *builder, name string, cfg outbounds)
 	return nil
 }
 
+// splitChildren decodes the configuration of the children of a split
+// outbound.
+func (c *Configurator) splitChildren(attrs config.AttributeMap) ([]splitChildSpec, error) {
+	var cfg splitOutbound
+	if err := attrs.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode split outbound: %v", err)
+	}
+	if len(cfg.Outbounds) == 0 {
+		return nil, errors.New("a split outbound requires at least one outbound")
+	}
+
+	children := make([]splitChildSpec, len(cfg.Outbounds))
+	for i, child := range cfg.Outbounds {
+		if child.Outbound.Type == _splitOutboundType {
+			return nil, errors.New("split outbounds cannot be nested")
+		}
+
+		spec, err := c.spec(child.Outbound.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		children[i] = splitChildSpec{
+			Name:       child.Name,
+			Weight:     child.Weight,
+			Spec:       spec,
+			Attributes: child.Outbound.Attributes,
+		}
+	}
+	return children, nil
+}
+
 func (c *Configurator) loadTransportInto(b *builder, name string, attrs config.AttributeMap) error {
 	spec, err := c.spec(name)
 	if err != nil {
@@ -21,6 +21,7 @@
 package yarpcconfig
 
 import (
+	"context"
 	"reflect"
 	"strings"
 	"testing"
@@ -30,9 +31,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/yarpc"
+	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/api/transport/transporttest"
 	"go.uber.org/yarpc/internal/interpolate"
 	"go.uber.org/yarpc/internal/whitespace"
+	"go.uber.org/yarpc/transport/x/split"
 	"gopkg.in/yaml.v2"
 )
 
@@ -763,6 +766,126 @@ func TestConfigurator(t *testing.T) {
 				return
 			},
 		},
+		{
+			desc: "split outbound unknown transport",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				tt.give = whitespace.Expand(`
+					outbounds:
+						foo:
+							split:
+								outbounds:
+									- weight: 1
+									  bar: {}
+				`)
+				tt.wantErr = []string{
+					`failed to load configuration for outbound "foo"`,
+					`unknown transport "bar"`,
+				}
+				return
+			},
+		},
+		{
+			desc: "split outbound without outbounds",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				tt.give = whitespace.Expand(`
+					outbounds:
+						foo:
+							unary:
+								split: {}
+				`)
+				tt.wantErr = []string{"a split outbound requires at least one outbound"}
+				return
+			},
+		},
+		{
+			desc: "nested split outbound",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				tt.give = whitespace.Expand(`
+					outbounds:
+						foo:
+							split:
+								outbounds:
+									- weight: 1
+									  split:
+											outbounds: []
+				`)
+				tt.wantErr = []string{"split outbounds cannot be nested"}
+				return
+			},
+		},
+		{
+			desc: "split outbound no common RPC type",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ Address string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						foo:
+							split:
+								outbounds:
+									- weight: 1
+									  tchannel:
+											address: localhost:4040
+									- weight: 1
+									  redis:
+											address: localhost:6379
+				`)
+
+				tchan := mockTransportSpecBuilder{
+					Name:                "tchannel",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+				}.Build(mockCtrl)
+				redis := mockTransportSpecBuilder{
+					Name:                 "redis",
+					TransportConfig:      _typeOfEmptyStruct,
+					OnewayOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+				}.Build(mockCtrl)
+
+				tt.specs = []TransportSpec{tchan.Spec(), redis.Spec()}
+				tt.wantErr = []string{"the transports of split outbounds do not support a common RPC type"}
+				return
+			},
+		},
+		{
+			desc: "split outbound duplicate names",
+			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
+				type outboundConfig struct{ Address string }
+				tt.give = whitespace.Expand(`
+					outbounds:
+						foo:
+							unary:
+								split:
+									outbounds:
+										- weight: 1
+										  tchannel:
+												address: localhost:4040
+										- weight: 1
+										  tchannel:
+												address: localhost:4041
+				`)
+
+				tchan := mockTransportSpecBuilder{
+					Name:                "tchannel",
+					TransportConfig:     _typeOfEmptyStruct,
+					UnaryOutboundConfig: reflect.TypeOf(&outboundConfig{}),
+				}.Build(mockCtrl)
+				transport := transporttest.NewMockTransport(mockCtrl)
+				tchan.EXPECT().BuildTransport(struct{}{}, kitMatcher{}).Return(transport, nil)
+				tchan.EXPECT().
+					BuildUnaryOutbound(&outboundConfig{Address: "localhost:4040"}, transport, kitMatcher{}).
+					Return(transporttest.NewMockUnaryOutbound(mockCtrl), nil)
+				tchan.EXPECT().
+					BuildUnaryOutbound(&outboundConfig{Address: "localhost:4041"}, transport, kitMatcher{}).
+					Return(transporttest.NewMockUnaryOutbound(mockCtrl), nil)
+
+				tt.specs = []TransportSpec{tchan.Spec()}
+				tt.wantErr = []string{
+					`failed to configure unary outbound for "foo"`,
+					`duplicate child outbound "tchannel"`,
+				}
+				return
+			},
+		},
 		{
 			desc: "implicit outbound service name override",
 			test: func(t *testing.T, mockCtrl *gomock.Controller) (tt testCase) {
@@ -1123,9 +1246,142 @@ func TestConfigurator(t *testing.T) {
 	}
 }
 
+func TestConfiguratorSplitOutbound(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	type (
+		httpOutboundConfig  struct{ URL string }
+		redisOutboundConfig struct{ Queue string }
+	)
+
+	http := mockTransportSpecBuilder{
+		Name:                 "http",
+		TransportConfig:      _typeOfEmptyStruct,
+		UnaryOutboundConfig:  reflect.TypeOf(httpOutboundConfig{}),
+		OnewayOutboundConfig: reflect.TypeOf(httpOutboundConfig{}),
+	}.Build(mockCtrl)
+	redis := mockTransportSpecBuilder{
+		Name:                 "redis",
+		TransportConfig:      _typeOfEmptyStruct,
+		OnewayOutboundConfig: reflect.TypeOf(redisOutboundConfig{}),
+	}.Build(mockCtrl)
+
+	httpTransport := transporttest.NewMockTransport(mockCtrl)
+	http.EXPECT().BuildTransport(struct{}{}, kitMatcher{ServiceName: "myservice"}).Return(httpTransport, nil)
+	redisTransport := transporttest.NewMockTransport(mockCtrl)
+	redis.EXPECT().BuildTransport(struct{}{}, kitMatcher{ServiceName: "myservice"}).Return(redisTransport, nil)
+
+	httpOneway := transporttest.NewMockOnewayOutbound(mockCtrl)
+	http.EXPECT().
+		BuildOnewayOutbound(httpOutboundConfig{URL: "http://localhost:8080"}, httpTransport, kitMatcher{ServiceName: "myservice"}).
+		Return(httpOneway, nil)
+	redisOneway := transporttest.NewMockOnewayOutbound(mockCtrl)
+	redis.EXPECT().
+		BuildOnewayOutbound(redisOutboundConfig{Queue: "requests"}, redisTransport, kitMatcher{ServiceName: "myservice"}).
+		Return(redisOneway, nil)
+
+	cfg := New()
+	require.NoError(t, cfg.RegisterTransport(http.Spec()))
+	require.NoError(t, cfg.RegisterTransport(redis.Spec()))
+
+	// Only oneway outbounds are built because redis does not support unary
+	// outbounds.
+	c, err := cfg.LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+		outbounds:
+			foo:
+				split:
+					outbounds:
+						- weight: 90
+						  http:
+							  url: http://localhost:8080
+						- name: queue
+						  weight: 10
+						  redis:
+							  queue: requests
+	`)))
+	require.NoError(t, err)
+
+	outbounds := c.Outbounds["foo"]
+	assert.Nil(t, outbounds.Unary)
+	require.IsType(t, &split.OnewayOutbound{}, outbounds.Oneway)
+
+	o := outbounds.Oneway.(*split.OnewayOutbound)
+	assert.Equal(t, map[string]int{"http": 90, "queue": 10}, o.Weights())
+
+	require.NoError(t, o.SetWeights(map[string]int{"http": 0}))
+	redisOneway.EXPECT().CallOneway(gomock.Any(), gomock.Any()).Return(nil, nil)
+	_, err = o.CallOneway(context.Background(), &transport.Request{})
+	require.NoError(t, err)
+}
+
 func mapVariableResolver(m map[string]string) interpolate.VariableResolver {
 	return func(name string) (value string, ok bool) {
 		value, ok = m[name]
 		return
 	}
 }
+
+func TestConfiguratorTTLs(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	type httpOutboundConfig struct{ URL string }
+
+	http := mockTransportSpecBuilder{
+		Name:                "http",
+		TransportConfig:     _typeOfEmptyStruct,
+		UnaryOutboundConfig: reflect.TypeOf(httpOutboundConfig{}),
+	}.Build(mockCtrl)
+
+	httpTransport := transporttest.NewMockTransport(mockCtrl)
+	http.EXPECT().BuildTransport(struct{}{}, kitMatcher{ServiceName: "myservice"}).Return(httpTransport, nil)
+	http.EXPECT().
+		BuildUnaryOutbound(httpOutboundConfig{URL: "http://localhost:8080"}, httpTransport, kitMatcher{ServiceName: "myservice"}).
+		Return(transporttest.NewMockUnaryOutbound(mockCtrl), nil)
+
+	cfg := New()
+	require.NoError(t, cfg.RegisterTransport(http.Spec()))
+
+	c, err := cfg.LoadConfigFromYAML("myservice", strings.NewReader(whitespace.Expand(`
+		ttls:
+			default: 1s
+			max: 10s
+			services:
+				users:
+					default: 500ms
+					procedures:
+						search:
+							max: 1m
+		outbounds:
+			keyvalue:
+				ttl:
+					max: 2s
+					procedures:
+						scan:
+							default: 5s
+				http:
+					url: http://localhost:8080
+	`)))
+	require.NoError(t, err)
+
+	assert.Equal(t, yarpc.TTLConfig{
+		TTL: yarpc.TTL{Default: time.Second, Max: 10 * time.Second},
+		Outbounds: map[string]yarpc.ServiceTTLConfig{
+			"keyvalue": {
+				TTL: yarpc.TTL{Max: 2 * time.Second},
+				Procedures: map[string]yarpc.TTL{
+					"scan": {Default: 5 * time.Second},
+				},
+			},
+		},
+		Services: map[string]yarpc.ServiceTTLConfig{
+			"users": {
+				TTL: yarpc.TTL{Default: 500 * time.Millisecond},
+				Procedures: map[string]yarpc.TTL{
+					"search": {Max: time.Minute},
+				},
+			},
+		},
+	}, c.TTLs)
+}
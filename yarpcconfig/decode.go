@@ -23,8 +23,10 @@ package yarpcconfig
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/uber-go/mapdecode"
+	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/internal/config"
 )
 
@@ -32,6 +34,59 @@ type yarpcConfig struct {
 	Inbounds   inbounds                       `config:"inbounds"`
 	Outbounds  clientConfigs                  `config:"outbounds"`
 	Transports map[string]config.AttributeMap `config:"transports"`
+	TTLs       ttlsConfig                     `config:"ttls"`
+}
+
+// ttlsConfig is the configuration of the TTLs of all outbound requests.
+//
+// 	ttls:
+// 	  default: 1s
+// 	  max: 10s
+// 	  services:
+// 	    users:
+// 	      default: 500ms
+// 	      procedures:
+// 	        search:
+// 	          default: 2s
+type ttlsConfig struct {
+	Default  time.Duration               `config:"default"`
+	Max      time.Duration               `config:"max"`
+	Services map[string]serviceTTLConfig `config:"services"`
+}
+
+func (c ttlsConfig) ttls() yarpc.TTLConfig {
+	cfg := yarpc.TTLConfig{TTL: yarpc.TTL{Default: c.Default, Max: c.Max}}
+	if len(c.Services) > 0 {
+		cfg.Services = make(map[string]yarpc.ServiceTTLConfig, len(c.Services))
+		for name, s := range c.Services {
+			cfg.Services[name] = s.ttls()
+		}
+	}
+	return cfg
+}
+
+// serviceTTLConfig is the configuration of the TTLs of the requests sent to
+// a service or through an outbound.
+type serviceTTLConfig struct {
+	Default    time.Duration        `config:"default"`
+	Max        time.Duration        `config:"max"`
+	Procedures map[string]ttlConfig `config:"procedures"`
+}
+
+func (c serviceTTLConfig) ttls() yarpc.ServiceTTLConfig {
+	cfg := yarpc.ServiceTTLConfig{TTL: yarpc.TTL{Default: c.Default, Max: c.Max}}
+	if len(c.Procedures) > 0 {
+		cfg.Procedures = make(map[string]yarpc.TTL, len(c.Procedures))
+		for name, t := range c.Procedures {
+			cfg.Procedures[name] = yarpc.TTL{Default: t.Default, Max: t.Max}
+		}
+	}
+	return cfg
+}
+
+type ttlConfig struct {
+	Default time.Duration `config:"default"`
+	Max     time.Duration `config:"max"`
 }
 
 type inbounds []inbound
@@ -96,6 +151,9 @@ func (cc *clientConfigs) Decode(into mapdecode.Into) error {
 type outbounds struct {
 	Service string
 
+	// TTLs of the requests sent through the outbound, if configured.
+	TTL *serviceTTLConfig
+
 	// Either (Unary and/or Oneway) will be set or Implicit will be set. For
 	// the latter case, we need to only use those configurations that that
 	// transport supports.
@@ -116,6 +174,10 @@ func (o *outbounds) Decode(into mapdecode.Into) error {
 		return fmt.Errorf("failed to read service name for outbound: %v", err)
 	}
 
+	if _, err := attrs.Pop("ttl", &o.TTL); err != nil {
+		return fmt.Errorf("failed to read TTLs of outbound: %v", err)
+	}
+
 	hasUnary, err := attrs.Pop("unary", &o.Unary)
 	if err != nil {
 		return fmt.Errorf("failed to unary outbound configuration: %v", err)
@@ -170,3 +232,54 @@ func (o *outbound) Decode(into mapdecode.Into) error {
 
 	return nil
 }
+
+// _splitOutboundType is the outbound type of outbounds which split traffic
+// between weighted child outbounds.
+const _splitOutboundType = "split"
+
+// splitOutbound is the configuration of an outbound which splits traffic
+// between weighted child outbounds.
+//
+// 	split:
+// 	  outbounds:
+// 	    - name: tchannel
+// 	      weight: 90
+// 	      tchannel:
+// 	        peer: 127.0.0.1:4040
+// 	    - name: grpc
+// 	      weight: 10
+// 	      grpc:
+// 	        peer: 127.0.0.1:5050
+type splitOutbound struct {
+	Outbounds []splitChild `config:"outbounds"`
+}
+
+type splitChild struct {
+	Name     string
+	Weight   int
+	Outbound outbound
+}
+
+func (c *splitChild) Decode(into mapdecode.Into) error {
+	var attrs config.AttributeMap
+	if err := into(&attrs); err != nil {
+		return fmt.Errorf("failed to decode split outbound: %v", err)
+	}
+
+	var err error
+	c.Name, err = attrs.PopString("name")
+	if err != nil {
+		return fmt.Errorf(`failed to read attribute "name" of split outbound: %v`, err)
+	}
+	if _, err := attrs.Pop("weight", &c.Weight); err != nil {
+		return fmt.Errorf(`failed to read attribute "weight" of split outbound: %v`, err)
+	}
+	if err := attrs.Decode(&c.Outbound); err != nil {
+		return err
+	}
+
+	if c.Name == "" {
+		c.Name = c.Outbound.Type
+	}
+	return nil
+}
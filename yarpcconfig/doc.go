@@ -188,6 +188,65 @@
 // 	  oneway:
 // 	    # ...
 //
+// Traffic may be split between several outbounds with the 'split' key, for
+// example to gradually move traffic from one transport to another. Each
+// request is sent to one of the listed outbounds, in proportion to their
+// weights. Requests with a shard key always go to the same outbound for as
+// long as the weights do not change.
+//
+// 	keyvalue:
+// 	  split:
+// 	    outbounds:
+// 	      - name: tchannel
+// 	        weight: 90
+// 	        tchannel:
+// 	          peer: 127.0.0.1:4040
+// 	      - name: grpc
+// 	        weight: 10
+// 	        grpc:
+// 	          peer: 127.0.0.1:5050
+//
+// The name of an outbound defaults to its transport. When used without the
+// 'unary' or 'oneway' keys, a split outbound is built for the RPC types
+// supported by all the listed transports. Weights may be changed at runtime
+// with the SetWeights method of the outbounds from the
+// go.uber.org/yarpc/transport/x/split package.
+//
+// TTL Configuration
+//
+// The 'ttl' key of an outbound sets the default TTL of its unary requests
+// made without a deadline, and their maximum TTL. Requests with a later
+// deadline are sent with the maximum TTL instead. TTLs may be set for
+// specific procedures.
+//
+// 	keyvalue:
+// 	  ttl:
+// 	    default: 500ms
+// 	    max: 2s
+// 	    procedures:
+// 	      scan:
+// 	        default: 5s
+// 	        max: 30s
+// 	  http:
+// 	    url: http://127.0.0.1:8080/
+//
+// The top-level 'ttls' key sets the TTLs of all outbound requests, and of
+// the requests sent to specific services.
+//
+// 	ttls:
+// 	  default: 1s
+// 	  max: 10s
+// 	  services:
+// 	    keyvalue:
+// 	      procedures:
+// 	        scan:
+// 	          max: 1m
+//
+// Each of the default and maximum TTLs of a request is taken from the most
+// specific configuration which sets it, in order: the procedure of the
+// service, the procedure of the outbound, the service, the outbound, and
+// the top-level configuration.
+//
 // Peer Configuration
 //
 // Transports that support peer management and selection through YARPC accept
@@ -230,6 +289,26 @@
 // 	    url: https://host/yarpc
 // 	    with: dev-proxy
 //
+// Multiple peer choosers may be combined with the `failover` key. Requests
+// are sent to the first chooser that is healthy, falling back to the next
+// one when a chooser has no available peers or fails too many requests.
+//
+// 	keyvalue:
+// 	  http:
+// 	    url: https://host/yarpc
+// 	    failover:
+// 	      failbackAfter: 30s
+// 	      choosers:
+// 	        - round-robin:
+// 	            peers:
+// 	              - 127.0.0.1:8080
+// 	        - round-robin:
+// 	            peers:
+// 	              - 10.0.1.1:8080
+// 	              - 10.0.1.2:8080
+//
+// See the PeerChooser documentation for all the failover options.
+//
 // Transport Configuration
 //
 // The 'transports' attribute configures the Transport objects that are shared